@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	_ "time/tzdata" // The runtime image has no time zone database, and TIMEZONE and USER_TIMEZONES need one
+
 	"github.com/mcncl/snagbot/internal/app"
 	"github.com/mcncl/snagbot/internal/logging"
 )
@@ -8,6 +11,16 @@ import (
 func main() {
 	// Initialize logging
 	logging.SetGlobalLevel(logging.INFO)
+	format, err := logging.ParseFormat(os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		logging.Warn("Invalid LOG_FORMAT, using text: %v", err)
+	}
+	logging.SetGlobalFormat(format)
+	sampling, err := logging.ParseDebugSampling(os.Getenv("LOG_DEBUG_SAMPLING"))
+	if err != nil {
+		logging.Warn("Invalid LOG_DEBUG_SAMPLING, logging every debug message: %v", err)
+	}
+	logging.SetGlobalDebugSampling(sampling)
 	logging.Info("Starting SnagBot...")
 
 	// Create and run the application
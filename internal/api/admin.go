@@ -0,0 +1,220 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/slack"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// ConfigExportResponse holds every custom channel configuration, keyed by channel ID
+type ConfigExportResponse struct {
+	Configs map[string]models.ChannelConfig `json:"configs"`
+}
+
+// maxAdminBodySize limits how much of an admin request body is read
+const maxAdminBodySize = 1 << 20
+
+// ResetChannelRequest is the body of a request to reset a channel's configuration
+type ResetChannelRequest struct {
+	ChannelID string `json:"channel_id"`
+}
+
+// ToggleRequest is the optional body of a request to turn SnagBot's responses on or off, flipping
+// them when Enabled is missing
+type ToggleRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// ToggleResponse reports whether SnagBot's responses are enabled after a toggle
+type ToggleResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+// ReloadResponse reports the reloadable settings now in use after a config reload
+type ReloadResponse struct {
+	DefaultItemName  string  `json:"default_item_name"`
+	DefaultItemPrice float64 `json:"default_item_price"`
+	LogLevel         string  `json:"log_level"`
+}
+
+// requireAdminToken only lets requests through that carry the configured admin token, or an
+// unexpired admin JWT signed with the JWT secret, as a bearer token. Without either configured
+// the admin endpoints don't exist.
+func requireAdminToken(cfg *config.Config, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jwtEnabled := adminJWTEnabled(cfg)
+		if cfg.AdminToken == "" && !jwtEnabled {
+			http.NotFound(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if cfg.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) == 1 {
+			next(w, r)
+			return
+		}
+
+		if !jwtEnabled {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := ValidateAdminToken(cfg.JWTSecret, token); err != nil {
+			log.Printf("Rejected admin token: %v", err)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// exportConfigsHandler returns every custom channel configuration in the store
+func exportConfigsHandler(configStore slack.ChannelConfigStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		configs, err := configStore.GetAllConfigs()
+		if err != nil {
+			log.Printf("Error exporting channel configs: %v", err)
+			http.Error(w, "Failed to export channel configurations", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(ConfigExportResponse{Configs: configs}); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// resetChannelHandler resets a channel to the default configuration and returns the config it now uses
+func resetChannelHandler(configStore slack.ChannelConfigStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request ResetChannelRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxAdminBodySize)).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if request.ChannelID == "" {
+			http.Error(w, "channel_id is required", http.StatusBadRequest)
+			return
+		}
+
+		// There's no Slack user behind an admin reset
+		if err := configStore.ResetConfig(request.ChannelID, ""); err != nil {
+			log.Printf("Error resetting config for channel %s: %v", request.ChannelID, err)
+			http.Error(w, "Failed to reset channel configuration", http.StatusInternalServerError)
+			return
+		}
+
+		config, err := configStore.GetConfig(request.ChannelID)
+		if err != nil {
+			log.Printf("Error getting config after reset: %v", err)
+			http.Error(w, "Failed to get channel configuration", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Admin reset the configuration for channel %s", request.ChannelID)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(config); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// reloadHandler re-reads the environment and applies the default item and log level to the live
+// config, so they change without a redeploy
+func reloadHandler(cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cfg.Reload(config.New())
+
+		itemName, itemPrice := cfg.DefaultItem()
+		level := strings.ToLower(logging.GlobalLevel().String())
+		log.Printf("Admin reloaded the configuration, default item %s at $%.2f, log level %s", itemName, itemPrice, level)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		response := ReloadResponse{
+			DefaultItemName:  itemName,
+			DefaultItemPrice: itemPrice,
+			LogLevel:         level,
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// toggleHandler turns SnagBot's responses on or off at runtime, the kill switch for incidents
+func toggleHandler(responses *slack.KillSwitch) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request ToggleRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxAdminBodySize)).Decode(&request); err != nil && err != io.EOF {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var enabled bool
+		if request.Enabled != nil {
+			enabled = *request.Enabled
+			responses.Set(enabled)
+		} else {
+			enabled = responses.Toggle()
+		}
+
+		if enabled {
+			log.Printf("Admin enabled SnagBot's responses")
+		} else {
+			log.Printf("Admin disabled SnagBot's responses")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(ToggleResponse{Enabled: enabled}); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
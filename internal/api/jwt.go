@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+)
+
+// Errors returned when an admin token is rejected
+var (
+	// ErrMalformedToken is returned when a token isn't an HS256 JWT with an expiry
+	ErrMalformedToken = errors.New("malformed admin token")
+
+	// ErrInvalidTokenSignature is returned when a token wasn't signed with the JWT secret
+	ErrInvalidTokenSignature = errors.New("invalid admin token signature")
+
+	// ErrTokenExpired is returned when a token's expiry has passed
+	ErrTokenExpired = errors.New("admin token has expired")
+)
+
+// adminTokenSubject identifies the tokens issued for the admin endpoints
+const adminTokenSubject = "snagbot-admin"
+
+// jwtHeader is the header of an HS256 JWT
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// adminClaims are the claims an admin token carries
+type adminClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// GenerateAdminToken issues a JWT for the admin endpoints, signed with HS256 using the secret
+// and expiring after ttl
+func GenerateAdminToken(secret string, ttl time.Duration) (string, error) {
+	if secret == "" {
+		return "", errors.New("a JWT secret is required to issue admin tokens")
+	}
+
+	header, err := json.Marshal(jwtHeader{Algorithm: "HS256", Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims, err := json.Marshal(adminClaims{
+		Subject:   adminTokenSubject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + signJWT(secret, unsigned), nil
+}
+
+// ValidateAdminToken checks that an admin token was signed with the secret and hasn't expired
+func ValidateAdminToken(secret, token string) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrMalformedToken
+	}
+
+	// Only HS256 is accepted, so a token can't opt out of being signed with "none"
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return ErrMalformedToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrMalformedToken
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(signJWT(secret, parts[0]+"."+parts[1]))
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidTokenSignature
+	}
+
+	var claims adminClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil || claims.ExpiresAt == 0 {
+		return ErrMalformedToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return ErrTokenExpired
+	}
+
+	return nil
+}
+
+// adminJWTEnabled reports whether admin tokens can be used, which needs JWT_SECRET to have been set
+// The placeholder secret is public, so tokens signed with it are never trusted
+func adminJWTEnabled(cfg *config.Config) bool {
+	return cfg.JWTSecret != "" && cfg.JWTSecret != config.DefaultJWTSecret
+}
+
+// signJWT returns the base64url encoded HS256 signature of the token's header and claims
+func signJWT(secret, unsigned string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// decodeJWTPart decodes a base64url encoded JSON part of a token
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/mcncl/snagbot/internal/calculator"
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/slack"
+)
+
+// maxPreviewBodySize limits how much of a preview request body is read
+const maxPreviewBodySize = 1 << 20
+
+// PreviewRequest is the body of a preview request
+type PreviewRequest struct {
+	ChannelID string `json:"channel_id"`
+	TeamID    string `json:"team_id"` // Workspace the channel belongs to, needed in multi-workspace mode
+	Text      string `json:"text"`
+}
+
+// PreviewResponse holds the reply SnagBot would post, empty if it would stay quiet
+type PreviewResponse struct {
+	Response string `json:"response"`
+}
+
+// previewHandler shows what SnagBot would reply to a message in a channel without posting to Slack,
+// reading the channel's config the way the event handler does for the request's workspace
+func previewHandler(cfg *config.Config, configStore slack.ChannelConfigStore) http.HandlerFunc {
+	amounts := calculator.NewOptions(cfg)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request PreviewRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxPreviewBodySize)).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if request.ChannelID == "" {
+			http.Error(w, "channel_id is required", http.StatusBadRequest)
+			return
+		}
+
+		store := slack.ForWorkspace(slack.WithContext(r.Context(), configStore), request.TeamID)
+		config, err := store.GetConfig(request.ChannelID)
+		if err != nil {
+			log.Printf("Error getting config for preview: %v", err)
+			http.Error(w, "Failed to get channel configuration", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		response := PreviewResponse{
+			Response: calculator.ProcessMessageWithOptions(request.Text, config, amounts),
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
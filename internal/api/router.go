@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/mcncl/snagbot/internal/command"
 	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/metrics"
 	"github.com/mcncl/snagbot/internal/slack"
 )
 
@@ -18,31 +21,106 @@ type Response struct {
 
 // SetupSimpleRouter creates a simple HTTP router without using the mux package
 func SetupSimpleRouter(cfg *config.Config) http.Handler {
+	return SetupRouterWithStore(cfg, slack.NewInMemoryConfigStoreWithConfig(cfg))
+}
+
+// SetupRouterWithStore creates the HTTP router, sharing one config store between
+// the event and command handlers
+func SetupRouterWithStore(cfg *config.Config, configStore slack.ChannelConfigStore) http.Handler {
+	return SetupRouterWithContext(context.Background(), nil, cfg, configStore)
+}
+
+// SetupRouterWithContext creates the HTTP router like SetupRouterWithStore, tracking Slack
+// events being processed in events and abandoning them once ctx is cancelled
+func SetupRouterWithContext(ctx context.Context, events *slack.EventGroup, cfg *config.Config, configStore slack.ChannelConfigStore) http.Handler {
 	mux := http.NewServeMux()
 
-	// Health check endpoint
-	mux.HandleFunc("/health", healthCheckHandler)
+	// Health check endpoint, kept for monitors set up before the liveness and readiness split
+	mux.HandleFunc("/health", healthCheckHandler(configStore))
+
+	// Liveness and readiness endpoints, for orchestrators like Kubernetes
+	mux.HandleFunc("/healthz", livenessHandler)
+	mux.HandleFunc("/readyz", healthCheckHandler(configStore))
 
 	// Hello world endpoint
 	mux.HandleFunc("/hello", helloWorldHandler)
 
+	// Prometheus metrics endpoint
+	mux.Handle("/metrics", metrics.Default().Handler())
+
 	// Debug endpoint - REMOVE IN PRODUCTION
 	mux.HandleFunc("/debug", slack.DebugHandler(cfg))
 
+	// Kill switch for replies and reactions, shared by the event handler and the admin toggle
+	responses := slack.NewKillSwitch(cfg.Enabled)
+
 	// Slack event endpoint
-	mux.HandleFunc("/api/events", slack.EventHandler(cfg))
+	mux.HandleFunc("/api/events", slack.EventHandlerWithContext(ctx, events, cfg, configStore, responses))
+
+	// Slack interactivity endpoint, for the "Change item" button and modal
+	mux.HandleFunc("/api/interactions", slack.InteractionHandler(cfg, configStore, slack.NewRealSlackAPI(cfg.SlackBotToken)))
 
 	// Slack command endpoint
-	mux.HandleFunc("/api/commands", command.CommandHandler(cfg))
+	mux.HandleFunc("/api/commands", command.CommandHandlerWithStore(cfg, configStore))
+
+	// Response preview endpoint, never posts to Slack, behind the admin token as it reads channel configs
+	mux.HandleFunc("/api/preview", requireAdminToken(cfg, previewHandler(cfg, configStore)))
+
+	// Admin endpoints, only usable when an admin token is configured
+	mux.HandleFunc("/api/admin/configs", requireAdminToken(cfg, exportConfigsHandler(configStore)))
+	mux.HandleFunc("/api/admin/reset", requireAdminToken(cfg, resetChannelHandler(configStore)))
+	mux.HandleFunc("/api/admin/toggle", requireAdminToken(cfg, toggleHandler(responses)))
+	mux.HandleFunc("/api/admin/reload", requireAdminToken(cfg, reloadHandler(cfg)))
 
 	// Log available routes
-	log.Printf("Available routes: /health, /hello, /debug, /api/events, /api/commands")
+	log.Printf("Available routes: /health, /healthz, /readyz, /hello, /metrics, /debug, /api/events, /api/interactions, /api/commands, /api/preview, /api/admin/configs, /api/admin/reset, /api/admin/toggle, /api/admin/reload")
 
 	return mux
 }
 
-// healthCheckHandler is a simple health check endpoint
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+// healthCheckTimeout bounds how long the health check waits for the config store
+const healthCheckTimeout = 2 * time.Second
+
+// healthCheckHandler reports whether SnagBot and its config store are reachable, serving both
+// /health and the /readyz readiness check
+// Returns 503 with a "degraded" status when the store can't be reached
+func healthCheckHandler(configStore slack.ChannelConfigStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		response := Response{
+			Message: "Snags are cooking 🌭",
+			Status:  "OK",
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		if err := configStore.Ping(ctx); err != nil {
+			log.Printf("Health check failed: %v", err)
+			response = Response{
+				Message: "Config store is unreachable",
+				Status:  "degraded",
+			}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Error encoding response: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// livenessHandler reports that the process is up and serving requests, whatever the state of the
+// config store, so a store outage doesn't get SnagBot restarted
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -13,13 +13,22 @@ import (
 	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/slack"
+	goslack "github.com/slack-go/slack"
 )
 
 // Application represents the main application
 type Application struct {
-	Config     *config.Config
-	HttpServer *http.Server
-	Router     http.Handler
+	Config      *config.Config
+	HttpServer  *http.Server
+	Router      http.Handler
+	ConfigStore slack.ChannelConfigStore
+
+	// events tracks Slack events being processed, so shutdown can wait for them
+	events *slack.EventGroup
+
+	// stopEvents cancels Slack events still being processed when the server shuts down
+	stopEvents context.CancelFunc
 }
 
 // New creates a new Application instance
@@ -33,9 +42,33 @@ func New() (*Application, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to load configuration")
 	}
+	logging.SetGlobalLevel(cfg.LogLevel)
+
+	// Catch a mistyped token now rather than at the first failed post
+	verifier := slack.NewRealSlackAPI(cfg.SlackBotToken)
+	identity, err := checkSlackToken(cfg.SlackTokenCheck, verifier)
+	if err != nil {
+		return nil, err
+	}
+
+	// Never respond to SnagBot's own messages, and only to other bots' when configured
+	recogniseOwnMessages(cfg, identity, verifier)
+
+	// The router starts with responses switched on or off as configured
+	if !cfg.Enabled {
+		logging.Warn("SNAGBOT_ENABLED is false, SnagBot won't respond until re-enabled")
+	}
+
+	// Create the channel config store shared by all handlers
+	configStore, err := slack.NewConfigStore(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	// Set up routes
-	router := api.SetupSimpleRouter(cfg)
+	// Set up routes, with events processed until the application shuts down
+	events := slack.NewEventGroup(cfg.EventWorkers, cfg.EventQueueSize)
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	router := api.SetupRouterWithContext(eventsCtx, events, cfg, configStore)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -47,9 +80,12 @@ func New() (*Application, error) {
 	}
 
 	app := &Application{
-		Config:     cfg,
-		HttpServer: server,
-		Router:     router,
+		Config:      cfg,
+		HttpServer:  server,
+		Router:      router,
+		ConfigStore: configStore,
+		events:      events,
+		stopEvents:  stopEvents,
 	}
 
 	return app, nil
@@ -71,10 +107,62 @@ func loadConfig() (*config.Config, error) {
 	}
 
 	logging.Info("Configuration loaded successfully")
-	logging.Debug("Using default item: %s at $%.2f", cfg.DefaultItemName, cfg.DefaultItemPrice)
+	itemName, itemPrice := cfg.DefaultItem()
+	logging.Debug("Using default item: %s at $%.2f", itemName, itemPrice)
 	return cfg, nil
 }
 
+// slackTokenCheckTimeout bounds the startup call to Slack's auth.test
+const slackTokenCheckTimeout = 10 * time.Second
+
+// checkSlackToken asks Slack who the bot token belongs to, logging the bot's identity. When Slack
+// rejects the token it logs a warning, or returns an error if mode is config.TokenCheckFail
+// Returns the bot's identity, or nil when it wasn't checked or Slack rejected the token
+func checkSlackToken(mode string, verifier slack.TokenVerifier) (*goslack.AuthTestResponse, error) {
+	if mode == "" || mode == config.TokenCheckOff {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), slackTokenCheckTimeout)
+	defer cancel()
+
+	identity, err := verifier.VerifyToken(ctx)
+	if err != nil {
+		if mode == config.TokenCheckFail {
+			return nil, errors.Wrap(err, "Slack rejected the bot token")
+		}
+		logging.Warn("Slack rejected the bot token, responses will fail until it's fixed: %v", err)
+		return nil, nil
+	}
+
+	logging.Info("Slack bot token belongs to %s (%s) in workspace %s (%s)", identity.User, identity.UserID, identity.Team, identity.TeamID)
+	return identity, nil
+}
+
+// recogniseOwnMessages fills in which bot is SnagBot on cfg from the token check, where config
+// didn't set it, so message processing can skip SnagBot's own messages. Responding to other bots
+// needs SnagBot's identity, so it's looked up with auth.test when neither gave it
+func recogniseOwnMessages(cfg *config.Config, identity *goslack.AuthTestResponse, verifier slack.TokenVerifier) {
+	userID, botID := cfg.SlackBotUserID, cfg.SlackBotID
+	respond := cfg.BotMessages == config.BotMessagesRespond
+	if respond && identity == nil && userID == "" && botID == "" {
+		identity, _ = checkSlackToken(config.TokenCheckWarn, verifier)
+	}
+	if identity != nil {
+		if userID == "" {
+			userID = identity.UserID
+		}
+		if botID == "" {
+			botID = identity.BotID
+		}
+	}
+
+	cfg.SlackBotUserID, cfg.SlackBotID = userID, botID
+	if respond && userID == "" && botID == "" {
+		logging.Warn("BOT_MESSAGES is respond but SnagBot's own bot ID isn't known, still ignoring bot messages")
+	}
+}
+
 // Start starts the application
 func (a *Application) Start() error {
 	logging.Info("Starting SnagBot on port %s", a.Config.Port)
@@ -110,9 +198,36 @@ func (a *Application) WaitForShutdown() {
 		logging.Error("Server forced to shutdown: %v", err)
 	}
 
+	a.drainEvents(ctx)
+	a.closeStores()
+
 	logging.Info("Server exited properly")
 }
 
+// drainEvents waits for Slack events still being processed, so in-flight responses get posted,
+// until ctx is done. Call it once the server has stopped accepting requests
+func (a *Application) drainEvents(ctx context.Context) {
+	if err := a.events.Wait(ctx); err != nil {
+		logging.Warn("Stopped waiting for Slack events still being processed: %v", err)
+	}
+}
+
+// closeStores stops any events still being processed and releases the connections
+// held by the application's stores
+func (a *Application) closeStores() {
+	if a.stopEvents != nil {
+		a.stopEvents()
+	}
+
+	if a.ConfigStore == nil {
+		return
+	}
+
+	if err := a.ConfigStore.Close(); err != nil {
+		logging.Error("Failed to close config store: %v", err)
+	}
+}
+
 // Run is a convenience function that starts the application and waits for shutdown
 func (a *Application) Run() error {
 	if err := a.Start(); err != nil {
@@ -145,9 +260,13 @@ func (a *Application) RunWithContext(ctx context.Context) error {
 
 	// Shutdown server
 	if err := a.HttpServer.Shutdown(shutdownCtx); err != nil {
+		a.closeStores()
 		return fmt.Errorf("server forced to shutdown: %v", err)
 	}
 
+	a.drainEvents(shutdownCtx)
+	a.closeStores()
+
 	logging.Info("Server exited properly")
 	return nil
 }
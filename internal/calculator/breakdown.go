@@ -0,0 +1,76 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// FormatResponseWithBreakdown formats the response like FormatResponseWithConfig, showing how the
+// amounts add up when the channel has turned breakdowns on and the message had more than one
+// Custom templates, the "too small" response and capped counts are left as they are
+func FormatResponseWithBreakdown(count int, values []float64, total float64, isExactDivision bool, config *models.ChannelConfig, opts *Options) string {
+	if !config.ShowBreakdown || count <= 0 || count > config.ItemCountLimit() || config.ResponseTemplate != "" {
+		return FormatResponseWithOptions(count, total, isExactDivision, config, opts)
+	}
+
+	// Show the amounts as they were counted, so ignored negatives don't appear
+	policy, err := ParseNegativeHandling(config.NegativeHandling)
+	if err != nil {
+		logging.Warn("Invalid negative handling for channel %s, including negatives: %v", config.ChannelID, err)
+	}
+	counted := countedValues(values, policy)
+	if len(counted) < 2 {
+		return FormatResponseWithOptions(count, total, isExactDivision, config, opts)
+	}
+
+	phrases := config.ResponsePhrases()
+	return phrases.Prefix + FormatBreakdown(counted, total, config.CurrencySymbol()) + ", " +
+		countPhraseWithConfig(count, isExactDivision, config) + phrases.Suffix
+}
+
+// FormatBreakdown shows how amounts add up to the total, like "$35 + $15 = $50" or "$100 - $35 = $65"
+func FormatBreakdown(values []float64, total float64, currency string) string {
+	var b strings.Builder
+	for i, value := range values {
+		switch {
+		case i == 0 && value < 0:
+			b.WriteString("-")
+		case i > 0 && value < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+		b.WriteString(formatAmount(math.Abs(value), currency))
+	}
+	b.WriteString(" = " + formatAmount(total, currency))
+	return b.String()
+}
+
+// countedValues returns the amounts that count towards the total under the policy
+func countedValues(values []float64, policy NegativeHandling) []float64 {
+	counted := make([]float64, 0, len(values))
+	for _, value := range values {
+		if value < 0 {
+			switch policy {
+			case NegativeIgnore:
+				continue
+			case NegativeAbsolute:
+				value = -value
+			}
+		}
+		counted = append(counted, value)
+	}
+	return counted
+}
+
+// formatAmount formats an amount with the currency symbol, dropping the cents from whole amounts
+func formatAmount(value float64, currency string) string {
+	if value == math.Trunc(value) {
+		return fmt.Sprintf("%s%.0f", currency, value)
+	}
+	return fmt.Sprintf("%s%.2f", currency, value)
+}
@@ -1,49 +1,204 @@
 package calculator
 
 import (
+	"fmt"
 	"math"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
 	"github.com/mcncl/snagbot/pkg/models"
 )
 
+// DefaultCurrencySymbol is the symbol matched when no override is configured
+const DefaultCurrencySymbol = "$"
+
 // ExtractDollarValues extracts all dollar values from a string
-// Matches patterns like $35, $35.00, etc.
-func ExtractDollarValues(text string) ([]float64, error) {
+// Matches patterns like $35, $35.00, etc., and negative amounts written -$35 or $-35
+// An optional set of currency symbols (e.g. "€", "£") can be provided to match
+// instead of the default dollar sign
+func ExtractDollarValues(text string, currencySymbols ...string) ([]float64, error) {
+	return ExtractDollarValuesWithLimit(text, 0, currencySymbols...)
+}
+
+// ExtractDollarValuesWithLimit extracts dollar values like ExtractDollarValues, stopping after limit values
+// If the text has more, the first limit values are returned together with an
+// ErrTooManyDollarValues error so the caller can decide whether to use them
+// A limit of zero or less extracts every value
+func ExtractDollarValuesWithLimit(text string, limit int, currencySymbols ...string) ([]float64, error) {
+	return ExtractDollarValuesWithFormat(text, limit, models.NumberFormatPoint, currencySymbols...)
+}
+
+// ExtractDollarValuesWithFormat extracts dollar values like ExtractDollarValuesWithLimit, reading amounts
+// written in the number format: models.NumberFormatPoint like $1,234.50, or models.NumberFormatComma
+// like $1.234,50, where $3.50 is still read as three-fifty
+func ExtractDollarValuesWithFormat(text string, limit int, numberFormat string, currencySymbols ...string) ([]float64, error) {
+	return extractDollarValues(text, extractOptions{
+		limit:           limit,
+		numberFormat:    numberFormat,
+		currencySymbols: currencySymbols,
+	})
+}
+
+// Options holds the deployment's settings for reading amounts and picking items, built from
+// config by NewOptions
+// A nil *Options reads amounts without converting other currencies, with DefaultMoneyWords
+type Options struct {
+	exchange   *exchange       // Converts amounts in other currencies, nil while they aren't converted
+	moneyWords map[string]bool // Words after an amount that mark it as money, nil for DefaultMoneyWords
+	random     *random         // Picks items and wordings, nil for a source seeded at startup
+}
+
+// NewOptions returns the options for reading amounts set by cfg, converting amounts in other
+// currencies to FX_CURRENCY when FX_RATES has rates for them, and recognising the
+// MONEY_WORDS in channels that read amounts in words
+func NewOptions(cfg *config.Config) *Options {
+	if cfg == nil {
+		return nil
+	}
+
+	opts := &Options{moneyWords: moneyWordSet(cfg.MoneyWords)}
+	if cfg.ExchangeCurrency != "" && len(cfg.ExchangeRates) > 0 {
+		opts.exchange = newExchange(NewStaticRates(cfg.ExchangeCurrency, cfg.ExchangeRates), cfg.ExchangeCurrency)
+	}
+	return opts
+}
+
+// extractOptions controls how amounts are read from a message
+type extractOptions struct {
+	limit           int             // Most values extracted, zero or less for all of them
+	numberFormat    string          // models.NumberFormatPoint or models.NumberFormatComma
+	keepRepeats     bool            // Count every occurrence of an amount, not just the first
+	moneyWords      bool            // Also read numbers followed by a money word, like "50 bucks"
+	moneyWordSet    map[string]bool // The money words, nil for DefaultMoneyWords
+	exchange        *exchange       // Converts amounts in other currencies, nil while they aren't converted
+	currencySymbols []string
+}
+
+// extractOptionsFor returns the options for reading amounts from a channel's messages
+func extractOptionsFor(config *models.ChannelConfig, opts *Options) extractOptions {
+	extract := extractOptions{
+		limit:           config.DollarValueLimit(),
+		numberFormat:    config.NumberFormat,
+		keepRepeats:     config.CountRepeats,
+		moneyWords:      config.WordAmounts,
+		currencySymbols: config.CurrencySymbols,
+	}
+	if opts != nil {
+		extract.moneyWordSet = opts.moneyWords
+		extract.exchange = opts.exchange
+	}
+	return extract
+}
+
+// extractDollarValues extracts the values of the amounts in text
+func extractDollarValues(text string, opts extractOptions) ([]float64, error) {
+	amounts, err := extractDollarAmounts(text, opts)
+	values := make([]float64, len(amounts))
+	for i, amount := range amounts {
+		values[i] = amount.value
+	}
+	return values, err
+}
+
+// dollarAmount is a value extracted from a message and where its match sits in the text
+// When amounts are converted, value is in the converted currency and
+// currency is the one the amount was written in
+type dollarAmount struct {
+	value      float64
+	start, end int
+	symbol     string
+	currency   string
+}
+
+// amountMatch is where the parts of an amount sit in a message, with -1 for the parts it doesn't have
+type amountMatch struct {
+	start, end             int
+	minusStart             int // A minus before the symbol, or before the number of a money word amount
+	symbolStart, symbolEnd int
+	minusAfterSymbol       bool
+	numberStart, numberEnd int
+	checkGrouping          bool // Whether the text after the match could continue the number
+}
+
+// extractDollarAmounts does the work of ExtractDollarValuesWithLimit, keeping each value's position
+// so the words around it can be checked
+// With opts.moneyWords, numbers followed by a money word are read too, unless they're part of an
+// amount with a currency symbol like "$35 dollars"
+// Repeats of an amount, like "$35 and another $35", are skipped unless opts.keepRepeats is set
+// Matches never overlap, so a kept repeat is always a separate amount in the text rather than
+// part of one already counted, like the ".25" of "$35.50.25"
+func extractDollarAmounts(text string, opts extractOptions) ([]dollarAmount, error) {
 	if text == "" {
 		logging.Debug("Empty text provided to ExtractDollarValues")
-		return []float64{}, nil
+		return []dollarAmount{}, nil
 	}
 
 	// Regular expression to match dollar values
-	// Handles both whole numbers and decimal values (up to 2 decimal places)
-	re := regexp.MustCompile(`\$([0-9]+(\.[0-9]{1,2})?)`)
-	matches := re.FindAllStringSubmatch(text, -1)
+	// Handles both whole numbers and decimal values (up to 2 decimal places),
+	// with optional grouped thousands like $1,250.50, or $1.250,50 for the comma number format
+	// Amounts in other currencies are matched too when they're converted
+	decimalComma := opts.numberFormat == models.NumberFormatComma
+	symbols := opts.currencySymbols
+	fx := opts.exchange
+	if fx != nil {
+		symbols = fx.symbols(symbols)
+	}
+	matches := symbolMatches(text, currencyRegex(symbols, decimalComma))
+	if opts.moneyWords {
+		matches = append(matches, moneyWordMatches(text, moneyWordRegex(opts.moneyWordSet, decimalComma), matches)...)
+		sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	}
 
 	// Process the matches to filter out duplicates
 	var seen = make(map[string]bool)
-	values := make([]float64, 0, len(matches))
+	values := make([]dollarAmount, 0, len(matches))
 	invalidValues := make([]string, 0)
 
 	for _, match := range matches {
-		if len(match) >= 2 {
-			// Use the whole match as key to avoid duplicates
-			if !seen[match[0]] {
-				seen[match[0]] = true
-
-				// Parse the value (without the $ symbol)
-				value, err := strconv.ParseFloat(match[1], 64)
-				if err == nil {
-					values = append(values, value)
-				} else {
-					invalidValues = append(invalidValues, match[1])
-					logging.Warn("Failed to parse dollar value: %s, error: %v", match[1], err)
-				}
+		// Reject malformed groupings like $1,00,0 or $1,2,3 rather than
+		// silently summing the leading digits
+		if match.checkGrouping && isMalformedGrouping(text[match.numberStart:match.numberEnd], text[match.end:], decimalComma) {
+			logging.Debug("Skipping malformed thousands grouping: %s", text[match.start:])
+			continue
+		}
+
+		symbol := text[match.symbolStart:match.symbolEnd]
+		currency := ""
+		if fx != nil {
+			currency = fx.currencyOf(symbol, text[match.end:])
+		}
+
+		// Use the whole match as key to avoid duplicates, with its currency so $10 USD isn't $10
+		whole := text[match.start:match.end] + currency
+		if seen[whole] && !opts.keepRepeats {
+			continue
+		}
+		seen[whole] = true
+
+		if limit := opts.limit; limit > 0 && len(values) >= limit {
+			logging.Warn("Message has more than %d dollar values, only counting the first %d", limit, limit)
+			return values, errors.Newf(errors.ErrTooManyDollarValues, "more than %d dollar values", limit)
+		}
+
+		// Parse the value (without the $ symbol or thousands separators)
+		amount := amountDigits(text[match.numberStart:match.numberEnd])
+		if match.minusAfterSymbol || (match.minusStart >= 0 && isSign(text, match.minusStart)) {
+			amount = "-" + amount
+		}
+		value, err := strconv.ParseFloat(amount, 64)
+		if err == nil {
+			if fx != nil {
+				value = fx.convert(value, currency)
 			}
+			values = append(values, dollarAmount{value: value, start: match.start, end: match.end, symbol: symbol, currency: currency})
+		} else {
+			invalidValues = append(invalidValues, amount)
+			logging.Warn("Failed to parse dollar value: %s, error: %v", amount, err)
 		}
 	}
 
@@ -56,33 +211,165 @@ func ExtractDollarValues(text string) ([]float64, error) {
 	return values, nil
 }
 
-// SumDollarValues sums an array of dollar values
-// Returns the total with 2 decimal place precision
-func SumDollarValues(values []float64) (float64, error) {
-	if len(values) == 0 {
-		logging.Debug("Empty array provided to SumDollarValues")
-		return 0, nil
+// symbolMatches finds the amounts written with a currency symbol, using a regex from currencyRegex
+func symbolMatches(text string, re *regexp.Regexp) []amountMatch {
+	var matches []amountMatch
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		if len(loc) < 8 {
+			continue
+		}
+
+		// The symbol sits between any leading minus and the number or a minus after it
+		match := amountMatch{
+			start:            loc[0],
+			end:              loc[1],
+			minusStart:       loc[2],
+			symbolStart:      loc[0],
+			symbolEnd:        loc[6],
+			minusAfterSymbol: loc[4] >= 0,
+			numberStart:      loc[6],
+			numberEnd:        loc[7],
+			checkGrouping:    true,
+		}
+		if loc[2] >= 0 {
+			match.symbolStart = loc[3]
+		}
+		if loc[4] >= 0 {
+			match.symbolEnd = loc[4]
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+// moneyWordMatches finds the numbers followed by a money word, using a regex from moneyWordRegex
+// Numbers overlapping an amount with a symbol, or following a separator like the "0" of "1,00,0 bucks", are skipped
+func moneyWordMatches(text string, re *regexp.Regexp, symbolAmounts []amountMatch) []amountMatch {
+	var matches []amountMatch
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		if len(loc) < 6 {
+			continue
+		}
+		if loc[4] > 0 && strings.ContainsRune(".,", rune(text[loc[4]-1])) {
+			continue
+		}
+		if overlapsAny(loc[0], loc[1], symbolAmounts) {
+			continue
+		}
+
+		matches = append(matches, amountMatch{
+			start:       loc[0],
+			end:         loc[1],
+			minusStart:  loc[2],
+			symbolStart: loc[4],
+			symbolEnd:   loc[4],
+			numberStart: loc[4],
+			numberEnd:   loc[5],
+		})
 	}
+	return matches
+}
 
-	var total float64
-	for i, value := range values {
-		// Check for negative values, which might be a mistake
-		if value < 0 {
-			logging.Warn("Negative dollar value found at index %d: %.2f", i, value)
+// overlapsAny reports whether the text from start to end overlaps any of the matches
+func overlapsAny(start, end int, matches []amountMatch) bool {
+	for _, match := range matches {
+		if start < match.end && match.start < end {
+			return true
 		}
-		total += value
 	}
+	return false
+}
 
-	// Round to 2 decimal places for currency precision
-	total = math.Round(total*100) / 100
+// IsTooManyDollarValues reports whether an error from ExtractDollarValuesWithLimit means the
+// message had more values than the limit, rather than that extraction failed
+func IsTooManyDollarValues(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Is(errors.ErrTooManyDollarValues)
+}
 
-	logging.Debug("Summed %d dollar values to get %.2f", len(values), total)
-	return total, nil
+// FormatTruncatedNote explains that only the first limit amounts in a message were counted
+func FormatTruncatedNote(limit int) string {
+	return fmt.Sprintf(" (Only the first %d amounts were counted.)", limit)
+}
+
+// currencyRegex builds the value-matching regex for the given currency symbols
+// Falls back to the default dollar sign when no symbols are provided
+// With decimalComma, thousands are grouped with points and the decimals follow a comma, though a
+// point followed by one or two digits is still read as decimals
+func currencyRegex(currencySymbols []string, decimalComma bool) *regexp.Regexp {
+	quoted := make([]string, 0, len(currencySymbols))
+	for _, symbol := range currencySymbols {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			quoted = append(quoted, regexp.QuoteMeta(symbol))
+		}
+	}
+	if len(quoted) == 0 {
+		quoted = append(quoted, regexp.QuoteMeta(DefaultCurrencySymbol))
+	}
+
+	number := `((?:[0-9]{1,3}(?:,[0-9]{3})+|[0-9]+)(?:\.[0-9]{1,2})?)`
+	if decimalComma {
+		number = `((?:[0-9]{1,3}(?:\.[0-9]{3})+|[0-9]+)(?:[,.][0-9]{1,2})?)`
+	}
+	return regexp.MustCompile(`(-)?(?:` + strings.Join(quoted, "|") + `)(-)?` + number)
+}
+
+// amountDigits drops the thousands separators from a matched amount, writing any decimals after a point
+// Groups always have three digits, so a last separator followed by one or two digits starts the decimals
+func amountDigits(number string) string {
+	fraction := ""
+	if i := strings.LastIndexAny(number, ".,"); i >= 0 && len(number)-i-1 <= 2 {
+		number, fraction = number[:i], "."+number[i+1:]
+	}
+	return strings.NewReplacer(",", "", ".", "").Replace(number) + fraction
+}
+
+// isSign reports whether the minus at index i starts a negative amount rather than joining
+// it to the word or number before, as in "$10-$20"
+func isSign(text string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := text[i-1]
+	return !(prev >= '0' && prev <= '9' || prev >= 'a' && prev <= 'z' || prev >= 'A' && prev <= 'Z')
+}
+
+// isMalformedGrouping reports whether the text following a match continues
+// with a thousands separator and another digit, meaning the grouping was invalid
+// A grouped number followed straight by another digit had a group that was too long, like $1,2345
+// With decimalComma, more digits straight after the match mean the comma wasn't a decimal
+// separator, like $1,234 written with comma-grouped thousands, so the amount is ambiguous
+func isMalformedGrouping(number, rest string, decimalComma bool) bool {
+	separator := byte(',')
+	if decimalComma {
+		separator = '.'
+	}
+	if len(rest) >= 1 && isDigit(rest[0]) && (decimalComma || strings.IndexByte(number, separator) >= 0) {
+		return true
+	}
+	return len(rest) >= 2 && rest[0] == separator && isDigit(rest[1])
+}
+
+// isDigit reports whether b is an ASCII digit
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// SumDollarValues sums an array of dollar values, subtracting any negative values
+// Returns the total with 2 decimal place precision
+func SumDollarValues(values []float64) (float64, error) {
+	return SumDollarValuesWithPolicy(values, NegativeInclude)
 }
 
 // CalculateItemCount calculates how many items the dollar amount could buy
 // Always rounds up for fun!
 func CalculateItemCount(total float64, pricePerItem float64) (int, error) {
+	return CalculateItemCountWithMode(total, pricePerItem, RoundUp)
+}
+
+// CalculateItemCountWithMode calculates how many items the dollar amount could buy
+// using the given rounding mode
+func CalculateItemCountWithMode(total float64, pricePerItem float64, mode RoundingMode) (int, error) {
 	// Safety check for invalid inputs
 	if total < 0 {
 		err := errors.Newf(errors.ErrInvalidDollarValue, "negative total amount: %.2f", total)
@@ -96,28 +383,69 @@ func CalculateItemCount(total float64, pricePerItem float64) (int, error) {
 		return 0, err
 	}
 
-	// Calculate count and round up
-	count := math.Ceil(total / pricePerItem)
+	// Calculate count and round according to the mode
+	count, _ := itemCount(total, pricePerItem, mode)
 	result := int(count)
 
-	logging.Debug("Calculated item count: $%.2f at $%.2f per item = %d items", total, pricePerItem, result)
+	logging.Debug("Calculated item count: $%.2f at $%.2f per item = %d items (rounding %s)",
+		total, pricePerItem, result, mode)
 	return result, nil
 }
 
-// IsExactDivision checks if the division results in a whole number
+// ExactTolerance is how close, in dollars, a total must be to a whole number of items to count as exact
+// It absorbs floating point error and sub-cent amounts so they aren't described as "nearly"
+const ExactTolerance = 0.005
+
+// itemCount returns how many items the total buys, rounded according to the mode, and whether
+// it buys an exact number of them
+// Prices in whole cents are divided exactly as Money, with the total rounded to the nearest
+// cent, which is the same as allowing ExactTolerance; other prices, like the price of a unit
+// of a fractional-cent item, fall back to dividing floats
+func itemCount(total float64, pricePerItem float64, mode RoundingMode) (int64, bool) {
+	if price, ok := moneyFromPrice(pricePerItem); ok && math.Abs(total) <= maxMoney {
+		amount := MoneyFromFloat(total)
+		return mode.divide(amount, price), amount%price == 0
+	}
+
+	quotient, exact := itemQuotient(total, pricePerItem)
+	return int64(mode.apply(quotient)), exact
+}
+
+// itemQuotient returns how many items the total buys, snapped to the whole number of items
+// when the total is within ExactTolerance of it, and whether it was snapped
+func itemQuotient(total float64, pricePerItem float64) (float64, bool) {
+	quotient := total / pricePerItem
+	whole := math.Round(quotient)
+	if math.Abs(total-whole*pricePerItem) <= ExactTolerance {
+		return whole, true
+	}
+	return quotient, false
+}
+
+// IsExactDivision checks if the total buys a whole number of items, to within ExactTolerance
 func IsExactDivision(total float64, pricePerItem float64) bool {
 	if pricePerItem <= 0 {
 		return false
 	}
 
-	// Calculate division and check if it's a whole number
-	quotient := total / pricePerItem
-	return quotient == float64(int(quotient))
+	_, exact := itemCount(total, pricePerItem, RoundUp)
+	return exact
+}
+
+// UsesExactWording reports whether a response should drop the "nearly" qualifier
+// Rounding down never overstates the count, so it is always worded exactly
+func UsesExactWording(total float64, pricePerItem float64, mode RoundingMode) bool {
+	return mode == RoundDown || IsExactDivision(total, pricePerItem)
 }
 
 // FormatResponse creates a fun response message with the item count
 // Handles pluralization automatically and only uses "nearly" for non-exact conversions
 func FormatResponse(count int, itemName string, isExactDivision bool) string {
+	return FormatResponseWithPhrases(count, itemName, isExactDivision, models.LocalePhrases[models.DefaultLocale])
+}
+
+// FormatResponseWithPhrases creates a response like FormatResponse, worded with the phrases
+func FormatResponseWithPhrases(count int, itemName string, isExactDivision bool, phrases models.Phrases) string {
 	if itemName == "" {
 		logging.Warn("Empty item name provided to FormatResponse, using default")
 		itemName = "item"
@@ -125,28 +453,94 @@ func FormatResponse(count int, itemName string, isExactDivision bool) string {
 
 	// Handle zero case (when the amount is too small to buy even one item)
 	if count <= 0 {
-		return "That wouldn't even buy a single " + getSingularForm(itemName) + "!"
+		return FormatZeroResponse(phrases.Zero, itemName)
 	}
 
-	// Format the beginning of the response based on whether it's an exact division
-	prefix := "That's "
+	return phrases.Prefix + countPhrase(count, itemName, isExactDivision, phrases) + phrases.Suffix
+}
+
+// countPhrase describes a count of items, like "nearly 15 Bunnings snags"
+// Only uses the phrases' "nearly" for non-exact conversions
+func countPhrase(count int, itemName string, isExactDivision bool, phrases models.Phrases) string {
+	prefix := ""
 	if !isExactDivision {
-		prefix = "That's nearly "
+		prefix = phrases.Nearly
 	}
 
 	// Handle pluralization
 	if count == 1 {
-		return prefix + "1 " + getSingularForm(itemName) + "!"
-	} else {
-		return prefix + strconv.Itoa(count) + " " + getPluralForm(itemName) + "!"
+		return prefix + "1 " + getSingularForm(itemName)
 	}
+	return prefix + strconv.Itoa(count) + " " + getPluralForm(itemName)
+}
+
+// FormatCappedResponse is the response for counts over the limit, like
+// "That's more Bunnings snags than anyone could handle — over 100,000!"
+func FormatCappedResponse(limit int, itemName string) string {
+	return "That's more " + getPluralForm(itemName) + " than anyone could handle — over " + formatThousands(limit) + "!"
+}
+
+// formatThousands formats a count with comma separated thousands, like 100,000
+func formatThousands(n int) string {
+	if n < 0 {
+		return "-" + formatThousands(-n)
+	}
+	return groupThousands(strconv.Itoa(n), ",")
+}
+
+// FormatResponseWithConfig formats the response using the channel's custom template
+// when one is set, falling back to FormatResponse otherwise
+// Items with a unit are counted in units, with the amount of the item alongside
+func FormatResponseWithConfig(count int, total float64, isExactDivision bool, config *models.ChannelConfig) string {
+	return FormatResponseWithOptions(count, total, isExactDivision, config, nil)
+}
+
+// FormatResponseWithOptions formats the response like FormatResponseWithConfig, wording it
+// with the options' random source when the channel has variety turned on
+func FormatResponseWithOptions(count int, total float64, isExactDivision bool, config *models.ChannelConfig, opts *Options) string {
+	name := config.CountedName()
+
+	// Counts past the channel's cap stop being funny, so say so instead of listing them
+	if limit := config.ItemCountLimit(); count > limit {
+		return FormatCappedResponse(limit, name)
+	}
+
+	// The "too small" response isn't templated since there's no count to show,
+	// but channels can set their own message for it
+	phrases := config.ResponsePhrases()
+	if count <= 0 {
+		if config.ZeroMessage != "" {
+			return FormatZeroResponse(config.ZeroMessage, name)
+		}
+		return FormatResponseWithPhrases(count, name, isExactDivision, phrases)
+	}
+
+	if config.ResponseTemplate == "" {
+		phrases = ChoosePhrasesWithOptions(config, opts)
+		return phrases.Prefix + countPhraseWithConfig(count, isExactDivision, config) + phrases.Suffix
+	}
+
+	if err := ValidateResponseTemplate(config.ResponseTemplate); err != nil {
+		logging.Warn("Invalid response template for channel %s, using default: %v", config.ChannelID, err)
+		return phrases.Prefix + countPhraseWithConfig(count, isExactDivision, config) + phrases.Suffix
+	}
+
+	return FormatResponseWithTemplate(config.ResponseTemplate, count, name, total)
 }
 
 // ProcessMessage is a convenience function that combines all steps
 // Takes a message text and price per item, returns the formatted response
-func ProcessMessage(text string, pricePerItem float64) (string, error) {
+// Optional currency symbols override the default dollar sign
+func ProcessMessage(text string, pricePerItem float64, currencySymbols ...string) (string, error) {
+	return ProcessMessageWithNegativeHandling(text, pricePerItem, NegativeInclude, currencySymbols...)
+}
+
+// ProcessMessageWithNegativeHandling processes a message like ProcessMessage, applying the
+// policy to negative amounts
+// Returns an empty response if the amounts add up to less than zero
+func ProcessMessageWithNegativeHandling(text string, pricePerItem float64, policy NegativeHandling, currencySymbols ...string) (string, error) {
 	// Extract dollar values
-	values, err := ExtractDollarValues(text)
+	values, err := ExtractDollarValues(text, currencySymbols...)
 	if err != nil {
 		return "", errors.WrapAndLog(err, "Failed to extract dollar values")
 	}
@@ -157,10 +551,14 @@ func ProcessMessage(text string, pricePerItem float64) (string, error) {
 	}
 
 	// Sum the values
-	total, err := SumDollarValues(values)
+	total, err := SumDollarValuesWithPolicy(values, policy)
 	if err != nil {
 		return "", errors.WrapAndLog(err, "Failed to sum dollar values")
 	}
+	if total < 0 {
+		logging.Debug("Dollar values add up to less than zero, skipping")
+		return "", nil
+	}
 
 	// Log the extracted values and total for debugging
 	logging.Debug("Processing message with values: %v, total: %.2f", values, total)
@@ -180,64 +578,134 @@ func ProcessMessage(text string, pricePerItem float64) (string, error) {
 		return "", errors.WrapAndLog(err, "Failed to calculate item count")
 	}
 
+	if count > models.DefaultMaxItemCount {
+		return FormatCappedResponse(models.DefaultMaxItemCount, "Bunnings snag"), nil
+	}
+
 	// Format and return the response
 	response := FormatResponse(count, "Bunnings snag", isExactDivision)
 	logging.Debug("Processed message: Total $%.2f, Count %d, Response: %s", total, count, response)
 	return response, nil
 }
 
-// ProcessMessageWithConfig is a shared utility that processes a message with the given channel configuration
-// and returns the formatted response string
-// This function centralizes the logic from both service/message.go and slack/service.go
+// ProcessMessageWithConfig extracts, sums and formats the dollar values in a message using the
+// channel's items, price, threshold, rounding and template, returning the response to send
+// Returns an empty string when SnagBot should stay quiet
 func ProcessMessageWithConfig(text string, config *models.ChannelConfig) string {
-	// Extract dollar values from the message
-	dollarValues, err := ExtractDollarValues(text)
-	if err != nil {
+	return ProcessMessageWithOptions(text, config, nil)
+}
+
+// ProcessMessageWithOptions returns the response to send like ProcessMessageWithConfig, reading
+// amounts with the deployment's options
+func ProcessMessageWithOptions(text string, config *models.ChannelConfig, opts *Options) string {
+	// Pick the item for this response so the count and name always match
+	config = ChooseItemWithOptions(config, opts)
+
+	// Extract dollar values from the message, up to the channel's cap
+	dollarValues, err := ExtractDollarValuesWithOptions(text, config, opts)
+	truncated := IsTooManyDollarValues(err)
+	if truncated && config.SkipsTooManyValues() {
+		logging.Debug("Too many dollar values in text, skipping")
+		return ""
+	}
+	if err != nil && !truncated {
 		logging.Error("Failed to extract dollar values: %v", err)
 		return ""
 	}
+
+	// Drop amounts that aren't really spending, like "a $0 fee" or "$35 off", if the channel asks
+	dollarValues = FilterFalseMatchesWithOptions(text, dollarValues, config, opts)
 	if len(dollarValues) == 0 {
 		// No dollar values found, nothing to do
 		logging.Debug("No dollar values found in text")
 		return ""
 	}
 
+	// Fall back to including negative amounts if the stored policy is unrecognised
+	policy, err := ParseNegativeHandling(config.NegativeHandling)
+	if err != nil {
+		logging.Warn("Invalid negative handling for channel %s, including negatives: %v", config.ChannelID, err)
+	}
+
 	// Calculate total dollar amount
-	total, err := SumDollarValues(dollarValues)
+	total, err := SumDollarValuesWithPolicy(dollarValues, policy)
 	if err != nil {
 		logging.Error("Failed to sum dollar values: %v", err)
 		return ""
 	}
+	if total < 0 {
+		logging.Debug("Dollar values add up to less than zero, skipping")
+		return ""
+	}
+
+	// Stay quiet for totals under the channel's minimum
+	if config.BelowThreshold(total) {
+		logging.Debug("Total %.2f is below channel threshold %.2f, skipping", total, config.MinThreshold)
+		return ""
+	}
+
+	// Let people know when some amounts were left out
+	note := ""
+	if truncated {
+		note = FormatTruncatedNote(config.DollarValueLimit())
+	}
+
+	// Convert each line on its own if the channel asks, for invoices with one cost per line
+	if config.SumsPerLine() {
+		if message := FormatPerLineResponseWithOptions(text, config, opts); message != "" {
+			return message + note
+		}
+	}
 
 	// For very small amounts that don't reach 1 item
-	if total < config.ItemPrice {
-		// Use the standard "zero" response for small amounts
-		return FormatResponse(0, config.ItemName, true)
+	if total < config.UnitPrice() {
+		// Use the "zero" response, or the channel's own message, for small amounts
+		return FormatResponseWithOptions(0, total, true, config, opts) + note
+	}
+
+	// Fall back to rounding up if the stored mode is unrecognised
+	mode, err := ParseRoundingMode(config.RoundingMode)
+	if err != nil {
+		logging.Warn("Invalid rounding mode for channel %s, rounding up: %v", config.ChannelID, err)
 	}
 
 	// Check if the division is exact (to decide whether to use "nearly")
-	isExactDivision := IsExactDivision(total, config.ItemPrice)
+	isExactDivision := UsesExactWording(total, config.UnitPrice(), mode)
 
 	// Calculate number of items
-	count, err := CalculateItemCount(total, config.ItemPrice)
+	count, err := CalculateItemCountWithMode(total, config.UnitPrice(), mode)
 	if err != nil {
 		logging.Error("Failed to calculate item count: %v", err)
 		return ""
 	}
 
 	// Format response message
-	return FormatResponse(count, config.ItemName, isExactDivision)
+	return FormatResponseWithBreakdown(count, dollarValues, total, isExactDivision, config, opts) + note
 }
 
 // getSingularForm ensures we have the singular form of the item name
+// In names like "cups of coffee" only the head noun changes, and names like "fish and chips"
+// are left as they are since the plural belongs to the dish
 func getSingularForm(itemName string) string {
+	if head, rest, ok := splitOfPhrase(itemName); ok {
+		return getSingularForm(head) + rest
+	}
+	if isCombinedName(itemName) {
+		return itemName
+	}
+
+	// Check irregular nouns like "people" -> "person" first
+	if singular, ok := irregularSingular(itemName); ok {
+		return singular
+	}
+
 	// If the item name ends with 's', try to get the singular form
 	if strings.HasSuffix(strings.ToLower(itemName), "s") {
 		// Check common pluralization patterns
 		if strings.HasSuffix(strings.ToLower(itemName), "ies") {
 			// Handle words like "candies" -> "candy"
 			return itemName[:len(itemName)-3] + "y"
-		} else if strings.HasSuffix(strings.ToLower(itemName), "es") {
+		} else if takesES(strings.ToLower(itemName)) {
 			// Handle words like "watches" -> "watch"
 			return itemName[:len(itemName)-2]
 		} else {
@@ -249,7 +717,18 @@ func getSingularForm(itemName string) string {
 }
 
 // getPluralForm ensures we have the plural form of the item name
+// In names like "cup of coffee" only the head noun changes, so it becomes "cups of coffee",
+// while names like "croissant and coffee" pluralise their last word
 func getPluralForm(itemName string) string {
+	if head, rest, ok := splitOfPhrase(itemName); ok {
+		return getPluralForm(head) + rest
+	}
+
+	// Check irregular nouns like "person" -> "people" and "loaf" -> "loaves" first
+	if plural, ok := irregularPlural(itemName); ok {
+		return plural
+	}
+
 	// If already plural (ending with 's'), return as is
 	if strings.HasSuffix(strings.ToLower(itemName), "s") {
 		return itemName
@@ -1,8 +1,13 @@
 package calculator
 
 import (
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -52,6 +57,41 @@ func TestExtractDollarValues(t *testing.T) {
 			text:     "$35.50.25 should only match $35.50 once",
 			expected: []float64{35.50},
 		},
+		{
+			name:     "Thousands separator",
+			text:     "The deposit is $1,000",
+			expected: []float64{1000.0},
+		},
+		{
+			name:     "Multiple thousands separators with decimals",
+			text:     "Budget is $1,234,567.89 this year",
+			expected: []float64{1234567.89},
+		},
+		{
+			name:     "Thousands separators alongside plain values",
+			text:     "Rent is $1,250.50 and bond is $10,000 plus $35",
+			expected: []float64{1250.50, 10000.0, 35.0},
+		},
+		{
+			name:     "Malformed grouping is rejected",
+			text:     "That's $1,00,0 apparently",
+			expected: []float64{},
+		},
+		{
+			name:     "Single digit groups are rejected",
+			text:     "Pay $1,2,3 or $20",
+			expected: []float64{20.0},
+		},
+		{
+			name:     "Groups longer than three digits are rejected",
+			text:     "Pay $1,2345 or $20",
+			expected: []float64{20.0},
+		},
+		{
+			name:     "Trailing comma in prose is fine",
+			text:     "It cost $35, which is fine",
+			expected: []float64{35.0},
+		},
 	}
 
 	for _, test := range tests {
@@ -65,6 +105,599 @@ func TestExtractDollarValues(t *testing.T) {
 	}
 }
 
+func TestExtractDollarValuesWithCurrencySymbols(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		symbols  []string
+		expected []float64
+	}{
+		{
+			name:     "No override uses dollar sign",
+			text:     "This costs $35 or €20",
+			symbols:  nil,
+			expected: []float64{35.0},
+		},
+		{
+			name:     "Euro only",
+			text:     "This costs €20 and that costs €15.50",
+			symbols:  []string{"€"},
+			expected: []float64{20.0, 15.50},
+		},
+		{
+			name:     "Mixed symbols only sums configured symbol",
+			text:     "Lunch was €20, the taxi £35, and the hotel $100",
+			symbols:  []string{"€"},
+			expected: []float64{20.0},
+		},
+		{
+			name:     "Multiple configured symbols",
+			text:     "Lunch was €20, the taxi £35, and the hotel ¥500",
+			symbols:  []string{"€", "£"},
+			expected: []float64{20.0, 35.0},
+		},
+		{
+			name:     "Blank symbols fall back to dollar sign",
+			text:     "This costs $35 or €20",
+			symbols:  []string{" "},
+			expected: []float64{35.0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ExtractDollarValues(test.text, test.symbols...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+// manyDollarValues builds a message with count distinct amounts: $1, $2, ...
+func manyDollarValues(count int) string {
+	amounts := make([]string, count)
+	for i := range amounts {
+		amounts[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return strings.Join(amounts, " ")
+}
+
+// exchangeOptions converts USD and EUR to AUD, at 1.5 and 1.6
+func exchangeOptions() *Options {
+	return NewOptions(&config.Config{ExchangeCurrency: "AUD", ExchangeRates: map[string]float64{"USD": 1.5, "EUR": 1.6}})
+}
+
+func TestExtractDollarValuesWithExchangeRates(t *testing.T) {
+	opts := exchangeOptions()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []float64
+	}{
+		{name: "Bare dollars are the target currency", text: "Lunch was $10", expected: []float64{10}},
+		{name: "Currency code after the amount", text: "Lunch was $10 USD", expected: []float64{15}},
+		{name: "Currency symbol", text: "Dinner was €10", expected: []float64{16}},
+		{name: "Dollar prefix", text: "Tickets were US$20", expected: []float64{30}},
+		{name: "Same number in different currencies", text: "$10 USD and €10 and $10", expected: []float64{15, 16, 10}},
+		{name: "Target currency code", text: "$10 AUD", expected: []float64{10}},
+		{name: "Currency without a rate", text: "£10 or $10 NZD", expected: []float64{10}},
+		{name: "Negative amount", text: "Refund of -€5", expected: []float64{-8}},
+		{name: "Conversion rounds to the cent", text: "$3.33 USD", expected: []float64{5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := ExtractDollarValuesWithOptions(test.text, &models.ChannelConfig{}, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestProcessMessageWithOptionsExchangeRates(t *testing.T) {
+	channel := &models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.10}
+
+	// Without conversion only the dollar amount is counted, at face value
+	assert.Equal(t, "That's nearly 4 Bunnings snags!", ProcessMessageWithConfig("$10 USD + €10", channel))
+	assert.Equal(t, "That's nearly 4 Bunnings snags!", ProcessMessageWithOptions("$10 USD + €10", channel, NewOptions(&config.Config{})))
+
+	// $10 USD is $15 AUD and €10 is $16 AUD, $31 in all
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithOptions("$10 USD + €10", channel, exchangeOptions()))
+}
+
+func TestStaticRates(t *testing.T) {
+	rates := NewStaticRates("AUD", map[string]float64{"USD": 1.5, "EUR": 1.6, "AUD": 2, "JPY": 0})
+
+	rate, ok := rates.Rate("USD", "AUD")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, rate)
+
+	rate, ok = rates.Rate("AUD", "AUD")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, rate)
+
+	rate, ok = rates.Rate("EUR", "USD")
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0667, rate, 0.0001)
+
+	_, ok = rates.Rate("JPY", "AUD")
+	assert.False(t, ok)
+	_, ok = rates.Rate("USD", "NZD")
+	assert.False(t, ok)
+}
+
+func TestExtractDollarValuesWithLimit(t *testing.T) {
+	// Under the limit everything is returned
+	values, err := ExtractDollarValuesWithLimit(manyDollarValues(5), 10)
+	assert.NoError(t, err)
+	assert.Len(t, values, 5)
+
+	// Exactly at the limit isn't too many
+	values, err = ExtractDollarValuesWithLimit(manyDollarValues(10), 10)
+	assert.NoError(t, err)
+	assert.Len(t, values, 10)
+
+	// Over the limit returns the first values and a distinguishable error
+	values, err = ExtractDollarValuesWithLimit(manyDollarValues(60), 50)
+	assert.True(t, IsTooManyDollarValues(err), "Expected too many dollar values error, got %v", err)
+	assert.Len(t, values, 50)
+	assert.Equal(t, 1.0, values[0])
+	assert.Equal(t, 50.0, values[49])
+
+	// No limit extracts everything
+	values, err = ExtractDollarValuesWithLimit(manyDollarValues(60), 0)
+	assert.NoError(t, err)
+	assert.Len(t, values, 60)
+	assert.False(t, IsTooManyDollarValues(err))
+}
+
+func TestExtractDollarValuesWithFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		numberFormat string
+		text         string
+		expected     []float64
+	}{
+		{"point with decimal point", models.NumberFormatPoint, "Lunch was $3.50", []float64{3.50}},
+		{"point ignores decimal comma", models.NumberFormatPoint, "Lunch was $3,50", []float64{}},
+		{"point with grouped thousands", models.NumberFormatPoint, "Rent is $1,234.56", []float64{1234.56}},
+		{"default format is point", "", "Lunch was $3.50", []float64{3.50}},
+		{"comma with decimal comma", models.NumberFormatComma, "Lunch was $3,50", []float64{3.50}},
+		{"comma with decimal point", models.NumberFormatComma, "Lunch was $3.50", []float64{3.50}},
+		{"comma with single decimal", models.NumberFormatComma, "Lunch was $3,5", []float64{3.50}},
+		{"comma with grouped thousands", models.NumberFormatComma, "Rent is $1.234,56", []float64{1234.56}},
+		{"comma with grouped thousands and no decimals", models.NumberFormatComma, "Rent is $1.234", []float64{1234}},
+		{"comma with several amounts", models.NumberFormatComma, "Coffee $4,50 and cake $6", []float64{4.50, 6}},
+		{"comma skips comma-grouped thousands", models.NumberFormatComma, "Rent is $1,234", []float64{}},
+		{"comma skips malformed grouping", models.NumberFormatComma, "Rent is $1.23.4", []float64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := ExtractDollarValuesWithFormat(tt.text, 0, tt.numberFormat)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, values)
+		})
+	}
+}
+
+func TestProcessMessageWithConfigNumberFormat(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	assert.Equal(t, "", ProcessMessageWithConfig("Lunch was $3,50", config))
+
+	config.NumberFormat = models.NumberFormatComma
+	assert.Equal(t, "That's 1 Bunnings snag!", ProcessMessageWithConfig("Lunch was $3,50", config))
+	assert.Equal(t, "That's nearly 353 Bunnings snags!", ProcessMessageWithConfig("Rent is $1.234,50", config))
+}
+
+func TestExtractDollarValuesWithConfigCountRepeats(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	text := "I paid $35 and you paid $35"
+
+	// By default a repeated amount is only counted once
+	values, err := ExtractDollarValuesWithConfig(text, config)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{35}, values)
+
+	config.CountRepeats = true
+	values, err = ExtractDollarValuesWithConfig(text, config)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{35, 35}, values)
+	total, err := SumDollarValues(values)
+	assert.NoError(t, err)
+	assert.Equal(t, 70.0, total)
+
+	// Trailing decimals still aren't matched as amounts of their own
+	values, err = ExtractDollarValuesWithConfig("$35.50.25 and $35.50", config)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{35.50, 35.50}, values)
+}
+
+func TestProcessMessageWithConfigCountRepeats(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("I paid $35 and you paid $35", config))
+
+	config.CountRepeats = true
+	assert.Equal(t, "That's 20 Bunnings snags!", ProcessMessageWithConfig("I paid $35 and you paid $35", config))
+}
+
+func TestProcessMessageWithConfigTooManyValues(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.ItemPrice = 1.00
+	config.MaxDollarValues = 3
+	text := manyDollarValues(5) // $1 to $5, so the first three add up to $6
+
+	assert.Equal(t, "That's 6 Bunnings snags! (Only the first 3 amounts were counted.)", ProcessMessageWithConfig(text, config))
+
+	config.TooManyValues = models.TooManyValuesSkip
+	assert.Equal(t, "", ProcessMessageWithConfig(text, config))
+
+	// Messages within the cap are unaffected by the policy
+	assert.Equal(t, "That's 6 Bunnings snags!", ProcessMessageWithConfig(manyDollarValues(3), config))
+}
+
+func TestParseWordAmounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []float64
+	}{
+		{name: "Hyphenated tens", text: "That'll be thirty-five dollars", expected: []float64{35}},
+		{name: "Spaced tens", text: "that'll be thirty five dollars", expected: []float64{35}},
+		{name: "A hundred", text: "Spent a hundred bucks on lunch", expected: []float64{100}},
+		{name: "A buck", text: "It only cost a buck", expected: []float64{1}},
+		{name: "Singular dollar", text: "one dollar coin", expected: []float64{1}},
+		{name: "Hundreds with and", text: "Three hundred and fifty dollars for the lot", expected: []float64{350}},
+		{name: "Thousands", text: "two thousand five hundred and twenty bucks", expected: []float64{2520}},
+		{name: "A thousand", text: "A THOUSAND DOLLARS?!", expected: []float64{1000}},
+		{name: "Fifteen hundred", text: "fifteen hundred dollars", expected: []float64{1500}},
+		{name: "Several amounts", text: "ten bucks for me and twenty dollars for you", expected: []float64{10, 20}},
+		{name: "Ignores digit amounts", text: "It was $35, or 35 dollars", expected: []float64{}},
+		{name: "Number not followed by dollars", text: "We need five people and some dollars", expected: []float64{}},
+		{name: "Number words inside other words", text: "It's often done, none of the dollars are gone", expected: []float64{}},
+		{name: "Random words", text: "The quick brown fox jumps over the lazy dog", expected: []float64{}},
+		{name: "Out of order numbers", text: "five twenty dollars", expected: []float64{}},
+		{name: "Repeated tens", text: "twenty twenty dollars", expected: []float64{}},
+		{name: "Zero amount", text: "zero dollars", expected: []float64{}},
+		{name: "Dangling and", text: "fifty and dollars", expected: []float64{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseWordAmounts(test.text)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestProcessMessageWithConfigWordAmounts(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	text := "Lunch was thirty-five dollars plus $7 for coffee"
+
+	// Word amounts are only counted once the channel turns them on
+	assert.Equal(t, "That's 2 Bunnings snags!", ProcessMessageWithConfig(text, config))
+
+	config.WordAmounts = true
+	assert.Equal(t, "That's 12 Bunnings snags!", ProcessMessageWithConfig(text, config))
+	assert.Equal(t, "That's nearly 29 Bunnings snags!", ProcessMessageWithConfig("a hundred bucks", config))
+
+	// Word amounts count towards the channel's cap
+	config.MaxDollarValues = 1
+	assert.Equal(t, "That's 2 Bunnings snags! (Only the first 1 amounts were counted.)", ProcessMessageWithConfig(text, config))
+}
+
+func TestExtractDollarValuesWithOptionsMoneyWords(t *testing.T) {
+	channel := models.NewChannelConfig("C12345")
+	channel.WordAmounts = true
+
+	tests := []struct {
+		name     string
+		words    []string
+		text     string
+		expected []float64
+	}{
+		{name: "Default words", text: "That was 50 bucks", expected: []float64{50}},
+		{name: "Default words ignore quid", text: "That was 20 quid", expected: []float64{}},
+		{name: "Configured words", words: []string{"bucks", "quid"}, text: "50 bucks and 20 quid", expected: []float64{50, 20}},
+		{name: "Any case", words: []string{"quid"}, text: "20 QUID", expected: []float64{20}},
+		{name: "No space", words: []string{"quid"}, text: "20quid", expected: []float64{20}},
+		{name: "Decimals", words: []string{"dollarydoos"}, text: "12.50 dollarydoos", expected: []float64{12.5}},
+		{name: "Spelled out", words: []string{"quid"}, text: "twenty quid", expected: []float64{20}},
+		{name: "Unlisted word", words: []string{"quid"}, text: "50 bucks or fifty bucks", expected: []float64{}},
+		{name: "Part of a longer word", words: []string{"quid"}, text: "20 quidditch players", expected: []float64{}},
+		{name: "With a symbol", text: "$35 dollars", expected: []float64{35}},
+		{name: "In order with symbols", text: "5 bucks then $7", expected: []float64{5, 7}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := NewOptions(&config.Config{MoneyWords: test.words})
+
+			values, err := ExtractDollarValuesWithOptions(test.text, channel, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestExtractDollarValuesWithConfigMoneyWordsOff(t *testing.T) {
+	// Amounts followed by money words are only read once the channel turns word amounts on
+	values, err := ExtractDollarValuesWithConfig("That was 50 bucks", models.NewChannelConfig("C12345"))
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestOptionsMoneyWords(t *testing.T) {
+	var none *Options
+	assert.Equal(t, []string{"buck", "bucks", "dollar", "dollars"}, none.MoneyWords())
+	assert.Equal(t, []string{"buck", "bucks", "dollar", "dollars"}, NewOptions(&config.Config{}).MoneyWords())
+
+	opts := NewOptions(&config.Config{MoneyWords: []string{" Quid ", "", "quid", "bucks"}})
+	assert.Equal(t, []string{"bucks", "quid"}, opts.MoneyWords())
+
+	// A list without any words keeps the defaults
+	opts = NewOptions(&config.Config{MoneyWords: []string{" ", ""}})
+	assert.Equal(t, []string{"buck", "bucks", "dollar", "dollars"}, opts.MoneyWords())
+}
+
+func TestOptionsWithRandomSource(t *testing.T) {
+	opts := NewOptions(&config.Config{MoneyWords: []string{"quid"}})
+	seeded := opts.WithRandomSource(rand.NewSource(42))
+
+	// The copy keeps the other settings, and the original keeps the shared source
+	assert.Equal(t, []string{"quid"}, seeded.MoneyWords())
+	assert.Nil(t, opts.random)
+	assert.NotNil(t, seeded.random)
+
+	var none *Options
+	assert.NotNil(t, none.WithRandomSource(rand.NewSource(42)).random)
+}
+
+func TestProcessMessageWithConfigFilterFalseMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Zero amount", text: "There's a $0 fee", expected: ""},
+		{name: "Zero with cents", text: "Shipping was $0.00", expected: ""},
+		{name: "Zero alongside a real amount", text: "$0 fee on a $35 order", expected: "That's 10 Bunnings snags!"},
+		{name: "Discount only", text: "$35 off everything today", expected: ""},
+		{name: "Discount from a price", text: "The $100 jacket has $35 off", expected: "That's nearly 19 Bunnings snags!"},
+		{name: "Discount word", text: "Paid $100 with a $30 discount", expected: "That's 20 Bunnings snags!"},
+		{name: "Discount of", text: "Paid $100 after a discount of $30", expected: "That's 20 Bunnings snags!"},
+		{name: "Off elsewhere in the message", text: "Spent $35 then logged off", expected: "That's 10 Bunnings snags!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := models.NewChannelConfig("C12345")
+			config.FilterFalseMatches = true
+			assert.Equal(t, test.expected, ProcessMessageWithConfig(test.text, config))
+		})
+	}
+
+	// Without the filter every amount counts as spending
+	config := models.NewChannelConfig("C12345")
+	assert.Equal(t, "That wouldn't even buy a single Bunnings snag!", ProcessMessageWithConfig("There's a $0 fee", config))
+	assert.Equal(t, "That wouldn't even buy a single Bunnings snag!", ProcessMessageWithConfig("Shipping was $0.00", config))
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("$35 off everything today", config))
+}
+
+func TestProcessMessageWithConfigBreakdown(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	text := "Lunch was $35 and parking was $15"
+
+	// The default response stays on one line without the amounts
+	assert.Equal(t, "That's nearly 15 Bunnings snags!", ProcessMessageWithConfig(text, config))
+
+	config.ShowBreakdown = true
+	assert.Equal(t, "That's $35 + $15 = $50, nearly 15 Bunnings snags!", ProcessMessageWithConfig(text, config))
+	assert.Equal(t, "That's $20.50 + $14.50 = $35, 10 Bunnings snags!", ProcessMessageWithConfig("$20.50 and $14.50", config))
+
+	// A single amount has nothing to break down
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("$35", config))
+
+	// Negative amounts are shown as they were counted
+	assert.Equal(t, "That's $100 - $30 = $70, 20 Bunnings snags!", ProcessMessageWithConfig("$100 and -$30", config))
+	config.NegativeHandling = "ignore"
+	assert.Equal(t, "That's 20 Bunnings snags!", ProcessMessageWithConfig("$70 and -$30", config))
+}
+
+func TestProcessMessageWithConfigMaxItemCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Below the cap", text: "$346.50", expected: "That's 99 Bunnings snags!"},
+		{name: "At the cap", text: "$350", expected: "That's 100 Bunnings snags!"},
+		{name: "Just above the cap", text: "$350.01", expected: "That's more Bunnings snags than anyone could handle — over 100!"},
+		{name: "Well above the cap", text: "$1,000,000", expected: "That's more Bunnings snags than anyone could handle — over 100!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := models.NewChannelConfig("C12345")
+			config.MaxItemCount = 100
+			assert.Equal(t, test.expected, ProcessMessageWithConfig(test.text, config))
+		})
+	}
+
+	// The default cap applies when the channel hasn't set one, even with a template or breakdown
+	config := models.NewChannelConfig("C12345")
+	assert.Equal(t, "That's nearly 100000 Bunnings snags!", ProcessMessageWithConfig("$349,999", config))
+	assert.Equal(t, "That's more Bunnings snags than anyone could handle — over 100,000!", ProcessMessageWithConfig("$1,000,000", config))
+	config.ResponseTemplate = "{count} {item}"
+	config.ShowBreakdown = true
+	assert.Equal(t, "That's more Bunnings snags than anyone could handle — over 100,000!", ProcessMessageWithConfig("$500,000 and $500,000", config))
+
+	response, err := ProcessMessage("The budget is $1,000,000", 3.50)
+	assert.NoError(t, err)
+	assert.Equal(t, "That's more Bunnings snags than anyone could handle — over 100,000!", response)
+}
+
+func TestFormatThousands(t *testing.T) {
+	assert.Equal(t, "0", formatThousands(0))
+	assert.Equal(t, "999", formatThousands(999))
+	assert.Equal(t, "1,000", formatThousands(1000))
+	assert.Equal(t, "100,000", formatThousands(100000))
+	assert.Equal(t, "-1,234,567", formatThousands(-1234567))
+}
+
+func TestProcessMessageWithConfigUnits(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.SetItem("L", 2.00)
+	config.SetUnit("tank", 60)
+
+	assert.Equal(t, "That's nearly 3 tanks (180 L)!", ProcessMessageWithConfig("Filled up for $350", config))
+	assert.Equal(t, "That's 1 tank (60 L)!", ProcessMessageWithConfig("Filled up for $120", config))
+	assert.Equal(t, "That wouldn't even buy a single tank!", ProcessMessageWithConfig("Filled up for $100", config))
+
+	// Spelled out item names are pluralised to match the amount
+	config.SetItem("litre", 2.00)
+	config.SetUnit("tank", 60)
+	assert.Equal(t, "That's 2 tanks (120 litres)!", ProcessMessageWithConfig("Filled up for $240", config))
+
+	// Templates count units too
+	config.ResponseTemplate = "{count} {item} of petrol"
+	assert.Equal(t, "2 tanks of petrol", ProcessMessageWithConfig("Filled up for $240", config))
+
+	// A new item drops the old item's unit
+	config.ResponseTemplate = ""
+	config.SetItem("coffee", 5.00)
+	assert.Equal(t, "That's 48 coffees!", ProcessMessageWithConfig("Filled up for $240", config))
+}
+
+func TestFormatQuantity(t *testing.T) {
+	assert.Equal(t, "180 L", FormatQuantity(180, "L"))
+	assert.Equal(t, "1 km", FormatQuantity(1, "km"))
+	assert.Equal(t, "1 litre", FormatQuantity(1, "litre"))
+	assert.Equal(t, "62.5 litres", FormatQuantity(62.5, "litre"))
+	assert.Equal(t, "tanks of 60 L", DescribeUnit("tank", 60, "L"))
+}
+
+func TestFormatBreakdown(t *testing.T) {
+	assert.Equal(t, "$35 + $15 = $50", FormatBreakdown([]float64{35, 15}, 50, "$"))
+	assert.Equal(t, "-£5 + £10.25 = £5.25", FormatBreakdown([]float64{-5, 10.25}, 5.25, "£"))
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency string
+		expected string
+	}{
+		{name: "USD", amount: 1234.56, currency: "$", expected: "$1,234.56"},
+		{name: "USD code", amount: 3.5, currency: "USD", expected: "$3.50"},
+		{name: "GBP", amount: 1234.5, currency: "£", expected: "£1,234.50"},
+		{name: "EUR", amount: 1234.56, currency: "€", expected: "1.234,56 €"},
+		{name: "EUR small amount", amount: 5, currency: "EUR", expected: "5,00 €"},
+		{name: "JPY has no decimals", amount: 1234.56, currency: "¥", expected: "¥1,235"},
+		{name: "Rounds up to the next whole amount", amount: 9.999, currency: "$", expected: "$10.00"},
+		{name: "Millions", amount: 1234567, currency: "£", expected: "£1,234,567.00"},
+		{name: "Negative", amount: -35, currency: "$", expected: "-$35.00"},
+		{name: "Unknown currency", amount: 1500, currency: "₹", expected: "₹1,500.00"},
+		{name: "Too large for whole cents", amount: 1e20, currency: "$", expected: "$100,000,000,000,000,000,000.00"},
+		{name: "Too large for whole yen", amount: -1e20, currency: "¥", expected: "-¥100,000,000,000,000,000,000"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, FormatMoney(test.amount, test.currency))
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected Money
+	}{
+		{text: "35", expected: 3500},
+		{text: "3.5", expected: 350},
+		{text: "0.07", expected: 7},
+		{text: ".5", expected: 50},
+		{text: "1,234.56", expected: 123456},
+		{text: "-3.50", expected: -350},
+		{text: " 10. ", expected: 1000},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			money, err := ParseMoney(test.text)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, money)
+		})
+	}
+
+	for _, text := range []string{"", "-", ".", "3.505", "abc", "1.2.3", "$5", "1e3", "99999999999999999999"} {
+		t.Run("invalid "+text, func(t *testing.T) {
+			_, err := ParseMoney(text)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// Adding cents is exact where adding floats isn't
+	var total Money
+	var floatTotal float64
+	for i := 0; i < 10; i++ {
+		total = total.Add(MoneyFromFloat(0.1))
+		floatTotal += 0.1
+	}
+	assert.NotEqual(t, 1.0, floatTotal)
+	assert.Equal(t, 1.0, total.Float())
+	assert.Equal(t, Money(30), MoneyFromFloat(0.1).Add(MoneyFromFloat(0.2)))
+
+	// 3.30 / 1.10 is 2.9999999999999996 in floating point
+	assert.Equal(t, int64(3), Money(330).DivCeil(110))
+	assert.Equal(t, int64(3), Money(330).DivFloor(110))
+	assert.Equal(t, int64(3), Money(330).DivRound(110))
+
+	assert.Equal(t, int64(11), Money(3600).DivCeil(350))
+	assert.Equal(t, int64(10), Money(3600).DivFloor(350))
+	assert.Equal(t, int64(10), Money(3600).DivRound(350))
+	assert.Equal(t, int64(11), Money(3675).DivRound(350)) // Halves round up
+
+	assert.Equal(t, "$1,234.56", Money(123456).String())
+	assert.Equal(t, "1.234,56 €", Money(123456).Format("€"))
+	assert.Equal(t, "-$0.07", Money(-7).String())
+}
+
+func TestCalculateItemCountIsExact(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        float64
+		pricePerItem float64
+		mode         RoundingMode
+		expected     int
+		exact        bool
+	}{
+		{name: "Floating point sum rounded down", total: 0.1 + 0.2, pricePerItem: 0.10, mode: RoundDown, expected: 3, exact: true},
+		{name: "Floating point quotient rounded down", total: 3.30, pricePerItem: 1.10, mode: RoundDown, expected: 3, exact: true},
+		{name: "Floating point quotient rounded up", total: 0.7, pricePerItem: 0.07, mode: RoundUp, expected: 10, exact: true},
+		{name: "Large total", total: 10_000_000, pricePerItem: 0.07, mode: RoundUp, expected: 142_857_143, exact: false},
+		{name: "Half rounds up", total: 36.75, pricePerItem: 3.50, mode: RoundNearest, expected: 11, exact: false},
+		{name: "Fractional cent price", total: 1.5, pricePerItem: 0.333, mode: RoundUp, expected: 5, exact: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			count, err := CalculateItemCountWithMode(test.total, test.pricePerItem, test.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, count)
+			assert.Equal(t, test.exact, IsExactDivision(test.total, test.pricePerItem))
+		})
+	}
+}
+
 func TestSumDollarValues(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -115,6 +748,138 @@ func TestSumDollarValues(t *testing.T) {
 	}
 }
 
+func TestExtractNegativeDollarValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []float64
+	}{
+		{name: "Minus before the symbol", text: "Refund of -$50", expected: []float64{-50}},
+		{name: "Minus after the symbol", text: "Balance is $-50.25", expected: []float64{-50.25}},
+		{name: "Mixed", text: "I lost -$50 but found $100", expected: []float64{-50, 100}},
+		{name: "Range isn't negative", text: "Somewhere around $10-$20", expected: []float64{10, 20}},
+		{name: "Hyphenated word isn't negative", text: "A pre-$50 deal", expected: []float64{50}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ExtractDollarValues(test.text)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestParseNegativeHandling(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  NegativeHandling
+		expectErr bool
+	}{
+		{input: "", expected: NegativeInclude},
+		{input: "include", expected: NegativeInclude},
+		{input: "IGNORE", expected: NegativeIgnore},
+		{input: "absolute", expected: NegativeAbsolute},
+		{input: "double", expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := ParseNegativeHandling(test.input)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestSumDollarValuesWithPolicy(t *testing.T) {
+	values := []float64{100, -50, 20.5}
+
+	tests := []struct {
+		policy   NegativeHandling
+		expected float64
+	}{
+		{policy: NegativeInclude, expected: 70.5},
+		{policy: NegativeIgnore, expected: 120.5},
+		{policy: NegativeAbsolute, expected: 170.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			result, err := SumDollarValuesWithPolicy(values, test.policy)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestProcessMessageWithNegativeHandling(t *testing.T) {
+	text := "I lost -$35 but found $70"
+
+	tests := []struct {
+		policy   NegativeHandling
+		expected string
+	}{
+		{policy: NegativeInclude, expected: "That's 10 Bunnings snags!"},
+		{policy: NegativeIgnore, expected: "That's 20 Bunnings snags!"},
+		{policy: NegativeAbsolute, expected: "That's 30 Bunnings snags!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			result, err := ProcessMessageWithNegativeHandling(text, 3.50, test.policy)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+
+	// A net loss gets no response
+	result, err := ProcessMessage("Down -$70 after finding $35", 3.50)
+	assert.NoError(t, err)
+	assert.Equal(t, "", result)
+
+	// Channels choose their policy in their config
+	config := &models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50, NegativeHandling: "ignore"}
+	assert.Equal(t, "That's 20 Bunnings snags!", ProcessMessageWithConfig(text, config))
+}
+
+func TestIsExactDivision(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        float64
+		pricePerItem float64
+		expected     bool
+	}{
+		{name: "Whole number of items", total: 35, pricePerItem: 3.50, expected: true},
+		{name: "Sub-cent over", total: 35.001, pricePerItem: 3.50, expected: true},
+		{name: "Sub-cent under", total: 34.996, pricePerItem: 3.50, expected: true},
+		{name: "Floating point error", total: 0.1 + 0.2, pricePerItem: 0.10, expected: true},
+		{name: "A cent under", total: 34.99, pricePerItem: 3.50, expected: false},
+		{name: "Genuinely fractional", total: 36, pricePerItem: 3.50, expected: false},
+		{name: "Zero price", total: 35, pricePerItem: 0, expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, IsExactDivision(test.total, test.pricePerItem))
+		})
+	}
+
+	// Counts snap to the exact number too, rather than rounding up past it
+	count, err := CalculateItemCount(35.001, 3.50)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, count)
+
+	// $3.30 / $1.10 isn't exactly 3 in floating point, but is worded as exact
+	result, err := ProcessMessage("One at $1.10 and two for $2.20", 1.10)
+	assert.NoError(t, err)
+	assert.Equal(t, "That's 3 Bunnings snags!", result)
+}
+
 func TestCalculateItemCount(t *testing.T) {
 	// Split tests into valid and invalid cases
 	validTests := []struct {
@@ -196,6 +961,52 @@ func TestCalculateItemCount(t *testing.T) {
 	}
 }
 
+func TestCalculateItemCountWithMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     RoundingMode
+		expected int
+	}{
+		{name: "Round down", mode: RoundDown, expected: 9},
+		{name: "Round nearest", mode: RoundNearest, expected: 10},
+		{name: "Round up", mode: RoundUp, expected: 10},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := CalculateItemCountWithMode(34.0, 3.50, test.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestParseRoundingMode(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  RoundingMode
+		expectErr bool
+	}{
+		{input: "", expected: RoundUp},
+		{input: "up", expected: RoundUp},
+		{input: "DOWN", expected: RoundDown},
+		{input: "nearest", expected: RoundNearest},
+		{input: "sideways", expectErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := ParseRoundingMode(test.input)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
 func TestFormatResponse(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -384,3 +1195,499 @@ func TestProcessMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessMessageWithConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		config   models.ChannelConfig
+		expected string
+	}{
+		{
+			name:     "No dollar values",
+			text:     "This has no dollar values",
+			config:   models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50},
+			expected: "",
+		},
+		{
+			name:     "Single dollar value (exact division)",
+			text:     "This costs $35",
+			config:   models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50},
+			expected: "That's 10 Bunnings snags!",
+		},
+		{
+			name:     "Single dollar value (not exact division)",
+			text:     "This costs $34",
+			config:   models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50},
+			expected: "That's nearly 10 Bunnings snags!",
+		},
+		{
+			name:     "Multiple dollar values (not exact division)",
+			text:     "This costs $35 and that costs $34",
+			config:   models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50},
+			expected: "That's nearly 20 Bunnings snags!",
+		},
+		{
+			name:     "Custom item and price (exact division)",
+			text:     "This costs $35",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: 5.00},
+			expected: "That's 7 coffees!",
+		},
+		{
+			name:     "Custom item and price (not exact division)",
+			text:     "This costs $33",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: 5.00},
+			expected: "That's nearly 7 coffees!",
+		},
+		{
+			name:     "Very small amount (less than one item)",
+			text:     "This costs $2",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: 5.00},
+			expected: "That wouldn't even buy a single coffee!",
+		},
+		{
+			name:     "Below the channel threshold",
+			text:     "This costs $10",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: 5.00, MinThreshold: 20},
+			expected: "",
+		},
+		{
+			name:     "Channel currency",
+			text:     "Lunch was €35 and the taxi £20",
+			config:   models.ChannelConfig{ItemName: "Bunnings snag", ItemPrice: 3.50, CurrencySymbols: []string{"€"}},
+			expected: "That's 10 Bunnings snags!",
+		},
+		{
+			name:     "Zero price per item",
+			text:     "This costs $35",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: 0},
+			expected: "",
+		},
+		{
+			name:     "Negative price per item",
+			text:     "This costs $35",
+			config:   models.ChannelConfig{ItemName: "coffee", ItemPrice: -1.0},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := test.config
+			assert.Equal(t, test.expected, ProcessMessageWithConfig(test.text, &config))
+		})
+	}
+}
+
+func TestProcessMessageWithCurrencySymbols(t *testing.T) {
+	result, err := ProcessMessage("Lunch was €35 and the taxi £20", 3.50, "€")
+	assert.NoError(t, err)
+	assert.Equal(t, "That's 10 Bunnings snags!", result)
+
+	result, err = ProcessMessage("Lunch was €35 and the taxi £20", 3.50)
+	assert.NoError(t, err)
+	assert.Equal(t, "", result)
+}
+
+func TestProcessMessageWithConfigRoundingMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		expected string
+	}{
+		{name: "Default rounds up", mode: "", expected: "That's nearly 10 Bunnings snags!"},
+		{name: "Round down drops nearly", mode: "down", expected: "That's 9 Bunnings snags!"},
+		{name: "Round nearest", mode: "nearest", expected: "That's nearly 10 Bunnings snags!"},
+		{name: "Unknown mode rounds up", mode: "sideways", expected: "That's nearly 10 Bunnings snags!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := models.NewChannelConfig("C12345")
+			config.RoundingMode = test.mode
+
+			assert.Equal(t, test.expected, ProcessMessageWithConfig("This costs $34", config))
+		})
+	}
+}
+
+func TestFormatResponseWithTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		count    int
+		itemName string
+		total    float64
+		expected string
+	}{
+		{
+			name:     "All placeholders",
+			template: "${total} buys {count} {item} 🌭",
+			count:    10,
+			itemName: "Bunnings snag",
+			total:    35,
+			expected: "$35.00 buys 10 Bunnings snags 🌭",
+		},
+		{
+			name:     "Singular item",
+			template: "Only {count} {item}",
+			count:    1,
+			itemName: "Bunnings snags",
+			total:    3.5,
+			expected: "Only 1 Bunnings snag",
+		},
+		{
+			name:     "Count only",
+			template: "{count}!",
+			count:    7,
+			itemName: "coffee",
+			total:    35,
+			expected: "7!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := FormatResponseWithTemplate(test.template, test.count, test.itemName, test.total)
+			assert.Equal(t, test.expected, result)
+		})
+	}
+}
+
+func TestValidateResponseTemplate(t *testing.T) {
+	assert.NoError(t, ValidateResponseTemplate("That's {count} {item}!"))
+	assert.Error(t, ValidateResponseTemplate("That's heaps of {item}!"))
+	assert.Error(t, ValidateResponseTemplate("  "))
+}
+
+func TestProcessMessageWithConfigTemplate(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.ResponseTemplate = "Mate, {count} {item} for ${total}"
+
+	assert.Equal(t, "Mate, 10 Bunnings snags for $35.00", ProcessMessageWithConfig("This costs $35", config))
+
+	// The "too small" response is unaffected by the template
+	assert.Equal(t, "That wouldn't even buy a single Bunnings snag!", ProcessMessageWithConfig("This costs $2", config))
+}
+
+func TestProcessMessageWithConfigZeroMessage(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+
+	// The default message is used until the channel sets one
+	assert.Equal(t, "That wouldn't even buy a single Bunnings snag!", ProcessMessageWithConfig("This costs $2", config))
+
+	config.ZeroMessage = "Not even one {item}, sorry"
+	assert.Equal(t, "Not even one Bunnings snag, sorry", ProcessMessageWithConfig("This costs $2", config))
+
+	// Totals that buy something are unaffected
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("This costs $35", config))
+}
+
+func TestProcessMessageWithConfigPhrases(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+
+	// The default phrasing is the original Australian wording
+	assert.Equal(t, "That's nearly 15 Bunnings snags!", ProcessMessageWithConfig("This costs $50", config))
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("This costs $35", config))
+	assert.Equal(t, "That wouldn't even buy a single Bunnings snag!", ProcessMessageWithConfig("This costs $2", config))
+
+	config.Locale = models.LocaleUS
+	assert.Equal(t, "That's about 15 Bunnings snags!", ProcessMessageWithConfig("This costs $50", config))
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("This costs $35", config))
+	assert.Equal(t, "That wouldn't even buy one Bunnings snag!", ProcessMessageWithConfig("This costs $2", config))
+
+	config.ShowBreakdown = true
+	assert.Equal(t, "That's $35 + $15 = $50, about 15 Bunnings snags!", ProcessMessageWithConfig("$35 and $15", config))
+
+	// Channels can override single phrases, keeping the rest of the locale's wording
+	config.ShowBreakdown = false
+	config.Phrases = &models.Phrases{Prefix: "Roughly speaking, that's ", Suffix: "."}
+	assert.Equal(t, "Roughly speaking, that's about 15 Bunnings snags.", ProcessMessageWithConfig("This costs $50", config))
+
+	// Unknown locales fall back to the default phrasing
+	config.Phrases = nil
+	config.Locale = "fr-FR"
+	assert.Equal(t, "That's nearly 15 Bunnings snags!", ProcessMessageWithConfig("This costs $50", config))
+}
+
+func TestFormatResponseWithPhrases(t *testing.T) {
+	phrases := models.LocalePhrases[models.LocaleGB]
+	assert.Equal(t, "That's roughly 2 pies!", FormatResponseWithPhrases(2, "pie", false, phrases))
+	assert.Equal(t, "That's 1 pie!", FormatResponseWithPhrases(1, "pie", true, phrases))
+	assert.Equal(t, "That wouldn't even stretch to a single pie!", FormatResponseWithPhrases(0, "pie", true, phrases))
+}
+
+func TestProcessMessageWithConfigSumMode(t *testing.T) {
+	invoice := "Invoice\nCatering: $35\nVenue hire $14\nCoffee: $2"
+
+	config := models.NewChannelConfig("C12345")
+	assert.Equal(t, "That's nearly 15 Bunnings snags!", ProcessMessageWithConfig(invoice, config))
+
+	config.SumMode = models.SumModePerLine
+	assert.Equal(t, "Line 2: 10 Bunnings snags, line 3: 4 Bunnings snags, line 4: not even 1 Bunnings snag",
+		ProcessMessageWithConfig(invoice, config))
+
+	// A single line with amounts is converted as a whole
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("Lunch\n$20 + $15", config))
+}
+
+func TestGetPluralForm(t *testing.T) {
+	tests := []struct {
+		itemName string
+		expected string
+	}{
+		{itemName: "snag", expected: "snags"},
+		{itemName: "Bunnings snag", expected: "Bunnings snags"},
+		{itemName: "coffee", expected: "coffees"},
+		{itemName: "candy", expected: "candies"},
+		{itemName: "person", expected: "people"},
+		{itemName: "Person", expected: "People"},
+		{itemName: "party person", expected: "party people"},
+		{itemName: "people", expected: "people"},
+		{itemName: "mouse", expected: "mice"},
+		{itemName: "sheep", expected: "sheep"},
+		{itemName: "loaf", expected: "loaves"},
+		{itemName: "knife", expected: "knives"},
+		{itemName: "chef", expected: "chefs"},
+		{itemName: "cafe", expected: "cafes"},
+		{itemName: "potato", expected: "potatoes"},
+		{itemName: "taco", expected: "tacos"},
+		{itemName: "kangaroo", expected: "kangaroos"},
+		{itemName: "cup of coffee", expected: "cups of coffee"},
+		{itemName: "Cup Of Coffee", expected: "Cups Of Coffee"},
+		{itemName: "piece of cake", expected: "pieces of cake"},
+		{itemName: "loaf of bread", expected: "loaves of bread"},
+		{itemName: "cups of coffee", expected: "cups of coffee"},
+		{itemName: "fish and chips", expected: "fish and chips"},
+		{itemName: "croissant and coffee", expected: "croissant and coffees"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.itemName, func(t *testing.T) {
+			assert.Equal(t, test.expected, getPluralForm(test.itemName))
+		})
+	}
+}
+
+func TestFormatResponseMultiWordItems(t *testing.T) {
+	assert.Equal(t, "That's 3 cups of coffee!", FormatResponse(3, "cup of coffee", true))
+	assert.Equal(t, "That's 1 piece of cake!", FormatResponse(1, "pieces of cake", true))
+	assert.Equal(t, "That wouldn't even buy a single fish and chips!", FormatResponse(0, "fish and chips", true))
+	assert.Equal(t, "That's nearly 2 fish and chips!", FormatResponse(2, "fish and chips", false))
+}
+
+func TestGetSingularForm(t *testing.T) {
+	tests := []struct {
+		itemName string
+		expected string
+	}{
+		{itemName: "snags", expected: "snag"},
+		{itemName: "people", expected: "person"},
+		{itemName: "mice", expected: "mouse"},
+		{itemName: "person", expected: "person"},
+		{itemName: "cactus", expected: "cactus"},
+		{itemName: "potatoes", expected: "potato"},
+		{itemName: "watches", expected: "watch"},
+		{itemName: "boxes", expected: "box"},
+		{itemName: "coffees", expected: "coffee"},
+		{itemName: "cups of coffee", expected: "cup of coffee"},
+		{itemName: "cup of coffee", expected: "cup of coffee"},
+		{itemName: "pieces of cake", expected: "piece of cake"},
+		{itemName: "piece of cake", expected: "piece of cake"},
+		{itemName: "fish and chips", expected: "fish and chips"},
+		{itemName: "salt & vinegar chips", expected: "salt & vinegar chips"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.itemName, func(t *testing.T) {
+			assert.Equal(t, test.expected, getSingularForm(test.itemName))
+		})
+	}
+}
+
+func TestProcessMessageWithConfigMinThreshold(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.MinThreshold = 20
+
+	// Under the threshold there's no response, not even the "too small" one
+	assert.Equal(t, "", ProcessMessageWithConfig("This costs $1", config))
+	assert.Equal(t, "That's nearly 6 Bunnings snags!", ProcessMessageWithConfig("This costs $20", config))
+	assert.Equal(t, "That's 10 Bunnings snags!", ProcessMessageWithConfig("This costs $35", config))
+}
+
+func TestChooseItem(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+
+	// Without extra items the config is used as is
+	assert.Same(t, config, ChooseItem(config))
+
+	config.AddItem("coffee", 4.00)
+	config.AddItem("pie", 6.00)
+
+	pick := func() []string {
+		opts := (&Options{}).WithRandomSource(rand.NewSource(42))
+		names := make([]string, 20)
+		for i := range names {
+			names[i] = ChooseItemWithOptions(config, opts).ItemName
+		}
+		return names
+	}
+
+	// The same seed gives the same picks
+	first := pick()
+	assert.Equal(t, first, pick())
+
+	// Every item gets picked, and the original config is untouched
+	assert.ElementsMatch(t, []string{"Bunnings snags", "coffee", "pie"}, uniqueStrings(first))
+	assert.Equal(t, "Bunnings snags", config.ItemName)
+	assert.Equal(t, 3.50, config.ItemPrice)
+}
+
+func TestProcessMessageWithConfigMultipleItems(t *testing.T) {
+	opts := (&Options{}).WithRandomSource(rand.NewSource(7))
+
+	config := models.NewChannelConfig("C12345")
+	config.AddItem("coffee", 4.00)
+	config.AddItem("pie", 6.00)
+
+	// The count must always use the price of the item that was picked
+	valid := map[string]bool{
+		"That's nearly 4 Bunnings snags!": true,
+		"That's 3 coffees!":               true,
+		"That's 2 pies!":                  true,
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 30; i++ {
+		response := ProcessMessageWithOptions("This costs $12", config, opts)
+		assert.True(t, valid[response], "Unexpected response: %s", response)
+		seen[response] = true
+	}
+	assert.Len(t, seen, len(valid))
+}
+
+func TestChooseItemRandomCatalog(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.AddItem("coffee", 4.00)
+	config.RandomItems = true
+
+	catalog := make(map[string]float64, len(models.ItemCatalog))
+	for _, item := range models.ItemCatalog {
+		catalog[item.Name] = item.Price
+	}
+
+	pick := func() []string {
+		opts := (&Options{}).WithRandomSource(rand.NewSource(42))
+		names := make([]string, 100)
+		for i := range names {
+			chosen := ChooseItemWithOptions(config, opts)
+			price, ok := catalog[chosen.ItemName]
+			assert.True(t, ok, "%s isn't in the catalog", chosen.ItemName)
+			assert.Equal(t, price, chosen.ItemPrice)
+			names[i] = chosen.ItemName
+		}
+		return names
+	}
+
+	// The same seed gives the same picks, and only catalog items are picked
+	first := pick()
+	assert.Equal(t, first, pick())
+	assert.Len(t, uniqueStrings(first), len(models.ItemCatalog))
+	assert.NotContains(t, first, "coffee")
+	assert.Equal(t, "Bunnings snags", config.ItemName)
+}
+
+func TestProcessMessageWithConfigRandomCatalog(t *testing.T) {
+	opts := (&Options{}).WithRandomSource(rand.NewSource(7))
+
+	config := models.NewChannelConfig("C12345")
+	config.RandomItems = true
+
+	// Every catalog item reads well and is counted at its own price
+	valid := make(map[string]bool, len(models.ItemCatalog))
+	for _, item := range models.ItemCatalog {
+		count, err := CalculateItemCount(66, item.Price)
+		assert.NoError(t, err)
+		valid[FormatResponse(count, item.Name, IsExactDivision(66, item.Price))] = true
+	}
+	assert.True(t, valid["That's 3 smashed avocados!"])
+	assert.True(t, valid["That's nearly 14 flat whites!"])
+	assert.True(t, valid["That's 44 Freddo Frogs!"])
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		response := ProcessMessageWithOptions("This costs $66", config, opts)
+		assert.True(t, valid[response], "Unexpected response: %s", response)
+		seen[response] = true
+	}
+	assert.Len(t, seen, len(valid))
+}
+
+func TestItemCatalogSingulars(t *testing.T) {
+	for _, item := range models.ItemCatalog {
+		assert.Equal(t, item.Name, getPluralForm(getSingularForm(item.Name)), item.Name)
+		assert.Positive(t, item.Price, item.Name)
+	}
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+func TestFormatResponseWithConfigVariety(t *testing.T) {
+	config := models.NewChannelConfig("C12345")
+	config.Variety = true
+
+	respond := func(count int, exact bool) []string {
+		opts := (&Options{}).WithRandomSource(rand.NewSource(42))
+		responses := make([]string, 20)
+		for i := range responses {
+			responses[i] = FormatResponseWithOptions(count, 0, exact, config, opts)
+		}
+		return responses
+	}
+
+	// The same seed words responses the same way
+	first := respond(10, true)
+	assert.Equal(t, first, respond(10, true))
+
+	// Every variation keeps the count, "nearly" and pluralization right
+	variants := map[string]bool{
+		"That's 10 Bunnings snags!":          true,
+		"Whoa, 10 Bunnings snags!":           true,
+		"That's 10 Bunnings snags worth!":    true,
+		"That'd get you 10 Bunnings snags!":  true,
+		"Hold up, that's 10 Bunnings snags!": true,
+	}
+	seen := make(map[string]bool)
+	for _, response := range first {
+		assert.True(t, variants[response], "Unexpected response %q", response)
+		seen[response] = true
+	}
+	assert.Greater(t, len(seen), 1, "Responses should vary")
+
+	for _, response := range respond(1, false) {
+		assert.Contains(t, response, "nearly 1 Bunnings snag")
+		assert.NotContains(t, response, "snags")
+	}
+
+	// A channel's own pool replaces the built-in one
+	config.Variations = []models.Phrases{{Prefix: "Crikey, ", Suffix: "!"}}
+	assert.Equal(t, []string{"Crikey, 2 Bunnings snags!", "Crikey, 2 Bunnings snags!"},
+		[]string{FormatResponseWithConfig(2, 0, true, config), FormatResponseWithConfig(2, 0, true, config)})
+
+	// Without variety the usual wording is used, and templates always win
+	config.Variety = false
+	assert.Equal(t, "That's 2 Bunnings snags!", FormatResponseWithConfig(2, 0, true, config))
+	config.Variety = true
+	config.ResponseTemplate = "{count} {item}, mate"
+	assert.Equal(t, "2 Bunnings snags, mate", FormatResponseWithConfig(2, 0, true, config))
+}
@@ -0,0 +1,117 @@
+package calculator
+
+import (
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// RateSource gives exchange rates between currencies, by ISO 4217 code like "USD"
+type RateSource interface {
+	// Rate returns how much one unit of from is worth in to, and false if it isn't known
+	Rate(from, to string) (float64, bool)
+}
+
+// StaticRates is a fixed table of exchange rates, the value of one unit of each currency in a
+// common base currency
+type StaticRates map[string]float64
+
+// NewStaticRates creates a rate table from the value of one unit of each currency in base
+func NewStaticRates(base string, rates map[string]float64) StaticRates {
+	table := StaticRates{base: 1}
+	for code, rate := range rates {
+		if code != base && rate > 0 {
+			table[code] = rate
+		}
+	}
+	return table
+}
+
+// Rate returns how much one unit of from is worth in to
+func (r StaticRates) Rate(from, to string) (float64, bool) {
+	if from == to {
+		return 1, true
+	}
+	fromRate, ok := r[from]
+	toRate, ok2 := r[to]
+	if !ok || !ok2 || toRate <= 0 {
+		return 0, false
+	}
+	return fromRate / toRate, true
+}
+
+// exchange converts amounts in other currencies to one currency before they're counted
+type exchange struct {
+	rates    RateSource
+	currency string
+}
+
+// newExchange converts amounts written in other currencies, like €10 or $10 USD, to currency
+// before they're counted, using rates. A nil rates or empty currency returns nil, which doesn't convert
+// Bare dollar signs, and other symbols without a known rate, are taken to be in currency already
+func newExchange(rates RateSource, currency string) *exchange {
+	if rates == nil || currency == "" {
+		return nil
+	}
+	return &exchange{rates: rates, currency: currency}
+}
+
+// currencySymbolCodes are the currencies written with a symbol other than a bare dollar sign
+var currencySymbolCodes = map[string]string{
+	"€":   "EUR",
+	"£":   "GBP",
+	"¥":   "JPY",
+	"₹":   "INR",
+	"US$": "USD",
+	"A$":  "AUD",
+	"AU$": "AUD",
+	"NZ$": "NZD",
+	"C$":  "CAD",
+	"CA$": "CAD",
+}
+
+// currencyCodeRegex matches a currency code written after an amount, like the USD of "$10 USD"
+var currencyCodeRegex = regexp.MustCompile(`^ ?([A-Z]{3})\b`)
+
+// symbols returns the currency symbols to match: the channel's, and those of every currency with a
+// known rate
+func (e *exchange) symbols(currencySymbols []string) []string {
+	if len(currencySymbols) == 0 {
+		currencySymbols = []string{DefaultCurrencySymbol}
+	}
+	symbols := append([]string(nil), currencySymbols...)
+	for symbol, code := range currencySymbolCodes {
+		if _, ok := e.rates.Rate(code, e.currency); ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// currencyOf returns the currency of an amount written with symbol, where rest is the text after
+// it, preferring a currency code after the amount. Amounts in currencies without a known rate
+// are taken to be in the exchange's currency
+func (e *exchange) currencyOf(symbol, rest string) string {
+	code := currencySymbolCodes[symbol]
+	if match := currencyCodeRegex.FindStringSubmatch(rest); match != nil {
+		code = match[1]
+	}
+	if _, ok := e.rates.Rate(code, e.currency); code == "" || !ok {
+		return e.currency
+	}
+	return code
+}
+
+// convert returns the value of an amount in currency in the exchange's currency, to the cent
+func (e *exchange) convert(value float64, currency string) float64 {
+	if currency == e.currency {
+		return value
+	}
+	rate, _ := e.rates.Rate(currency, e.currency)
+	converted := math.Round(value*rate*100) / 100
+	logging.Debug("Converted %.2f %s to %.2f %s", value, currency, converted, e.currency)
+	return converted
+}
@@ -0,0 +1,81 @@
+package calculator
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// discountWords mark an amount as money saved rather than spent, as in "$35 off" or "a $10 discount"
+var discountWords = map[string]bool{
+	"off":      true,
+	"discount": true,
+}
+
+// FilterFalseMatches drops or adjusts amounts that aren't really spending, when the channel has
+// turned the heuristics on
+// Amounts followed by "off" or "discount", or after "discount of", count as negative, so the
+// channel's negative handling decides what they do to the total
+// Messages whose only amounts are zero, like "a $0 fee", get no values so SnagBot stays quiet
+func FilterFalseMatches(text string, values []float64, config *models.ChannelConfig) []float64 {
+	return FilterFalseMatchesWithOptions(text, values, config, nil)
+}
+
+// FilterFalseMatchesWithOptions drops or adjusts amounts like FilterFalseMatches, for values
+// extracted with the deployment's options by ExtractDollarValuesWithOptions
+func FilterFalseMatchesWithOptions(text string, values []float64, config *models.ChannelConfig, opts *Options) []float64 {
+	if !config.FilterFalseMatches || len(values) == 0 {
+		return values
+	}
+
+	// Values from ExtractDollarValuesWithOptions start with the symbol amounts, in the same order
+	amounts, _ := extractDollarAmounts(text, extractOptionsFor(config, opts))
+
+	filtered := make([]float64, len(values))
+	copy(filtered, values)
+	for i, amount := range amounts {
+		if i >= len(filtered) {
+			break
+		}
+		if filtered[i] > 0 && isDiscount(text, amount) {
+			logging.Debug("Counting discounted amount %.2f as negative", filtered[i])
+			filtered[i] = -filtered[i]
+		}
+	}
+
+	for _, value := range filtered {
+		if value != 0 {
+			return filtered
+		}
+	}
+	logging.Debug("Only zero amounts found in text, skipping")
+	return []float64{}
+}
+
+// isDiscount reports whether the words around an amount describe it as a discount
+func isDiscount(text string, amount dollarAmount) bool {
+	if discountWords[nextWord(text[amount.end:])] {
+		return true
+	}
+
+	before := strings.Fields(strings.ToLower(text[:amount.start]))
+	n := len(before)
+	return n >= 2 && before[n-1] == "of" && strings.TrimFunc(before[n-2], isNotLetter) == "discount"
+}
+
+// nextWord returns the lowercase word at the start of text, skipping leading spaces
+func nextWord(text string) string {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	end := strings.IndexFunc(text, isNotLetter)
+	if end < 0 {
+		end = len(text)
+	}
+	return strings.ToLower(text[:end])
+}
+
+// isNotLetter reports whether r ends a word
+func isNotLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
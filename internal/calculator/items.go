@@ -0,0 +1,96 @@
+package calculator
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// random picks items for channels with several configured, and wordings for channels with
+// variety turned on, safe to share between goroutines
+type random struct {
+	rand  *rand.Rand
+	mutex sync.Mutex
+}
+
+// defaultRandom is used without options, or options without their own source
+var defaultRandom = newRandom(rand.NewSource(time.Now().UnixNano()))
+
+func newRandom(src rand.Source) *random {
+	return &random{rand: rand.New(src)}
+}
+
+// intn returns a random number in [0, n)
+func (r *random) intn(n int) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rand.Intn(n)
+}
+
+// WithRandomSource returns a copy of the options that picks items and wordings using src
+// Tests use a seeded source so the selection is deterministic
+func (o *Options) WithRandomSource(src rand.Source) *Options {
+	withSource := &Options{}
+	if o != nil {
+		*withSource = *o
+	}
+	withSource.random = newRandom(src)
+	return withSource
+}
+
+// randomFor returns the source the options pick items and wordings with
+func (o *Options) randomFor() *random {
+	if o == nil || o.random == nil {
+		return defaultRandom
+	}
+	return o.random
+}
+
+// ChooseItem picks one of the channel's items at random, or one from models.ItemCatalog if
+// the channel is in random mode
+// Returns a copy of the config with ItemName and ItemPrice set to the chosen item,
+// or the config unchanged if the channel only has one item
+func ChooseItem(config *models.ChannelConfig) *models.ChannelConfig {
+	return ChooseItemWithOptions(config, nil)
+}
+
+// ChooseItemWithOptions picks the item like ChooseItem, using the options' random source
+func ChooseItemWithOptions(config *models.ChannelConfig, opts *Options) *models.ChannelConfig {
+	if len(config.Items) == 0 && !config.RandomItems {
+		return config
+	}
+
+	items := config.AllItems()
+	if config.RandomItems {
+		items = models.ItemCatalog
+	}
+
+	item := items[opts.randomFor().intn(len(items))]
+
+	chosen := *config
+	chosen.SetItem(item.Name, item.Price)
+	return &chosen
+}
+
+// ChoosePhrases returns the phrases a response is worded with: the channel's usual phrases, with
+// the prefix and suffix of one of its variations picked at random when variety is on
+func ChoosePhrases(config *models.ChannelConfig) models.Phrases {
+	return ChoosePhrasesWithOptions(config, nil)
+}
+
+// ChoosePhrasesWithOptions returns the phrases like ChoosePhrases, using the options' random source
+func ChoosePhrasesWithOptions(config *models.ChannelConfig, opts *Options) models.Phrases {
+	phrases := config.ResponsePhrases()
+	pool := config.VariationPool()
+	if !config.Variety || len(pool) == 0 {
+		return phrases
+	}
+
+	variation := pool[opts.randomFor().intn(len(pool))]
+
+	phrases.Prefix = variation.Prefix
+	phrases.Suffix = variation.Suffix
+	return phrases
+}
@@ -0,0 +1,194 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+)
+
+// Money is an amount of money in whole cents, so sums and divisions are exact rather than
+// picking up floating point error, like 0.1 + 0.2 adding up to 0.30000000000000004
+type Money int64
+
+// maxMoney is the largest amount, in dollars, that converts to Money without losing cents
+const maxMoney = 1e13
+
+// MoneyFromFloat converts a dollar amount to Money, rounding to the nearest cent
+func MoneyFromFloat(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// moneyFromPrice converts a price to Money, as long as it is a positive, whole number of cents
+// that fits, so dividing by it gives the same count as dividing by the price itself
+func moneyFromPrice(price float64) (Money, bool) {
+	if !(price > 0) || price > maxMoney {
+		return 0, false
+	}
+	cents := MoneyFromFloat(price)
+	if cents <= 0 || math.Abs(price*100-float64(cents)) > 1e-6 {
+		return 0, false
+	}
+	return cents, true
+}
+
+// ParseMoney parses an amount like "35", "3.5", "-1234.56" or "1,234.56" into Money
+// Amounts with more than two decimal places are rejected rather than rounded
+func ParseMoney(text string) (Money, error) {
+	digits := strings.ReplaceAll(strings.TrimSpace(text), ",", "")
+	sign := Money(1)
+	if rest, ok := strings.CutPrefix(digits, "-"); ok {
+		sign, digits = -1, rest
+	}
+
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if (whole == "" && fraction == "") || len(fraction) > 2 || !allDigits(whole) || !allDigits(fraction) {
+		return 0, errors.Newf(errors.ErrInvalidDollarValue, "not an amount of money: %s", text)
+	}
+
+	var dollars int64
+	if whole != "" {
+		var err error
+		dollars, err = strconv.ParseInt(whole, 10, 64)
+		if err != nil || dollars > maxMoney {
+			return 0, errors.Newf(errors.ErrInvalidDollarValue, "amount too large: %s", text)
+		}
+	}
+
+	cents := int64(0)
+	if fraction != "" {
+		cents, _ = strconv.ParseInt(fraction+strings.Repeat("0", 2-len(fraction)), 10, 64)
+	}
+	return sign * Money(dollars*100+cents), nil
+}
+
+// allDigits reports whether s contains only ASCII digits
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Float returns the amount in dollars
+func (m Money) Float() float64 {
+	return float64(m) / 100
+}
+
+// Add returns the sum of the two amounts
+func (m Money) Add(other Money) Money {
+	return m + other
+}
+
+// DivCeil returns how many of price it takes to cover the amount, rounding up
+// price must be positive
+func (m Money) DivCeil(price Money) int64 {
+	count := int64(m / price)
+	if m%price > 0 {
+		count++
+	}
+	return count
+}
+
+// DivFloor returns how many of price the amount covers in full
+// price must be positive
+func (m Money) DivFloor(price Money) int64 {
+	count := int64(m / price)
+	if m%price < 0 {
+		count--
+	}
+	return count
+}
+
+// DivRound returns how many of price the amount is nearest to, with halves rounded up
+// price must be positive
+func (m Money) DivRound(price Money) int64 {
+	return (m * 2).Add(price).DivFloor(price * 2)
+}
+
+// Format formats the amount the way the currency is usually written, like FormatMoney
+func (m Money) Format(currency string) string {
+	return FormatMoney(m.Float(), currency)
+}
+
+// String formats the amount in dollars, like "$1,234.56"
+func (m Money) String() string {
+	return m.Format("$")
+}
+
+// moneyFormat describes how amounts in a currency are written
+type moneyFormat struct {
+	symbol   string
+	suffix   bool   // Symbol goes after the amount, like "1.234,56 €"
+	decimals int    // Digits after the decimal separator
+	group    string // Thousands separator
+	decimal  string // Decimal separator
+}
+
+// moneyFormats holds the formats of known currencies, by symbol and ISO code
+var moneyFormats = map[string]moneyFormat{
+	"$":   {symbol: "$", decimals: 2, group: ",", decimal: "."},
+	"USD": {symbol: "$", decimals: 2, group: ",", decimal: "."},
+	"AUD": {symbol: "$", decimals: 2, group: ",", decimal: "."},
+	"£":   {symbol: "£", decimals: 2, group: ",", decimal: "."},
+	"GBP": {symbol: "£", decimals: 2, group: ",", decimal: "."},
+	"€":   {symbol: "€", suffix: true, decimals: 2, group: ".", decimal: ","},
+	"EUR": {symbol: "€", suffix: true, decimals: 2, group: ".", decimal: ","},
+	"¥":   {symbol: "¥", decimals: 0, group: ",", decimal: "."},
+	"JPY": {symbol: "¥", decimals: 0, group: ",", decimal: "."},
+}
+
+// FormatMoney formats an amount the way the currency is usually written, like "$1,234.56",
+// "1.234,56 €" or "¥1,235"
+// currency is a symbol or ISO code, and unknown currencies are written like dollars with their symbol
+func FormatMoney(amount float64, currency string) string {
+	format, ok := moneyFormats[currency]
+	if !ok {
+		format = moneyFormats["$"]
+		format.symbol = currency
+	}
+
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	// Round once so the whole and fractional parts agree, like 9.999 becoming 10.00
+	scale := math.Pow(10, float64(format.decimals))
+	var whole, fraction string
+	if scaled := math.Round(amount * scale); scaled < math.MaxInt64 {
+		units := int64(scaled)
+		whole = strconv.FormatInt(units/int64(scale), 10)
+		fraction = fmt.Sprintf("%0*d", format.decimals, units%int64(scale))
+	} else {
+		// Too large to count in whole cents, so let strconv round it
+		whole, fraction, _ = strings.Cut(strconv.FormatFloat(amount, 'f', format.decimals, 64), ".")
+	}
+
+	number := groupThousands(whole, format.group)
+	if format.decimals > 0 {
+		number += format.decimal + fraction
+	}
+
+	if format.suffix {
+		return sign + number + " " + format.symbol
+	}
+	return sign + format.symbol + number
+}
+
+// groupThousands inserts the separator between each group of three digits
+func groupThousands(digits, separator string) string {
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(separator)
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
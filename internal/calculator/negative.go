@@ -0,0 +1,77 @@
+package calculator
+
+import (
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// NegativeHandling controls how negative amounts like -$50 count towards a message's total
+type NegativeHandling int
+
+const (
+	// NegativeInclude subtracts negative amounts from the total (the default)
+	NegativeInclude NegativeHandling = iota
+	// NegativeIgnore leaves negative amounts out of the total
+	NegativeIgnore
+	// NegativeAbsolute counts negative amounts as if they were positive
+	NegativeAbsolute
+)
+
+// String returns the name used for the policy in config
+func (h NegativeHandling) String() string {
+	switch h {
+	case NegativeInclude:
+		return "include"
+	case NegativeIgnore:
+		return "ignore"
+	case NegativeAbsolute:
+		return "absolute"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseNegativeHandling converts a policy name to a NegativeHandling
+// An empty name maps to NegativeInclude so unset channel configs keep the default behaviour
+func ParseNegativeHandling(name string) (NegativeHandling, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "include":
+		return NegativeInclude, nil
+	case "ignore":
+		return NegativeIgnore, nil
+	case "absolute", "abs":
+		return NegativeAbsolute, nil
+	default:
+		return NegativeInclude, errors.Newf(errors.ErrInvalidRequest, "unknown negative handling: %s", name)
+	}
+}
+
+// SumDollarValuesWithPolicy sums dollar values like SumDollarValues, applying the policy to negative values
+func SumDollarValuesWithPolicy(values []float64, policy NegativeHandling) (float64, error) {
+	if len(values) == 0 {
+		logging.Debug("Empty array provided to SumDollarValues")
+		return 0, nil
+	}
+
+	var total Money
+	for i, value := range values {
+		if value < 0 {
+			switch policy {
+			case NegativeIgnore:
+				logging.Debug("Ignoring negative dollar value at index %d: %.2f", i, value)
+				continue
+			case NegativeAbsolute:
+				value = -value
+			default:
+				logging.Debug("Subtracting negative dollar value at index %d: %.2f", i, value)
+			}
+		}
+		// Add in whole cents so the total has exactly 2 decimal places
+		total = total.Add(MoneyFromFloat(value))
+	}
+
+	logging.Debug("Summed %d dollar values to get %s", len(values), total)
+	return total.Float(), nil
+}
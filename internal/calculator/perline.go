@@ -0,0 +1,69 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// FormatPerLineResponse converts each line of a message with amounts on its own, like
+// "Line 1: 10 Bunnings snags, line 3: nearly 4 Bunnings snags"
+// Lines are numbered as they appear in the message, and lines without amounts are left out
+// Returns an empty string when fewer than two lines have amounts, so the message is converted as a whole
+func FormatPerLineResponse(text string, config *models.ChannelConfig) string {
+	return FormatPerLineResponseWithOptions(text, config, nil)
+}
+
+// FormatPerLineResponseWithOptions converts each line like FormatPerLineResponse, reading amounts
+// with the deployment's options
+func FormatPerLineResponseWithOptions(text string, config *models.ChannelConfig, opts *Options) string {
+	policy, _ := ParseNegativeHandling(config.NegativeHandling)
+	mode, _ := ParseRoundingMode(config.RoundingMode)
+
+	var parts []string
+	for i, line := range strings.Split(text, "\n") {
+		values, err := ExtractDollarValuesWithOptions(line, config, opts)
+		if err != nil && !IsTooManyDollarValues(err) {
+			continue
+		}
+		values = FilterFalseMatchesWithOptions(line, values, config, opts)
+		if len(values) == 0 {
+			continue
+		}
+
+		total, err := SumDollarValuesWithPolicy(values, policy)
+		if err != nil || total < 0 {
+			continue
+		}
+
+		parts = append(parts, fmt.Sprintf("line %d: %s", i+1, lineCountPhrase(total, mode, config)))
+	}
+
+	if len(parts) < 2 {
+		return ""
+	}
+	logging.Debug("Converted %d lines separately", len(parts))
+
+	parts[0] = strings.ToUpper(parts[0][:1]) + parts[0][1:]
+	return strings.Join(parts, ", ")
+}
+
+// lineCountPhrase describes how many of the channel's item one line's total buys
+func lineCountPhrase(total float64, mode RoundingMode, config *models.ChannelConfig) string {
+	name := config.CountedName()
+	if total < config.UnitPrice() {
+		return "not even 1 " + getSingularForm(name)
+	}
+
+	count, err := CalculateItemCountWithMode(total, config.UnitPrice(), mode)
+	if err != nil {
+		return "not even 1 " + getSingularForm(name)
+	}
+	if limit := config.ItemCountLimit(); count > limit {
+		return "over " + formatThousands(limit) + " " + getPluralForm(name)
+	}
+
+	return countPhraseWithConfig(count, UsesExactWording(total, config.UnitPrice(), mode), config)
+}
@@ -0,0 +1,186 @@
+package calculator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// irregularPlurals maps common irregular singular nouns to their plural forms
+var irregularPlurals = map[string]string{
+	"person": "people",
+	"child":  "children",
+	"man":    "men",
+	"woman":  "women",
+	"mouse":  "mice",
+	"goose":  "geese",
+	"tooth":  "teeth",
+	"foot":   "feet",
+	"ox":     "oxen",
+	"cactus": "cacti",
+	"sheep":  "sheep",
+	"fish":   "fish",
+	"deer":   "deer",
+}
+
+// irregularSingulars is the reverse of irregularPlurals
+var irregularSingulars = func() map[string]string {
+	singulars := make(map[string]string, len(irregularPlurals))
+	for singular, plural := range irregularPlurals {
+		singulars[plural] = singular
+	}
+	return singulars
+}()
+
+// unitAbbreviations are written the same whether there's one or many, like "180 L"
+var unitAbbreviations = map[string]bool{
+	"l":   true,
+	"ml":  true,
+	"kl":  true,
+	"m":   true,
+	"km":  true,
+	"g":   true,
+	"kg":  true,
+	"kwh": true,
+	"gb":  true,
+	"tb":  true,
+}
+
+// regularFWords end in -f or -fe but still just take an 's' (e.g. "chefs", "cafes")
+var regularFWords = map[string]bool{
+	"belief": true,
+	"brief":  true,
+	"cafe":   true,
+	"chef":   true,
+	"chief":  true,
+	"proof":  true,
+	"reef":   true,
+	"roof":   true,
+	"safe":   true,
+}
+
+// regularOWords end in a consonant and -o but still just take an 's' (e.g. "tacos")
+var regularOWords = map[string]bool{
+	"avocado":    true,
+	"burrito":    true,
+	"cappuccino": true,
+	"combo":      true,
+	"demo":       true,
+	"disco":      true,
+	"espresso":   true,
+	"euro":       true,
+	"gelato":     true,
+	"kilo":       true,
+	"logo":       true,
+	"macchiato":  true,
+	"memo":       true,
+	"mojito":     true,
+	"photo":      true,
+	"piano":      true,
+	"pro":        true,
+	"risotto":    true,
+	"taco":       true,
+}
+
+// splitLastWord splits an item name into everything before its last word and the last word
+func splitLastWord(itemName string) (string, string) {
+	i := strings.LastIndex(itemName, " ")
+	return itemName[:i+1], itemName[i+1:]
+}
+
+// takesES reports whether a plural ends in an "es" that was added to the singular, as in
+// "watches" or "potatoes", rather than one that was already there, as in "pieces"
+func takesES(lower string) bool {
+	for _, suffix := range []string{"ches", "shes", "sses", "xes", "zes", "oes"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitOfPhrase splits an item name like "cup of coffee" into its head noun phrase ("cup") and
+// the rest (" of coffee"), since it's the cups being counted
+func splitOfPhrase(itemName string) (string, string, bool) {
+	i := strings.Index(strings.ToLower(itemName), " of ")
+	if i <= 0 {
+		return "", "", false
+	}
+	return itemName[:i], itemName[i:], true
+}
+
+// isCombinedName reports whether an item name like "fish and chips" names two things served
+// together, which are counted as one item with the last word as written
+func isCombinedName(itemName string) bool {
+	lower := strings.ToLower(itemName)
+	return strings.Contains(lower, " and ") || strings.Contains(lower, " & ")
+}
+
+// matchCase capitalises the replacement's first letter if the original word was capitalised
+func matchCase(original, replacement string) string {
+	if original == "" || replacement == "" || !unicode.IsUpper([]rune(original)[0]) {
+		return replacement
+	}
+	runes := []rune(replacement)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// irregularPlural returns the plural of the item name's last word if it is irregular, a unit
+// abbreviation, or ends in -f, -fe or a consonant followed by -o
+func irregularPlural(itemName string) (string, bool) {
+	prefix, word := splitLastWord(itemName)
+	lower := strings.ToLower(word)
+
+	if unitAbbreviations[lower] {
+		return itemName, true
+	}
+
+	if plural, ok := irregularPlurals[lower]; ok {
+		return prefix + matchCase(word, plural), true
+	}
+
+	// Already an irregular plural, e.g. "people"
+	if _, ok := irregularSingulars[lower]; ok {
+		return itemName, true
+	}
+
+	if regularFWords[lower] || regularOWords[lower] {
+		return "", false
+	}
+
+	switch {
+	case strings.HasSuffix(lower, "fe") && !strings.HasSuffix(lower, "ffe"):
+		// "knife" -> "knives"
+		return itemName[:len(itemName)-2] + "ves", true
+	case strings.HasSuffix(lower, "f") && !strings.HasSuffix(lower, "ff"):
+		// "loaf" -> "loaves"
+		return itemName[:len(itemName)-1] + "ves", true
+	case strings.HasSuffix(lower, "o") && len(lower) > 1 && !strings.ContainsRune("aeiou", rune(lower[len(lower)-2])):
+		// "potato" -> "potatoes"
+		return itemName + "es", true
+	}
+
+	return "", false
+}
+
+// irregularSingular returns the singular of the item name's last word if it is a
+// known irregular plural, or the word itself if it is a known irregular singular or a unit abbreviation
+func irregularSingular(itemName string) (string, bool) {
+	prefix, word := splitLastWord(itemName)
+	lower := strings.ToLower(word)
+
+	if unitAbbreviations[lower] {
+		return itemName, true
+	}
+
+	if singular, ok := irregularSingulars[lower]; ok {
+		return prefix + matchCase(word, singular), true
+	}
+
+	// Already an irregular singular, e.g. "cactus"
+	if _, ok := irregularPlurals[lower]; ok {
+		return itemName, true
+	}
+
+	return "", false
+}
@@ -0,0 +1,73 @@
+package calculator
+
+import (
+	"math"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+)
+
+// RoundingMode controls how fractional item counts are rounded
+type RoundingMode int
+
+const (
+	// RoundUp always rounds up (the default, for fun!)
+	RoundUp RoundingMode = iota
+	// RoundDown rounds down to the number of items actually affordable
+	RoundDown
+	// RoundNearest rounds to the nearest whole item
+	RoundNearest
+)
+
+// String returns the name used for the rounding mode in commands and config
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundUp:
+		return "up"
+	case RoundDown:
+		return "down"
+	case RoundNearest:
+		return "nearest"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRoundingMode converts a rounding mode name to a RoundingMode
+// An empty name maps to RoundUp so unset channel configs keep the default behaviour
+func ParseRoundingMode(name string) (RoundingMode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "up", "ceil":
+		return RoundUp, nil
+	case "down", "floor":
+		return RoundDown, nil
+	case "nearest", "round":
+		return RoundNearest, nil
+	default:
+		return RoundUp, errors.Newf(errors.ErrInvalidRequest, "unknown rounding mode: %s", name)
+	}
+}
+
+// divide returns how many of price the amount buys, rounded according to the rounding mode
+func (m RoundingMode) divide(amount, price Money) int64 {
+	switch m {
+	case RoundDown:
+		return amount.DivFloor(price)
+	case RoundNearest:
+		return amount.DivRound(price)
+	default:
+		return amount.DivCeil(price)
+	}
+}
+
+// apply rounds the value according to the rounding mode
+func (m RoundingMode) apply(value float64) float64 {
+	switch m {
+	case RoundDown:
+		return math.Floor(value)
+	case RoundNearest:
+		return math.Round(value)
+	default:
+		return math.Ceil(value)
+	}
+}
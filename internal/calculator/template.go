@@ -0,0 +1,52 @@
+package calculator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+)
+
+// Placeholders supported in custom response templates
+const (
+	CountPlaceholder = "{count}"
+	ItemPlaceholder  = "{item}"
+	TotalPlaceholder = "{total}"
+)
+
+// ValidateResponseTemplate checks that a custom response template can be rendered
+// Templates must include the {count} placeholder
+func ValidateResponseTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return errors.New(errors.ErrInvalidRequest, "response template cannot be empty")
+	}
+
+	if !strings.Contains(template, CountPlaceholder) {
+		return errors.Newf(errors.ErrInvalidRequest, "response template must include %s", CountPlaceholder)
+	}
+
+	return nil
+}
+
+// FormatZeroResponse renders a channel's custom message for totals too small to buy a single item
+// {item} is the singular item name
+func FormatZeroResponse(message string, itemName string) string {
+	return strings.ReplaceAll(message, ItemPlaceholder, getSingularForm(itemName))
+}
+
+// FormatResponseWithTemplate renders a custom response template
+// {item} is singular or plural to match the count, and {total} is the summed amount
+func FormatResponseWithTemplate(template string, count int, itemName string, total float64) string {
+	item := getPluralForm(itemName)
+	if count == 1 {
+		item = getSingularForm(itemName)
+	}
+
+	replacer := strings.NewReplacer(
+		CountPlaceholder, strconv.Itoa(count),
+		ItemPlaceholder, item,
+		TotalPlaceholder, fmt.Sprintf("%.2f", total),
+	)
+	return replacer.Replace(template)
+}
@@ -0,0 +1,33 @@
+package calculator
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// FormatQuantity describes an amount of an item, like "180 L" or "1 litre"
+func FormatQuantity(quantity float64, itemName string) string {
+	quantity = math.Round(quantity*100) / 100
+	name := getPluralForm(itemName)
+	if quantity == 1 {
+		name = getSingularForm(itemName)
+	}
+	return strconv.FormatFloat(quantity, 'f', -1, 64) + " " + name
+}
+
+// DescribeUnit describes a unit of an item, like "tanks of 60 L"
+func DescribeUnit(unitName string, unitSize float64, itemName string) string {
+	return getPluralForm(unitName) + " of " + FormatQuantity(unitSize, itemName)
+}
+
+// countPhraseWithConfig describes a count of the channel's item in its phrasing, like "nearly 15 Bunnings snags",
+// adding how much of the item it is when counting units, like "nearly 3 tanks (180 L)"
+func countPhraseWithConfig(count int, isExactDivision bool, config *models.ChannelConfig) string {
+	phrase := countPhrase(count, config.CountedName(), isExactDivision, config.ResponsePhrases())
+	if config.HasUnit() {
+		phrase += " (" + FormatQuantity(float64(count)*config.UnitSize, config.ItemName) + ")"
+	}
+	return phrase
+}
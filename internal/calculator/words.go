@@ -0,0 +1,222 @@
+package calculator
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// wordRegex splits text into lowercase words, so "thirty-five" becomes "thirty" and "five"
+var wordRegex = regexp.MustCompile(`[a-z]+`)
+
+// smallNumberWords maps the number words below a hundred that are combined into larger numbers
+var smallNumberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+	"eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15,
+	"sixteen": 16, "seventeen": 17, "eighteen": 18, "nineteen": 19,
+	"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50,
+	"sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+// Scales multiply the number words before them
+const (
+	hundredWord  = "hundred"
+	thousandWord = "thousand"
+)
+
+// DefaultMoneyWords are the words that mark an amount as money when MONEY_WORDS isn't configured
+var DefaultMoneyWords = []string{"dollar", "dollars", "buck", "bucks"}
+
+// moneyWordSet returns the money words as a set, matched in any case, or nil if there aren't any
+func moneyWordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			set[word] = true
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return set
+}
+
+// MoneyWords returns the words that mark an amount as money when they follow it, like "quid" in
+// "20 quid" or "twenty quid", sorted
+func (o *Options) MoneyWords() []string {
+	if o == nil {
+		return sortedMoneyWords(nil)
+	}
+	return sortedMoneyWords(o.moneyWords)
+}
+
+// sortedMoneyWords returns the words in set sorted, or DefaultMoneyWords for a nil set
+func sortedMoneyWords(set map[string]bool) []string {
+	if set == nil {
+		words := append([]string(nil), DefaultMoneyWords...)
+		sort.Strings(words)
+		return words
+	}
+
+	words := make([]string, 0, len(set))
+	for word := range set {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// isCurrencyWord reports whether the word is a money word, ending a spelled-out amount
+// A nil set uses DefaultMoneyWords
+func isCurrencyWord(word string, set map[string]bool) bool {
+	if set != nil {
+		return set[word]
+	}
+	return slices.Contains(DefaultMoneyWords, word)
+}
+
+// moneyWordRegex builds the regex matching a number written in digits and followed by one of the
+// money words in set, like "50 bucks", reading the number like currencyRegex
+func moneyWordRegex(set map[string]bool, decimalComma bool) *regexp.Regexp {
+	words := sortedMoneyWords(set)
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+
+	number := `((?:[0-9]{1,3}(?:,[0-9]{3})+|[0-9]+)(?:\.[0-9]{1,2})?)`
+	if decimalComma {
+		number = `((?:[0-9]{1,3}(?:\.[0-9]{3})+|[0-9]+)(?:[,.][0-9]{1,2})?)`
+	}
+	return regexp.MustCompile(`(?i)(-)?\b` + number + `[ \t]*(?:` + strings.Join(quoted, "|") + `)\b`)
+}
+
+// isNumberWord reports whether the word can be part of a spelled-out number
+func isNumberWord(word string) bool {
+	_, small := smallNumberWords[word]
+	return small || word == hundredWord || word == thousandWord
+}
+
+// ParseWordAmounts extracts spelled-out dollar amounts from a string
+// Matches numbers written in words followed by a money word like "dollars" or "bucks", as in
+// "thirty-five dollars", "a hundred bucks" or "two thousand and fifty dollars"
+// Numbers that don't read as a single amount, like "five twenty dollars", are skipped
+func ParseWordAmounts(text string) ([]float64, error) {
+	return parseWordAmounts(text, nil)
+}
+
+// parseWordAmounts does the work of ParseWordAmounts, ending amounts at the money words in
+// moneyWords, or DefaultMoneyWords when it's nil
+func parseWordAmounts(text string, moneyWords map[string]bool) ([]float64, error) {
+	words := wordRegex.FindAllString(strings.ToLower(text), -1)
+
+	values := make([]float64, 0)
+	var run []string
+	for i, word := range words {
+		next := ""
+		if i+1 < len(words) {
+			next = words[i+1]
+		}
+
+		switch {
+		case isCurrencyWord(word, moneyWords):
+			if value, ok := wordsToNumber(run); ok && value > 0 {
+				values = append(values, float64(value))
+			}
+			run = nil
+		case isNumberWord(word):
+			run = append(run, word)
+		case word == "a" && (next == hundredWord || next == thousandWord || isCurrencyWord(next, moneyWords)):
+			// "a hundred" is one hundred, and "a buck" is one dollar
+			run = []string{"a"}
+		case word == "and" && len(run) > 0 && isNumberWord(next):
+			// "one hundred and fifty" reads as one number
+		default:
+			run = nil
+		}
+	}
+
+	logging.Debug("Extracted %d word amounts from text", len(values))
+	return values, nil
+}
+
+// wordsToNumber converts number words like ["thirty", "five"] to the number they spell
+// Returns false if the words don't form a single number
+func wordsToNumber(words []string) (int, bool) {
+	if len(words) == 0 {
+		return 0, false
+	}
+
+	total, current := 0, 0
+	// The smallest number word allowed next, so "twenty five" is fine but "five twenty" isn't
+	lastSmall := 0
+	for _, word := range words {
+		switch word {
+		case "a":
+			current, lastSmall = 1, 1
+		case hundredWord:
+			if current == 0 {
+				current = 1
+			}
+			if current >= 100 {
+				return 0, false
+			}
+			current *= 100
+			lastSmall = 0
+		case thousandWord:
+			if current == 0 && total == 0 {
+				current = 1
+			}
+			if total > 0 {
+				return 0, false
+			}
+			total, current = (total+current)*1000, 0
+			lastSmall = 0
+		default:
+			value := smallNumberWords[word]
+			if lastSmall != 0 && (lastSmall < 20 || lastSmall%10 != 0 || value >= 10) {
+				return 0, false
+			}
+			current += value
+			lastSmall = value
+		}
+	}
+
+	return total + current, true
+}
+
+// ExtractDollarValuesWithConfig extracts the dollar values in a message using the channel's
+// currency symbols, number format, repeat counting and cap, adding spelled-out amounts when the channel has turned them on
+// Like ExtractDollarValuesWithLimit, an ErrTooManyDollarValues error comes with the first values
+func ExtractDollarValuesWithConfig(text string, config *models.ChannelConfig) ([]float64, error) {
+	return ExtractDollarValuesWithOptions(text, config, nil)
+}
+
+// ExtractDollarValuesWithOptions extracts the dollar values in a message like
+// ExtractDollarValuesWithConfig, reading amounts with the deployment's options
+func ExtractDollarValuesWithOptions(text string, config *models.ChannelConfig, opts *Options) ([]float64, error) {
+	limit := config.DollarValueLimit()
+	extract := extractOptionsFor(config, opts)
+	values, err := extractDollarValues(text, extract)
+	if err != nil || !config.WordAmounts {
+		return values, err
+	}
+
+	wordValues, err := parseWordAmounts(text, extract.moneyWordSet)
+	if err != nil {
+		return values, errors.Wrap(err, "Failed to extract word amounts")
+	}
+
+	values = append(values, wordValues...)
+	if len(values) > limit {
+		logging.Warn("Message has more than %d dollar values, only counting the first %d", limit, limit)
+		return values[:limit], errors.Newf(errors.ErrTooManyDollarValues, "more than %d dollar values", limit)
+	}
+	return values, nil
+}
@@ -1,17 +1,21 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/mcncl/snagbot/internal/calculator"
 	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/metrics"
 	slack "github.com/mcncl/snagbot/internal/slack"
-	slackgo "github.com/slack-go/slack"
+	"github.com/mcncl/snagbot/pkg/models"
 )
 
 // Global store for backward compatibility
@@ -28,40 +32,70 @@ func SetGlobalStore(store slack.ChannelConfigStore) {
 // CommandHandler creates a handler for Slack slash commands
 func CommandHandler(cfg *config.Config) http.HandlerFunc {
 	// Create a single instance of the config store for all requests
-	configStore := slack.NewInMemoryConfigStoreWithConfig(cfg)
+	return CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStoreWithConfig(cfg))
+}
+
+// CommandHandlerWithStore creates a handler for Slack slash commands using the given config store
+func CommandHandlerWithStore(cfg *config.Config, configStore slack.ChannelConfigStore) http.HandlerFunc {
+	return CommandHandlerWithAPI(cfg, configStore, slack.NewRealSlackAPI(cfg.SlackBotToken))
+}
 
+// CommandHandlerWithAPI creates a handler for Slack slash commands using the given config store,
+// and Slack API to look up users' time zones with
+func CommandHandlerWithAPI(cfg *config.Config, configStore slack.ChannelConfigStore, api slack.SlackAPI) http.HandlerFunc {
 	// Set the global store for backward compatibility
 	globalConfigStore = configStore
+	verifier := slack.NewRequestVerifier(cfg.SlackSigningSecret, cfg.SlackRequestMaxAge)
+	tokenStore := slack.TokenStoreFor(cfg, configStore)
+
+	// Answer every configured name for the command, showing the first in help
+	commandNames := cfg.SlashCommands
+	if len(commandNames) == 0 {
+		commandNames = []string{config.DefaultSlashCommand}
+	}
+	amounts := calculator.NewOptions(cfg)
+	acceptedCommands := make(map[string]bool, len(commandNames))
+	for _, name := range commandNames {
+		acceptedCommands[name] = true
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests for commands
 		if r.Method != http.MethodPost {
 			logging.Warn("Method not allowed for command: %s", r.Method)
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			w.Header().Set("Allow", http.MethodPost)
+			writeResponse(w, http.StatusMethodNotAllowed, NewEphemeralResponse("Method not allowed"))
 			return
 		}
 
 		// Check if Slack signing secret is configured
 		if cfg.SlackSigningSecret == "" {
 			logging.Error("Slack signing secret not configured")
-			http.Error(w, "Server configuration error", http.StatusInternalServerError)
+			writeResponse(w, http.StatusInternalServerError, NewEphemeralResponse("SnagBot isn't set up to accept commands yet"))
 			return
 		}
 
-		// Read and verify the request from Slack
-		_, err := verifySlackRequest(r, cfg.SlackSigningSecret)
+		// Read and verify the request from Slack, rejecting stale requests
+		slack.LimitRequestBody(w, r, cfg.MaxRequestBodySize)
+		_, err := verifier.Verify(r)
+		if slack.IsRequestTooLarge(err) {
+			logging.Warn("Rejected oversized command request: %v", err)
+			writeResponse(w, http.StatusRequestEntityTooLarge, NewEphemeralResponse("Request body too large"))
+			return
+		}
 		if err != nil {
 			appErr := errors.Wrap(err, "Failed to verify Slack request")
 			logging.Error("Slack verification error: %v", appErr)
-			http.Error(w, "Invalid request", http.StatusUnauthorized)
+			writeResponse(w, http.StatusUnauthorized, NewEphemeralResponse("Invalid request"))
 			return
 		}
 
 		// Parse the form to get command data
+		// From here on the request is known to be from Slack, so failures are shown to the user
 		err = r.ParseForm()
 		if err != nil {
-			appErr := errors.WrapAndLog(err, "Error parsing form")
-			http.Error(w, appErr.Message, http.StatusBadRequest)
+			errors.WrapAndLog(err, "Error parsing form")
+			writeResponse(w, http.StatusOK, NewEphemeralResponse("Sorry, SnagBot couldn't read that command. Please try again."))
 			return
 		}
 
@@ -71,112 +105,752 @@ func CommandHandler(cfg *config.Config) http.HandlerFunc {
 		channelID := r.Form.Get("channel_id")
 		userID := r.Form.Get("user_id")
 		userName := r.Form.Get("user_name")
+		teamID := r.Form.Get("team_id")
 
 		// Log the command
 		logging.Info("Received command %s with text '%s' from user %s (%s) in channel %s",
 			command, text, userName, userID, channelID)
 
-		// Only process /snagbot command
-		if command != "/snagbot" {
+		// Only process SnagBot's own commands
+		if !acceptedCommands[strings.ToLower(command)] {
 			logging.Warn("Received unknown command: %s", command)
-			http.Error(w, "Unknown command", http.StatusBadRequest)
+			writeResponse(w, http.StatusOK, NewEphemeralResponse(fmt.Sprintf("SnagBot doesn't handle %s, try `%s help`", command, commandNames[0])))
 			return
 		}
 
+		// Channels without their own config use their workspace's default item
+		store := slack.ForWorkspace(configStore, teamID)
+
 		// Handle different subcommands with error handling
 		response := ""
 		var cmdErr error
 
+		// Only item changes can be announced in the channel, everything else is just for the user
+		announce := false
+
+		// Subcommand name for metrics, kept to a fixed set of values
+		subcommand := "item"
+
 		trimmedText := strings.TrimSpace(strings.ToLower(text))
 		switch {
 		case trimmedText == "reset":
-			response, cmdErr = safeHandleResetCommand(configStore, channelID)
+			subcommand = "reset"
+			response, cmdErr = safeHandleResetCommand(store, channelID, userID)
+		case trimmedText == "on" || trimmedText == "off":
+			subcommand = trimmedText
+			response, cmdErr = safeHandleSwitchCommand(store, trimmedText == "on", channelID, commandNames[0])
 		case trimmedText == "status" || trimmedText == "":
 			// Empty command will show status too
-			response, cmdErr = safeHandleStatusCommand(configStore, channelID)
+			subcommand = "status"
+			response, cmdErr = safeHandleStatusCommand(store, channelID, commandNames[0])
+		case trimmedText == "debug":
+			subcommand = "debug"
+			response, cmdErr = safeHandleDebugCommand(store, channelID)
+		case trimmedText == "list":
+			subcommand = "list"
+			response, cmdErr = safeHandleListCommand(cfg, configStore, userID, teamID)
+		case trimmedText == "undo":
+			subcommand = "undo"
+			response, cmdErr = safeHandleUndoCommand(configStore, store, channelID)
+		case trimmedText == "info":
+			subcommand = "info"
+			response, cmdErr = safeHandleInfoCommand(tokenStore, teamID, displayTimeZone(r.Context(), cfg, api, teamID, userID))
+		case trimmedText == "stats":
+			subcommand = "stats"
+			response, cmdErr = safeHandleStatsCommand(configStore, channelID)
 		case strings.HasPrefix(trimmedText, "help"):
-			response = handleHelpCommand()
+			subcommand = "help"
+			response = handleHelpCommand(commandNames[0])
+		case strings.HasPrefix(trimmedText, "rounding"):
+			subcommand = "rounding"
+			response, cmdErr = safeHandleRoundingCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "mode"):
+			subcommand = "mode"
+			response, cmdErr = safeHandleResponseModeCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "reply"):
+			subcommand = "reply"
+			response, cmdErr = safeHandleReplyCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "sum"):
+			subcommand = "sum"
+			response, cmdErr = safeHandleSumCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "trigger"):
+			subcommand = "trigger"
+			response, cmdErr = safeHandleTriggerCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "decimal"):
+			subcommand = "decimal"
+			response, cmdErr = safeHandleDecimalCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "locale"):
+			subcommand = "locale"
+			response, cmdErr = safeHandleLocaleCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "button"):
+			subcommand = "button"
+			response, cmdErr = safeHandleButtonCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "private"):
+			subcommand = "private"
+			response, cmdErr = safeHandlePrivateCommand(store, text, channelID)
+		case trimmedText == "export":
+			subcommand = "export"
+			response, cmdErr = safeHandleExportCommand(store, channelID, commandNames[0])
+		case strings.HasPrefix(trimmedText, "import"):
+			subcommand = "import"
+			response, cmdErr = safeHandleImportCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "ignore") || strings.HasPrefix(trimmedText, "unignore"):
+			subcommand = "ignore"
+			response, cmdErr = safeHandleIgnoreCommand(configStore, text, channelID, commandNames[0])
+		case strings.HasPrefix(trimmedText, "words"):
+			subcommand = "words"
+			response, cmdErr = safeHandleWordsCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "random"):
+			subcommand = "random"
+			response, cmdErr = safeHandleRandomCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "variety"):
+			subcommand = "variety"
+			response, cmdErr = safeHandleVarietyCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "repeats"):
+			subcommand = "repeats"
+			response, cmdErr = safeHandleRepeatsCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "filter"):
+			subcommand = "filter"
+			response, cmdErr = safeHandleFilterCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "breakdown"):
+			subcommand = "breakdown"
+			response, cmdErr = safeHandleBreakdownCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "emoji"):
+			subcommand = "emoji"
+			response, cmdErr = safeHandleEmojiCommand(store, text, channelID, commandNames[0])
+		case strings.HasPrefix(trimmedText, "threshold"):
+			subcommand = "threshold"
+			response, cmdErr = safeHandleThresholdCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "template-default"):
+			subcommand = "default"
+			response, cmdErr = safeHandleDefaultItemCommand(configStore, text, teamID, userID)
+		case strings.HasPrefix(trimmedText, "preview"):
+			subcommand = "preview"
+			response, cmdErr = safeHandlePreviewCommand(store, text, channelID, amounts, commandNames[0])
+		case strings.HasPrefix(trimmedText, "price"):
+			subcommand = "price"
+			response, cmdErr = safeHandlePriceCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "template"):
+			subcommand = "template"
+			response, cmdErr = safeHandleTemplateCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "zeromsg"):
+			subcommand = "zeromsg"
+			response, cmdErr = safeHandleZeroMessageCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "currency"):
+			subcommand = "currency"
+			response, cmdErr = safeHandleCurrencyCommand(store, text, channelID)
+		case strings.HasPrefix(trimmedText, "feedback"):
+			subcommand = "feedback"
+			response, cmdErr = safeHandleFeedbackCommand(r.Context(), api, cfg.FeedbackChannel, text, channelID, userID, teamID)
+		case strings.HasPrefix(trimmedText, "history"):
+			subcommand = "history"
+			response, cmdErr = safeHandleHistoryCommand(configStore, text, channelID, displayTimeZone(r.Context(), cfg, api, teamID, userID))
+		case strings.HasPrefix(trimmedText, "default"):
+			subcommand = "default"
+			response, cmdErr = safeHandleDefaultItemCommand(configStore, text, teamID, userID)
+		case strings.HasPrefix(trimmedText, "add"):
+			subcommand = "add"
+			response, cmdErr = safeHandleAddItemCommand(store, text, channelID)
 		default:
-			response, cmdErr = safeHandleConfigCommand(configStore, text, channelID)
+			response, announce, cmdErr = handleItemCommand(store, text, channelID, userID, cfg.AnnounceConfigChanges)
 		}
+		metrics.Default().CommandsHandled.WithLabelValues(subcommand).Inc()
 
 		// If there was an error, include a user-friendly error message
 		if cmdErr != nil {
 			logging.Error("Error handling command: %v", cmdErr)
-			response = fmt.Sprintf("Error: %s\n\nTry `/snagbot help` for usage information.",
-				errors.UserFriendlyError(cmdErr))
+			response = fmt.Sprintf("Error: %s\n\nTry `%s help` for usage information.",
+				errors.UserFriendlyError(cmdErr), commandNames[0])
 		}
 
 		// Return the response immediately with 200 OK
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		slackResponse := NewEphemeralResponse(response)
+		if announce && cmdErr == nil {
+			slackResponse = NewChannelResponse(response)
+		}
+		writeResponse(w, http.StatusOK, slackResponse)
+	}
+}
+
+// writeResponse writes a Slack response as JSON with the given status code
+// Slack only shows the response to the user with 200 OK, so other codes are kept for requests
+// that couldn't be verified as coming from Slack
+func writeResponse(w http.ResponseWriter, status int, response *SlackResponse) {
+	respJSON, err := response.ToJSON()
+	if err != nil {
+		logging.Error("Error marshalling response: %v", err)
+		respJSON = `{"response_type": "ephemeral", "text": "Error generating response"}`
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(respJSON))
+}
 
-		// Format the response as JSON
-		respJSON, err := json.Marshal(map[string]string{
-			"response_type": "ephemeral",
-			"text":          response,
-		})
+// safeHandleConfigCommand processes the command text and updates the channel configuration
+// with error handling, recording the change against userID
+func safeHandleConfigCommand(store slack.ChannelConfigStore, text, channelID, userID string) (string, error) {
+	response, _, err := handleItemCommand(store, text, channelID, userID, false)
+	return response, err
+}
+
+// handleItemCommand updates the channel configuration like safeHandleConfigCommand, also reporting
+// whether the confirmation should be announced in the channel
+// Changes are announced when the command ends in "public" or announceAll is set, but updates that
+// change nothing never are
+func handleItemCommand(store slack.ChannelConfigStore, text, channelID, userID string, announceAll bool) (string, bool, error) {
+	// Parse the command
+	result, err := ParseConfigCommand(text)
+	if err != nil {
+		return "", false, errors.Wrap(err, "Failed to parse command")
+	}
+
+	// Update the channel configuration
+	changed, err := slack.UpdateConfigChanged(store, channelID, result.ItemName, result.ItemPrice, userID)
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Is(errors.ErrItemNameTooLong) {
+		// The store's message already says how long names can be
+		return "", false, appErr
+	}
+	if err != nil {
+		return "", false, errors.Wrap(err, "Failed to update configuration")
+	}
+	if !changed && result.UnitName == "" {
+		return formatConfigUnchangedResponse(store, result, channelID), false, nil
+	}
 
+	// Changing the item clears its unit, so set the new one afterwards
+	if result.UnitName != "" {
+		config, err := store.GetConfig(channelID)
 		if err != nil {
-			logging.Error("Error marshalling response: %v", err)
-			w.Write([]byte(`{"response_type": "ephemeral", "text": "Error generating response"}`))
-			return
+			return "", false, errors.Wrap(err, "Failed to get configuration")
+		}
+		config.SetUnit(result.UnitName, result.UnitSize)
+		if err := store.SaveConfig(config); err != nil {
+			return "", false, errors.Wrap(err, "Failed to update configuration")
 		}
+	}
+
+	// Return success message
+	return formatConfigUpdatedResponse(store, result, channelID), result.Public || announceAll, nil
+}
 
-		w.Write(respJSON)
+// formatConfigUnchangedResponse explains that the channel already uses the item and price
+func formatConfigUnchangedResponse(store slack.ChannelConfigStore, result CommandParseResult, channelID string) string {
+	return fmt.Sprintf("Nothing to change, this channel already converts dollar amounts to %s (at %s each).",
+		result.ItemName, FormatPrice(result.ItemPrice, channelCurrency(store, channelID)))
+}
+
+// formatConfigUpdatedResponse formats the item update message in the channel's currency
+func formatConfigUpdatedResponse(store slack.ChannelConfigStore, result CommandParseResult, channelID string) string {
+	return FormatCommandResponseWithCurrency(result, channelCurrency(store, channelID))
+}
+
+// channelCurrency returns the symbol the channel shows prices in, or the default if its config can't be read
+func channelCurrency(store slack.ChannelConfigStore, channelID string) string {
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		logging.Warn("Failed to get configuration for currency, using default: %v", err)
+		return models.DefaultCurrency
 	}
+	return config.CurrencySymbol()
 }
 
-// verifySlackRequest verifies that a request is coming from Slack
-// Returns the request body if verification succeeds, or an error if it fails
-func verifySlackRequest(r *http.Request, signingSecret string) ([]byte, error) {
-	// Verify that the request is coming from Slack
-	sv, err := slackgo.NewSecretsVerifier(r.Header, signingSecret)
+// updateChannelSetting changes one of a channel's settings, saving it on top of the channel's
+// existing config so its item, price and other settings are kept
+func updateChannelSetting(store slack.ChannelConfigStore, channelID string, update func(config *models.ChannelConfig)) (*models.ChannelConfig, error) {
+	config, err := store.GetConfig(channelID)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to create secrets verifier")
+		return nil, errors.Wrap(err, "Failed to get configuration")
 	}
+	update(config)
 
-	// Read the body
-	body, err := io.ReadAll(r.Body)
+	if err := store.SaveConfig(config); err != nil {
+		return nil, errors.Wrap(err, "Failed to update configuration")
+	}
+	return config, nil
+}
+
+// safeHandleRoundingCommand sets how item counts are rounded for a channel with error handling
+func safeHandleRoundingCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	mode, err := ParseRoundingCommand(text)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to read request body")
+		return "", errors.Wrap(err, "Failed to parse command")
 	}
 
-	// Replace the body for later use (since ReadAll depletes it)
-	r.Body = io.NopCloser(strings.NewReader(string(body)))
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.RoundingMode = mode.String()
+	}); err != nil {
+		return "", err
+	}
 
-	// Add the body to the signature verification
-	sv.Write(body)
-	if err := sv.Ensure(); err != nil {
-		return nil, errors.Wrap(err, "Failed to verify Slack signature")
+	description := "round " + mode.String()
+	if mode == calculator.RoundNearest {
+		description = "round to the nearest item"
 	}
 
-	return body, nil
+	return fmt.Sprintf("Rounding updated! Item counts will now %s.", description), nil
 }
 
-// safeHandleConfigCommand processes the command text and updates the channel configuration
-// with error handling
-func safeHandleConfigCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+// safeHandleResponseModeCommand sets whether a channel gets messages or reactions with error handling
+func safeHandleResponseModeCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
 	// Parse the command
-	result, err := ParseConfigCommand(text)
+	mode, err := ParseResponseModeCommand(text)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to parse command")
 	}
 
-	// Update the channel configuration
-	err = store.UpdateConfig(channelID, result.ItemName, result.ItemPrice)
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ResponseMode = mode
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if config.RespondsWithReaction() {
+		return "Response mode updated! SnagBot will now react to dollar amounts with an emoji.", nil
+	}
+	return "Response mode updated! SnagBot will now reply to dollar amounts in a thread.", nil
+}
+
+// safeHandleReplyCommand sets whether a channel's replies go in a thread or the channel with error handling
+func safeHandleReplyCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	placement, err := ParseReplyCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ReplyPlacement = placement
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if config.ReplyInThread() {
+		return "Reply placement updated! SnagBot will now reply in a thread on the message.", nil
+	}
+	return "Reply placement updated! SnagBot will now reply in the channel.", nil
+}
+
+// safeHandleSumCommand sets whether a channel's messages are summed or converted line by line with error handling
+func safeHandleSumCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	mode, err := ParseSumCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.SumMode = mode
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if config.SumsPerLine() {
+		return "Sum mode updated! SnagBot will now convert each line of a message separately.", nil
+	}
+	return "Sum mode updated! SnagBot will now add up all the amounts in a message.", nil
+}
+
+// safeHandleTriggerCommand sets whether a channel gets a response to every message or only when
+// asked with its trigger word, with error handling
+func safeHandleTriggerCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	mode, word, err := ParseTriggerCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.TriggerMode = mode
+		if word != "" {
+			config.TriggerWord = word
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if mode == models.TriggerModeKeyword {
+		return fmt.Sprintf("Trigger updated! SnagBot will now only respond to messages containing \"%s\".", config.TriggerKeyword()), nil
+	}
+	return "Trigger updated! SnagBot will now respond to every message with dollar amounts.", nil
+}
+
+// safeHandleDecimalCommand sets the decimal separator of amounts in a channel's messages with error handling
+func safeHandleDecimalCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	format, err := ParseDecimalCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.NumberFormat = format
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if config.DecimalComma() {
+		return "Decimal separator updated! SnagBot will now read amounts written like $3,50 and $1.234,50.", nil
+	}
+	return "Decimal separator updated! SnagBot will now read amounts written like $3.50 and $1,234.50.", nil
+}
+
+// safeHandleLocaleCommand sets the built-in phrasing of a channel's responses with error handling
+func safeHandleLocaleCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	locale, err := ParseLocaleCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.Locale = locale
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Show how a typical response now reads
+	example := calculator.FormatResponseWithPhrases(15, config.CountedName(), false, config.ResponsePhrases())
+	return fmt.Sprintf("Locale updated to %s! Responses will now read like: %s", locale, example), nil
+}
+
+// safeHandleButtonCommand turns the "Change item" button on responses on or off with error handling
+func safeHandleButtonCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseButtonCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ChangeItemButton = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Button turned on! SnagBot's replies will include a \"Change item\" button.", nil
+	}
+	return "Button turned off! SnagBot's replies will be plain messages.", nil
+}
+
+// safeHandleWordsCommand turns counting amounts spelled out in words on or off with error handling
+func safeHandleWordsCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseWordsCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.WordAmounts = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Word amounts turned on! SnagBot will also count amounts like \"thirty-five dollars\".", nil
+	}
+	return "Word amounts turned off! SnagBot will only count amounts like $35.", nil
+}
+
+// safeHandleRandomCommand turns converting to random items from the built-in catalog on or off with error handling
+func safeHandleRandomCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseRandomCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.RandomItems = enabled
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Random items turned on! Each response will convert to something different, like flat whites, meat pies or movie tickets.", nil
+	}
+	return fmt.Sprintf("Random items turned off! SnagBot will convert to %s again.", config.ItemName), nil
+}
+
+// safeHandleVarietyCommand turns varied response wording on or off with error handling
+func safeHandleVarietyCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseVarietyCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.Variety = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Variety turned on! Each response will be worded a little differently, like \"Whoa, 15 snags!\"", nil
+	}
+	return "Variety turned off! Responses will be worded the usual way again.", nil
+}
+
+// safeHandleRepeatsCommand turns counting every occurrence of a repeated amount on or off with error handling
+func safeHandleRepeatsCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseRepeatsCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.CountRepeats = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Repeats turned on! \"I paid $35 and you paid $35\" will now add up to $70.", nil
+	}
+	return "Repeats turned off! An amount mentioned more than once in a message will only be counted once.", nil
+}
+
+// safeHandleFilterCommand turns filtering out false matches on or off with error handling
+func safeHandleFilterCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseFilterCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.FilterFalseMatches = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Filter turned on! SnagBot will ignore $0 amounts and count discounts like \"$35 off\" as savings.", nil
+	}
+	return "Filter turned off! SnagBot will count every amount as spending.", nil
+}
+
+// safeHandleBreakdownCommand turns showing how amounts add up on or off with error handling
+func safeHandleBreakdownCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParseBreakdownCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ShowBreakdown = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Breakdown turned on! SnagBot's replies will show how amounts add up, like \"$35 + $15 = $50\".", nil
+	}
+	return "Breakdown turned off! SnagBot's replies will only show the count.", nil
+}
+
+// safeHandlePrivateCommand turns replies visible only to the message's author on or off with error handling
+func safeHandlePrivateCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	enabled, err := ParsePrivateCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if _, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.Ephemeral = enabled
+	}); err != nil {
+		return "", err
+	}
+
+	if enabled {
+		return "Private replies turned on! Only the person who posted the amounts will see SnagBot's replies.", nil
+	}
+	return "Private replies turned off! Everyone in the channel will see SnagBot's replies.", nil
+}
+
+// safeHandleEmojiCommand sets the emoji a channel's reactions use with error handling
+func safeHandleEmojiCommand(store slack.ChannelConfigStore, text, channelID, commandName string) (string, error) {
+	// Parse the command
+	emoji, err := ParseEmojiCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.Emoji = emoji
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if config.RespondsWithReaction() {
+		return fmt.Sprintf("Emoji updated! SnagBot will now react with :%s:.", emoji), nil
+	}
+	return fmt.Sprintf("Emoji updated! SnagBot will react with :%s: once `%s mode reaction` is set.", emoji, commandName), nil
+}
+
+// safeHandleThresholdCommand sets the minimum total a channel responds to with error handling
+func safeHandleThresholdCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	threshold, err := ParseThresholdCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.MinThreshold = threshold
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if threshold == 0 {
+		return "Threshold removed! SnagBot will respond to any dollar amount.", nil
+	}
+	return fmt.Sprintf("Threshold updated! SnagBot will only respond to totals of at least %s.",
+		FormatPrice(threshold, config.CurrencySymbol())), nil
+}
+
+// safeHandleTemplateCommand sets a custom response template for a channel with error handling
+func safeHandleTemplateCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	template, err := ParseTemplateCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ResponseTemplate = template
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Show an example so users can check the template reads well
+	example := calculator.FormatResponseWithTemplate(template, 10, config.ItemName, 10*config.ItemPrice)
+	return fmt.Sprintf("Response template updated! Example: %s", example), nil
+}
+
+// safeHandleZeroMessageCommand sets the channel's message for totals too small to buy a single item
+func safeHandleZeroMessageCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	message, err := ParseZeroMessageCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.ZeroMessage = message
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if message == "" {
+		return fmt.Sprintf("Back to the usual message: %s", calculator.FormatResponseWithPhrases(0, config.ItemName, true, config.ResponsePhrases())), nil
+	}
+	return fmt.Sprintf("Zero message updated! Example: %s", calculator.FormatZeroResponse(message, config.ItemName)), nil
+}
+
+// safeHandleCurrencyCommand sets the currency symbol for a channel with error handling
+func safeHandleCurrencyCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	currency, err := ParseCurrencyCommand(text)
 	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := updateChannelSetting(store, channelID, func(config *models.ChannelConfig) {
+		config.SetCurrency(currency)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Currency updated! SnagBot will now look for %s amounts, with %s at %s each.",
+		currency, config.ItemName, FormatPrice(config.ItemPrice, currency)), nil
+}
+
+// safeHandleAddItemCommand adds an extra item for a channel to pick from at random with error handling
+func safeHandleAddItemCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	result, err := ParseAddItemCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	// Add to the existing config so the main item and other settings are kept
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	if len(config.Items) >= models.MaxChannelItems {
+		return "", errors.Wrap(ErrTooManyItems, "Failed to add item")
+	}
+	config.AddItem(result.ItemName, result.ItemPrice)
+
+	if err := store.SaveConfig(config); err != nil {
 		return "", errors.Wrap(err, "Failed to update configuration")
 	}
 
-	// Return success message
-	return FormatCommandResponse(result), nil
+	return fmt.Sprintf("Item added! SnagBot will now pick at random from %s.", formatItemList(config.AllItems(), config.CurrencySymbol())), nil
+}
+
+// formatItemList describes a list of items and their prices
+func formatItemList(items []models.ChannelItem, currency string) string {
+	descriptions := make([]string, len(items))
+	for i, item := range items {
+		descriptions[i] = fmt.Sprintf("%s (%s)", item.Name, FormatPrice(item.Price, currency))
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// safeHandleSwitchCommand switches SnagBot on or off in the channel with error handling, keeping
+// the channel's item and settings either way
+func safeHandleSwitchCommand(store slack.ChannelConfigStore, enabled bool, channelID, commandName string) (string, error) {
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	if config.Enabled() == enabled {
+		if enabled {
+			return "SnagBot is already on in this channel.", nil
+		}
+		return fmt.Sprintf("SnagBot is already off in this channel. Use `%s on` to switch it back on.", commandName), nil
+	}
+	config.Disabled = !enabled
+
+	if err := store.SaveConfig(config); err != nil {
+		return "", errors.Wrap(err, "Failed to update configuration")
+	}
+
+	if enabled {
+		return fmt.Sprintf("SnagBot is back on! Converting dollar amounts to %s again.", config.ItemName), nil
+	}
+	return fmt.Sprintf("SnagBot is off in this channel. Your item and settings are kept, use `%s on` to switch it back on.", commandName), nil
 }
 
-// safeHandleResetCommand resets a channel's configuration to the default with error handling
-func safeHandleResetCommand(store slack.ChannelConfigStore, channelID string) (string, error) {
+// safeHandleResetCommand resets a channel's configuration to the default with error handling,
+// recording the change against userID
+func safeHandleResetCommand(store slack.ChannelConfigStore, channelID, userID string) (string, error) {
 	// Reset the config
-	err := store.ResetConfig(channelID)
+	err := store.ResetConfig(channelID, userID)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to reset configuration")
 	}
@@ -187,50 +861,549 @@ func safeHandleResetCommand(store slack.ChannelConfigStore, channelID string) (s
 		return "", errors.Wrap(err, "Failed to get default configuration")
 	}
 
-	return fmt.Sprintf("Configuration has been reset! Now using the default item: %s (at $%.2f each).",
-		config.ItemName, config.ItemPrice), nil
+	return fmt.Sprintf("Configuration has been reset! Now using the default item: %s (at %s each).",
+		config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol())), nil
+}
+
+// safeHandleUndoCommand restores the channel's config from before its last change with error handling
+// The undo happens in configStore, while store shows the result with the workspace's default item
+func safeHandleUndoCommand(configStore, store slack.ChannelConfigStore, channelID string) (string, error) {
+	undoer, ok := configStore.(slack.ConfigUndoer)
+	if !ok {
+		return "Undo isn't available for this workspace.", nil
+	}
+
+	if err := undoer.Undo(channelID); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Is(errors.ErrNothingToUndo) {
+			return "There's nothing to undo in this channel.", nil
+		}
+		return "", errors.Wrap(err, "Failed to undo configuration change")
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	return fmt.Sprintf("Undone! Now converting dollar amounts to %s (at %s each).",
+		config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol())), nil
+}
+
+// configSourceDescriptions explain where a channel's effective setting came from
+var configSourceDescriptions = map[string]string{
+	models.ConfigSourceChannel:   "set in this channel",
+	models.ConfigSourceWorkspace: "the workspace default",
+	models.ConfigSourceGlobal:    "SnagBot's global default",
+}
+
+// safeHandleDebugCommand shows the channel's effective item and price and where each came from with error handling
+func safeHandleDebugCommand(store slack.ChannelConfigStore, channelID string) (string, error) {
+	resolved, err := slack.ResolveConfig(store, channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+	config := resolved.Config
+
+	return fmt.Sprintf("Effective configuration for this channel:\n• Item: %s (%s)\n• Price: %s (%s)",
+		config.ItemName, configSourceDescriptions[resolved.ItemSource],
+		FormatPrice(config.ItemPrice, config.CurrencySymbol()), configSourceDescriptions[resolved.PriceSource]), nil
+}
+
+// safeHandleListCommand lists the workspace's channels with a custom configuration for admins with error handling
+func safeHandleListCommand(cfg *config.Config, store slack.ChannelConfigStore, userID, teamID string) (string, error) {
+	if !cfg.AdminUsers[userID] {
+		return "Only SnagBot admins can list configured channels.", nil
+	}
+
+	all, err := store.GetAllConfigs()
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configurations")
+	}
+
+	// Configs saved without a workspace belong to the only workspace unless several are installed
+	configs := make([]models.ChannelConfig, 0, len(all))
+	for _, config := range all {
+		if config.WorkspaceID == teamID || (config.WorkspaceID == "" && !cfg.EnableMultiWorkspace) {
+			configs = append(configs, config)
+		}
+	}
+
+	return FormatChannelList(configs), nil
+}
+
+// FormatChannelList lists channels and their items, sorted by channel ID
+func FormatChannelList(configs []models.ChannelConfig) string {
+	if len(configs) == 0 {
+		return "No channels have a custom configuration yet."
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ChannelID < configs[j].ChannelID
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Configured channels (%d):*", len(configs))
+	for _, config := range configs {
+		fmt.Fprintf(&b, "\n• <#%s>: %s (at %s each)", config.ChannelID, config.ItemName,
+			FormatPrice(config.ItemPrice, config.CurrencySymbol()))
+		switch extra := len(config.Items); extra {
+		case 0:
+		case 1:
+			b.WriteString(" plus 1 more item")
+		default:
+			fmt.Fprintf(&b, " plus %d more items", extra)
+		}
+	}
+	return b.String()
+}
+
+// timeLayout is how times are written in command responses, like "5 Mar 2024 09:30 AEDT"
+const timeLayout = "2 Jan 2006 15:04 MST"
+
+// userTimeZoneTimeout bounds looking up a user's time zone, as Slack expects a reply to a
+// command within 3 seconds
+const userTimeZoneTimeout = time.Second
+
+// FormatTime writes a time for a command response in the time zone
+func FormatTime(t time.Time, location *time.Location) string {
+	return t.In(location).Format(timeLayout)
+}
+
+// displayTimeZone returns the time zone to show the user times in: their own from Slack
+// when USER_TIMEZONES is set and it can be looked up, otherwise the configured TIMEZONE
+func displayTimeZone(ctx context.Context, cfg *config.Config, api slack.SlackAPI, teamID, userID string) *time.Location {
+	location := cfg.TimeZone
+	if location == nil {
+		location = time.UTC
+	}
+
+	lookup, ok := api.(slack.UserTimeZoneLookup)
+	if !cfg.UserTimeZones || !ok || userID == "" {
+		return location
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, userTimeZoneTimeout)
+	defer cancel()
+	userLocation, err := lookup.GetUserTimeZone(ctx, teamID, userID)
+	if err != nil {
+		logging.Warn("Failed to look up time zone for user %s, using %s: %v", userID, location, err)
+		return location
+	}
+	return userLocation
+}
+
+// safeHandleHistoryCommand lists the channel's most recent item and price changes with error handling
+// Times are shown in the location
+func safeHandleHistoryCommand(store slack.ChannelConfigStore, text, channelID string, location *time.Location) (string, error) {
+	// Parse the command
+	limit, err := ParseHistoryCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	provider, ok := store.(slack.ConfigHistoryProvider)
+	if !ok {
+		return "Configuration history isn't available for this workspace.", nil
+	}
+
+	changes := provider.GetHistory(channelID, limit)
+	if len(changes) == 0 {
+		return "No configuration changes have been recorded for this channel yet.", nil
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	return formatHistory(changes, config.CurrencySymbol(), location), nil
+}
+
+// formatHistory describes a list of configuration changes, one per line, with times in the location
+func formatHistory(changes []models.ConfigChange, currency string, location *time.Location) string {
+	var b strings.Builder
+	b.WriteString("Recent configuration changes:")
+	for _, change := range changes {
+		action := "changed"
+		if change.Reset {
+			action = "reset"
+		}
+		fmt.Fprintf(&b, "\n• %s <@%s> %s %s (%s) → %s (%s)",
+			FormatTime(change.ChangedAt, location), change.UserID, action,
+			change.OldItemName, FormatPrice(change.OldItemPrice, currency),
+			change.NewItemName, FormatPrice(change.NewItemPrice, currency))
+	}
+	return b.String()
+}
+
+// safeHandleStatsCommand reports how often SnagBot has responded in a channel with error handling
+func safeHandleStatsCommand(store slack.ChannelConfigStore, channelID string) (string, error) {
+	recorder, ok := store.(slack.ChannelStatsRecorder)
+	if !ok {
+		return "Stats aren't available for this workspace.", nil
+	}
+
+	stats := recorder.GetStats(channelID)
+	if stats.Responses == 0 {
+		return "SnagBot hasn't responded in this channel yet.", nil
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	times := "times"
+	if stats.Responses == 1 {
+		times = "time"
+	}
+	return fmt.Sprintf("SnagBot has responded %d %s in this channel, converting %s in total.",
+		stats.Responses, times, FormatPrice(stats.TotalDollars, config.CurrencySymbol())), nil
+}
+
+// safeHandleInfoCommand shows who installed SnagBot to the workspace and when, in the location
+func safeHandleInfoCommand(tokenStore slack.TokenStore, teamID string, location *time.Location) (string, error) {
+	// A single-workspace bot token wasn't installed through OAuth, so there's nothing to show
+	if _, ok := tokenStore.(*slack.SingleTokenStore); ok {
+		return "SnagBot is running with a single bot token, so there are no install details for this workspace.", nil
+	}
+
+	token, err := tokenStore.GetToken(teamID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get install details for this workspace")
+	}
+	return FormatWorkspaceInfo(token, location), nil
+}
+
+// FormatWorkspaceInfo describes a workspace's install, leaving out details the token doesn't have
+// The install time is shown in the location
+func FormatWorkspaceInfo(token *models.WorkspaceToken, location *time.Location) string {
+	name := token.TeamName
+	if name == "" {
+		name = token.WorkspaceID
+	}
+
+	var b strings.Builder
+	b.WriteString("*SnagBot install info*")
+	fmt.Fprintf(&b, "\n• Workspace: %s", name)
+	if !token.InstalledAt.IsZero() {
+		fmt.Fprintf(&b, "\n• Installed: %s", FormatTime(token.InstalledAt, location))
+	}
+	if token.InstalledBy != "" {
+		fmt.Fprintf(&b, "\n• Installed by: <@%s>", token.InstalledBy)
+	}
+	return b.String()
+}
+
+// safeHandleExportCommand returns the channel's configuration as JSON for /snagbot import with error handling
+func safeHandleExportCommand(store slack.ChannelConfigStore, channelID, commandName string) (string, error) {
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+
+	// The workspace is set by the channel the config is imported into
+	config.WorkspaceID = ""
+
+	// Slash commands are a single line, so the JSON is kept compact for pasting into one
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to export configuration")
+	}
+
+	return fmt.Sprintf("Here's this channel's configuration. To copy it to another channel, run `%s import` there followed by:\n```%s```", commandName, data), nil
+}
+
+// safeHandleImportCommand applies a configuration exported from another channel with error handling
+func safeHandleImportCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	imported, err := ParseImportCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	// Keep the channel's own identity rather than the one it was exported from
+	current, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+	imported.ChannelID = channelID
+	imported.WorkspaceID = current.WorkspaceID
+
+	if err := store.SaveConfig(imported); err != nil {
+		return "", errors.Wrap(err, "Failed to update configuration")
+	}
+
+	return FormatCommandResponseWithCurrency(CommandParseResult{
+		ItemName:  imported.ItemName,
+		ItemPrice: imported.ItemPrice,
+	}, imported.CurrencySymbol()), nil
+}
+
+// safeHandleIgnoreCommand adds the channel or a user to SnagBot's ignore list, or removes them, with error handling
+func safeHandleIgnoreCommand(store slack.ChannelConfigStore, text, channelID, commandName string) (string, error) {
+	// Parse the command
+	target, err := ParseIgnoreCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	ignorer, ok := store.(slack.IgnoreListStore)
+	if !ok {
+		return "Ignoring channels and users isn't available for this workspace.", nil
+	}
+
+	if target.UserID == "" {
+		if err := ignorer.SetChannelIgnored(channelID, target.Ignore); err != nil {
+			return "", errors.Wrap(err, "Failed to update ignore list")
+		}
+		switch {
+		case target.Ignore:
+			return fmt.Sprintf("SnagBot will ignore this channel. Use `%s unignore` to have it respond here again.", commandName), nil
+		case ignorer.IsIgnored(channelID, ""):
+			return "This channel is still ignored by the IGNORED_CHANNELS setting, so SnagBot won't respond here.", nil
+		default:
+			return "SnagBot will respond in this channel again.", nil
+		}
+	}
+
+	if err := ignorer.SetUserIgnored(target.UserID, target.Ignore); err != nil {
+		return "", errors.Wrap(err, "Failed to update ignore list")
+	}
+	switch {
+	case target.Ignore:
+		return fmt.Sprintf("SnagBot will ignore messages from <@%s>.", target.UserID), nil
+	case ignorer.IsIgnored("", target.UserID):
+		return fmt.Sprintf("<@%s> is still ignored by the IGNORED_USERS setting, so SnagBot won't respond to them.", target.UserID), nil
+	default:
+		return fmt.Sprintf("SnagBot will respond to <@%s> again.", target.UserID), nil
+	}
 }
 
+// unknownConfigSourceNote is added to the status when the store can't say if the config is the channel's own
+const unknownConfigSourceNote = "Whether this is the channel's own configuration or the default is unknown right now, as the config store couldn't be checked."
+
 // safeHandleStatusCommand returns the current configuration for a channel with error handling
-func safeHandleStatusCommand(store slack.ChannelConfigStore, channelID string) (string, error) {
+func safeHandleStatusCommand(store slack.ChannelConfigStore, channelID, commandName string) (string, error) {
 	config, err := store.GetConfig(channelID)
 	if err != nil {
 		return "", errors.Wrap(err, "Failed to get configuration")
 	}
 
-	// Check if this is a custom or default config
-	isCustom := false
-	if checker, ok := store.(slack.ConfigExistsChecker); ok {
-		isCustom = checker.ConfigExists(channelID)
+	status := describeConfig(store, config, channelID)
+	if !config.Enabled() {
+		status += fmt.Sprintf("\nSnagBot is switched off in this channel. Use `%s on` to switch it back on.", commandName)
+	}
+	return status, nil
+}
+
+// describeConfig says which item or items the channel converts to, and whether they're its own
+func describeConfig(store slack.ChannelConfigStore, config *models.ChannelConfig, channelID string) string {
+	if len(config.Items) > 0 {
+		return fmt.Sprintf("Current configuration: picking at random from %s.",
+			formatItemList(config.AllItems(), config.CurrencySymbol()))
+	}
+
+	// Check if this is a custom or default config, saying so when the store can't tell us
+	isCustom, err := slack.CheckConfigExists(store, channelID)
+	if err != nil {
+		logging.Warn("Failed to check for a custom config in channel %s: %v", channelID, err)
+		return fmt.Sprintf("Current configuration: %s (at %s each). %s",
+			config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol()), unknownConfigSourceNote)
+	}
+
+	if isCustom && config.HasUnit() {
+		return fmt.Sprintf("Current configuration: %s (at %s each), counted in %s.",
+			config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol()),
+			calculator.DescribeUnit(config.UnitName, config.UnitSize, config.ItemName))
 	}
 
 	if isCustom {
-		return fmt.Sprintf("Current configuration: %s (at $%.2f each).",
-			config.ItemName, config.ItemPrice), nil
+		return fmt.Sprintf("Current configuration: %s (at %s each).",
+			config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol()))
 	} else {
-		return fmt.Sprintf("This channel is using the default configuration: %s (at $%.2f each).",
-			config.ItemName, config.ItemPrice), nil
+		return fmt.Sprintf("This channel is using the default configuration: %s (at %s each).",
+			config.ItemName, FormatPrice(config.ItemPrice, config.CurrencySymbol()))
+	}
+}
+
+// safeHandlePriceCommand sets only the channel's price with error handling, so a channel without
+// its own item keeps inheriting the workspace's default item, or the global default
+func safeHandlePriceCommand(store slack.ChannelConfigStore, text, channelID string) (string, error) {
+	// Parse the command
+	price, err := ParsePriceCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
+	}
+	config.InheritItem = config.InheritItem || !store.ConfigExists(channelID)
+	config.ItemPrice = price
+
+	if err := store.SaveConfig(config); err != nil {
+		return "", errors.Wrap(err, "Failed to update configuration")
+	}
+
+	response := fmt.Sprintf("Price updated! This channel now uses %s at %s each.",
+		config.ItemName, FormatPrice(price, config.CurrencySymbol()))
+	if config.InheritItem {
+		response += " The item still follows the workspace default."
+	}
+	return response, nil
+}
+
+// safeHandlePreviewCommand shows the response an amount would get with another item and price,
+// using the channel's other settings, without saving anything
+func safeHandlePreviewCommand(store slack.ChannelConfigStore, text, channelID string, amounts *calculator.Options, commandName string) (string, error) {
+	// Parse the command
+	result, amount, err := ParsePreviewCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get configuration")
 	}
+
+	// Try the item on a throwaway copy, with the amount written the way it's parsed by default
+	preview := *config
+	preview.SetItem(result.ItemName, result.ItemPrice)
+	preview.SetUnit(result.UnitName, result.UnitSize)
+	preview.Items = nil
+	preview.NumberFormat = models.NumberFormatPoint
+
+	message := FormatPrice(amount, preview.CurrencySymbol())
+	reply := calculator.ProcessMessageWithOptions(message, &preview, amounts)
+	if reply == "" {
+		return fmt.Sprintf("Preview: SnagBot wouldn't respond to %s in this channel.", message), nil
+	}
+	return fmt.Sprintf("Preview for %s: %s\nNothing has been saved, use `%s item` to keep it.", message, reply, commandName), nil
+}
+
+// feedbackTimeout bounds relaying feedback, as Slack expects a reply to a command within 3 seconds
+const feedbackTimeout = 2 * time.Second
+
+// safeHandleFeedbackCommand relays feedback to the configured feedback channel with error handling
+// Feedback always goes to the feedback channel in SnagBot's own workspace, whichever one it came from
+func safeHandleFeedbackCommand(ctx context.Context, api slack.SlackAPI, feedbackChannel, text, channelID, userID, teamID string) (string, error) {
+	// Parse the command
+	message, err := ParseFeedbackCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	if feedbackChannel == "" {
+		return "Feedback isn't set up for this SnagBot, so it can't be sent.", nil
+	}
+
+	from := fmt.Sprintf("<@%s> in <#%s>", userID, channelID)
+	if teamID != "" {
+		from += fmt.Sprintf(" (workspace %s)", teamID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, feedbackTimeout)
+	defer cancel()
+	err = api.PostMessage(ctx, slack.SlackResponse{
+		ChannelID: feedbackChannel,
+		Text:      fmt.Sprintf("Feedback from %s:\n>%s", from, strings.ReplaceAll(message, "\n", "\n>")),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to send feedback")
+	}
+
+	logging.Info("Relayed feedback from user %s to channel %s", userID, feedbackChannel)
+	return "Thanks for the feedback! It's been passed on to the people who run SnagBot.", nil
+}
+
+// safeHandleDefaultItemCommand sets the item used by the workspace's channels that haven't
+// chosen their own, with error handling
+func safeHandleDefaultItemCommand(store slack.ChannelConfigStore, text, teamID, userID string) (string, error) {
+	// Parse the command
+	result, err := ParseDefaultItemCommand(text)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse command")
+	}
+
+	defaults, ok := store.(slack.WorkspaceDefaultsStore)
+	if !ok || teamID == "" {
+		return "Workspace defaults aren't available for this workspace.", nil
+	}
+
+	err = defaults.SaveWorkspaceDefault(&models.WorkspaceDefault{
+		WorkspaceID: teamID,
+		ItemName:    result.ItemName,
+		ItemPrice:   result.ItemPrice,
+		UpdatedBy:   userID,
+		UpdatedAt:   time.Now(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to update workspace default")
+	}
+
+	return fmt.Sprintf("Workspace default updated! Channels without their own item will now use %s (at %s each).",
+		result.ItemName, FormatPrice(result.ItemPrice, models.DefaultCurrency)), nil
 }
 
 // handleHelpCommand returns help information about how to use the bot
-func handleHelpCommand() string {
-	return `*SnagBot Help*
+// Commands are shown under commandName, the primary name SnagBot answers to
+func handleHelpCommand(commandName string) string {
+	return strings.ReplaceAll(`*SnagBot Help*
 
 SnagBot automatically responds to messages containing dollar amounts by converting them to a fun comparison.
 
 *Available Commands:*
 • /snagbot or /snagbot status - Show current configuration
-• /snagbot item "coffee" price 5.00 - Set custom item and price
+• /snagbot item "coffee" price 5.00 - Set custom item and price (add public to announce it in the channel)
+• /snagbot item "pie" price 2 for 5.00 - Set the price from a deal, like 2 for $5.00
+• /snagbot preview item "coffee" price 5.00 amount 35 - See the response $35 would get, without saving anything
+• /snagbot item "L" price 2.00 unit "tank" size 60 - Count the item in units, like "nearly 3 tanks (180 L)"
+• /snagbot add item "pie" price 6.00 - Add another item to pick from at random
+• /snagbot rounding up|down|nearest - Choose how item counts are rounded
+• /snagbot mode message|reaction - Reply in a thread or react with an emoji
+• /snagbot emoji :taco: - Choose the emoji used in reaction mode
+• /snagbot reply thread|channel - Reply in a thread on the message or in the channel
+• /snagbot sum total|lines - Add up all of a message's amounts, or convert each line separately
+• /snagbot trigger always|keyword [word] - Respond to every message, or only to messages containing a word (defaults to "snagify")
+• /snagbot decimal point|comma - Read amounts like $3.50 (default) or $3,50
+• /snagbot locale en-AU|en-US|en-GB - Choose the phrasing of replies, like "nearly", "about" or "roughly"
+• /snagbot button on|off - Add a "Change item" button to replies
+• /snagbot private on|off - Only show replies to the person who posted the amounts
+• /snagbot export - Show this channel's configuration as JSON
+• /snagbot import {json} - Apply a configuration from /snagbot export
+• /snagbot ignore [@user] - Stop responding in this channel, or to a user (/snagbot unignore to undo)
+• /snagbot words on|off - Also count amounts written in words, like "thirty-five dollars"
+• /snagbot variety on|off - Word each response differently, like "Whoa, 15 snags!"
+• /snagbot random on|off - Convert to a random item from SnagBot's catalog, like flat whites or meat pies, in each response
+• /snagbot repeats on|off - Count an amount every time it appears, so "$35 and another $35" adds up to $70
+• /snagbot filter on|off - Ignore $0 amounts and count discounts like "$35 off" as savings
+• /snagbot breakdown on|off - Show how several amounts add up, like "$35 + $15 = $50"
+• /snagbot threshold 20 - Only respond to totals of at least $20 (0 to respond to any amount)
+• /snagbot currency £ - Set the currency symbol to look for and show prices in
+• /snagbot template "That's {count} {item}!" - Customise the response ({count}, {item} and {total} are filled in)
+• /snagbot zeromsg "Not even one {item}!" - Customise the response when the total won't buy a single item (default to undo)
+• /snagbot info - Show the workspace SnagBot is installed to, when and by whom
+• /snagbot stats - Show how often SnagBot has responded here and the dollars converted
+• /snagbot history 10 - Show who recently changed the item or price (defaults to the last 5 changes)
+• /snagbot default item "coffee" price 5.00 - Set the item used by channels in this workspace that haven't chosen one (or /snagbot template-default)
+• /snagbot price 4.00 - Set only this channel's price, keeping the workspace default item
+• /snagbot undo - Undo the last configuration change
+• /snagbot debug - Show the item and price this channel uses and where each comes from
+• /snagbot off - Stop responding in this channel, keeping its item and settings (/snagbot on to switch back on)
+• /snagbot feedback your message here - Send feedback to the people who run SnagBot
 • /snagbot reset - Reset to default configuration
+• /snagbot list - List the channels with a custom configuration (admins only)
 • /snagbot help - Show this help message
 
-By default, dollar amounts are converted to Bunnings snags at $3.50 each.`
+By default, dollar amounts are converted to Bunnings snags at $3.50 each.`, config.DefaultSlashCommand, commandName)
 }
 
 // handleConfigCommandWithService processes a configuration command with the specified service
 // This function is used by tests and addresses the missing function issue
-func handleConfigCommandWithService(text, channelID string, service *slack.SlackService) string {
+func handleConfigCommandWithService(text, channelID, userID string, service *slack.SlackService) string {
 	// Parse the command
 	result, err := ParseConfigCommand(text)
 	if err != nil {
@@ -239,12 +1412,12 @@ func handleConfigCommandWithService(text, channelID string, service *slack.Slack
 	}
 
 	// Update the channel configuration
-	err = service.ConfigStore.UpdateConfig(channelID, result.ItemName, result.ItemPrice)
+	err = service.ConfigStore.UpdateConfig(channelID, result.ItemName, result.ItemPrice, userID)
 	if err != nil {
 		logging.Error("Error updating channel config: %v", err)
 		return fmt.Sprintf("Error updating configuration: %v", err)
 	}
 
 	// Return success message
-	return FormatCommandResponse(result)
+	return formatConfigUpdatedResponse(service.ConfigStore, result, channelID)
 }
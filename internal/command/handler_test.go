@@ -1,13 +1,50 @@
 package command
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/slack"
+	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// TestConfigStores checks the slack package's stores are usable through the one ChannelConfigStore interface
+func TestConfigStores(t *testing.T) {
+	cfg := &config.Config{DefaultItemName: "Bunnings snags", DefaultItemPrice: 3.50}
+	configStore, err := slack.NewConfigStore(cfg)
+	require.NoError(t, err)
+	stores := []slack.ChannelConfigStore{
+		slack.NewInMemoryConfigStore(),
+		slack.NewInMemoryConfigStoreWithConfig(cfg),
+		configStore,
+	}
+
+	for _, store := range stores {
+		config, err := store.GetConfig("C12345")
+		assert.NoError(t, err)
+		assert.Equal(t, "Bunnings snags", config.ItemName)
+
+		response, err := safeHandleStatusCommand(store, "C12345", "/snagbot")
+		assert.NoError(t, err)
+		assert.Equal(t, "This channel is using the default configuration: Bunnings snags (at $3.50 each).", response)
+	}
+}
+
 // TestHandleConfigCommand tests the command handler logic
 func TestHandleConfigCommand(t *testing.T) {
 	// Store the original channelConfigs map
@@ -74,7 +111,7 @@ func TestHandleConfigCommand(t *testing.T) {
 			globalConfigStore = configStore
 
 			// Process the command
-			response := handleConfigCommand(configStore, test.commandText, test.channelID)
+			response := handleConfigCommand(configStore, test.commandText, test.channelID, "U12345")
 
 			// Check if response indicates success or failure
 			if test.expectedSuccess {
@@ -133,7 +170,7 @@ func TestHandleConfigCommandWithService(t *testing.T) {
 			service := slack.NewSlackServiceWithDependencies(configStore, mockAPI, cfg)
 
 			// Process the command
-			response := handleConfigCommandWithService(test.commandText, test.channelID, service)
+			response := handleConfigCommandWithService(test.commandText, test.channelID, "U12345", service)
 
 			// Check if response indicates success or failure
 			if test.expectedSuccess {
@@ -154,3 +191,1311 @@ func TestHandleConfigCommandWithService(t *testing.T) {
 		})
 	}
 }
+
+// TestSafeHandleRoundingCommand tests that the rounding mode is saved without touching the item
+func TestSafeHandleRoundingCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleRoundingCommand(configStore, "rounding down", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Rounding updated! Item counts will now round down.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "down", config.RoundingMode)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 5.00, config.ItemPrice)
+
+	_, err = safeHandleRoundingCommand(configStore, "rounding sideways", "C12345")
+	assert.Error(t, err)
+}
+
+// TestSafeHandleTemplateCommand tests that a custom template is saved for the channel
+func TestSafeHandleTemplateCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleTemplateCommand(configStore, `template "Mate, that's {count} {item}"`, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Response template updated! Example: Mate, that's 10 Bunnings snags", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Mate, that's {count} {item}", config.ResponseTemplate)
+
+	_, err = safeHandleTemplateCommand(configStore, `template "no count here"`, "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleZeroMessageCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleZeroMessageCommand(configStore, `zeromsg "Not even one {item}!"`, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Zero message updated! Example: Not even one Bunnings snag!", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Not even one {item}!", config.ZeroMessage)
+
+	response, err = safeHandleZeroMessageCommand(configStore, "zeromsg default", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Back to the usual message: That wouldn't even buy a single Bunnings snag!", response)
+
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Empty(t, config.ZeroMessage)
+
+	_, err = safeHandleZeroMessageCommand(configStore, "zeromsg", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleAddItemCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleAddItemCommand(configStore, `add item "pie" price 6.00`, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Item added! SnagBot will now pick at random from Bunnings snags ($3.50), pie ($6.00).", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.ChannelItem{{Name: "pie", Price: 6.00}}, config.Items)
+
+	response, err = safeHandleStatusCommand(configStore, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Current configuration: picking at random from Bunnings snags ($3.50), pie ($6.00).", response)
+
+	// Adding beyond the limit is rejected
+	for i := len(config.Items); i < models.MaxChannelItems; i++ {
+		_, err = safeHandleAddItemCommand(configStore, `add item "pie" price 6.00`, "C12345")
+		assert.NoError(t, err)
+	}
+	_, err = safeHandleAddItemCommand(configStore, `add item "pie" price 6.00`, "C12345")
+	assert.Error(t, err)
+}
+
+// unreachableConfigStore keeps configs in memory but can't say whether a channel has its own
+type unreachableConfigStore struct {
+	*slack.InMemoryConfigStore
+}
+
+func (s *unreachableConfigStore) ConfigExistsChecked(channelID string) (bool, error) {
+	return false, fmt.Errorf("connection refused")
+}
+
+func TestSafeHandleStatusCommand_UnknownSource(t *testing.T) {
+	store := &unreachableConfigStore{slack.NewInMemoryConfigStore()}
+
+	response, err := safeHandleStatusCommand(store, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Current configuration: Bunnings snags (at $3.50 each). "+unknownConfigSourceNote, response)
+
+	service := NewCommandService(store)
+	assert.Equal(t, "Current configuration: Bunnings snags (at $3.50 each). "+unknownConfigSourceNote, service.HandleStatusCommand("C12345"))
+}
+
+func TestSafeHandleDefaultItemCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleDefaultItemCommand(configStore, `default item "coffee" price 5.00`, "T12345", "U12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Workspace default updated! Channels without their own item will now use coffee (at $5.00 each).", response)
+
+	// Channels in the workspace pick up the new default, other workspaces keep the global one
+	response, err = safeHandleStatusCommand(slack.ForWorkspace(configStore, "T12345"), "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "This channel is using the default configuration: coffee (at $5.00 each).", response)
+
+	response, err = safeHandleStatusCommand(slack.ForWorkspace(configStore, "T67890"), "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "This channel is using the default configuration: Bunnings snags (at $3.50 each).", response)
+
+	// The command needs to know which workspace it came from
+	response, err = safeHandleDefaultItemCommand(configStore, `default item "coffee" price 5.00`, "", "U12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Workspace defaults aren't available for this workspace.", response)
+}
+
+func TestSafeHandlePriceCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	_, err := safeHandleDefaultItemCommand(configStore, `template-default item "coffee" price 5.00`, "T12345", "U12345")
+	require.NoError(t, err)
+	store := slack.ForWorkspace(configStore, "T12345")
+
+	// A channel without its own item only overrides the price
+	response, err := safeHandlePriceCommand(store, "price 4.00", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Price updated! This channel now uses coffee at $4.00 each. The item still follows the workspace default.", response)
+
+	// And picks up the template's new item
+	_, err = safeHandleDefaultItemCommand(configStore, `template-default item "pie" price 6.00`, "T12345", "U12345")
+	require.NoError(t, err)
+	config, err := store.GetConfig("C12345")
+	require.NoError(t, err)
+	assert.Equal(t, "pie", config.ItemName)
+	assert.Equal(t, 4.00, config.ItemPrice)
+
+	// A channel with its own item keeps it
+	require.NoError(t, configStore.UpdateConfig("C67890", "tea", 3.00, "U12345"))
+	response, err = safeHandlePriceCommand(store, "price 2 for 5", "C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "Price updated! This channel now uses tea at $2.50 each.", response)
+
+	_, err = safeHandlePriceCommand(store, "price nothing", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleCurrencyCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleCurrencyCommand(configStore, "currency £", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Currency updated! SnagBot will now look for £ amounts, with Bunnings snags at £3.50 each.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "£", config.Currency)
+	assert.Equal(t, []string{"£"}, config.CurrencySymbols)
+
+	// The status and item responses echo the configured symbol
+	response, err = safeHandleStatusCommand(configStore, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Current configuration: Bunnings snags (at £3.50 each).", response)
+
+	response, err = safeHandleConfigCommand(configStore, `item "coffee" price 4.50`, "C12345", "U12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Configuration updated! Now converting dollar amounts to coffee (at £4.50 each).", response)
+
+	_, err = safeHandleCurrencyCommand(configStore, "currency €", "C12345")
+	assert.NoError(t, err)
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "€", config.CurrencySymbol())
+
+	_, err = safeHandleCurrencyCommand(configStore, "currency EUR", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleHistoryCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleHistoryCommand(configStore, "history", "C12345", time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, "No configuration changes have been recorded for this channel yet.", response)
+
+	_, err = safeHandleConfigCommand(configStore, `item "coffee" price 5.00`, "C12345", "U111")
+	assert.NoError(t, err)
+	_, err = safeHandleResetCommand(configStore, "C12345", "U222")
+	assert.NoError(t, err)
+
+	// Newest changes are listed first, limited to the requested count
+	response, err = safeHandleHistoryCommand(configStore, "history 1", "C12345", time.UTC)
+	assert.NoError(t, err)
+	assert.Contains(t, response, "<@U222> reset coffee ($5.00) → Bunnings snags ($3.50)")
+	assert.NotContains(t, response, "U111")
+
+	response, err = safeHandleHistoryCommand(configStore, "history", "C12345", time.UTC)
+	assert.NoError(t, err)
+	assert.Less(t, strings.Index(response, "<@U222> reset"), strings.Index(response, "<@U111> changed Bunnings snags ($3.50) → coffee ($5.00)"))
+
+	_, err = safeHandleHistoryCommand(configStore, "history none", "C12345", time.UTC)
+	assert.Error(t, err)
+}
+
+func TestFormatTime(t *testing.T) {
+	instant := time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	assert.Equal(t, "5 Mar 2024 09:30 UTC", FormatTime(instant, time.UTC))
+	assert.Equal(t, "5 Mar 2024 20:30 AEDT", FormatTime(instant, sydney))
+	assert.Equal(t, "5 Mar 2024 04:30 EST", FormatTime(instant, newYork))
+}
+
+func TestFormatHistoryTimeZones(t *testing.T) {
+	changes := []models.ConfigChange{{
+		ChangedAt:    time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC),
+		UserID:       "U111",
+		OldItemName:  "Bunnings snags",
+		OldItemPrice: 3.50,
+		NewItemName:  "coffee",
+		NewItemPrice: 5.00,
+	}}
+
+	perth, err := time.LoadLocation("Australia/Perth")
+	require.NoError(t, err)
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	assert.Contains(t, formatHistory(changes, "$", perth), "• 5 Mar 2024 17:30 AWST <@U111> changed")
+	assert.Contains(t, formatHistory(changes, "$", london), "• 5 Mar 2024 09:30 GMT <@U111> changed")
+}
+
+func TestDisplayTimeZone(t *testing.T) {
+	sydney := time.FixedZone("AEDT", 11*60*60)
+	denver := time.FixedZone("MST", -7*60*60)
+	api := slack.NewMockSlackAPI()
+	api.UserTimeZones = map[string]*time.Location{"U111": denver}
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		userID   string
+		expected *time.Location
+	}{
+		{name: "No time zone configured", cfg: &config.Config{}, userID: "U111", expected: time.UTC},
+		{name: "Configured time zone", cfg: &config.Config{TimeZone: sydney}, userID: "U111", expected: sydney},
+		{name: "User's own time zone", cfg: &config.Config{TimeZone: sydney, UserTimeZones: true}, userID: "U111", expected: denver},
+		{name: "User's time zone can't be looked up", cfg: &config.Config{TimeZone: sydney, UserTimeZones: true}, userID: "U222", expected: sydney},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, displayTimeZone(ctx, tt.cfg, api, "T12345", tt.userID))
+		})
+	}
+
+	// Slack clients that can't look up users fall back to the configured time zone
+	assert.Equal(t, sydney, displayTimeZone(ctx, &config.Config{TimeZone: sydney, UserTimeZones: true}, nil, "T12345", "U111"))
+}
+
+func TestCommandHandlerWithAPI_HistoryInUserTimeZone(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U111"))
+
+	api := slack.NewMockSlackAPI()
+	api.UserTimeZones = map[string]*time.Location{"U111": time.FixedZone("SNAG", 10*60*60)}
+	cfg := &config.Config{SlackSigningSecret: "test-secret", UserTimeZones: true}
+	handler := CommandHandlerWithAPI(cfg, configStore, api)
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+		"command":    {"/snagbot"},
+		"text":       {"history"},
+		"channel_id": {"C12345"},
+		"user_id":    {"U111"},
+		"team_id":    {"T12345"},
+	}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Contains(t, response["text"], " SNAG <@U111> changed")
+}
+
+func TestSafeHandleConfigCommandWithUnit(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleConfigCommand(configStore, `item "L" price 2.00 unit "tank" size 60`, "C12345", "U12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Configuration updated! Now converting dollar amounts to tanks of 60 L (at $2.00 per L).", response)
+
+	response, err = safeHandleStatusCommand(configStore, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Current configuration: L (at $2.00 each), counted in tanks of 60 L.", response)
+
+	// Setting an item without a unit clears it
+	_, err = safeHandleConfigCommand(configStore, `item "coffee" price 5.00`, "C12345", "U12345")
+	assert.NoError(t, err)
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.False(t, config.HasUnit())
+}
+
+func TestSafeHandleConfigCommandItemNameTooLong(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	_, err := safeHandleConfigCommand(configStore, `item "`+strings.Repeat("snag", 20)+`" price 5.00`, "C12345", "U12345")
+	assert.Error(t, err)
+	assert.Equal(t, "Item names can be at most 50 characters, that one is 80", errors.UserFriendlyError(err))
+	assert.False(t, configStore.ConfigExists("C12345"))
+}
+
+func TestSafeHandleConfigCommandUnchanged(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleConfigCommand(configStore, `item "coffee" price 5.00`, "C12345", "U12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Configuration updated! Now converting dollar amounts to coffee (at $5.00 each).", response)
+
+	response, err = safeHandleConfigCommand(configStore, `item "coffee" price 5.00`, "C12345", "U67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "Nothing to change, this channel already converts dollar amounts to coffee (at $5.00 each).", response)
+	assert.Len(t, configStore.GetHistory("C12345", 10), 1)
+}
+
+func TestSafeHandleUndoCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	_, err := safeHandleConfigCommand(configStore, `item "coffee" price 5.00`, "C12345", "U12345")
+	assert.NoError(t, err)
+	_, err = safeHandleConfigCommand(configStore, `item "donut" price 4.00`, "C12345", "U12345")
+	assert.NoError(t, err)
+
+	response, err := safeHandleUndoCommand(configStore, configStore, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Undone! Now converting dollar amounts to coffee (at $5.00 each).", response)
+
+	response, err = safeHandleUndoCommand(configStore, configStore, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Undone! Now converting dollar amounts to Bunnings snags (at $3.50 each).", response)
+
+	response, err = safeHandleUndoCommand(configStore, configStore, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "There's nothing to undo in this channel.", response)
+}
+
+func TestSafeHandleDebugCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.SaveWorkspaceDefault(&models.WorkspaceDefault{WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00}))
+	assert.NoError(t, configStore.UpdateConfig("C11111", "pie", 6.00, "U12345"))
+
+	response, err := safeHandleDebugCommand(slack.ForWorkspace(configStore, "T12345"), "C11111")
+	assert.NoError(t, err)
+	assert.Equal(t, "Effective configuration for this channel:\n• Item: pie (set in this channel)\n• Price: $6.00 (set in this channel)", response)
+
+	response, err = safeHandleDebugCommand(slack.ForWorkspace(configStore, "T12345"), "C22222")
+	assert.NoError(t, err)
+	assert.Equal(t, "Effective configuration for this channel:\n• Item: coffee (the workspace default)\n• Price: $5.00 (the workspace default)", response)
+
+	response, err = safeHandleDebugCommand(slack.ForWorkspace(configStore, "T67890"), "C22222")
+	assert.NoError(t, err)
+	assert.Equal(t, "Effective configuration for this channel:\n• Item: Bunnings snags (SnagBot's global default)\n• Price: $3.50 (SnagBot's global default)", response)
+}
+
+func TestFormatChannelList(t *testing.T) {
+	assert.Equal(t, "No channels have a custom configuration yet.", FormatChannelList(nil))
+
+	response := FormatChannelList([]models.ChannelConfig{
+		{ChannelID: "C22222", ItemName: "pie", ItemPrice: 6.00, Currency: "£"},
+		{ChannelID: "C11111", ItemName: "coffee", ItemPrice: 5.00, Items: []models.ChannelItem{{Name: "donut", Price: 4.00}}},
+		{ChannelID: "C33333", ItemName: "taco", ItemPrice: 4.50, Items: []models.ChannelItem{{Name: "burrito", Price: 12.00}, {Name: "nachos", Price: 9.00}}},
+	})
+	assert.Equal(t, "*Configured channels (3):*\n"+
+		"• <#C11111>: coffee (at $5.00 each) plus 1 more item\n"+
+		"• <#C22222>: pie (at £6.00 each)\n"+
+		"• <#C33333>: taco (at $4.50 each) plus 2 more items", response)
+}
+
+// fakeTokenStore holds workspace tokens in a map
+type fakeTokenStore map[string]*models.WorkspaceToken
+
+func (s fakeTokenStore) SaveToken(token *models.WorkspaceToken) error {
+	s[token.WorkspaceID] = token
+	return nil
+}
+
+func (s fakeTokenStore) GetToken(workspaceID string) (*models.WorkspaceToken, error) {
+	token, ok := s[workspaceID]
+	if !ok {
+		return nil, fmt.Errorf("token not found for workspace %s", workspaceID)
+	}
+	return token, nil
+}
+
+func (s fakeTokenStore) DeleteToken(workspaceID string) error {
+	delete(s, workspaceID)
+	return nil
+}
+
+func (s fakeTokenStore) ListWorkspaces() ([]string, error) { return nil, nil }
+
+func TestFormatWorkspaceInfo(t *testing.T) {
+	installedAt := time.Date(2024, time.March, 5, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		token    *models.WorkspaceToken
+		expected string
+	}{
+		{
+			name:  "all details",
+			token: &models.WorkspaceToken{WorkspaceID: "T12345", TeamName: "Snag Lovers", InstalledBy: "U12345", InstalledAt: installedAt},
+			expected: "*SnagBot install info*\n" +
+				"• Workspace: Snag Lovers\n" +
+				"• Installed: 5 Mar 2024 09:30 UTC\n" +
+				"• Installed by: <@U12345>",
+		},
+		{
+			name:     "no team name or installer",
+			token:    &models.WorkspaceToken{WorkspaceID: "T12345", InstalledAt: installedAt},
+			expected: "*SnagBot install info*\n• Workspace: T12345\n• Installed: 5 Mar 2024 09:30 UTC",
+		},
+		{
+			name:     "no install date",
+			token:    &models.WorkspaceToken{WorkspaceID: "T12345", TeamName: "Snag Lovers", InstalledBy: "U12345"},
+			expected: "*SnagBot install info*\n• Workspace: Snag Lovers\n• Installed by: <@U12345>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatWorkspaceInfo(tt.token, time.UTC))
+		})
+	}
+}
+
+func TestSafeHandleInfoCommand(t *testing.T) {
+	tokenStore := fakeTokenStore{}
+	token := models.NewWorkspaceToken("T12345", "Snag Lovers", "xoxb-test", "B12345", "chat:write", "bot", "U12345")
+	assert.NoError(t, tokenStore.SaveToken(token))
+
+	response, err := safeHandleInfoCommand(tokenStore, "T12345", time.UTC)
+	assert.NoError(t, err)
+	assert.Equal(t, FormatWorkspaceInfo(token, time.UTC), response)
+	assert.Contains(t, response, "• Installed by: <@U12345>")
+
+	// Workspaces without a stored token get an error
+	_, err = safeHandleInfoCommand(tokenStore, "T67890", time.UTC)
+	assert.Error(t, err)
+
+	// A single bot token has no install details
+	response, err = safeHandleInfoCommand(slack.NewSingleTokenStore(&config.Config{SlackBotToken: "xoxb-test"}), "T12345", time.UTC)
+	assert.NoError(t, err)
+	assert.Contains(t, response, "no install details")
+}
+
+func TestSafeHandleListCommand(t *testing.T) {
+	cfg := &config.Config{AdminUsers: map[string]bool{"UADMIN": true}}
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C11111", "coffee", 5.00, "UADMIN"))
+	assert.NoError(t, configStore.SaveConfig(&models.ChannelConfig{ChannelID: "C22222", WorkspaceID: "T12345", ItemName: "pie", ItemPrice: 6.00}))
+	assert.NoError(t, configStore.SaveConfig(&models.ChannelConfig{ChannelID: "C33333", WorkspaceID: "T67890", ItemName: "taco", ItemPrice: 4.50}))
+
+	// Users not on the allowlist can't see other channels' configs
+	response, err := safeHandleListCommand(cfg, configStore, "U12345", "T12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Only SnagBot admins can list configured channels.", response)
+
+	// Other workspaces' channels are left out
+	response, err = safeHandleListCommand(cfg, configStore, "UADMIN", "T12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "*Configured channels (2):*\n"+
+		"• <#C11111>: coffee (at $5.00 each)\n"+
+		"• <#C22222>: pie (at $6.00 each)", response)
+
+	// With several workspaces installed, configs without a workspace can't be attributed
+	cfg.EnableMultiWorkspace = true
+	response, err = safeHandleListCommand(cfg, configStore, "UADMIN", "T12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "*Configured channels (1):*\n• <#C22222>: pie (at $6.00 each)", response)
+}
+
+func TestCommandHandlerWithStore_ListAfterItem(t *testing.T) {
+	cfg := &config.Config{
+		SlackSigningSecret:   "test-secret",
+		EnableMultiWorkspace: true,
+		AdminUsers:           map[string]bool{"UADMIN": true},
+	}
+	handler := CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStore())
+
+	run := func(teamID, channelID, text string) string {
+		rec := httptest.NewRecorder()
+		handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+			"command":    {"/snagbot"},
+			"text":       {text},
+			"channel_id": {channelID},
+			"user_id":    {"UADMIN"},
+			"team_id":    {teamID},
+		}))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var response SlackResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		return response.Text
+	}
+
+	run("T12345", "C11111", `item "coffee" price 5`)
+	run("T12345", "C22222", "words on")
+	run("T67890", "C33333", `item "taco" price 4.50`)
+
+	// Channels configured with commands are listed for their own workspace only
+	assert.Equal(t, "*Configured channels (2):*\n"+
+		"• <#C11111>: coffee (at $5.00 each)\n"+
+		"• <#C22222>: Bunnings snags (at $3.50 each)", run("T12345", "C11111", "list"))
+	assert.Equal(t, "*Configured channels (1):*\n• <#C33333>: taco (at $4.50 each)", run("T67890", "C33333", "list"))
+}
+
+func TestSafeHandleStatsCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	mockAPI := slack.NewMockSlackAPI()
+
+	response, err := safeHandleStatsCommand(configStore, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot hasn't responded in this channel yet.", response)
+
+	// Messages without dollar values aren't counted
+	for _, text := range []string{"This costs $35", "Lunch was $12.50", "No money here", "Taxi was $2"} {
+		event := &slack.MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: text, TS: "1234567890.123456"}
+		assert.NoError(t, slack.ProcessMessageEvent(event.ToSlackEvent(), configStore, mockAPI))
+	}
+
+	response, err = safeHandleStatsCommand(configStore, "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot has responded 3 times in this channel, converting $49.50 in total.", response)
+
+	// Other channels have their own counts
+	response, err = safeHandleStatsCommand(configStore, "C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot hasn't responded in this channel yet.", response)
+}
+
+func TestSafeHandleSumCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleSumCommand(configStore, "sum lines", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Sum mode updated! SnagBot will now convert each line of a message separately.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.SumsPerLine())
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleSumCommand(configStore, "sum total", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Sum mode updated! SnagBot will now add up all the amounts in a message.", response)
+
+	_, err = safeHandleSumCommand(configStore, "sum everything", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleTriggerCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleTriggerCommand(configStore, "trigger keyword", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Trigger updated! SnagBot will now only respond to messages containing \"snagify\".", response)
+
+	response, err = safeHandleTriggerCommand(configStore, "trigger keyword caffeinate", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Trigger updated! SnagBot will now only respond to messages containing \"caffeinate\".", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TriggerModeKeyword, config.TriggerMode)
+	assert.Equal(t, "caffeinate", config.TriggerWord)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	// Going back to always keeps the word for next time
+	response, err = safeHandleTriggerCommand(configStore, "trigger always", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Trigger updated! SnagBot will now respond to every message with dollar amounts.", response)
+
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, models.TriggerModeAlways, config.TriggerMode)
+	assert.Equal(t, "caffeinate", config.TriggerWord)
+
+	_, err = safeHandleTriggerCommand(configStore, "trigger sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleDecimalCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleDecimalCommand(configStore, "decimal comma", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Decimal separator updated! SnagBot will now read amounts written like $3,50 and $1.234,50.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.DecimalComma())
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleDecimalCommand(configStore, "decimal point", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Decimal separator updated! SnagBot will now read amounts written like $3.50 and $1,234.50.", response)
+
+	_, err = safeHandleDecimalCommand(configStore, "decimal semicolon", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleLocaleCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleLocaleCommand(configStore, "locale en-us", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Locale updated to en-US! Responses will now read like: That's about 15 coffees!", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, models.LocaleUS, config.Locale)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	_, err = safeHandleLocaleCommand(configStore, "locale klingon", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleReplyCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleReplyCommand(configStore, "reply channel", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Reply placement updated! SnagBot will now reply in the channel.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.False(t, config.ReplyInThread())
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleReplyCommand(configStore, "reply thread", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Reply placement updated! SnagBot will now reply in a thread on the message.", response)
+
+	_, err = safeHandleReplyCommand(configStore, "reply somewhere", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleButtonCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleButtonCommand(configStore, "button on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, `Button turned on! SnagBot's replies will include a "Change item" button.`, response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.ChangeItemButton)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleButtonCommand(configStore, "button off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Button turned off! SnagBot's replies will be plain messages.", response)
+
+	_, err = safeHandleButtonCommand(configStore, "button sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleWordsCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleWordsCommand(configStore, "words on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, `Word amounts turned on! SnagBot will also count amounts like "thirty-five dollars".`, response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.WordAmounts)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleWordsCommand(configStore, "words off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Word amounts turned off! SnagBot will only count amounts like $35.", response)
+
+	_, err = safeHandleWordsCommand(configStore, "words sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleRandomCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleRandomCommand(configStore, "random on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Random items turned on! Each response will convert to something different, like flat whites, meat pies or movie tickets.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.RandomItems)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleRandomCommand(configStore, "random off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Random items turned off! SnagBot will convert to coffee again.", response)
+
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.False(t, config.RandomItems)
+
+	_, err = safeHandleRandomCommand(configStore, "random sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleVarietyCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleVarietyCommand(configStore, "variety on", "C12345")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "Variety turned on!")
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.Variety)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleVarietyCommand(configStore, "variety off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Variety turned off! Responses will be worded the usual way again.", response)
+
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.False(t, config.Variety)
+
+	_, err = safeHandleVarietyCommand(configStore, "variety sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleRepeatsCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleRepeatsCommand(configStore, "repeats on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, `Repeats turned on! "I paid $35 and you paid $35" will now add up to $70.`, response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.CountRepeats)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleRepeatsCommand(configStore, "repeats off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Repeats turned off! An amount mentioned more than once in a message will only be counted once.", response)
+
+	_, err = safeHandleRepeatsCommand(configStore, "repeats sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleFilterCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleFilterCommand(configStore, "filter on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, `Filter turned on! SnagBot will ignore $0 amounts and count discounts like "$35 off" as savings.`, response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.FilterFalseMatches)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleFilterCommand(configStore, "filter off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Filter turned off! SnagBot will count every amount as spending.", response)
+
+	_, err = safeHandleFilterCommand(configStore, "filter sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleBreakdownCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandleBreakdownCommand(configStore, "breakdown on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, `Breakdown turned on! SnagBot's replies will show how amounts add up, like "$35 + $15 = $50".`, response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.ShowBreakdown)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandleBreakdownCommand(configStore, "breakdown off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Breakdown turned off! SnagBot's replies will only show the count.", response)
+
+	_, err = safeHandleBreakdownCommand(configStore, "breakdown sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandlePrivateCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	response, err := safeHandlePrivateCommand(configStore, "private on", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Private replies turned on! Only the person who posted the amounts will see SnagBot's replies.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.Ephemeral)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	response, err = safeHandlePrivateCommand(configStore, "private off", "C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Private replies turned off! Everyone in the channel will see SnagBot's replies.", response)
+
+	_, err = safeHandlePrivateCommand(configStore, "private sometimes", "C12345")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleExportAndImportCommands(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.SaveConfig(&models.ChannelConfig{
+		ChannelID:        "C12345",
+		WorkspaceID:      "T12345",
+		ItemName:         "coffee",
+		ItemPrice:        5.00,
+		Currency:         "£",
+		CurrencySymbols:  []string{"£"},
+		RoundingMode:     "nearest",
+		ResponseTemplate: "That's {count} {item}!",
+		MinThreshold:     10,
+		Items:            []models.ChannelItem{{Name: "pie", Price: 6.00}},
+	}))
+
+	response, err := safeHandleExportCommand(configStore, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "`/snagbot import`")
+
+	// The export's code block pastes straight into an import in another channel
+	start := strings.Index(response, "```")
+	assert.GreaterOrEqual(t, start, 0)
+	exported := response[start:]
+	assert.NotContains(t, exported, "T12345")
+
+	response, err = safeHandleImportCommand(configStore, "import "+exported, "C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "Configuration updated! Now converting dollar amounts to coffee (at £5.00 each).", response)
+
+	original, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	copied, err := configStore.GetConfig("C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "C67890", copied.ChannelID)
+	assert.Empty(t, copied.WorkspaceID)
+
+	// Everything but the channel's identity is carried over
+	original.ChannelID, original.WorkspaceID = copied.ChannelID, copied.WorkspaceID
+	assert.Equal(t, original, copied)
+
+	// Malformed imports leave the channel alone
+	_, err = safeHandleImportCommand(configStore, `import {"item_name": "pie"`, "C67890")
+	assert.Error(t, err)
+	copied, err = configStore.GetConfig("C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", copied.ItemName)
+}
+
+func TestSafeHandleIgnoreCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStoreWithConfig(&config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+		IgnoredUsers:     map[string]bool{"UCONFIG": true},
+	})
+
+	response, err := safeHandleIgnoreCommand(configStore, "ignore", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot will ignore this channel. Use `/snagbot unignore` to have it respond here again.", response)
+	assert.True(t, configStore.IsIgnored("C12345", ""))
+
+	response, err = safeHandleIgnoreCommand(configStore, "unignore", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot will respond in this channel again.", response)
+	assert.False(t, configStore.IsIgnored("C12345", ""))
+
+	response, err = safeHandleIgnoreCommand(configStore, "ignore <@UNOISY|noisy-bot>", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot will ignore messages from <@UNOISY>.", response)
+	assert.True(t, configStore.IsIgnored("C67890", "UNOISY"))
+
+	// Users ignored by IGNORED_USERS can't be unignored from Slack
+	response, err = safeHandleIgnoreCommand(configStore, "unignore <@UCONFIG>", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "<@UCONFIG> is still ignored by the IGNORED_USERS setting, so SnagBot won't respond to them.", response)
+
+	_, err = safeHandleIgnoreCommand(configStore, "ignore everyone", "C12345", "/snagbot")
+	assert.Error(t, err)
+}
+
+func TestSafeHandleEmojiCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+
+	response, err := safeHandleEmojiCommand(configStore, "emoji :taco:", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Emoji updated! SnagBot will react with :taco: once `/snagbot mode reaction` is set.", response)
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "taco", config.EmojiName())
+
+	_, err = safeHandleResponseModeCommand(configStore, "mode reaction", "C12345")
+	assert.NoError(t, err)
+	response, err = safeHandleEmojiCommand(configStore, "emoji :pie:", "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Emoji updated! SnagBot will now react with :pie:.", response)
+
+	_, err = safeHandleEmojiCommand(configStore, "emoji taco", "C12345", "/snagbot")
+	assert.Error(t, err)
+}
+
+// signedCommandRequest builds a slash command request signed the way Slack signs them
+func signedCommandRequest(t *testing.T, secret string, form url.Values) *http.Request {
+	t.Helper()
+
+	body := form.Encode()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestCommandHandlerWithStore_SlashCommandNames(t *testing.T) {
+	tests := []struct {
+		name          string
+		slashCommands []string
+		command       string
+		expectedCode  int
+	}{
+		{name: "Default name", command: "/snagbot", expectedCode: http.StatusOK},
+		{name: "Alias not configured", command: "/snag", expectedCode: http.StatusOK},
+		{name: "Primary name", slashCommands: []string{"/snagbot", "/snag"}, command: "/snagbot", expectedCode: http.StatusOK},
+		{name: "Alias", slashCommands: []string{"/snagbot", "/snag"}, command: "/snag", expectedCode: http.StatusOK},
+		{name: "Unknown command", slashCommands: []string{"/snagbot", "/snag"}, command: "/sausage", expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Config{SlackSigningSecret: "test-secret", SlashCommands: test.slashCommands}
+			handler := CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStore())
+
+			rec := httptest.NewRecorder()
+			handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+				"command":    {test.command},
+				"text":       {"status"},
+				"channel_id": {"C12345"},
+				"user_id":    {"U12345"},
+			}))
+			assert.Equal(t, test.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestCommandHandlerWithStore_ErrorResponses(t *testing.T) {
+	form := url.Values{
+		"command":    {"/snagbot"},
+		"text":       {"status"},
+		"channel_id": {"C12345"},
+		"user_id":    {"U12345"},
+	}
+	withText := func(text string) url.Values {
+		values := url.Values{}
+		for key, value := range form {
+			values[key] = value
+		}
+		values.Set("text", text)
+		return values
+	}
+
+	tests := []struct {
+		name         string
+		cfg          *config.Config
+		request      func(t *testing.T) *http.Request
+		expectedCode int
+		expectedText string
+	}{
+		{
+			name: "Method not allowed",
+			cfg:  &config.Config{SlackSigningSecret: "test-secret"},
+			request: func(t *testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/api/commands", nil)
+			},
+			expectedCode: http.StatusMethodNotAllowed,
+			expectedText: "Method not allowed",
+		},
+		{
+			name: "Signing secret not configured",
+			cfg:  &config.Config{},
+			request: func(t *testing.T) *http.Request {
+				return signedCommandRequest(t, "test-secret", form)
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedText: "isn't set up to accept commands",
+		},
+		{
+			name: "Invalid signature",
+			cfg:  &config.Config{SlackSigningSecret: "test-secret"},
+			request: func(t *testing.T) *http.Request {
+				return signedCommandRequest(t, "other-secret", form)
+			},
+			expectedCode: http.StatusUnauthorized,
+			expectedText: "Invalid request",
+		},
+		{
+			name: "Body too large",
+			cfg:  &config.Config{SlackSigningSecret: "test-secret", MaxRequestBodySize: 64},
+			request: func(t *testing.T) *http.Request {
+				return signedCommandRequest(t, "test-secret", withText(strings.Repeat("a", 128)))
+			},
+			expectedCode: http.StatusRequestEntityTooLarge,
+			expectedText: "Request body too large",
+		},
+		{
+			name: "Unknown command",
+			cfg:  &config.Config{SlackSigningSecret: "test-secret"},
+			request: func(t *testing.T) *http.Request {
+				values := withText("status")
+				values.Set("command", "/sausage")
+				return signedCommandRequest(t, "test-secret", values)
+			},
+			expectedCode: http.StatusOK,
+			expectedText: "SnagBot doesn't handle /sausage, try `/snagbot help`",
+		},
+		{
+			name: "Invalid subcommand",
+			cfg:  &config.Config{SlackSigningSecret: "test-secret"},
+			request: func(t *testing.T) *http.Request {
+				return signedCommandRequest(t, "test-secret", withText("item coffee"))
+			},
+			expectedCode: http.StatusOK,
+			expectedText: "Error: ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := CommandHandlerWithStore(test.cfg, slack.NewInMemoryConfigStore())
+
+			rec := httptest.NewRecorder()
+			handler(rec, test.request(t))
+			assert.Equal(t, test.expectedCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var response SlackResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, "ephemeral", response.ResponseType)
+			assert.Contains(t, response.Text, test.expectedText)
+		})
+	}
+}
+
+func TestCommandHandlerWithStore_HelpShowsPrimaryName(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret", SlashCommands: []string{"/snag", "/snagbot"}}
+	handler := CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStore())
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+		"command":    {"/snagbot"},
+		"text":       {"help"},
+		"channel_id": {"C12345"},
+	}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Contains(t, response["text"], "• /snag item \"coffee\" price 5.00")
+	assert.NotContains(t, response["text"], "/snagbot")
+}
+
+func TestCommandHandlerWithStore_RepliesShowPrimaryName(t *testing.T) {
+	tests := []struct {
+		text         string
+		expectedText string
+	}{
+		{text: "off", expectedText: "use `/snag on` to switch it back on"},
+		{text: "rounding sideways", expectedText: "Try `/snag help` for usage information."},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			cfg := &config.Config{SlackSigningSecret: "test-secret", SlashCommands: []string{"/snag", "/snagbot"}}
+			handler := CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStore())
+
+			rec := httptest.NewRecorder()
+			handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+				"command":    {"/snagbot"},
+				"text":       {test.text},
+				"channel_id": {"C12345"},
+			}))
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response map[string]string
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Contains(t, response["text"], test.expectedText)
+			assert.NotContains(t, response["text"], "/snagbot")
+		})
+	}
+}
+
+func TestCommandHandlerWithStore_ResponseType(t *testing.T) {
+	tests := []struct {
+		name         string
+		announceAll  bool
+		commands     []string
+		expectedType string
+	}{
+		{name: "Item change", commands: []string{`item "coffee" price 5.00`}, expectedType: "ephemeral"},
+		{name: "Public item change", commands: []string{`item "coffee" price 5.00 public`}, expectedType: "in_channel"},
+		{name: "Public item change with nothing to change", commands: []string{`item "coffee" price 5.00`, `item "coffee" price 5.00 public`}, expectedType: "ephemeral"},
+		{name: "Public item change with an error", commands: []string{`item "coffee" price -1 public`}, expectedType: "ephemeral"},
+		{name: "Item change announced for all", announceAll: true, commands: []string{`item "coffee" price 5.00`}, expectedType: "in_channel"},
+		{name: "Other commands announced for all", announceAll: true, commands: []string{"status"}, expectedType: "ephemeral"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Config{SlackSigningSecret: "test-secret", AnnounceConfigChanges: test.announceAll}
+			handler := CommandHandlerWithStore(cfg, slack.NewInMemoryConfigStore())
+
+			// Only the last command's response is checked
+			var rec *httptest.ResponseRecorder
+			for _, text := range test.commands {
+				rec = httptest.NewRecorder()
+				handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+					"command":    {"/snagbot"},
+					"text":       {text},
+					"channel_id": {"C12345"},
+					"user_id":    {"U12345"},
+				}))
+				assert.Equal(t, http.StatusOK, rec.Code)
+			}
+
+			var response SlackResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+			assert.Equal(t, test.expectedType, response.ResponseType)
+			assert.NotEmpty(t, response.Text)
+		})
+	}
+}
+
+func TestSafeHandlePreviewCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "snag", 3.50, "U12345"))
+
+	response, err := safeHandlePreviewCommand(configStore, `preview item "coffee" price 5 amount 35`, "C12345", nil, "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Preview for $35.00: That's 7 coffees!\nNothing has been saved, use `/snagbot item` to keep it.", response)
+
+	// The channel's own settings still apply
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	config.SetCurrency("£")
+	config.MinThreshold = 50
+	assert.NoError(t, configStore.SaveConfig(config))
+
+	response, err = safeHandlePreviewCommand(configStore, `preview item "coffee" price 5 amount 35`, "C12345", nil, "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Preview: SnagBot wouldn't respond to £35.00 in this channel.", response)
+
+	// Nothing was saved
+	config, err = configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "snag", config.ItemName)
+	assert.Equal(t, 3.50, config.ItemPrice)
+
+	_, err = safeHandlePreviewCommand(configStore, `preview item "coffee" price 5`, "C12345", nil, "/snagbot")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestSafeHandleSwitchCommand(t *testing.T) {
+	configStore := slack.NewInMemoryConfigStore()
+	assert.NoError(t, configStore.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	respond := func() []slack.SlackResponse {
+		mockAPI := slack.NewMockSlackAPI()
+		event := (&slack.MockMessageEvent{
+			ChannelID: "C12345",
+			UserID:    "U12345",
+			Text:      "Lunch was $35",
+			TS:        "1234567890.123456",
+		}).ToSlackEvent()
+		assert.NoError(t, slack.ProcessMessageEvent(event, configStore, mockAPI))
+		return mockAPI.SentMessages
+	}
+
+	response, err := safeHandleSwitchCommand(configStore, false, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot is off in this channel. Your item and settings are kept, use `/snagbot on` to switch it back on.", response)
+	assert.Empty(t, respond())
+
+	response, err = safeHandleSwitchCommand(configStore, false, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "already off")
+
+	response, err = safeHandleStatusCommand(configStore, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "Current configuration: coffee (at $5.00 each).\nSnagBot is switched off in this channel. Use `/snagbot on` to switch it back on.", response)
+
+	// Switching back on responds with the item from before
+	response, err = safeHandleSwitchCommand(configStore, true, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot is back on! Converting dollar amounts to coffee again.", response)
+
+	messages := respond()
+	if assert.Len(t, messages, 1) {
+		assert.Contains(t, messages[0].Text, "7 coffees")
+	}
+
+	config, err := configStore.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.True(t, config.Enabled())
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 5.00, config.ItemPrice)
+
+	response, err = safeHandleSwitchCommand(configStore, true, "C12345", "/snagbot")
+	assert.NoError(t, err)
+	assert.Equal(t, "SnagBot is already on in this channel.", response)
+}
+
+func TestCommandHandlerWithAPI_Feedback(t *testing.T) {
+	api := slack.NewMockSlackAPI()
+	cfg := &config.Config{SlackSigningSecret: "test-secret", FeedbackChannel: "C0FEEDBACK"}
+	handler := CommandHandlerWithAPI(cfg, slack.NewInMemoryConfigStore(), api)
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedCommandRequest(t, cfg.SlackSigningSecret, url.Values{
+		"command":    {"/snagbot"},
+		"text":       {"feedback Could you add a pie option?"},
+		"channel_id": {"C12345"},
+		"user_id":    {"U111"},
+		"team_id":    {"T12345"},
+	}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]string
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "ephemeral", response["response_type"])
+	assert.Equal(t, "Thanks for the feedback! It's been passed on to the people who run SnagBot.", response["text"])
+
+	messages := api.Messages()
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, "C0FEEDBACK", messages[0].ChannelID)
+		assert.Equal(t, "Feedback from <@U111> in <#C12345> (workspace T12345):\n>Could you add a pie option?", messages[0].Text)
+	}
+}
+
+func TestSafeHandleFeedbackCommand(t *testing.T) {
+	api := slack.NewMockSlackAPI()
+
+	// Each line of the feedback is quoted
+	response, err := safeHandleFeedbackCommand(context.Background(), api, "C0FEEDBACK", "feedback More snags\nand sauce", "C12345", "U111", "")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "Thanks for the feedback!")
+	if assert.Len(t, api.SentMessages, 1) {
+		assert.Equal(t, "Feedback from <@U111> in <#C12345>:\n>More snags\n>and sauce", api.SentMessages[0].Text)
+	}
+
+	// Without a feedback channel nothing is sent
+	response, err = safeHandleFeedbackCommand(context.Background(), api, "", "feedback More snags", "C12345", "U111", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Feedback isn't set up for this SnagBot, so it can't be sent.", response)
+	assert.Len(t, api.SentMessages, 1)
+
+	_, err = safeHandleFeedbackCommand(context.Background(), api, "C0FEEDBACK", "feedback", "C12345", "U111", "")
+	assert.ErrorIs(t, err, ErrMissingFeedback)
+
+	// Failing to post is reported to the user
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = safeHandleFeedbackCommand(ctx, api, "C0FEEDBACK", "feedback More snags", "C12345", "U111", "")
+	assert.Error(t, err)
+	assert.Len(t, api.SentMessages, 1)
+}
@@ -1,17 +1,26 @@
 package command
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode"
+
+	"github.com/mcncl/snagbot/internal/calculator"
+	"github.com/mcncl/snagbot/pkg/models"
 )
 
 // CommandParseResult holds the parsed item name and price
 type CommandParseResult struct {
 	ItemName  string
 	ItemPrice float64
+	UnitName  string  // Optional unit the item is counted in, e.g. "tank"
+	UnitSize  float64 // How many of the item make up one unit
+	Public    bool    // Announce the change in the channel rather than only to the user who made it
 }
 
 var (
@@ -26,10 +35,109 @@ var (
 
 	// ErrInvalidPrice is returned when the price is not a valid positive number
 	ErrInvalidPrice = errors.New("price must be a positive number")
+
+	// ErrInvalidQuantity is returned when a price like "2 for 5" isn't for a positive quantity
+	ErrInvalidQuantity = errors.New("quantity must be a positive number, e.g. 2 for 5.00")
+
+	// ErrInvalidRoundingMode is returned when the rounding mode is not recognised
+	ErrInvalidRoundingMode = errors.New("rounding mode must be one of: up, down, nearest")
+
+	// ErrInvalidResponseMode is returned when the response mode is not recognised
+	ErrInvalidResponseMode = errors.New("response mode must be one of: message, reaction")
+
+	// ErrInvalidReplyPlacement is returned when the reply placement is not recognised
+	ErrInvalidReplyPlacement = errors.New("reply placement must be one of: thread, channel")
+
+	// ErrInvalidSumMode is returned when the sum mode is not recognised
+	ErrInvalidSumMode = errors.New("sum mode must be one of: total, lines")
+
+	// ErrInvalidTriggerMode is returned when the trigger mode is not recognised
+	ErrInvalidTriggerMode = errors.New("trigger mode must be one of: always, keyword")
+
+	// ErrInvalidTriggerWord is returned when the trigger word isn't a single word of letters and numbers
+	ErrInvalidTriggerWord = errors.New("trigger word must be a single word of letters and numbers")
+
+	// ErrInvalidNumberFormat is returned when the decimal separator is not recognised
+	ErrInvalidNumberFormat = errors.New("decimal separator must be one of: point, comma")
+
+	// ErrInvalidLocale is returned when the locale has no built-in phrasing
+	ErrInvalidLocale = errors.New("locale must be one of: en-AU, en-US, en-GB")
+
+	// ErrInvalidButtonSetting is returned when the change item button isn't turned on or off
+	ErrInvalidButtonSetting = errors.New("button setting must be one of: on, off")
+
+	// ErrInvalidWordsSetting is returned when counting spelled-out amounts isn't turned on or off
+	ErrInvalidWordsSetting = errors.New("words setting must be one of: on, off")
+
+	// ErrInvalidRandomSetting is returned when random catalog items aren't turned on or off
+	ErrInvalidRandomSetting = errors.New("random setting must be one of: on, off")
+
+	// ErrInvalidVarietySetting is returned when varied response wording isn't turned on or off
+	ErrInvalidVarietySetting = errors.New("variety setting must be one of: on, off")
+
+	// ErrInvalidRepeatsSetting is returned when counting repeated amounts isn't turned on or off
+	ErrInvalidRepeatsSetting = errors.New("repeats setting must be one of: on, off")
+
+	// ErrInvalidFilterSetting is returned when the false-match filter isn't turned on or off
+	ErrInvalidFilterSetting = errors.New("filter setting must be one of: on, off")
+
+	// ErrInvalidBreakdownSetting is returned when showing the breakdown of amounts isn't turned on or off
+	ErrInvalidBreakdownSetting = errors.New("breakdown setting must be one of: on, off")
+
+	// ErrInvalidPrivateSetting is returned when private replies aren't turned on or off
+	ErrInvalidPrivateSetting = errors.New("private setting must be one of: on, off")
+
+	// ErrInvalidEmoji is returned when the emoji isn't written as :name:
+	ErrInvalidEmoji = errors.New("emoji must be written as :name:, e.g. :taco:")
+
+	// ErrInvalidThreshold is returned when the threshold is not a valid non-negative number
+	ErrInvalidThreshold = errors.New("threshold must be zero or a positive number")
+
+	// ErrMissingTemplate is returned when the response template is missing
+	ErrMissingTemplate = errors.New("missing response template")
+
+	// ErrInvalidTemplate is returned when the response template can't be used
+	ErrInvalidTemplate = errors.New("response template must include {count}")
+
+	// ErrMissingZeroMessage is returned when the zero message is missing
+	ErrMissingZeroMessage = errors.New("missing zero message")
+
+	// ErrTooManyItems is returned when a channel already has the maximum number of items
+	ErrTooManyItems = errors.New("too many items for this channel")
+
+	// ErrInvalidCurrency is returned when the currency isn't a single symbol
+	ErrInvalidCurrency = errors.New("currency must be a single symbol such as $, £ or €")
+
+	// ErrInvalidHistoryLimit is returned when the number of history entries isn't a positive whole number
+	ErrInvalidHistoryLimit = errors.New("history limit must be a positive whole number")
+
+	// ErrInvalidImport is returned when imported JSON isn't a valid channel configuration
+	ErrInvalidImport = errors.New("import must be a channel configuration from /snagbot export")
+
+	// ErrInvalidUnit is returned when a unit isn't a name followed by a positive size
+	ErrInvalidUnit = errors.New("unit must be a name followed by a positive size, e.g. unit \"tank\" size 60")
+
+	// ErrInvalidIgnoreTarget is returned when an ignore command names something other than a user
+	ErrInvalidIgnoreTarget = errors.New("ignore target must be a user mention, e.g. @someone")
+
+	// ErrInvalidAmount is returned when a preview's amount is missing or not a positive number
+	ErrInvalidAmount = errors.New("amount must be a positive number, e.g. amount 35")
+
+	// ErrMissingFeedback is returned when a feedback command has no message
+	ErrMissingFeedback = errors.New("missing feedback message")
 )
 
+// IgnoreTarget is what an ignore or unignore command applies to
+type IgnoreTarget struct {
+	Ignore bool   // False to stop ignoring
+	UserID string // Empty for the channel the command was run in
+}
+
+// unitRegex matches the optional unit after an item's price, e.g. unit "tank" size 60
+var unitRegex = regexp.MustCompile(`(?i)^unit (?:"([^"]+)"|(\S+)) size (\S+)$`)
+
 // ParseConfigCommand parses a Slack slash command for configuring the bot.
-// Expected format: /snagbot item "item name" price 5.00
+// Expected format: /snagbot item "item name" price 5.00 [unit "unit name" size 60] [public]
 // The item name can be in quotes (for multi-word items) or a single word without quotes.
 // Note: Case is preserved for the item name to allow for proper pluralization.
 func ParseConfigCommand(commandText string) (CommandParseResult, error) {
@@ -40,18 +148,23 @@ func ParseConfigCommand(commandText string) (CommandParseResult, error) {
 	spaceRegex := regexp.MustCompile(`\s+`)
 	commandText = spaceRegex.ReplaceAllString(strings.TrimSpace(commandText), " ")
 
+	// A trailing "public" announces the change to the whole channel
+	if hasSuffixFold(commandText, " "+publicKeyword) {
+		commandText = strings.TrimSpace(commandText[:len(commandText)-len(publicKeyword)])
+		result.Public = true
+	}
+
 	// Check if the command starts with "item"
-	if !strings.HasPrefix(strings.ToLower(commandText), "item") {
+	if !hasPrefixFold(commandText, "item") {
 		return result, fmt.Errorf("%w: command must start with 'item'", ErrInvalidCommand)
 	}
 
 	// Remove the "item" prefix
-	// We use len("item") instead of the regex-matched length to handle case differences
 	commandText = commandText[len("item"):]
 	commandText = strings.TrimSpace(commandText)
 
 	// Check if we have "price" immediately after "item" (missing item)
-	if strings.HasPrefix(strings.ToLower(commandText), "price") {
+	if hasPrefixFold(commandText, "price") {
 		return result, ErrMissingItem
 	}
 
@@ -74,7 +187,7 @@ func ParseConfigCommand(commandText string) (CommandParseResult, error) {
 	} else {
 		// No quotes, so the item name is the first word
 		parts := strings.SplitN(commandText, " ", 2)
-		if len(parts) == 0 || parts[0] == "" || strings.HasPrefix(strings.ToLower(parts[0]), "price") {
+		if len(parts) == 0 || parts[0] == "" || hasPrefixFold(parts[0], "price") {
 			return result, ErrMissingItem
 		}
 
@@ -84,7 +197,8 @@ func ParseConfigCommand(commandText string) (CommandParseResult, error) {
 		}
 	}
 
-	// Validate item name
+	// Validate item name, after removing characters that would break responses
+	itemName = models.NormalizeItemName(itemName)
 	if itemName == "" {
 		return result, ErrMissingItem
 	}
@@ -95,27 +209,32 @@ func ParseConfigCommand(commandText string) (CommandParseResult, error) {
 	}
 
 	// Handle case insensitivity for "price" keyword
-	if !strings.HasPrefix(strings.ToLower(remainingText), "price") {
+	if !hasPrefixFold(remainingText, "price") {
 		return result, fmt.Errorf("%w: expected 'price' keyword after item name", ErrInvalidCommand)
 	}
 
 	// Extract price value
-	priceText := remainingText[strings.Index(strings.ToLower(remainingText), "price")+len("price"):]
+	priceText := remainingText[len("price"):]
 	priceText = strings.TrimSpace(priceText)
 
 	if priceText == "" {
 		return result, ErrMissingPrice
 	}
 
-	// Parse price as float
-	price, err := strconv.ParseFloat(priceText, 64)
-	if err != nil {
-		return result, fmt.Errorf("%w: %s is not a valid number", ErrInvalidPrice, priceText)
+	// The price can be followed by the unit the item is counted in
+	if value, unitText := splitPrice(priceText); unitText != "" && hasPrefixFold(unitText, "unit") {
+		priceText = value
+		unitName, unitSize, err := parseUnit(unitText)
+		if err != nil {
+			return result, err
+		}
+		result.UnitName = unitName
+		result.UnitSize = unitSize
 	}
 
-	// Validate price is positive
-	if price <= 0 {
-		return result, ErrInvalidPrice
+	price, err := parsePrice(priceText)
+	if err != nil {
+		return result, err
 	}
 
 	// Set result values
@@ -125,9 +244,792 @@ func ParseConfigCommand(commandText string) (CommandParseResult, error) {
 	return result, nil
 }
 
+// splitPrice splits a price, written as a number or per item like "5 per 2" or "2 for 5", from
+// any text after it
+func splitPrice(text string) (string, string) {
+	words := strings.SplitN(text, " ", 4)
+	n := 1
+	if len(words) >= 3 && (strings.EqualFold(words[1], "per") || strings.EqualFold(words[1], "for")) {
+		n = 3
+	}
+	if len(words) <= n {
+		return text, ""
+	}
+	return strings.Join(words[:n], " "), strings.Join(words[n:], " ")
+}
+
+// parsePrice parses a price, either a number or the price of several items written as
+// "5 per 2" or "2 for 5", returning the price of a single item
+func parsePrice(text string) (float64, error) {
+	words := strings.Split(text, " ")
+	if len(words) != 3 {
+		return parsePositiveNumber(text, ErrInvalidPrice)
+	}
+
+	amountText, quantityText := words[0], words[2]
+	switch {
+	case strings.EqualFold(words[1], "per"):
+	case strings.EqualFold(words[1], "for"):
+		amountText, quantityText = words[2], words[0]
+	default:
+		return parsePositiveNumber(text, ErrInvalidPrice)
+	}
+
+	amount, err := parsePositiveNumber(amountText, ErrInvalidPrice)
+	if err != nil {
+		return 0, err
+	}
+	quantity, err := parsePositiveNumber(quantityText, ErrInvalidQuantity)
+	if err != nil {
+		return 0, err
+	}
+
+	price := amount / quantity
+	if price <= 0 || math.IsInf(price, 0) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidPrice, text)
+	}
+	return price, nil
+}
+
+// parsePositiveNumber parses a positive, finite number, wrapping invalid with the reason it isn't
+func parsePositiveNumber(text string, invalid error) (float64, error) {
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not a valid number", invalid, text)
+	}
+
+	// ParseFloat also accepts "NaN" and "Inf"
+	if value <= 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("%w: %s", invalid, text)
+	}
+	return value, nil
+}
+
+// hasPrefixFold reports whether s begins with the ASCII keyword prefix, ignoring case
+// Comparing against s itself, rather than a lowercased copy, keeps len(prefix) a valid offset
+// into s, as lowercasing can change the length of some characters, like "İ" to "i"
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// hasSuffixFold reports whether s ends with the ASCII keyword suffix, ignoring case
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
+
+// publicKeyword ends an item command whose confirmation should be posted to the channel
+const publicKeyword = "public"
+
+// parseUnit parses the unit an item is counted in, e.g. unit "tank" size 60
+func parseUnit(text string) (string, float64, error) {
+	matches := unitRegex.FindStringSubmatch(text)
+	if matches == nil {
+		return "", 0, fmt.Errorf("%w: %s", ErrInvalidUnit, text)
+	}
+
+	name := strings.TrimSpace(matches[1] + matches[2])
+	size, err := strconv.ParseFloat(matches[3], 64)
+	if name == "" || err != nil || size <= 0 || math.IsInf(size, 0) || math.IsNaN(size) {
+		return "", 0, fmt.Errorf("%w: %s", ErrInvalidUnit, text)
+	}
+	return name, size, nil
+}
+
+// ParseRoundingCommand parses a Slack slash command for setting the rounding mode.
+// Expected format: /snagbot rounding up|down|nearest
+func ParseRoundingCommand(commandText string) (calculator.RoundingMode, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "rounding" {
+		return calculator.RoundUp, fmt.Errorf("%w: command must start with 'rounding'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return calculator.RoundUp, ErrInvalidRoundingMode
+	}
+
+	mode, err := calculator.ParseRoundingMode(fields[1])
+	if err != nil {
+		return calculator.RoundUp, fmt.Errorf("%w: %s", ErrInvalidRoundingMode, fields[1])
+	}
+
+	return mode, nil
+}
+
+// ParseResponseModeCommand parses a Slack slash command for setting how SnagBot responds.
+// Expected format: /snagbot mode message|reaction
+func ParseResponseModeCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "mode" {
+		return "", fmt.Errorf("%w: command must start with 'mode'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidResponseMode
+	}
+
+	switch mode := strings.ToLower(fields[1]); mode {
+	case models.ResponseModeMessage, models.ResponseModeReaction:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidResponseMode, fields[1])
+	}
+}
+
+// ParseSumCommand parses a Slack slash command for setting whether a message's amounts are
+// summed or converted line by line.
+// Expected format: /snagbot sum total|lines
+func ParseSumCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "sum" {
+		return "", fmt.Errorf("%w: command must start with 'sum'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidSumMode
+	}
+
+	switch mode := strings.ToLower(fields[1]); mode {
+	case models.SumModeTotal, models.SumModePerLine:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidSumMode, fields[1])
+	}
+}
+
+// ParseTriggerCommand parses a Slack slash command for setting whether SnagBot responds to every
+// message or only to messages containing a trigger word, returning the mode and any new word.
+// Expected format: /snagbot trigger always|keyword [word]
+func ParseTriggerCommand(commandText string) (string, string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "trigger" {
+		return "", "", fmt.Errorf("%w: command must start with 'trigger'", ErrInvalidCommand)
+	}
+
+	if len(fields) < 2 {
+		return "", "", ErrInvalidTriggerMode
+	}
+
+	switch mode := strings.ToLower(fields[1]); mode {
+	case models.TriggerModeAlways:
+		if len(fields) != 2 {
+			return "", "", ErrInvalidTriggerMode
+		}
+		return mode, "", nil
+	case models.TriggerModeKeyword:
+		if len(fields) == 2 {
+			return mode, "", nil
+		}
+		if len(fields) != 3 || !isTriggerWord(fields[2]) {
+			return "", "", fmt.Errorf("%w: %s", ErrInvalidTriggerWord, strings.Join(fields[2:], " "))
+		}
+		return mode, strings.ToLower(fields[2]), nil
+	default:
+		return "", "", fmt.Errorf("%w: %s", ErrInvalidTriggerMode, fields[1])
+	}
+}
+
+// isTriggerWord reports whether word can be matched as a trigger, being only letters and numbers
+func isTriggerWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseDecimalCommand parses a Slack slash command for setting whether amounts in messages use a
+// point or a comma as the decimal separator.
+// Expected format: /snagbot decimal point|comma
+func ParseDecimalCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "decimal" {
+		return "", fmt.Errorf("%w: command must start with 'decimal'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidNumberFormat
+	}
+
+	switch format := strings.ToLower(fields[1]); format {
+	case models.NumberFormatPoint, models.NumberFormatComma:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidNumberFormat, fields[1])
+	}
+}
+
+// ParseLocaleCommand parses a Slack slash command for choosing the phrasing of responses.
+// Expected format: /snagbot locale en-AU|en-US|en-GB
+func ParseLocaleCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "locale" {
+		return "", fmt.Errorf("%w: command must start with 'locale'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidLocale
+	}
+
+	locale, ok := models.NormalizeLocale(fields[1])
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidLocale, fields[1])
+	}
+	return locale, nil
+}
+
+// ParseReplyCommand parses a Slack slash command for setting where SnagBot's replies are posted.
+// Expected format: /snagbot reply thread|channel
+func ParseReplyCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "reply" {
+		return "", fmt.Errorf("%w: command must start with 'reply'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidReplyPlacement
+	}
+
+	switch placement := strings.ToLower(fields[1]); placement {
+	case models.ReplyPlacementThread, models.ReplyPlacementChannel:
+		return placement, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidReplyPlacement, fields[1])
+	}
+}
+
+// toggleSettingErrors are the errors for settings that are turned on or off, by subcommand
+var toggleSettingErrors = map[string]error{
+	"button":    ErrInvalidButtonSetting,
+	"words":     ErrInvalidWordsSetting,
+	"random":    ErrInvalidRandomSetting,
+	"variety":   ErrInvalidVarietySetting,
+	"repeats":   ErrInvalidRepeatsSetting,
+	"filter":    ErrInvalidFilterSetting,
+	"breakdown": ErrInvalidBreakdownSetting,
+	"private":   ErrInvalidPrivateSetting,
+}
+
+// parseToggle parses a Slack slash command that turns a setting on or off.
+// Expected format: /snagbot <name> on|off
+// Settings other than on or off get the subcommand's error from toggleSettingErrors
+func parseToggle(name, commandText string) (bool, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != name {
+		return false, fmt.Errorf("%w: command must start with '%s'", ErrInvalidCommand, name)
+	}
+
+	errSetting := toggleSettingErrors[name]
+	if len(fields) != 2 {
+		return false, errSetting
+	}
+
+	switch strings.ToLower(fields[1]) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %s", errSetting, fields[1])
+	}
+}
+
+// ParseButtonCommand parses a Slack slash command for showing the "Change item" button on responses.
+// Expected format: /snagbot button on|off
+func ParseButtonCommand(commandText string) (bool, error) {
+	return parseToggle("button", commandText)
+}
+
+// ParseWordsCommand parses a Slack slash command for counting amounts spelled out in words.
+// Expected format: /snagbot words on|off
+func ParseWordsCommand(commandText string) (bool, error) {
+	return parseToggle("words", commandText)
+}
+
+// ParseRandomCommand parses a Slack slash command for converting to random items from the built-in catalog.
+// Expected format: /snagbot random on|off
+func ParseRandomCommand(commandText string) (bool, error) {
+	return parseToggle("random", commandText)
+}
+
+// ParseVarietyCommand parses a Slack slash command for wording each response differently.
+// Expected format: /snagbot variety on|off
+func ParseVarietyCommand(commandText string) (bool, error) {
+	return parseToggle("variety", commandText)
+}
+
+// ParseRepeatsCommand parses a Slack slash command for counting every occurrence of a repeated amount.
+// Expected format: /snagbot repeats on|off
+func ParseRepeatsCommand(commandText string) (bool, error) {
+	return parseToggle("repeats", commandText)
+}
+
+// ParseFilterCommand parses a Slack slash command for filtering out false matches like "$0" or "$35 off".
+// Expected format: /snagbot filter on|off
+func ParseFilterCommand(commandText string) (bool, error) {
+	return parseToggle("filter", commandText)
+}
+
+// ParseBreakdownCommand parses a Slack slash command for showing how a message's amounts add up.
+// Expected format: /snagbot breakdown on|off
+func ParseBreakdownCommand(commandText string) (bool, error) {
+	return parseToggle("breakdown", commandText)
+}
+
+// ParsePrivateCommand parses a Slack slash command for replying only to the message's author.
+// Expected format: /snagbot private on|off
+func ParsePrivateCommand(commandText string) (bool, error) {
+	return parseToggle("private", commandText)
+}
+
+// emojiRegex matches a Slack emoji code such as :taco: or :+1:
+var emojiRegex = regexp.MustCompile(`^:([a-z0-9_+'-]+):$`)
+
+// ParseEmojiCommand parses a Slack slash command for setting the emoji SnagBot reacts with.
+// Expected format: /snagbot emoji :taco:
+// Returns the emoji name without colons, which is how Slack's API refers to it.
+func ParseEmojiCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "emoji" {
+		return "", fmt.Errorf("%w: command must start with 'emoji'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidEmoji
+	}
+
+	matches := emojiRegex.FindStringSubmatch(strings.ToLower(fields[1]))
+	if matches == nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidEmoji, fields[1])
+	}
+
+	return matches[1], nil
+}
+
+// ParseThresholdCommand parses a Slack slash command for setting the minimum total to respond to.
+// Expected format: /snagbot threshold 20 (use 0 to respond to any amount)
+func ParseThresholdCommand(commandText string) (float64, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "threshold" {
+		return 0, fmt.Errorf("%w: command must start with 'threshold'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return 0, ErrInvalidThreshold
+	}
+
+	// Allow "$20" as well as "20"
+	thresholdText := strings.TrimPrefix(fields[1], "$")
+	threshold, err := strconv.ParseFloat(thresholdText, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s is not a valid number", ErrInvalidThreshold, fields[1])
+	}
+
+	// ParseFloat also accepts "NaN" and "Inf"
+	if threshold < 0 || math.IsInf(threshold, 0) || math.IsNaN(threshold) {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidThreshold, fields[1])
+	}
+
+	return threshold, nil
+}
+
+// ParseTemplateCommand parses a Slack slash command for setting the response template.
+// Expected format: /snagbot template "That's {count} {item}!"
+// Quotes around the template are optional, and case is preserved.
+func ParseTemplateCommand(commandText string) (string, error) {
+	template, err := parseQuotedArgument(commandText, "template")
+	if err != nil {
+		return "", err
+	}
+
+	if template == "" {
+		return "", ErrMissingTemplate
+	}
+
+	if err := calculator.ValidateResponseTemplate(template); err != nil {
+		return "", ErrInvalidTemplate
+	}
+
+	return template, nil
+}
+
+// ParseZeroMessageCommand parses a Slack slash command for setting the message used when a total
+// wouldn't buy a single item.
+// Expected format: /snagbot zeromsg "Not even one {item}!"
+// Quotes around the message are optional, and case is preserved. "default" clears the message.
+func ParseZeroMessageCommand(commandText string) (string, error) {
+	message, err := parseQuotedArgument(commandText, "zeromsg")
+	if err != nil {
+		return "", err
+	}
+
+	if message == "" {
+		return "", ErrMissingZeroMessage
+	}
+	if strings.EqualFold(message, "default") {
+		return "", nil
+	}
+
+	return message, nil
+}
+
+// parseQuotedArgument returns the text after a subcommand, without the quotes around it if it has them
+func parseQuotedArgument(commandText, subcommand string) (string, error) {
+	commandText = strings.TrimSpace(commandText)
+	if !strings.HasPrefix(strings.ToLower(commandText), subcommand) {
+		return "", fmt.Errorf("%w: command must start with '%s'", ErrInvalidCommand, subcommand)
+	}
+
+	argument := strings.TrimSpace(commandText[len(subcommand):])
+	if strings.HasPrefix(argument, "\"") {
+		if len(argument) < 2 || !strings.HasSuffix(argument, "\"") {
+			return "", fmt.Errorf("%w: unclosed quote in %s", ErrInvalidCommand, subcommand)
+		}
+		argument = strings.TrimSpace(argument[1 : len(argument)-1])
+	}
+
+	return argument, nil
+}
+
+// ParseCurrencyCommand parses a Slack slash command for setting the channel's currency symbol.
+// Expected format: /snagbot currency £
+func ParseCurrencyCommand(commandText string) (string, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "currency" {
+		return "", fmt.Errorf("%w: command must start with 'currency'", ErrInvalidCommand)
+	}
+
+	if len(fields) != 2 {
+		return "", ErrInvalidCurrency
+	}
+
+	// Only a single non-alphanumeric character makes sense as a symbol
+	symbol := []rune(fields[1])
+	if len(symbol) != 1 || unicode.IsLetter(symbol[0]) || unicode.IsDigit(symbol[0]) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidCurrency, fields[1])
+	}
+
+	return fields[1], nil
+}
+
+// DefaultHistoryLimit is how many changes /snagbot history shows when no limit is given
+const DefaultHistoryLimit = 5
+
+// ParseHistoryCommand parses a Slack slash command for showing recent configuration changes.
+// Expected format: /snagbot history [count]
+func ParseHistoryCommand(commandText string) (int, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 || strings.ToLower(fields[0]) != "history" {
+		return 0, fmt.Errorf("%w: command must start with 'history'", ErrInvalidCommand)
+	}
+
+	switch len(fields) {
+	case 1:
+		return DefaultHistoryLimit, nil
+	case 2:
+		limit, err := strconv.Atoi(fields[1])
+		if err != nil || limit <= 0 {
+			return 0, fmt.Errorf("%w: %s", ErrInvalidHistoryLimit, fields[1])
+		}
+		return limit, nil
+	default:
+		return 0, ErrInvalidHistoryLimit
+	}
+}
+
+// ParseAddItemCommand parses a Slack slash command for adding an extra item.
+// Expected format: /snagbot add item "coffee" price 5.00
+func ParseAddItemCommand(commandText string) (CommandParseResult, error) {
+	commandText = strings.TrimSpace(commandText)
+	if !strings.HasPrefix(strings.ToLower(commandText), "add") {
+		return CommandParseResult{}, fmt.Errorf("%w: command must start with 'add'", ErrInvalidCommand)
+	}
+
+	return parseItemWithoutUnit(strings.TrimSpace(commandText[len("add"):]))
+}
+
+// ParsePriceCommand parses a Slack slash command for setting only the channel's price, which keeps
+// inheriting the workspace's default item unless the channel has set its own.
+// Expected format: /snagbot price 4.00, or a deal like /snagbot price 2 for 5.00
+func ParsePriceCommand(commandText string) (float64, error) {
+	commandText = strings.Join(strings.Fields(commandText), " ")
+	if !hasPrefixFold(commandText, "price") {
+		return 0, fmt.Errorf("%w: command must start with 'price'", ErrInvalidCommand)
+	}
+
+	priceText := strings.TrimSpace(commandText[len("price"):])
+	if priceText == "" {
+		return 0, ErrMissingPrice
+	}
+	return parsePrice(priceText)
+}
+
+// ParsePreviewCommand parses a Slack slash command for trying an item and price on an amount
+// without saving them, returning the item and the amount.
+// Expected format: /snagbot preview item "coffee" price 5.00 amount 35
+func ParsePreviewCommand(commandText string) (CommandParseResult, float64, error) {
+	commandText = strings.Join(strings.Fields(commandText), " ")
+	if !hasPrefixFold(commandText, "preview") {
+		return CommandParseResult{}, 0, fmt.Errorf("%w: command must start with 'preview'", ErrInvalidCommand)
+	}
+	commandText = commandText[len("preview"):]
+
+	// The amount comes last, after the item and price
+	i := strings.LastIndex(strings.ToLower(commandText), " amount ")
+	if i < 0 {
+		return CommandParseResult{}, 0, ErrInvalidAmount
+	}
+	amount, err := parsePositiveNumber(strings.TrimPrefix(commandText[i+len(" amount "):], "$"), ErrInvalidAmount)
+	if err != nil {
+		return CommandParseResult{}, 0, err
+	}
+
+	result, err := ParseConfigCommand(commandText[:i])
+	if err != nil {
+		return CommandParseResult{}, 0, err
+	}
+	return result, amount, nil
+}
+
+// ParseFeedbackCommand parses a Slack slash command for sending feedback to the people who run
+// SnagBot, returning the message as written.
+// Expected format: /snagbot feedback your message here
+func ParseFeedbackCommand(commandText string) (string, error) {
+	commandText = strings.TrimSpace(commandText)
+	if !hasPrefixFold(commandText, "feedback") {
+		return "", fmt.Errorf("%w: command must start with 'feedback'", ErrInvalidCommand)
+	}
+
+	message := strings.TrimSpace(commandText[len("feedback"):])
+	if message == "" {
+		return "", ErrMissingFeedback
+	}
+	return message, nil
+}
+
+// ParseDefaultItemCommand parses a Slack slash command for setting the workspace's default item,
+// the template its channels inherit.
+// Expected format: /snagbot default item "coffee" price 5.00, or /snagbot template-default item "coffee" price 5.00
+func ParseDefaultItemCommand(commandText string) (CommandParseResult, error) {
+	commandText = strings.TrimSpace(commandText)
+	if hasPrefixFold(commandText, "template-") {
+		commandText = commandText[len("template-"):]
+	}
+	if !strings.HasPrefix(strings.ToLower(commandText), "default") {
+		return CommandParseResult{}, fmt.Errorf("%w: command must start with 'default'", ErrInvalidCommand)
+	}
+
+	return parseItemWithoutUnit(strings.TrimSpace(commandText[len("default"):]))
+}
+
+// parseItemWithoutUnit parses an item and price like ParseConfigCommand, rejecting units since
+// only a channel's main item can have one
+func parseItemWithoutUnit(commandText string) (CommandParseResult, error) {
+	result, err := ParseConfigCommand(commandText)
+	if err == nil && result.UnitName != "" {
+		return CommandParseResult{}, fmt.Errorf("%w: only a channel's main item can have a unit", ErrInvalidUnit)
+	}
+	return result, err
+}
+
+// userMentionRegex matches a user as Slack escapes them in commands, e.g. <@U0123ABCD|someone>,
+// or a bare user ID
+var userMentionRegex = regexp.MustCompile(`^(?:<@([UW][A-Z0-9]+)(?:\|[^>]*)?>|([UW][A-Z0-9]+))$`)
+
+// ParseIgnoreCommand parses a Slack slash command for ignoring a channel or user, or no longer ignoring them.
+// Expected format: /snagbot ignore|unignore [@user]
+func ParseIgnoreCommand(commandText string) (IgnoreTarget, error) {
+	fields := strings.Fields(commandText)
+	if len(fields) == 0 {
+		return IgnoreTarget{}, fmt.Errorf("%w: command must start with 'ignore' or 'unignore'", ErrInvalidCommand)
+	}
+
+	var target IgnoreTarget
+	switch strings.ToLower(fields[0]) {
+	case "ignore":
+		target.Ignore = true
+	case "unignore":
+		target.Ignore = false
+	default:
+		return IgnoreTarget{}, fmt.Errorf("%w: command must start with 'ignore' or 'unignore'", ErrInvalidCommand)
+	}
+
+	switch len(fields) {
+	case 1:
+		return target, nil
+	case 2:
+		match := userMentionRegex.FindStringSubmatch(fields[1])
+		if match == nil {
+			return IgnoreTarget{}, fmt.Errorf("%w: %s", ErrInvalidIgnoreTarget, fields[1])
+		}
+		target.UserID = match[1] + match[2]
+		return target, nil
+	default:
+		return IgnoreTarget{}, ErrInvalidIgnoreTarget
+	}
+}
+
+// slackTextReplacer undoes the escaping Slack applies to command text, and the smart quotes
+// Slack clients can turn pasted JSON's quotes into
+var slackTextReplacer = strings.NewReplacer(
+	"&lt;", "<", "&gt;", ">", "&amp;", "&",
+	"“", `"`, "”", `"`, "‘", "'", "’", "'",
+)
+
+// ParseImportCommand parses a Slack slash command for applying a configuration exported from another channel.
+// Expected format: /snagbot import {"item_name":"coffee","item_price":5}
+// The JSON uses the same fields as models.ChannelConfig, and may be wrapped in a code block.
+func ParseImportCommand(commandText string) (*models.ChannelConfig, error) {
+	commandText = strings.TrimSpace(commandText)
+	if !strings.HasPrefix(strings.ToLower(commandText), "import") {
+		return nil, fmt.Errorf("%w: command must start with 'import'", ErrInvalidCommand)
+	}
+
+	data := slackTextReplacer.Replace(strings.TrimSpace(commandText[len("import"):]))
+	data = strings.TrimSpace(strings.Trim(data, "`"))
+	if data == "" {
+		return nil, ErrInvalidImport
+	}
+
+	// Unknown fields are most likely typos, so they're rejected rather than dropped
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var config models.ChannelConfig
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("%w: unexpected text after the JSON", ErrInvalidImport)
+	}
+
+	if err := validateImportedConfig(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	return &config, nil
+}
+
+// validateImportedConfig checks an imported configuration holds the same values the other commands would accept
+func validateImportedConfig(config *models.ChannelConfig) error {
+	config.ItemName = strings.TrimSpace(config.ItemName)
+	if config.ItemName == "" {
+		return fmt.Errorf("item_name is required")
+	}
+	if config.ItemPrice <= 0 {
+		return fmt.Errorf("item_price must be a positive number")
+	}
+
+	if len(config.Items) > models.MaxChannelItems {
+		return fmt.Errorf("at most %d extra items are allowed", models.MaxChannelItems)
+	}
+	for _, item := range config.Items {
+		if strings.TrimSpace(item.Name) == "" || item.Price <= 0 {
+			return fmt.Errorf("every item needs a name and a positive price")
+		}
+	}
+
+	if len(config.Variations) > models.MaxVariations {
+		return fmt.Errorf("at most %d variations are allowed", models.MaxVariations)
+	}
+	for _, variation := range config.Variations {
+		if strings.TrimSpace(variation.Prefix+variation.Suffix) == "" {
+			return fmt.Errorf("every variation needs a prefix or suffix")
+		}
+	}
+
+	if _, err := calculator.ParseRoundingMode(config.RoundingMode); err != nil {
+		return fmt.Errorf("rounding_mode must be one of: up, down, nearest")
+	}
+	if _, err := calculator.ParseNegativeHandling(config.NegativeHandling); err != nil {
+		return fmt.Errorf("negative_handling must be one of: include, ignore, absolute")
+	}
+
+	switch config.ResponseMode {
+	case "", models.ResponseModeMessage, models.ResponseModeReaction:
+	default:
+		return ErrInvalidResponseMode
+	}
+	switch config.ReplyPlacement {
+	case "", models.ReplyPlacementThread, models.ReplyPlacementChannel:
+	default:
+		return ErrInvalidReplyPlacement
+	}
+	switch config.SumMode {
+	case "", models.SumModeTotal, models.SumModePerLine:
+	default:
+		return ErrInvalidSumMode
+	}
+	switch config.TriggerMode {
+	case "", models.TriggerModeAlways, models.TriggerModeKeyword:
+	default:
+		return ErrInvalidTriggerMode
+	}
+	if config.TriggerWord != "" && !isTriggerWord(config.TriggerWord) {
+		return ErrInvalidTriggerWord
+	}
+	switch config.NumberFormat {
+	case "", models.NumberFormatPoint, models.NumberFormatComma:
+	default:
+		return ErrInvalidNumberFormat
+	}
+	if config.Locale != "" {
+		if _, ok := models.LocalePhrases[config.Locale]; !ok {
+			return ErrInvalidLocale
+		}
+	}
+	switch config.TooManyValues {
+	case "", models.TooManyValuesTruncate, models.TooManyValuesSkip:
+	default:
+		return fmt.Errorf("too_many_values must be one of: truncate, skip")
+	}
+
+	if config.ResponseTemplate != "" {
+		if err := calculator.ValidateResponseTemplate(config.ResponseTemplate); err != nil {
+			return ErrInvalidTemplate
+		}
+	}
+	if config.Emoji != "" && !emojiRegex.MatchString(":"+config.Emoji+":") {
+		return ErrInvalidEmoji
+	}
+	if config.MinThreshold < 0 {
+		return ErrInvalidThreshold
+	}
+	if config.MaxDollarValues < 0 {
+		return fmt.Errorf("max_dollar_values must be zero or a positive number")
+	}
+	if config.UnitSize < 0 || (config.UnitName == "") != (config.UnitSize == 0) {
+		return ErrInvalidUnit
+	}
+	if config.MaxItemCount < 0 {
+		return fmt.Errorf("max_item_count must be zero or a positive number")
+	}
+	for _, symbol := range config.CurrencySymbols {
+		if strings.TrimSpace(symbol) == "" {
+			return ErrInvalidCurrency
+		}
+	}
+
+	return nil
+}
+
 // FormatCommandResponse formats a response message for the command
 func FormatCommandResponse(result CommandParseResult) string {
-	return fmt.Sprintf("Configuration updated! Now converting dollar amounts to %s (at $%.2f each).", result.ItemName, result.ItemPrice)
+	return FormatCommandResponseWithCurrency(result, models.DefaultCurrency)
+}
+
+// FormatCommandResponseWithCurrency formats a response message for the command using the
+// channel's currency symbol
+func FormatCommandResponseWithCurrency(result CommandParseResult, currency string) string {
+	if result.UnitName != "" {
+		return fmt.Sprintf("Configuration updated! Now converting dollar amounts to %s (at %s per %s).",
+			calculator.DescribeUnit(result.UnitName, result.UnitSize, result.ItemName),
+			FormatPrice(result.ItemPrice, currency), result.ItemName)
+	}
+	return fmt.Sprintf("Configuration updated! Now converting dollar amounts to %s (at %s each).",
+		result.ItemName, FormatPrice(result.ItemPrice, currency))
 }
 
 // FormatCommandErrorResponse formats an error message for the command
@@ -148,6 +1050,62 @@ func FormatCommandErrorResponse(err error) string {
 		errorMsg += "\nPlease provide a price value." + helpText
 	case errors.Is(err, ErrInvalidPrice):
 		errorMsg += "\nThe price must be a positive number (e.g., 3.50)." + helpText
+	case errors.Is(err, ErrInvalidQuantity):
+		errorMsg += "\n\nUsage example: `/snagbot item \"pie\" price 2 for 11.00`"
+	case errors.Is(err, ErrInvalidUnit):
+		errorMsg += "\n\nUsage example: `/snagbot item \"L\" price 2.00 unit \"tank\" size 60`"
+	case errors.Is(err, ErrInvalidRoundingMode):
+		errorMsg += "\n\nUsage example: `/snagbot rounding down`"
+	case errors.Is(err, ErrInvalidResponseMode):
+		errorMsg += "\n\nUsage example: `/snagbot mode reaction`"
+	case errors.Is(err, ErrInvalidReplyPlacement):
+		errorMsg += "\n\nUsage example: `/snagbot reply channel`"
+	case errors.Is(err, ErrInvalidSumMode):
+		errorMsg += "\n\nUsage example: `/snagbot sum lines`"
+	case errors.Is(err, ErrInvalidTriggerMode), errors.Is(err, ErrInvalidTriggerWord):
+		errorMsg += "\n\nUsage example: `/snagbot trigger keyword snagify`"
+	case errors.Is(err, ErrInvalidNumberFormat):
+		errorMsg += "\n\nUsage example: `/snagbot decimal comma`"
+	case errors.Is(err, ErrInvalidLocale):
+		errorMsg += "\n\nUsage example: `/snagbot locale en-US`"
+	case errors.Is(err, ErrInvalidButtonSetting):
+		errorMsg += "\n\nUsage example: `/snagbot button on`"
+	case errors.Is(err, ErrInvalidWordsSetting):
+		errorMsg += "\n\nUsage example: `/snagbot words on`"
+	case errors.Is(err, ErrInvalidRandomSetting):
+		errorMsg += "\n\nUsage example: `/snagbot random on`"
+	case errors.Is(err, ErrInvalidVarietySetting):
+		errorMsg += "\n\nUsage example: `/snagbot variety on`"
+	case errors.Is(err, ErrInvalidRepeatsSetting):
+		errorMsg += "\n\nUsage example: `/snagbot repeats on`"
+	case errors.Is(err, ErrInvalidFilterSetting):
+		errorMsg += "\n\nUsage example: `/snagbot filter on`"
+	case errors.Is(err, ErrInvalidBreakdownSetting):
+		errorMsg += "\n\nUsage example: `/snagbot breakdown on`"
+	case errors.Is(err, ErrInvalidPrivateSetting):
+		errorMsg += "\n\nUsage example: `/snagbot private on`"
+	case errors.Is(err, ErrInvalidEmoji):
+		errorMsg += "\n\nUsage example: `/snagbot emoji :taco:`"
+	case errors.Is(err, ErrInvalidThreshold):
+		errorMsg += "\n\nUsage example: `/snagbot threshold 20`"
+	case errors.Is(err, ErrInvalidCurrency):
+		errorMsg += "\n\nUsage example: `/snagbot currency £`"
+	case errors.Is(err, ErrInvalidHistoryLimit):
+		errorMsg += "\n\nUsage example: `/snagbot history 10`"
+	case errors.Is(err, ErrInvalidImport):
+		errorMsg += "\n\nUse `/snagbot export` in another channel and paste its JSON after `/snagbot import`."
+	case errors.Is(err, ErrMissingFeedback):
+		errorMsg += "\n\nUsage example: `/snagbot feedback Could you add a pie option?`"
+	case errors.Is(err, ErrInvalidAmount):
+		errorMsg += "\n\nUsage example: `/snagbot preview item \"coffee\" price 5.00 amount 35`"
+	case errors.Is(err, ErrInvalidIgnoreTarget):
+		errorMsg += "\n\nUsage example: `/snagbot ignore @someone`, or `/snagbot ignore` for this channel"
+	case errors.Is(err, ErrTooManyItems):
+		errorMsg += fmt.Sprintf("\nA channel can have at most %d extra items. Use `/snagbot reset` to start again.", models.MaxChannelItems)
+	case errors.Is(err, ErrMissingTemplate), errors.Is(err, ErrInvalidTemplate):
+		errorMsg += "\n\nUsage example: `/snagbot template \"That's {count} {item}!\"`"
+	case errors.Is(err, ErrMissingZeroMessage):
+		errorMsg += "\n\nUsage example: `/snagbot zeromsg \"Not even one {item}!\"`, or `/snagbot zeromsg default` to go back to the usual message"
 	default:
 		errorMsg += helpText
 	}
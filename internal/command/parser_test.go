@@ -3,8 +3,12 @@ package command
 import (
 	"errors"
 	"fmt"
+	"math"
 	"testing"
+	"unicode/utf8"
 
+	"github.com/mcncl/snagbot/internal/calculator"
+	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,12 +44,48 @@ func TestParseConfigCommand(t *testing.T) {
 			expected:    CommandParseResult{ItemName: "coffee", ItemPrice: 5.00},
 			expectError: false,
 		},
+		{
+			name:        "Public change",
+			commandText: "item \"coffee\" price 5.00 Public",
+			expected:    CommandParseResult{ItemName: "coffee", ItemPrice: 5.00, Public: true},
+			expectError: false,
+		},
+		{
+			name:        "Public change with unit",
+			commandText: "item \"L\" price 2.00 unit \"tank\" size 60 public",
+			expected:    CommandParseResult{ItemName: "L", ItemPrice: 2.00, UnitName: "tank", UnitSize: 60, Public: true},
+			expectError: false,
+		},
 		{
 			name:        "Valid command with integer price",
 			commandText: "item coffee price 5",
 			expected:    CommandParseResult{ItemName: "coffee", ItemPrice: 5.0},
 			expectError: false,
 		},
+		{
+			name:        "Quoted item with padding and repeated spaces",
+			commandText: "item \"  flat   white  \" price 5.00",
+			expected:    CommandParseResult{ItemName: "flat white", ItemPrice: 5.00},
+			expectError: false,
+		},
+		{
+			name:        "Quoted item with newlines",
+			commandText: "item \"flat\nwhite\r\n\" price 5.00",
+			expected:    CommandParseResult{ItemName: "flat white", ItemPrice: 5.00},
+			expectError: false,
+		},
+		{
+			name:        "Quoted item with control characters",
+			commandText: "item \"cof\x00fee\x1b\" price 5.00",
+			expected:    CommandParseResult{ItemName: "coffee", ItemPrice: 5.00},
+			expectError: false,
+		},
+		{
+			name:        "Quoted item of only whitespace",
+			commandText: "item \" \" price 5.00",
+			expectError: true,
+			errorType:   ErrMissingItem,
+		},
 		{
 			name:        "Missing item prefix",
 			commandText: "coffee price 5.00",
@@ -100,6 +140,126 @@ func TestParseConfigCommand(t *testing.T) {
 			expected:    CommandParseResult{ItemName: "Coffee", ItemPrice: 5.00},
 			expectError: false,
 		},
+		{
+			name:        "Quoted unit",
+			commandText: "item \"L\" price 2.00 unit \"tank\" size 60",
+			expected:    CommandParseResult{ItemName: "L", ItemPrice: 2.00, UnitName: "tank", UnitSize: 60},
+		},
+		{
+			name:        "Single word unit in any case",
+			commandText: "item litre PRICE 2 UNIT jerrycan SIZE 20.5",
+			expected:    CommandParseResult{ItemName: "litre", ItemPrice: 2.00, UnitName: "jerrycan", UnitSize: 20.5},
+		},
+		{
+			name:        "Unit without size",
+			commandText: "item L price 2.00 unit tank",
+			expectError: true,
+			errorType:   ErrInvalidUnit,
+		},
+		{
+			name:        "Unit with zero size",
+			commandText: "item L price 2.00 unit tank size 0",
+			expectError: true,
+			errorType:   ErrInvalidUnit,
+		},
+		{
+			name:        "Unit with invalid size",
+			commandText: "item L price 2.00 unit tank size big",
+			expectError: true,
+			errorType:   ErrInvalidUnit,
+		},
+		{
+			name:        "Text after the price that isn't a unit",
+			commandText: "item coffee price 5.00 please",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
+		{
+			name:        "Lone opening quote",
+			commandText: "item \"",
+			expectError: true,
+			errorType:   ErrInvalidCommand,
+		},
+		{
+			name:        "Price keyword twice",
+			commandText: "item price price",
+			expectError: true,
+			errorType:   ErrMissingItem,
+		},
+		{
+			name:        "Curly quotes are part of a single word item",
+			commandText: "item “coffee” price 5",
+			expected:    CommandParseResult{ItemName: "“coffee”", ItemPrice: 5},
+		},
+		{
+			name:        "Curly quoted multi-word item",
+			commandText: "item “flat white” price 5",
+			expectError: true,
+			errorType:   ErrInvalidCommand,
+		},
+		{
+			name:        "NaN price",
+			commandText: "item coffee price NaN",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
+		{
+			name:        "Infinite price",
+			commandText: "item coffee price Inf",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
+		{
+			name:        "Price too large to represent",
+			commandText: "item coffee price 1e400",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
+		{
+			name:        "Character that changes length when lowercased before item",
+			commandText: "İtem price 5",
+			expectError: true,
+			errorType:   ErrInvalidCommand,
+		},
+		{
+			name:        "Price for several items",
+			commandText: "item pie price 2 for 5",
+			expected:    CommandParseResult{ItemName: "pie", ItemPrice: 2.50},
+		},
+		{
+			name:        "Price per several items",
+			commandText: "item pie price 5 PER 2",
+			expected:    CommandParseResult{ItemName: "pie", ItemPrice: 2.50},
+		},
+		{
+			name:        "Price for several items with unit and public",
+			commandText: "item L price 4 for 10 unit \"tank\" size 60 public",
+			expected:    CommandParseResult{ItemName: "L", ItemPrice: 2.50, UnitName: "tank", UnitSize: 60, Public: true},
+		},
+		{
+			name:        "Price for no items",
+			commandText: "item pie price 0 for 5",
+			expectError: true,
+			errorType:   ErrInvalidQuantity,
+		},
+		{
+			name:        "Price per negative items",
+			commandText: "item pie price 5 per -2",
+			expectError: true,
+			errorType:   ErrInvalidQuantity,
+		},
+		{
+			name:        "Free items",
+			commandText: "item pie price 2 for 0",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
+		{
+			name:        "Price with unknown quantity word",
+			commandText: "item pie price 2 with 5",
+			expectError: true,
+			errorType:   ErrInvalidPrice,
+		},
 	}
 
 	for _, test := range tests {
@@ -113,68 +273,955 @@ func TestParseConfigCommand(t *testing.T) {
 				}
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, test.expected.ItemName, result.ItemName)
-				assert.Equal(t, test.expected.ItemPrice, result.ItemPrice)
+				assert.Equal(t, test.expected, result)
 			}
 		})
 	}
 }
 
-func TestFormatCommandResponse(t *testing.T) {
-	result := CommandParseResult{
-		ItemName:  "coffee",
-		ItemPrice: 5.00,
+// parseConfigErrors are the errors ParseConfigCommand may return
+var parseConfigErrors = []error{ErrInvalidCommand, ErrMissingItem, ErrMissingPrice, ErrInvalidPrice, ErrInvalidQuantity, ErrInvalidUnit}
+
+func FuzzParseConfigCommand(f *testing.F) {
+	for _, seed := range []string{
+		`item "coffee" price 5.00`,
+		`item coffee price 5.00`,
+		`item "Bunnings snags" price 3.50 public`,
+		`item fuel price 2.10 unit "tank" size 60`,
+		`item "`,
+		`item price price`,
+		`item “coffee” price 5`,
+		`item coffee price NaN`,
+		`ITEM coffee PRICE 5 PUBLIC`,
+		`item pie price 2 for 5`,
+		`item pie price 5 per 0`,
+	} {
+		f.Add(seed)
 	}
 
-	response := FormatCommandResponse(result)
-	expected := "Configuration updated! Now converting dollar amounts to coffee (at $5.00 each)."
-	assert.Equal(t, expected, response)
+	f.Fuzz(func(t *testing.T, commandText string) {
+		result, err := ParseConfigCommand(commandText)
+		if err != nil {
+			for _, target := range parseConfigErrors {
+				if errors.Is(err, target) {
+					return
+				}
+			}
+			t.Fatalf("ParseConfigCommand(%q) returned an untyped error: %v", commandText, err)
+		}
+
+		if result.ItemName == "" || result.ItemName != models.NormalizeItemName(result.ItemName) {
+			t.Fatalf("ParseConfigCommand(%q) returned item name %q", commandText, result.ItemName)
+		}
+		if utf8.ValidString(commandText) && !utf8.ValidString(result.ItemName) {
+			t.Fatalf("ParseConfigCommand(%q) returned invalid UTF-8 item name %q", commandText, result.ItemName)
+		}
+		if !(result.ItemPrice > 0) || math.IsInf(result.ItemPrice, 0) {
+			t.Fatalf("ParseConfigCommand(%q) returned price %v", commandText, result.ItemPrice)
+		}
+		if (result.UnitName == "") != (result.UnitSize == 0) || result.UnitSize < 0 || math.IsInf(result.UnitSize, 0) {
+			t.Fatalf("ParseConfigCommand(%q) returned unit %q of size %v", commandText, result.UnitName, result.UnitSize)
+		}
+	})
 }
 
-func TestFormatCommandErrorResponse(t *testing.T) {
+func TestParseAddItemCommandRejectsUnits(t *testing.T) {
+	_, err := ParseAddItemCommand(`add item "L" price 2.00 unit "tank" size 60`)
+	assert.True(t, errors.Is(err, ErrInvalidUnit), "Expected error type %v, got %v", ErrInvalidUnit, err)
+}
+
+func TestParseRoundingCommand(t *testing.T) {
 	tests := []struct {
-		name           string
-		err            error
-		shouldContain  []string
-		shouldNotMatch string
+		name        string
+		commandText string
+		expected    calculator.RoundingMode
+		errorType   error
 	}{
-		{
-			name:          "Invalid command error",
-			err:           fmt.Errorf("%w: command must start with 'item'", ErrInvalidCommand),
-			shouldContain: []string{"invalid command syntax", "command must start with 'item'", "Usage example:"},
-		},
-		{
-			name:          "Missing item error",
-			err:           ErrMissingItem,
-			shouldContain: []string{"missing item name", "Please provide an item name", "Usage example:"},
-		},
-		{
-			name:          "Missing price error",
-			err:           ErrMissingPrice,
-			shouldContain: []string{"missing price value", "Please provide a price value", "Usage example:"},
-		},
-		{
-			name:          "Invalid price error",
-			err:           fmt.Errorf("%w: 3.5.0 is not a valid number", ErrInvalidPrice),
-			shouldContain: []string{"price must be a positive number", "3.5.0 is not a valid number", "Usage example:"},
-		},
-		{
-			name:          "Default error case",
-			err:           errors.New("unexpected error"),
-			shouldContain: []string{"unexpected error", "Usage example:"},
-		},
+		{name: "Round up", commandText: "rounding up", expected: calculator.RoundUp},
+		{name: "Round down", commandText: "rounding down", expected: calculator.RoundDown},
+		{name: "Round nearest with extra whitespace", commandText: "  rounding   Nearest ", expected: calculator.RoundNearest},
+		{name: "Missing mode", commandText: "rounding", errorType: ErrInvalidRoundingMode},
+		{name: "Unknown mode", commandText: "rounding sideways", errorType: ErrInvalidRoundingMode},
+		{name: "Wrong subcommand", commandText: "item coffee price 5", errorType: ErrInvalidCommand},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			response := FormatCommandErrorResponse(test.err)
+			result, err := ParseRoundingCommand(test.commandText)
 
-			for _, text := range test.shouldContain {
-				assert.Contains(t, response, text)
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
 			}
+		})
+	}
+}
 
-			if test.shouldNotMatch != "" {
-				assert.NotContains(t, response, test.shouldNotMatch)
+func TestParseResponseModeCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Reaction", commandText: "mode reaction", expected: "reaction"},
+		{name: "Message", commandText: "MODE Message", expected: "message"},
+		{name: "Missing mode", commandText: "mode", errorType: ErrInvalidResponseMode},
+		{name: "Unknown mode", commandText: "mode carrier-pigeon", errorType: ErrInvalidResponseMode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseResponseModeCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseReplyCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Thread", commandText: "reply thread", expected: models.ReplyPlacementThread},
+		{name: "Channel mixed case", commandText: "Reply Channel", expected: models.ReplyPlacementChannel},
+		{name: "Missing placement", commandText: "reply", errorType: ErrInvalidReplyPlacement},
+		{name: "Unknown placement", commandText: "reply dm", errorType: ErrInvalidReplyPlacement},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseReplyCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseSumCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Total", commandText: "sum total", expected: models.SumModeTotal},
+		{name: "Lines mixed case", commandText: "Sum Lines", expected: models.SumModePerLine},
+		{name: "Missing mode", commandText: "sum", errorType: ErrInvalidSumMode},
+		{name: "Unknown mode", commandText: "sum columns", errorType: ErrInvalidSumMode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseSumCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseTriggerCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		commandText  string
+		expectedMode string
+		expectedWord string
+		errorType    error
+	}{
+		{name: "Always", commandText: "trigger always", expectedMode: models.TriggerModeAlways},
+		{name: "Keyword", commandText: "trigger keyword", expectedMode: models.TriggerModeKeyword},
+		{name: "Keyword with word", commandText: "Trigger Keyword Sausage", expectedMode: models.TriggerModeKeyword, expectedWord: "sausage"},
+		{name: "Missing mode", commandText: "trigger", errorType: ErrInvalidTriggerMode},
+		{name: "Unknown mode", commandText: "trigger sometimes", errorType: ErrInvalidTriggerMode},
+		{name: "Always with word", commandText: "trigger always snagify", errorType: ErrInvalidTriggerMode},
+		{name: "Word with punctuation", commandText: "trigger keyword snag!", errorType: ErrInvalidTriggerWord},
+		{name: "Several words", commandText: "trigger keyword snag me", errorType: ErrInvalidTriggerWord},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mode, word, err := ParseTriggerCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expectedMode, mode)
+				assert.Equal(t, test.expectedWord, word)
+			}
+		})
+	}
+}
+
+func TestParseDecimalCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Point", commandText: "decimal point", expected: models.NumberFormatPoint},
+		{name: "Comma mixed case", commandText: "Decimal Comma", expected: models.NumberFormatComma},
+		{name: "Missing separator", commandText: "decimal", errorType: ErrInvalidNumberFormat},
+		{name: "Unknown separator", commandText: "decimal semicolon", errorType: ErrInvalidNumberFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseDecimalCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseLocaleCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Australian", commandText: "locale en-AU", expected: models.LocaleAU},
+		{name: "Mixed case", commandText: "Locale EN-us", expected: models.LocaleUS},
+		{name: "Underscore", commandText: "locale en_GB", expected: models.LocaleGB},
+		{name: "Missing locale", commandText: "locale", errorType: ErrInvalidLocale},
+		{name: "Unknown locale", commandText: "locale fr-FR", errorType: ErrInvalidLocale},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseLocaleCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseButtonCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "button on", expected: true},
+		{name: "Off mixed case", commandText: "Button OFF", expected: false},
+		{name: "Missing setting", commandText: "button", errorType: ErrInvalidButtonSetting},
+		{name: "Unknown setting", commandText: "button maybe", errorType: ErrInvalidButtonSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseButtonCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseWordsCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "words on", expected: true},
+		{name: "Off mixed case", commandText: "Words OFF", expected: false},
+		{name: "Missing setting", commandText: "words", errorType: ErrInvalidWordsSetting},
+		{name: "Unknown setting", commandText: "words maybe", errorType: ErrInvalidWordsSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseWordsCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseRandomCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "random on", expected: true},
+		{name: "Off mixed case", commandText: "Random OFF", expected: false},
+		{name: "Missing setting", commandText: "random", errorType: ErrInvalidRandomSetting},
+		{name: "Unknown setting", commandText: "random please", errorType: ErrInvalidRandomSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseRandomCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseVarietyCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "variety on", expected: true},
+		{name: "Off mixed case", commandText: "Variety OFF", expected: false},
+		{name: "Missing setting", commandText: "variety", errorType: ErrInvalidVarietySetting},
+		{name: "Unknown setting", commandText: "variety lots", errorType: ErrInvalidVarietySetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseVarietyCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseRepeatsCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "repeats on", expected: true},
+		{name: "Off mixed case", commandText: "Repeats OFF", expected: false},
+		{name: "Missing setting", commandText: "repeats", errorType: ErrInvalidRepeatsSetting},
+		{name: "Unknown setting", commandText: "repeats twice", errorType: ErrInvalidRepeatsSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseRepeatsCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseFilterCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "filter on", expected: true},
+		{name: "Off mixed case", commandText: "Filter OFF", expected: false},
+		{name: "Missing setting", commandText: "filter", errorType: ErrInvalidFilterSetting},
+		{name: "Unknown setting", commandText: "filter maybe", errorType: ErrInvalidFilterSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseFilterCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseBreakdownCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "breakdown on", expected: true},
+		{name: "Off mixed case", commandText: "Breakdown OFF", expected: false},
+		{name: "Missing setting", commandText: "breakdown", errorType: ErrInvalidBreakdownSetting},
+		{name: "Unknown setting", commandText: "breakdown maybe", errorType: ErrInvalidBreakdownSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseBreakdownCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParsePrivateCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    bool
+		errorType   error
+	}{
+		{name: "On", commandText: "private on", expected: true},
+		{name: "Off mixed case", commandText: "Private OFF", expected: false},
+		{name: "Missing setting", commandText: "private", errorType: ErrInvalidPrivateSetting},
+		{name: "Unknown setting", commandText: "private maybe", errorType: ErrInvalidPrivateSetting},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParsePrivateCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseIgnoreCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    IgnoreTarget
+		errorType   error
+	}{
+		{name: "Ignore channel", commandText: "ignore", expected: IgnoreTarget{Ignore: true}},
+		{name: "Unignore channel", commandText: "Unignore", expected: IgnoreTarget{Ignore: false}},
+		{name: "Ignore escaped user", commandText: "ignore <@U0123ABCD|someone>", expected: IgnoreTarget{Ignore: true, UserID: "U0123ABCD"}},
+		{name: "Unignore user", commandText: "unignore <@W0123ABCD>", expected: IgnoreTarget{Ignore: false, UserID: "W0123ABCD"}},
+		{name: "Bare user ID", commandText: "ignore U0123ABCD", expected: IgnoreTarget{Ignore: true, UserID: "U0123ABCD"}},
+		{name: "Unescaped name", commandText: "ignore @someone", errorType: ErrInvalidIgnoreTarget},
+		{name: "Channel mention", commandText: "ignore <#C0123ABCD|bots>", errorType: ErrInvalidIgnoreTarget},
+		{name: "Too many users", commandText: "ignore <@U0123ABCD> <@U0456EFGH>", errorType: ErrInvalidIgnoreTarget},
+		{name: "Wrong command", commandText: "ignored", errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseIgnoreCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseImportCommand(t *testing.T) {
+	config, err := ParseImportCommand(`import {"item_name":"coffee","item_price":5,"rounding_mode":"down","items":[{"name":"pie","price":6}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 5.0, config.ItemPrice)
+	assert.Equal(t, "down", config.RoundingMode)
+	assert.Equal(t, []models.ChannelItem{{Name: "pie", Price: 6}}, config.Items)
+
+	// Locales and phrases come across too
+	config, err = ParseImportCommand(`import {"item_name":"coffee","item_price":5,"locale":"en-US","phrases":{"nearly":"around "}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, models.LocaleUS, config.Locale)
+	assert.Equal(t, &models.Phrases{Nearly: "around "}, config.Phrases)
+
+	// So does a custom variety pool
+	config, err = ParseImportCommand(`import {"item_name":"coffee","item_price":5,"variety":true,"variations":[{"prefix":"Crikey, ","suffix":"!"}]}`)
+	assert.NoError(t, err)
+	assert.True(t, config.Variety)
+	assert.Equal(t, []models.Phrases{{Prefix: "Crikey, ", Suffix: "!"}}, config.Variations)
+
+	// Code blocks and the smart quotes some Slack clients type are accepted
+	config, err = ParseImportCommand("import ```{“item_name”:“pie”,“item_price”:6}```")
+	assert.NoError(t, err)
+	assert.Equal(t, "pie", config.ItemName)
+
+	tests := []struct {
+		name        string
+		commandText string
+	}{
+		{name: "Missing JSON", commandText: "import"},
+		{name: "Malformed JSON", commandText: `import {"item_name": "coffee", "item_price": }`},
+		{name: "Not an object", commandText: `import ["coffee", 5]`},
+		{name: "Trailing text", commandText: `import {"item_name":"coffee","item_price":5} please`},
+		{name: "Unknown field", commandText: `import {"item_name":"coffee","item_price":5,"item_prise":6}`},
+		{name: "Missing item", commandText: `import {"item_price":5}`},
+		{name: "Negative price", commandText: `import {"item_name":"coffee","item_price":-5}`},
+		{name: "Wrong price type", commandText: `import {"item_name":"coffee","item_price":"5"}`},
+		{name: "Unknown rounding mode", commandText: `import {"item_name":"coffee","item_price":5,"rounding_mode":"sideways"}`},
+		{name: "Template without count", commandText: `import {"item_name":"coffee","item_price":5,"response_template":"Yum"}`},
+		{name: "Item without price", commandText: `import {"item_name":"coffee","item_price":5,"items":[{"name":"pie"}]}`},
+		{name: "Unknown locale", commandText: `import {"item_name":"coffee","item_price":5,"locale":"fr-FR"}`},
+		{name: "Blank variation", commandText: `import {"item_name":"coffee","item_price":5,"variations":[{"prefix":" "}]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseImportCommand(test.commandText)
+			assert.True(t, errors.Is(err, ErrInvalidImport), "Expected error type %v, got %v", ErrInvalidImport, err)
+		})
+	}
+}
+
+func TestParseEmojiCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Simple emoji", commandText: "emoji :taco:", expected: "taco"},
+		{name: "Underscores and digits", commandText: "Emoji :meat_on_bone2:", expected: "meat_on_bone2"},
+		{name: "Plus sign", commandText: "emoji :+1:", expected: "+1"},
+		{name: "Uppercase is lowered", commandText: "emoji :Taco:", expected: "taco"},
+		{name: "Missing emoji", commandText: "emoji", errorType: ErrInvalidEmoji},
+		{name: "Missing colons", commandText: "emoji taco", errorType: ErrInvalidEmoji},
+		{name: "Unicode emoji", commandText: "emoji 🌮", errorType: ErrInvalidEmoji},
+		{name: "Empty name", commandText: "emoji ::", errorType: ErrInvalidEmoji},
+		{name: "Spaces in name", commandText: "emoji :meat pie:", errorType: ErrInvalidEmoji},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseEmojiCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseThresholdCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    float64
+		errorType   error
+	}{
+		{name: "Whole number", commandText: "threshold 20", expected: 20},
+		{name: "With dollar sign", commandText: "threshold $12.50", expected: 12.50},
+		{name: "Zero disables", commandText: "threshold 0", expected: 0},
+		{name: "Missing value", commandText: "threshold", errorType: ErrInvalidThreshold},
+		{name: "Negative value", commandText: "threshold -5", errorType: ErrInvalidThreshold},
+		{name: "Not a number", commandText: "threshold lots", errorType: ErrInvalidThreshold},
+		{name: "NaN", commandText: "threshold NaN", errorType: ErrInvalidThreshold},
+		{name: "Infinity", commandText: "threshold Inf", errorType: ErrInvalidThreshold},
+		{name: "Negative infinity", commandText: "threshold -Inf", errorType: ErrInvalidThreshold},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseThresholdCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseTemplateCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Quoted template", commandText: `template "That's {count} {item}!"`, expected: "That's {count} {item}!"},
+		{name: "Unquoted template", commandText: "Template {count} {item} 🌭", expected: "{count} {item} 🌭"},
+		{name: "Missing template", commandText: "template", errorType: ErrMissingTemplate},
+		{name: "Empty quotes", commandText: `template ""`, errorType: ErrMissingTemplate},
+		{name: "Missing count placeholder", commandText: `template "Lots of {item}"`, errorType: ErrInvalidTemplate},
+		{name: "Unclosed quote", commandText: `template "{count} {item}`, errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseTemplateCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseZeroMessageCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Quoted message", commandText: `zeromsg "Not even one {item}!"`, expected: "Not even one {item}!"},
+		{name: "Unquoted message", commandText: "ZeroMsg Keep saving 🐖", expected: "Keep saving 🐖"},
+		{name: "Back to the default", commandText: "zeromsg default", expected: ""},
+		{name: "Missing message", commandText: "zeromsg", errorType: ErrMissingZeroMessage},
+		{name: "Empty quotes", commandText: `zeromsg ""`, errorType: ErrMissingZeroMessage},
+		{name: "Unclosed quote", commandText: `zeromsg "Not even one`, errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseZeroMessageCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseCurrencyCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Pound", commandText: "currency £", expected: "£"},
+		{name: "Euro", commandText: "Currency €", expected: "€"},
+		{name: "Missing symbol", commandText: "currency", errorType: ErrInvalidCurrency},
+		{name: "Multiple characters", commandText: "currency AU$", errorType: ErrInvalidCurrency},
+		{name: "Currency code", commandText: "currency GBP", errorType: ErrInvalidCurrency},
+		{name: "Letter", commandText: "currency p", errorType: ErrInvalidCurrency},
+		{name: "Digit", commandText: "currency 5", errorType: ErrInvalidCurrency},
+		{name: "Extra arguments", commandText: "currency £ €", errorType: ErrInvalidCurrency},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseCurrencyCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseHistoryCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    int
+		errorType   error
+	}{
+		{name: "Default limit", commandText: "history", expected: DefaultHistoryLimit},
+		{name: "Custom limit", commandText: "History 10", expected: 10},
+		{name: "Zero", commandText: "history 0", errorType: ErrInvalidHistoryLimit},
+		{name: "Not a number", commandText: "history lots", errorType: ErrInvalidHistoryLimit},
+		{name: "Extra arguments", commandText: "history 5 10", errorType: ErrInvalidHistoryLimit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseHistoryCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseAddItemCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    CommandParseResult
+		errorType   error
+	}{
+		{name: "Quoted item", commandText: `add item "meat pie" price 6.00`, expected: CommandParseResult{ItemName: "meat pie", ItemPrice: 6.00}},
+		{name: "Unquoted item", commandText: "Add item coffee price 4.50", expected: CommandParseResult{ItemName: "coffee", ItemPrice: 4.50}},
+		{name: "Missing item", commandText: "add", errorType: ErrInvalidCommand},
+		{name: "Missing price", commandText: `add item "pie"`, errorType: ErrMissingPrice},
+		{name: "Invalid price", commandText: `add item "pie" price -1`, errorType: ErrInvalidPrice},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseAddItemCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParsePriceCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    float64
+		errorType   error
+	}{
+		{name: "Price", commandText: "price 4.00", expected: 4.00},
+		{name: "Deal", commandText: "Price  2 for 5", expected: 2.50},
+		{name: "Missing price", commandText: "price", errorType: ErrMissingPrice},
+		{name: "Invalid price", commandText: "price free", errorType: ErrInvalidPrice},
+		{name: "Deal for nothing", commandText: "price 0 for 5", errorType: ErrInvalidQuantity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParsePriceCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDefaultItemCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    CommandParseResult
+		errorType   error
+	}{
+		{name: "Quoted item", commandText: `default item "flat white" price 5.50`, expected: CommandParseResult{ItemName: "flat white", ItemPrice: 5.50}},
+		{name: "Unquoted item", commandText: "Default item coffee price 4.50", expected: CommandParseResult{ItemName: "coffee", ItemPrice: 4.50}},
+		{name: "Missing item", commandText: "default", errorType: ErrInvalidCommand},
+		{name: "Missing price", commandText: `default item "coffee"`, errorType: ErrMissingPrice},
+		{name: "Invalid price", commandText: `default item "coffee" price 0`, errorType: ErrInvalidPrice},
+		{name: "Template default", commandText: `template-default item "x" price 7`, expected: CommandParseResult{ItemName: "x", ItemPrice: 7}},
+		{name: "Template without default", commandText: `template-item "x" price 7`, errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseDefaultItemCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatCommandResponse(t *testing.T) {
+	result := CommandParseResult{
+		ItemName:  "coffee",
+		ItemPrice: 5.00,
+	}
+
+	response := FormatCommandResponse(result)
+	expected := "Configuration updated! Now converting dollar amounts to coffee (at $5.00 each)."
+	assert.Equal(t, expected, response)
+
+	response = FormatCommandResponseWithCurrency(result, "€")
+	expected = "Configuration updated! Now converting dollar amounts to coffee (at 5,00 € each)."
+	assert.Equal(t, expected, response)
+}
+
+func TestFormatCommandErrorResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		shouldContain  []string
+		shouldNotMatch string
+	}{
+		{
+			name:          "Invalid command error",
+			err:           fmt.Errorf("%w: command must start with 'item'", ErrInvalidCommand),
+			shouldContain: []string{"invalid command syntax", "command must start with 'item'", "Usage example:"},
+		},
+		{
+			name:          "Missing item error",
+			err:           ErrMissingItem,
+			shouldContain: []string{"missing item name", "Please provide an item name", "Usage example:"},
+		},
+		{
+			name:          "Missing price error",
+			err:           ErrMissingPrice,
+			shouldContain: []string{"missing price value", "Please provide a price value", "Usage example:"},
+		},
+		{
+			name:          "Invalid price error",
+			err:           fmt.Errorf("%w: 3.5.0 is not a valid number", ErrInvalidPrice),
+			shouldContain: []string{"price must be a positive number", "3.5.0 is not a valid number", "Usage example:"},
+		},
+		{
+			name:          "Default error case",
+			err:           errors.New("unexpected error"),
+			shouldContain: []string{"unexpected error", "Usage example:"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := FormatCommandErrorResponse(test.err)
+
+			for _, text := range test.shouldContain {
+				assert.Contains(t, response, text)
+			}
+
+			if test.shouldNotMatch != "" {
+				assert.NotContains(t, response, test.shouldNotMatch)
+			}
+		})
+	}
+}
+
+func TestParsePreviewCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		item        string
+		price       float64
+		unitName    string
+		amount      float64
+		errorType   error
+	}{
+		{name: "Quoted item", commandText: `preview item "coffee" price 5 amount 35`, item: "coffee", price: 5, amount: 35},
+		{name: "Dollar sign and extra spaces", commandText: `preview  item pie   price 2 for 5 amount $12.50`, item: "pie", price: 2.5, amount: 12.5},
+		{name: "Mixed case", commandText: `Preview Item "flat white" Price 4.50 Amount 9`, item: "flat white", price: 4.5, amount: 9},
+		{name: "Unit", commandText: `preview item "L" price 2 unit "tank" size 60 amount 240`, item: "L", price: 2, unitName: "tank", amount: 240},
+		{name: "Missing amount", commandText: `preview item "coffee" price 5`, errorType: ErrInvalidAmount},
+		{name: "Invalid amount", commandText: `preview item "coffee" price 5 amount lots`, errorType: ErrInvalidAmount},
+		{name: "Negative amount", commandText: `preview item "coffee" price 5 amount -35`, errorType: ErrInvalidAmount},
+		{name: "Missing price", commandText: `preview item "coffee" amount 35`, errorType: ErrMissingPrice},
+		{name: "Invalid price", commandText: `preview item "coffee" price free amount 35`, errorType: ErrInvalidPrice},
+		{name: "Missing item", commandText: `preview amount 35`, errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, amount, err := ParsePreviewCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.item, result.ItemName)
+				assert.InDelta(t, test.price, result.ItemPrice, 0.001)
+				assert.Equal(t, test.unitName, result.UnitName)
+				assert.Equal(t, test.amount, amount)
+			}
+		})
+	}
+}
+
+func TestParseFeedbackCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		commandText string
+		expected    string
+		errorType   error
+	}{
+		{name: "Message", commandText: "feedback Could you add a pie option?", expected: "Could you add a pie option?"},
+		{name: "Case kept", commandText: "Feedback   SnagBot is GREAT  ", expected: "SnagBot is GREAT"},
+		{name: "Several lines", commandText: "feedback Two things:\nmore snags", expected: "Two things:\nmore snags"},
+		{name: "Missing message", commandText: "feedback", errorType: ErrMissingFeedback},
+		{name: "Blank message", commandText: "feedback   ", errorType: ErrMissingFeedback},
+		{name: "Other command", commandText: "filter on", errorType: ErrInvalidCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := ParseFeedbackCommand(test.commandText)
+
+			if test.errorType != nil {
+				assert.True(t, errors.Is(err, test.errorType), "Expected error type %v, got %v", test.errorType, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, result)
 			}
 		})
 	}
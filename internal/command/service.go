@@ -3,6 +3,7 @@ package command
 import (
 	"fmt"
 
+	"github.com/mcncl/snagbot/internal/calculator"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
 	"github.com/mcncl/snagbot/internal/slack"
@@ -22,7 +23,7 @@ func NewCommandService(store slack.ChannelConfigStore) *CommandService {
 
 // handleConfigCommand processes the command text and updates the channel configuration
 // This function is for backward compatibility
-func handleConfigCommand(store slack.ChannelConfigStore, text, channelID string) string {
+func handleConfigCommand(store slack.ChannelConfigStore, text, channelID, userID string) string {
 	// Parse the command
 	result, err := ParseConfigCommand(text)
 	if err != nil {
@@ -31,18 +32,18 @@ func handleConfigCommand(store slack.ChannelConfigStore, text, channelID string)
 	}
 
 	// Update the channel configuration
-	err = store.UpdateConfig(channelID, result.ItemName, result.ItemPrice)
+	err = store.UpdateConfig(channelID, result.ItemName, result.ItemPrice, userID)
 	if err != nil {
 		logging.Error("Error updating channel config: %v", err)
 		return fmt.Sprintf("Error updating configuration: %v", err)
 	}
 
 	// Return success message
-	return FormatCommandResponse(result)
+	return formatConfigUpdatedResponse(store, result, channelID)
 }
 
-// HandleConfigCommand processes a configuration command
-func (s *CommandService) HandleConfigCommand(text, channelID string) string {
+// HandleConfigCommand processes a configuration command made by userID
+func (s *CommandService) HandleConfigCommand(text, channelID, userID string) string {
 	// Parse the command
 	result, err := ParseConfigCommand(text)
 	if err != nil {
@@ -52,7 +53,10 @@ func (s *CommandService) HandleConfigCommand(text, channelID string) string {
 	}
 
 	// Update the channel configuration
-	err = s.ConfigStore.UpdateConfig(channelID, result.ItemName, result.ItemPrice)
+	err = s.ConfigStore.UpdateConfig(channelID, result.ItemName, result.ItemPrice, userID)
+	if appErr, ok := err.(*errors.AppError); ok && appErr.Is(errors.ErrItemNameTooLong) {
+		return "Error updating configuration: " + errors.UserFriendlyError(appErr)
+	}
 	if err != nil {
 		appErr := errors.Wrap(err, "Failed to update configuration")
 		logging.Error("Config update error: %v", appErr)
@@ -60,13 +64,13 @@ func (s *CommandService) HandleConfigCommand(text, channelID string) string {
 	}
 
 	// Return success message
-	return FormatCommandResponse(result)
+	return formatConfigUpdatedResponse(s.ConfigStore, result, channelID)
 }
 
-// HandleResetCommand resets a channel's configuration
-func (s *CommandService) HandleResetCommand(channelID string) string {
+// HandleResetCommand resets a channel's configuration on behalf of userID
+func (s *CommandService) HandleResetCommand(channelID, userID string) string {
 	// Reset the config
-	err := s.ConfigStore.ResetConfig(channelID)
+	err := s.ConfigStore.ResetConfig(channelID, userID)
 	if err != nil {
 		appErr := errors.Wrap(err, "Failed to reset configuration")
 		logging.Error("Config reset error: %v", appErr)
@@ -81,8 +85,8 @@ func (s *CommandService) HandleResetCommand(channelID string) string {
 	}
 
 	return "Configuration has been reset! Now using the default item: " +
-		config.ItemName + " (at $" +
-		FormatPrice(config.ItemPrice) + " each)."
+		config.ItemName + " (at " +
+		FormatPrice(config.ItemPrice, config.CurrencySymbol()) + " each)."
 }
 
 // HandleStatusCommand returns the current configuration for a channel
@@ -97,18 +101,28 @@ func (s *CommandService) HandleStatusCommand(channelID string) string {
 	// Check if this is a custom or default config
 	var statusPrefix string
 
-	if checker, ok := s.ConfigStore.(slack.ConfigExistsChecker); ok && !checker.ConfigExists(channelID) {
+	isCustom, existsErr := slack.CheckConfigExists(s.ConfigStore, channelID)
+	if existsErr != nil {
+		logging.Warn("Failed to check for a custom config in channel %s: %v", channelID, existsErr)
+	}
+	if existsErr == nil && !isCustom {
 		statusPrefix = "This channel is using the default configuration: "
 	} else {
 		statusPrefix = "Current configuration: "
 	}
 
-	return statusPrefix + config.ItemName + " (at $" +
-		FormatPrice(config.ItemPrice) + " each)."
+	status := statusPrefix + config.ItemName + " (at " +
+		FormatPrice(config.ItemPrice, config.CurrencySymbol()) + " each)"
+	if config.HasUnit() {
+		status += ", counted in " + calculator.DescribeUnit(config.UnitName, config.UnitSize, config.ItemName)
+	}
+	if existsErr != nil {
+		return status + ". " + unknownConfigSourceNote
+	}
+	return status + "."
 }
 
-// FormatPrice formats a price with 2 decimal places
-// This is a widely used utility function that could be moved to a common package
-func FormatPrice(price float64) string {
-	return fmt.Sprintf("%.2f", price)
+// FormatPrice formats a price for command responses the way its currency is usually written
+func FormatPrice(price float64, currency string) string {
+	return calculator.FormatMoney(price, currency)
 }
@@ -1,8 +1,69 @@
 package config
 
-import "os"
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// Built-in default item, used when DEFAULT_ITEM_NAME or DEFAULT_ITEM_PRICE aren't set
+const (
+	DefaultItemName  = "Bunnings Snag"
+	DefaultItemPrice = 3.50
+)
+
+// DefaultJWTSecret is the placeholder used when JWT_SECRET isn't set, never trusted for admin tokens
+const DefaultJWTSecret = "snagbot-jwt-secret-change-me-in-production"
+
+// DefaultRedisConfigTTL is how long unused channel configs are kept in Redis by default
+const DefaultRedisConfigTTL = 30 * 24 * time.Hour
+
+// DefaultMaxItemNameLength is the longest item name a channel can set by default, in characters
+const DefaultMaxItemNameLength = 50
+
+// DefaultSlashCommand is the slash command SnagBot answers when SLASH_COMMANDS isn't set
+const DefaultSlashCommand = "/snagbot"
+
+// DefaultIgnoredMessageSubTypes are the message subtypes SnagBot skips when IGNORED_MESSAGE_SUBTYPES
+// isn't set: membership, channel setting and housekeeping notices that nobody is asking about
+const DefaultIgnoredMessageSubTypes = "channel_join,channel_leave,channel_topic,channel_purpose,channel_name," +
+	"channel_archive,channel_unarchive,group_join,group_leave,group_topic,group_purpose,group_name," +
+	"group_archive,group_unarchive,pinned_item,unpinned_item,message_deleted,message_replied"
+
+// DefaultConvertReaction is the emoji, 💰, that asks SnagBot to convert a message when
+// CONVERT_REACTION isn't set
+const DefaultConvertReaction = "moneybag"
+
+// Startup checks of the Slack bot token for SLACK_TOKEN_CHECK
+const (
+	TokenCheckOff  = "off"  // Don't check the token
+	TokenCheckWarn = "warn" // Log a warning when Slack rejects the token
+	TokenCheckFail = "fail" // Refuse to start when Slack rejects the token
+)
+
+// How messages from other bots are treated, for BOT_MESSAGES
+const (
+	BotMessagesIgnore  = "ignore"  // Skip every bot's messages
+	BotMessagesRespond = "respond" // Respond to other bots, but never to SnagBot itself
+)
+
+// Built-in config store backends for STORE_BACKEND
+const (
+	StoreBackendRedis  = "redis"
+	StoreBackendMemory = "memory"
+	StoreBackendSQLite = "sqlite"
+)
 
 type Config struct {
+	// mu guards the fields Reload changes while handlers are reading them
+	mu sync.RWMutex
+
+	Enabled             bool // Whether SnagBot responds at all, flipped at runtime by /api/admin/toggle
 	Port                string
 	SlackBotToken       string // Legacy - for backward compatibility
 	SlackSigningSecret  string
@@ -12,11 +73,71 @@ type Config struct {
 	DefaultItemPrice    float64
 	RedisURL            string
 	UseRedis            bool
+	StoreBackend        string // Config store backend, by default "redis" with REDIS_URL, "sqlite" with SQLITE_PATH, otherwise "memory"
+	SQLitePath          string // Optional - SQLite database file used by the sqlite store backend
+	RedisConfigTTL      time.Duration // How long unused channel configs are kept in Redis (0 keeps them forever)
+	MemoryConfigTTL     time.Duration // How long unused channel configs are kept by the memory store (0 keeps them forever)
+	ConfigCacheTTL      time.Duration // How long Redis channel configs are cached in memory (0 disables)
 	OAuthRedirectURL    string
 	AppBaseURL          string
 	CookieSecret        string
 	JWTSecret           string
 	EnableMultiWorkspace bool
+	ConfigStorePath     string // Optional - persists in-memory channel configs to this file
+	ResponseCooldown    time.Duration // Minimum time between responses in a channel (0 disables)
+	ResponseDelay       time.Duration // Pause before each response so replies feel less instant (0 disables)
+	EventDedupeWindow   time.Duration // How long handled Slack events are remembered (0 uses the default)
+	EventDedupeSize     int // Most handled Slack events remembered at once (0 uses the default)
+	EventWorkers        int // Slack events processed at once (0 uses the default)
+	EventQueueSize      int // Slack events waiting to be processed before more are dropped (0 uses the default)
+	SlackRequestMaxAge  time.Duration // Oldest Slack request timestamp accepted (0 uses the default)
+	MaxRequestBodySize  int64 // Largest Slack request body read, in bytes (0 uses the default)
+	MaxItemNameLength   int // Longest item name a channel can set, in characters (0 uses the default)
+	AdminToken          string // Optional - bearer token for the admin endpoints, which are disabled without it
+	IgnoredChannels     map[string]bool // Channels SnagBot never responds in
+	IgnoredUsers        map[string]bool // Users, such as noisy integrations, SnagBot never responds to
+	IgnoreDirectMessages bool // Never respond in direct messages with SnagBot
+	MessageSubTypes     map[string]bool // When set, the only message subtypes processed, besides plain messages
+	IgnoredMessageSubTypes map[string]bool // Message subtypes never processed
+	AdminUsers          map[string]bool // Users allowed to run admin commands like /snagbot list
+	SlashCommands       []string // Slash command names SnagBot answers, the first shown in help (empty uses the default)
+	AnnounceConfigChanges bool // Post item changes to the channel instead of only to the user who made them
+	TimeZone            *time.Location // Time zone times are shown in, like in /snagbot history
+	UserTimeZones       bool // Show times in each user's own Slack time zone where it can be looked up
+	LogLevel            logging.LogLevel // Minimum level logged, from LOG_LEVEL (default info)
+	SlackTokenCheck     string // Whether the bot token is checked with Slack at startup: "off" (the default), "warn" or "fail"
+	BotMessages         string // Whether other bots' messages are skipped: "ignore" (the default) or "respond"
+	SlackBotUserID      string // Optional - SnagBot's own bot user ID, looked up with auth.test when not set
+	SlackBotID          string // Optional - SnagBot's own bot ID, looked up with auth.test when not set
+	ConvertReaction     string // Emoji name that asks SnagBot to convert the message it's added to (empty turns this off)
+	ExchangeCurrency    string // Currency code, like "AUD", amounts in other currencies are converted to (empty turns this off)
+	ExchangeRates       map[string]float64 // Value of one unit of each currency in ExchangeCurrency, by code like "USD"
+	FeedbackChannel     string // Optional - channel ID /snagbot feedback is relayed to, which turns the command on
+	MoneyWords          []string // Words after an amount that mark it as money, like "bucks" (empty uses the calculator's defaults)
+}
+
+// DefaultItem returns the item used by channels that haven't chosen their own, safe to call while
+// the config is being reloaded
+func (c *Config) DefaultItem() (string, float64) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.DefaultItemName, c.DefaultItemPrice
+}
+
+// Reload applies the reloadable settings from next, the default item and log level, so handlers
+// and stores holding this config see them straight away. Everything else needs a restart.
+func (c *Config) Reload(next *Config) {
+	next.mu.RLock()
+	name, price, level := next.DefaultItemName, next.DefaultItemPrice, next.LogLevel
+	next.mu.RUnlock()
+
+	c.mu.Lock()
+	c.DefaultItemName = name
+	c.DefaultItemPrice = price
+	c.LogLevel = level
+	c.mu.Unlock()
+
+	logging.SetGlobalLevel(level)
 }
 
 func New() *Config {
@@ -30,6 +151,21 @@ func New() *Config {
 	slackClientID := os.Getenv("SLACK_CLIENT_ID")
 	slackClientSecret := os.Getenv("SLACK_CLIENT_SECRET")
 	
+	// Item channels use until they choose their own
+	defaultItemName := strings.TrimSpace(os.Getenv("DEFAULT_ITEM_NAME"))
+	if defaultItemName == "" {
+		defaultItemName = DefaultItemName
+	}
+	defaultItemPrice := DefaultItemPrice
+	if value := os.Getenv("DEFAULT_ITEM_PRICE"); value != "" {
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || price <= 0 {
+			logging.Warn("Invalid DEFAULT_ITEM_PRICE %q, must be a positive number, using $%.2f", value, DefaultItemPrice)
+		} else {
+			defaultItemPrice = price
+		}
+	}
+
 	redisURL := os.Getenv("REDIS_URL")
 	useRedis := redisURL != ""
 
@@ -50,26 +186,315 @@ func New() *Config {
 
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
-		jwtSecret = "snagbot-jwt-secret-change-me-in-production"
+		jwtSecret = DefaultJWTSecret
+	}
+
+	// Which registered backend stores channel configs
+	sqlitePath := os.Getenv("SQLITE_PATH")
+	storeBackend := strings.ToLower(strings.TrimSpace(os.Getenv("STORE_BACKEND")))
+	if storeBackend == "" {
+		switch {
+		case useRedis:
+			storeBackend = StoreBackendRedis
+		case sqlitePath != "":
+			storeBackend = StoreBackendSQLite
+		default:
+			storeBackend = StoreBackendMemory
+		}
+	}
+
+	// Only used by the in-memory store when Redis isn't configured
+	configStorePath := os.Getenv("CONFIG_STORE_PATH")
+
+	// Cooldown between responses in the same channel, in seconds
+	var responseCooldown time.Duration
+	if seconds, err := strconv.Atoi(os.Getenv("RESPONSE_COOLDOWN_SECONDS")); err == nil && seconds > 0 {
+		responseCooldown = time.Duration(seconds) * time.Second
+	}
+
+	// Pause before responding, in milliseconds
+	var responseDelay time.Duration
+	if ms, err := strconv.Atoi(os.Getenv("RESPONSE_DELAY_MS")); err == nil && ms > 0 {
+		responseDelay = time.Duration(ms) * time.Millisecond
+	}
+
+	// Remember handled events so Slack retries don't get duplicate responses
+	var eventDedupeWindow time.Duration
+	if seconds, err := strconv.Atoi(os.Getenv("EVENT_DEDUPE_WINDOW_SECONDS")); err == nil && seconds > 0 {
+		eventDedupeWindow = time.Duration(seconds) * time.Second
+	}
+	eventDedupeSize, err := strconv.Atoi(os.Getenv("EVENT_DEDUPE_SIZE"))
+	if err != nil || eventDedupeSize < 0 {
+		eventDedupeSize = 0
+	}
+
+	// Process events on a bounded pool, dropping them when the queue is full
+	eventWorkers, err := strconv.Atoi(os.Getenv("EVENT_WORKERS"))
+	if err != nil || eventWorkers < 0 {
+		eventWorkers = 0
+	}
+	eventQueueSize, err := strconv.Atoi(os.Getenv("EVENT_QUEUE_SIZE"))
+	if err != nil || eventQueueSize < 0 {
+		eventQueueSize = 0
+	}
+
+	// Reject Slack requests with timestamps further than this from now to prevent replays
+	var slackRequestMaxAge time.Duration
+	if seconds, err := strconv.Atoi(os.Getenv("SLACK_REQUEST_MAX_AGE_SECONDS")); err == nil && seconds > 0 {
+		slackRequestMaxAge = time.Duration(seconds) * time.Second
+	}
+
+	// Refuse Slack request bodies larger than this rather than reading them into memory
+	var maxRequestBodySize int64
+	if size, err := strconv.ParseInt(os.Getenv("MAX_REQUEST_BODY_BYTES"), 10, 64); err == nil && size > 0 {
+		maxRequestBodySize = size
+	}
+
+	// Keep item names short enough to fit in a response
+	var maxItemNameLength int
+	if length, err := strconv.Atoi(os.Getenv("MAX_ITEM_NAME_LENGTH")); err == nil && length > 0 {
+		maxItemNameLength = length
+	}
+
+	// Channel configs in Redis expire after this long without being read or changed
+	redisConfigTTL := DefaultRedisConfigTTL
+	if value, ok := os.LookupEnv("REDIS_CONFIG_TTL_SECONDS"); ok {
+		if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+			redisConfigTTL = time.Duration(seconds) * time.Second
+		}
 	}
 
+	// The memory store keeps channel configs forever unless told to evict unused ones
+	var memoryConfigTTL time.Duration
+	if seconds, err := strconv.Atoi(os.Getenv("MEMORY_CONFIG_TTL_SECONDS")); err == nil && seconds > 0 {
+		memoryConfigTTL = time.Duration(seconds) * time.Second
+	}
+
+	// Channel configs read from Redis can be cached briefly so busy channels don't read it on every message
+	var configCacheTTL time.Duration
+	if ms, err := strconv.Atoi(os.Getenv("CONFIG_CACHE_TTL_MS")); err == nil && ms > 0 {
+		configCacheTTL = time.Duration(ms) * time.Millisecond
+	}
+
+	// Admin endpoints stay disabled unless a token is set
+	adminToken := os.Getenv("ADMIN_TOKEN")
+
+	// Channels and users to never respond to, as comma separated IDs
+	ignoredChannels := parseIDSet(os.Getenv("IGNORED_CHANNELS"))
+	ignoredUsers := parseIDSet(os.Getenv("IGNORED_USERS"))
+
+	// SnagBot responds unless explicitly switched off, e.g. during an incident
+	enabled := true
+	if value, ok := os.LookupEnv("SNAGBOT_ENABLED"); ok {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			enabled = parsed
+		} else {
+			logging.Warn("Invalid SNAGBOT_ENABLED %q, must be true or false, leaving SnagBot enabled", value)
+		}
+	}
+
+	// Direct messages with SnagBot can be ignored like any other channel
+	ignoreDirectMessages, _ := strconv.ParseBool(os.Getenv("IGNORE_DIRECT_MESSAGES"))
+
+	// Which message subtypes to process, as comma separated names like "file_share,me_message"
+	// Plain messages are always processed, and the ignore list replaces the defaults when set
+	messageSubTypes := parseIDSet(os.Getenv("MESSAGE_SUBTYPES"))
+	ignoredSubTypes, ok := os.LookupEnv("IGNORED_MESSAGE_SUBTYPES")
+	if !ok {
+		ignoredSubTypes = DefaultIgnoredMessageSubTypes
+	}
+	ignoredMessageSubTypes := parseIDSet(ignoredSubTypes)
+
+	// Slack users allowed to run admin commands, as comma separated IDs
+	adminUsers := parseIDSet(os.Getenv("ADMIN_USERS"))
+
+	// Slash command names to answer, like "/snagbot,/snag", the first being the primary name
+	slashCommands := parseSlashCommands(os.Getenv("SLASH_COMMANDS"))
+
+	// Item changes can be announced to the whole channel, not just the user who made them
+	announceConfigChanges, _ := strconv.ParseBool(os.Getenv("ANNOUNCE_CONFIG_CHANGES"))
+
+	// Times are shown in the workspace's time zone, or each user's own if it can be looked up
+	timeZone := time.UTC
+	if value := strings.TrimSpace(os.Getenv("TIMEZONE")); value != "" {
+		if location, err := time.LoadLocation(value); err == nil {
+			timeZone = location
+		} else {
+			logging.Warn("Invalid TIMEZONE %q, must be a name like Australia/Sydney, using UTC", value)
+		}
+	}
+	userTimeZones, _ := strconv.ParseBool(os.Getenv("USER_TIMEZONES"))
+
+	// How much to log, from debug to error
+	logLevel, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		logging.Warn("Invalid LOG_LEVEL, must be debug, info, warn or error, using info: %v", err)
+	}
+
+	// Check the bot token with Slack at startup, off by default so tests and offline runs don't need Slack
+	slackTokenCheck := strings.ToLower(strings.TrimSpace(os.Getenv("SLACK_TOKEN_CHECK")))
+	switch slackTokenCheck {
+	case "":
+		slackTokenCheck = TokenCheckOff
+	case TokenCheckOff, TokenCheckWarn, TokenCheckFail:
+	default:
+		logging.Warn("Invalid SLACK_TOKEN_CHECK %q, must be off, warn or fail, not checking the token", slackTokenCheck)
+		slackTokenCheck = TokenCheckOff
+	}
+
+	// Other bots' messages are skipped unless asked for, and SnagBot's own always are
+	botMessages := strings.ToLower(strings.TrimSpace(os.Getenv("BOT_MESSAGES")))
+	switch botMessages {
+	case "":
+		botMessages = BotMessagesIgnore
+	case BotMessagesIgnore, BotMessagesRespond:
+	default:
+		logging.Warn("Invalid BOT_MESSAGES %q, must be ignore or respond, ignoring bot messages", botMessages)
+		botMessages = BotMessagesIgnore
+	}
+
+	// Reacting to a message with this emoji asks SnagBot to convert it, written with or without colons
+	convertReaction, ok := os.LookupEnv("CONVERT_REACTION")
+	if !ok {
+		convertReaction = DefaultConvertReaction
+	}
+	convertReaction = strings.Trim(strings.TrimSpace(convertReaction), ":")
+
+	// Amounts in other currencies can be converted to one currency before they're counted, using
+	// a fixed rate table like "USD=1.52,EUR=1.65" giving each currency's value in that currency
+	exchangeCurrency := strings.ToUpper(strings.TrimSpace(os.Getenv("FX_CURRENCY")))
+	exchangeRates := parseExchangeRates(os.Getenv("FX_RATES"))
+	if exchangeCurrency != "" && len(exchangeRates) == 0 {
+		logging.Warn("FX_CURRENCY is set without any FX_RATES, amounts won't be converted")
+	}
+
+	// Words like "bucks" or "quid" that mark an amount as money, as in "50 bucks" or "twenty quid"
+	moneyWords := parseWordList(os.Getenv("MONEY_WORDS"))
+
 	// Enable multi-workspace if Redis is available and client credentials are set
 	enableMulti := useRedis && slackClientID != "" && slackClientSecret != ""
 
 	return &Config{
+		Enabled:             enabled,
 		Port:                port,
 		SlackBotToken:       slackBotToken,
 		SlackSigningSecret:  slackSigningSecret,
 		SlackClientID:       slackClientID,
 		SlackClientSecret:   slackClientSecret,
-		DefaultItemName:     "Bunnings Snag",
-		DefaultItemPrice:    3.50,
+		DefaultItemName:     defaultItemName,
+		DefaultItemPrice:    defaultItemPrice,
 		RedisURL:            redisURL,
 		UseRedis:            useRedis,
+		StoreBackend:        storeBackend,
+		SQLitePath:          sqlitePath,
+		RedisConfigTTL:      redisConfigTTL,
+		MemoryConfigTTL:     memoryConfigTTL,
+		ConfigCacheTTL:      configCacheTTL,
 		OAuthRedirectURL:    oauthRedirectURL,
 		AppBaseURL:          appBaseURL,
 		CookieSecret:        cookieSecret,
 		JWTSecret:           jwtSecret,
 		EnableMultiWorkspace: enableMulti,
+		ConfigStorePath:     configStorePath,
+		ResponseCooldown:    responseCooldown,
+		ResponseDelay:       responseDelay,
+		EventDedupeWindow:   eventDedupeWindow,
+		EventDedupeSize:     eventDedupeSize,
+		EventWorkers:        eventWorkers,
+		EventQueueSize:      eventQueueSize,
+		SlackRequestMaxAge:  slackRequestMaxAge,
+		MaxRequestBodySize:  maxRequestBodySize,
+		MaxItemNameLength:   maxItemNameLength,
+		AdminToken:          adminToken,
+		IgnoredChannels:     ignoredChannels,
+		IgnoredUsers:        ignoredUsers,
+		IgnoreDirectMessages: ignoreDirectMessages,
+		MessageSubTypes:     messageSubTypes,
+		IgnoredMessageSubTypes: ignoredMessageSubTypes,
+		AdminUsers:          adminUsers,
+		SlashCommands:       slashCommands,
+		AnnounceConfigChanges: announceConfigChanges,
+		TimeZone:            timeZone,
+		UserTimeZones:       userTimeZones,
+		LogLevel:            logLevel,
+		SlackTokenCheck:     slackTokenCheck,
+		BotMessages:         botMessages,
+		SlackBotUserID:      strings.TrimSpace(os.Getenv("SLACK_BOT_USER_ID")),
+		SlackBotID:          strings.TrimSpace(os.Getenv("SLACK_BOT_ID")),
+		ConvertReaction:     convertReaction,
+		ExchangeCurrency:    exchangeCurrency,
+		ExchangeRates:       exchangeRates,
+		FeedbackChannel:     strings.TrimSpace(os.Getenv("FEEDBACK_CHANNEL")),
+		MoneyWords:          moneyWords,
+	}
+}
+
+// parseSlashCommands splits a comma separated list of slash command names, adding any missing
+// leading slash and skipping blanks and repeats, falling back to the default when there are none
+func parseSlashCommands(value string) []string {
+	var commands []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "/" {
+			continue
+		}
+		if !strings.HasPrefix(name, "/") {
+			name = "/" + name
+		}
+		if !seen[name] {
+			seen[name] = true
+			commands = append(commands, name)
+		}
+	}
+
+	if len(commands) == 0 {
+		return []string{DefaultSlashCommand}
+	}
+	return commands
+}
+
+// parseWordList splits a comma separated list of words, lowercasing them and skipping blanks and repeats
+func parseWordList(value string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Split(value, ",") {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// parseExchangeRates parses a comma separated list of rates like "USD=1.52,EUR=1.65", skipping
+// any that aren't a currency code and a positive number
+func parseExchangeRates(value string) map[string]float64 {
+	rates := make(map[string]float64)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		code, rateText, _ := strings.Cut(entry, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateText), 64)
+		if len(code) != 3 || err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			logging.Warn("Invalid FX_RATES entry %q, must be a currency code and rate like USD=1.52, skipping", entry)
+			continue
+		}
+		rates[code] = rate
+	}
+	return rates
+}
+
+// parseIDSet splits a comma separated list of Slack IDs into a set, skipping blanks
+func parseIDSet(value string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids[id] = true
+		}
 	}
+	return ids
 }
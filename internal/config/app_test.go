@@ -0,0 +1,324 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_DefaultItem(t *testing.T) {
+	tests := []struct {
+		name          string
+		itemName      string
+		itemPrice     string
+		expectedName  string
+		expectedPrice float64
+	}{
+		{name: "Unset", expectedName: DefaultItemName, expectedPrice: DefaultItemPrice},
+		{name: "Custom item", itemName: "flat white", itemPrice: "5.50", expectedName: "flat white", expectedPrice: 5.50},
+		{name: "Invalid price", itemName: "flat white", itemPrice: "five", expectedName: "flat white", expectedPrice: DefaultItemPrice},
+		{name: "Zero price", itemPrice: "0", expectedName: DefaultItemName, expectedPrice: DefaultItemPrice},
+		{name: "Negative price", itemPrice: "-2", expectedName: DefaultItemName, expectedPrice: DefaultItemPrice},
+		{name: "Blank name", itemName: "  ", itemPrice: " 4 ", expectedName: DefaultItemName, expectedPrice: 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("DEFAULT_ITEM_NAME", test.itemName)
+			t.Setenv("DEFAULT_ITEM_PRICE", test.itemPrice)
+
+			cfg := New()
+			assert.Equal(t, test.expectedName, cfg.DefaultItemName)
+			assert.Equal(t, test.expectedPrice, cfg.DefaultItemPrice)
+		})
+	}
+}
+
+func TestNew_IgnoredChannelsAndUsers(t *testing.T) {
+	t.Setenv("IGNORED_CHANNELS", "C11111, C22222,,")
+	t.Setenv("IGNORED_USERS", "U12345")
+
+	cfg := New()
+	assert.Equal(t, map[string]bool{"C11111": true, "C22222": true}, cfg.IgnoredChannels)
+	assert.Equal(t, map[string]bool{"U12345": true}, cfg.IgnoredUsers)
+
+	t.Setenv("IGNORED_CHANNELS", "")
+	assert.Empty(t, New().IgnoredChannels)
+}
+
+func TestNew_AdminUsers(t *testing.T) {
+	t.Setenv("ADMIN_USERS", "U11111,U22222")
+	assert.Equal(t, map[string]bool{"U11111": true, "U22222": true}, New().AdminUsers)
+
+	t.Setenv("ADMIN_USERS", "")
+	assert.Empty(t, New().AdminUsers)
+}
+
+func TestNew_MaxRequestBodySize(t *testing.T) {
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "4096")
+	assert.Equal(t, int64(4096), New().MaxRequestBodySize)
+
+	// Invalid limits fall back to the default
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "-1")
+	assert.Zero(t, New().MaxRequestBodySize)
+}
+
+func TestNew_MaxItemNameLength(t *testing.T) {
+	t.Setenv("MAX_ITEM_NAME_LENGTH", "80")
+	assert.Equal(t, 80, New().MaxItemNameLength)
+
+	// Invalid lengths fall back to the default
+	t.Setenv("MAX_ITEM_NAME_LENGTH", "0")
+	assert.Zero(t, New().MaxItemNameLength)
+}
+
+func TestNew_StoreBackend(t *testing.T) {
+	t.Setenv("REDIS_URL", "")
+	t.Setenv("STORE_BACKEND", "")
+	t.Setenv("SQLITE_PATH", "")
+	assert.Equal(t, StoreBackendMemory, New().StoreBackend)
+
+	t.Setenv("SQLITE_PATH", "./snagbot.db")
+	assert.Equal(t, StoreBackendSQLite, New().StoreBackend)
+	assert.Equal(t, "./snagbot.db", New().SQLitePath)
+
+	t.Setenv("REDIS_URL", "redis://localhost:6379")
+	assert.Equal(t, StoreBackendRedis, New().StoreBackend)
+
+	t.Setenv("STORE_BACKEND", " DynamoDB ")
+	assert.Equal(t, "dynamodb", New().StoreBackend)
+}
+
+func TestNew_ResponseDelay(t *testing.T) {
+	t.Setenv("RESPONSE_DELAY_MS", "1500")
+	assert.Equal(t, 1500*time.Millisecond, New().ResponseDelay)
+
+	t.Setenv("RESPONSE_DELAY_MS", "soon")
+	assert.Zero(t, New().ResponseDelay)
+}
+
+func TestNew_MemoryConfigTTL(t *testing.T) {
+	t.Setenv("MEMORY_CONFIG_TTL_SECONDS", "")
+	assert.Zero(t, New().MemoryConfigTTL)
+
+	t.Setenv("MEMORY_CONFIG_TTL_SECONDS", "3600")
+	assert.Equal(t, time.Hour, New().MemoryConfigTTL)
+}
+
+func TestNew_ConfigCacheTTL(t *testing.T) {
+	t.Setenv("CONFIG_CACHE_TTL_MS", "")
+	assert.Zero(t, New().ConfigCacheTTL)
+
+	t.Setenv("CONFIG_CACHE_TTL_MS", "2000")
+	assert.Equal(t, 2*time.Second, New().ConfigCacheTTL)
+
+	t.Setenv("CONFIG_CACHE_TTL_MS", "-1")
+	assert.Zero(t, New().ConfigCacheTTL)
+}
+
+func TestNew_IgnoreDirectMessages(t *testing.T) {
+	t.Setenv("IGNORE_DIRECT_MESSAGES", "")
+	assert.False(t, New().IgnoreDirectMessages)
+
+	t.Setenv("IGNORE_DIRECT_MESSAGES", "true")
+	assert.True(t, New().IgnoreDirectMessages)
+}
+
+func TestNew_Enabled(t *testing.T) {
+	// t.Setenv restores the environment after the test, so it's safe to unset
+	t.Setenv("SNAGBOT_ENABLED", "")
+	os.Unsetenv("SNAGBOT_ENABLED")
+	assert.True(t, New().Enabled)
+
+	t.Setenv("SNAGBOT_ENABLED", "false")
+	assert.False(t, New().Enabled)
+
+	// Invalid values leave SnagBot enabled
+	t.Setenv("SNAGBOT_ENABLED", "nope")
+	assert.True(t, New().Enabled)
+}
+
+func TestNew_SlashCommands(t *testing.T) {
+	t.Setenv("SLASH_COMMANDS", "")
+	assert.Equal(t, []string{DefaultSlashCommand}, New().SlashCommands)
+
+	// Names are tidied, with the first kept as the primary
+	t.Setenv("SLASH_COMMANDS", " /snagbot, snag ,/SNAG,, /")
+	assert.Equal(t, []string{"/snagbot", "/snag"}, New().SlashCommands)
+}
+
+func TestNew_TimeZone(t *testing.T) {
+	t.Setenv("TIMEZONE", "")
+	assert.Equal(t, time.UTC, New().TimeZone)
+
+	t.Setenv("TIMEZONE", "Australia/Sydney")
+	assert.Equal(t, "Australia/Sydney", New().TimeZone.String())
+
+	t.Setenv("TIMEZONE", "Mars/Olympus_Mons")
+	assert.Equal(t, time.UTC, New().TimeZone)
+
+	t.Setenv("USER_TIMEZONES", "")
+	assert.False(t, New().UserTimeZones)
+
+	t.Setenv("USER_TIMEZONES", "true")
+	assert.True(t, New().UserTimeZones)
+}
+
+func TestNew_AnnounceConfigChanges(t *testing.T) {
+	t.Setenv("ANNOUNCE_CONFIG_CHANGES", "")
+	assert.False(t, New().AnnounceConfigChanges)
+
+	t.Setenv("ANNOUNCE_CONFIG_CHANGES", "true")
+	assert.True(t, New().AnnounceConfigChanges)
+}
+
+func TestNew_LogLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "")
+	assert.Equal(t, logging.INFO, New().LogLevel)
+
+	t.Setenv("LOG_LEVEL", "debug")
+	assert.Equal(t, logging.DEBUG, New().LogLevel)
+
+	t.Setenv("LOG_LEVEL", "chatty")
+	assert.Equal(t, logging.INFO, New().LogLevel)
+}
+
+func TestReload(t *testing.T) {
+	t.Cleanup(func() { logging.SetGlobalLevel(logging.INFO) })
+
+	t.Setenv("DEFAULT_ITEM_NAME", "")
+	t.Setenv("DEFAULT_ITEM_PRICE", "")
+	t.Setenv("PORT", "8080")
+	cfg := New()
+
+	t.Setenv("DEFAULT_ITEM_NAME", "flat whites")
+	t.Setenv("DEFAULT_ITEM_PRICE", "5.50")
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("PORT", "9090")
+	cfg.Reload(New())
+
+	name, price := cfg.DefaultItem()
+	assert.Equal(t, "flat whites", name)
+	assert.Equal(t, 5.50, price)
+	assert.Equal(t, logging.WARN, logging.GlobalLevel())
+
+	// Settings that need a restart are left alone
+	assert.Equal(t, "8080", cfg.Port)
+}
+
+func TestNew_EventPool(t *testing.T) {
+	t.Setenv("EVENT_WORKERS", "")
+	t.Setenv("EVENT_QUEUE_SIZE", "")
+	cfg := New()
+	assert.Zero(t, cfg.EventWorkers)
+	assert.Zero(t, cfg.EventQueueSize)
+
+	t.Setenv("EVENT_WORKERS", "4")
+	t.Setenv("EVENT_QUEUE_SIZE", "100")
+	cfg = New()
+	assert.Equal(t, 4, cfg.EventWorkers)
+	assert.Equal(t, 100, cfg.EventQueueSize)
+
+	t.Setenv("EVENT_WORKERS", "-1")
+	t.Setenv("EVENT_QUEUE_SIZE", "lots")
+	cfg = New()
+	assert.Zero(t, cfg.EventWorkers)
+	assert.Zero(t, cfg.EventQueueSize)
+}
+
+func TestNew_MessageSubTypes(t *testing.T) {
+	t.Setenv("IGNORED_MESSAGE_SUBTYPES", "")
+	os.Unsetenv("IGNORED_MESSAGE_SUBTYPES") // Restored by t.Setenv
+	t.Setenv("MESSAGE_SUBTYPES", "")
+	cfg := New()
+	assert.Empty(t, cfg.MessageSubTypes)
+	assert.True(t, cfg.IgnoredMessageSubTypes["channel_join"])
+	assert.False(t, cfg.IgnoredMessageSubTypes["file_share"])
+
+	t.Setenv("MESSAGE_SUBTYPES", "file_share, me_message")
+	t.Setenv("IGNORED_MESSAGE_SUBTYPES", "thread_broadcast")
+	cfg = New()
+	assert.Equal(t, map[string]bool{"file_share": true, "me_message": true}, cfg.MessageSubTypes)
+	assert.Equal(t, map[string]bool{"thread_broadcast": true}, cfg.IgnoredMessageSubTypes)
+
+	// An empty ignore list ignores nothing, rather than using the defaults
+	t.Setenv("IGNORED_MESSAGE_SUBTYPES", "")
+	assert.Empty(t, New().IgnoredMessageSubTypes)
+}
+
+func TestNew_SlackTokenCheck(t *testing.T) {
+	t.Setenv("SLACK_TOKEN_CHECK", "")
+	assert.Equal(t, TokenCheckOff, New().SlackTokenCheck)
+
+	t.Setenv("SLACK_TOKEN_CHECK", "FAIL")
+	assert.Equal(t, TokenCheckFail, New().SlackTokenCheck)
+
+	t.Setenv("SLACK_TOKEN_CHECK", "sometimes")
+	assert.Equal(t, TokenCheckOff, New().SlackTokenCheck)
+}
+
+func TestNew_BotMessages(t *testing.T) {
+	t.Setenv("BOT_MESSAGES", "")
+	t.Setenv("SLACK_BOT_USER_ID", "")
+	t.Setenv("SLACK_BOT_ID", "")
+	cfg := New()
+	assert.Equal(t, BotMessagesIgnore, cfg.BotMessages)
+	assert.Empty(t, cfg.SlackBotUserID)
+	assert.Empty(t, cfg.SlackBotID)
+
+	t.Setenv("BOT_MESSAGES", "Respond")
+	t.Setenv("SLACK_BOT_USER_ID", " U0SNAGBOT ")
+	t.Setenv("SLACK_BOT_ID", "B0SNAGBOT")
+	cfg = New()
+	assert.Equal(t, BotMessagesRespond, cfg.BotMessages)
+	assert.Equal(t, "U0SNAGBOT", cfg.SlackBotUserID)
+	assert.Equal(t, "B0SNAGBOT", cfg.SlackBotID)
+
+	t.Setenv("BOT_MESSAGES", "sometimes")
+	assert.Equal(t, BotMessagesIgnore, New().BotMessages)
+}
+
+func TestNew_ConvertReaction(t *testing.T) {
+	t.Setenv("CONVERT_REACTION", "")
+	os.Unsetenv("CONVERT_REACTION")
+	assert.Equal(t, DefaultConvertReaction, New().ConvertReaction)
+
+	t.Setenv("CONVERT_REACTION", " :money_with_wings: ")
+	assert.Equal(t, "money_with_wings", New().ConvertReaction)
+
+	t.Setenv("CONVERT_REACTION", "")
+	assert.Empty(t, New().ConvertReaction)
+}
+
+func TestNew_ExchangeRates(t *testing.T) {
+	t.Setenv("FX_CURRENCY", "")
+	t.Setenv("FX_RATES", "")
+	cfg := New()
+	assert.Empty(t, cfg.ExchangeCurrency)
+	assert.Empty(t, cfg.ExchangeRates)
+
+	t.Setenv("FX_CURRENCY", " aud ")
+	t.Setenv("FX_RATES", "USD=1.52, eur = 1.65,,GBP=lots,JPY=-1,DOLLARS=1")
+	cfg = New()
+	assert.Equal(t, "AUD", cfg.ExchangeCurrency)
+	assert.Equal(t, map[string]float64{"USD": 1.52, "EUR": 1.65}, cfg.ExchangeRates)
+}
+
+func TestNew_FeedbackChannel(t *testing.T) {
+	t.Setenv("FEEDBACK_CHANNEL", "")
+	assert.Empty(t, New().FeedbackChannel)
+
+	t.Setenv("FEEDBACK_CHANNEL", " C0FEEDBACK ")
+	assert.Equal(t, "C0FEEDBACK", New().FeedbackChannel)
+}
+
+func TestNew_MoneyWords(t *testing.T) {
+	t.Setenv("MONEY_WORDS", "")
+	assert.Empty(t, New().MoneyWords)
+
+	t.Setenv("MONEY_WORDS", " Bucks,quid,, dollarydoos ,QUID")
+	assert.Equal(t, []string{"bucks", "quid", "dollarydoos"}, New().MoneyWords)
+}
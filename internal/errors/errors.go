@@ -13,9 +13,21 @@ var (
 	// ErrInvalidDollarValue is returned when a dollar value is invalid
 	ErrInvalidDollarValue = errors.New("invalid dollar value")
 
+	// ErrTooManyDollarValues is returned when a message has more dollar values than are counted
+	ErrTooManyDollarValues = errors.New("too many dollar values")
+
+	// ErrNothingToUndo is returned when a channel has no config changes left to undo
+	ErrNothingToUndo = errors.New("nothing to undo")
+
+	// ErrItemNameTooLong is returned for item names over the configured length limit
+	ErrItemNameTooLong = errors.New("item name too long")
+
 	// ErrInvalidRequest is returned for invalid requests
 	ErrInvalidRequest = errors.New("invalid request")
 
+	// ErrRequestTooLarge is returned for request bodies over the configured size limit
+	ErrRequestTooLarge = errors.New("request too large")
+
 	// ErrInvalidSignature is returned for requests with invalid signatures
 	ErrInvalidSignature = errors.New("invalid signature")
 
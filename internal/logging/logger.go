@@ -1,11 +1,16 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,22 +30,45 @@ const (
 	FATAL
 )
 
+// LogFormat represents the output format of log lines
+type LogFormat int
+
+const (
+	// TEXT format writes human-readable lines (the default)
+	TEXT LogFormat = iota
+	// JSON format writes one JSON object per line for log aggregators
+	JSON
+)
+
 var (
+	// exitFunc is called after a FATAL message is written; replaced in tests
+	exitFunc = os.Exit
+
 	// Default logger settings
-	defaultLogger = &Logger{
-		level:  INFO, // Default log level
-		prefix: "",
-		flags:  log.LstdFlags,
-		logger: log.New(os.Stdout, "", log.LstdFlags),
-	}
+	defaultLogger = NewLogger(INFO, "", log.LstdFlags)
 )
 
 // Logger represents a custom logger with levels
 type Logger struct {
-	level  LogLevel
+	level  atomic.Int64 // LogLevel, changed while logging when the config is reloaded
 	prefix string
 	flags  int
+	format LogFormat
 	logger *log.Logger
+	mutex  sync.Mutex // Serializes JSON writes, which bypass log.Logger
+
+	// debugSampling writes only 1 in this many DEBUG messages, 0 or 1 writes them all
+	debugSampling atomic.Uint64
+	// debugCount counts the DEBUG messages at or above the level, to pick which to write
+	debugCount atomic.Uint64
+}
+
+// jsonEntry is the structure of a log line in JSON format
+type jsonEntry struct {
+	Timestamp string `json:"ts"`
+	Level     string `json:"level"`
+	Caller    string `json:"caller"`
+	Message   string `json:"msg"`
 }
 
 // GetLogger returns the default logger
@@ -50,17 +78,101 @@ func GetLogger() *Logger {
 
 // NewLogger creates a new logger with custom settings
 func NewLogger(level LogLevel, prefix string, flags int) *Logger {
-	return &Logger{
-		level:  level,
+	l := &Logger{
 		prefix: prefix,
 		flags:  flags,
 		logger: log.New(os.Stdout, prefix, flags),
 	}
+	l.SetLevel(level)
+	return l
 }
 
 // SetLevel sets the minimum log level
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	l.level.Store(int64(level))
+}
+
+// Level returns the minimum log level
+func (l *Logger) Level() LogLevel {
+	return LogLevel(l.level.Load())
+}
+
+// ParseLevel converts a level name such as "debug" to a LogLevel
+// An empty name maps to INFO
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DEBUG, nil
+	case "", "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// SetOutput sets the writer log lines are written to
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logger.SetOutput(w)
+}
+
+// SetFormat sets the output format (TEXT or JSON)
+func (l *Logger) SetFormat(format LogFormat) {
+	l.format = format
+}
+
+// ParseFormat converts a format name such as "json" to a LogFormat
+// An empty name maps to TEXT
+func ParseFormat(name string) (LogFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "text":
+		return TEXT, nil
+	case "json":
+		return JSON, nil
+	default:
+		return TEXT, fmt.Errorf("unknown log format: %s", name)
+	}
+}
+
+// SetDebugSampling writes only 1 in every n DEBUG messages, to keep busy workspaces from
+// flooding the logs
+// INFO and above are always written; n of 0 or 1 writes every DEBUG message
+func (l *Logger) SetDebugSampling(n int) {
+	if n < 0 {
+		n = 0
+	}
+	l.debugSampling.Store(uint64(n))
+}
+
+// ParseDebugSampling converts a sampling rate such as "10" (1 in 10) to a number for SetDebugSampling
+// An empty value maps to 1, writing every DEBUG message
+func ParseDebugSampling(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 1, fmt.Errorf("invalid debug sampling rate: %s", value)
+	}
+	return n, nil
+}
+
+// sampled reports whether a message at level should be written under the DEBUG sampling rate
+func (l *Logger) sampled(level LogLevel) bool {
+	if level != DEBUG {
+		return true
+	}
+	n := l.debugSampling.Load()
+	if n <= 1 {
+		return true
+	}
+	return (l.debugCount.Add(1)-1)%n == 0
 }
 
 // SetPrefix sets the log prefix
@@ -69,6 +181,11 @@ func (l *Logger) SetPrefix(prefix string) {
 	l.logger.SetPrefix(prefix)
 }
 
+// String returns the level's name, like "DEBUG"
+func (level LogLevel) String() string {
+	return levelToString(level)
+}
+
 // levelToString converts a LogLevel to its string representation
 func levelToString(level LogLevel) string {
 	switch level {
@@ -101,7 +218,7 @@ func getCallerInfo(skip int) string {
 
 // log logs a message at the specified level
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.level {
+	if level < l.Level() || !l.sampled(level) {
 		return
 	}
 
@@ -113,15 +230,38 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 	levelStr := levelToString(level)
 	message := fmt.Sprintf(format, args...)
 
-	logLine := fmt.Sprintf("[%s] [%s] %s - %s", timestamp, levelStr, caller, message)
-	l.logger.Println(logLine)
+	if l.format == JSON {
+		l.writeJSON(jsonEntry{
+			Timestamp: timestamp,
+			Level:     levelStr,
+			Caller:    caller,
+			Message:   message,
+		})
+	} else {
+		logLine := fmt.Sprintf("[%s] [%s] %s - %s", timestamp, levelStr, caller, message)
+		l.logger.Println(logLine)
+	}
 
-	// If fatal, exit the application
+	// If fatal, exit the application now the message has been written
 	if level == FATAL {
-		os.Exit(1)
+		exitFunc(1)
 	}
 }
 
+// writeJSON writes a single JSON log line directly to the underlying writer
+func (l *Logger) writeJSON(entry jsonEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// Should never happen for string fields, but don't lose the message
+		line = []byte(fmt.Sprintf(`{"level":"ERROR","msg":%q}`, "failed to encode log entry: "+err.Error()))
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logger.Writer().Write(line)
+}
+
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
 	l.log(DEBUG, format, args...)
@@ -152,6 +292,26 @@ func SetGlobalLevel(level LogLevel) {
 	defaultLogger.SetLevel(level)
 }
 
+// GlobalLevel returns the level of the default logger
+func GlobalLevel() LogLevel {
+	return defaultLogger.Level()
+}
+
+// SetGlobalOutput sets the writer of the default logger
+func SetGlobalOutput(w io.Writer) {
+	defaultLogger.SetOutput(w)
+}
+
+// SetGlobalFormat sets the output format of the default logger
+func SetGlobalFormat(format LogFormat) {
+	defaultLogger.SetFormat(format)
+}
+
+// SetGlobalDebugSampling sets the DEBUG sampling rate of the default logger
+func SetGlobalDebugSampling(n int) {
+	defaultLogger.SetDebugSampling(n)
+}
+
 // SetGlobalPrefix sets the prefix of the default logger
 func SetGlobalPrefix(prefix string) {
 	defaultLogger.SetPrefix(prefix)
@@ -0,0 +1,206 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+	logger.SetFormat(JSON)
+
+	logger.Info("Hello %s", "snags")
+	logger.Warn("Second line with \"quotes\"")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, "Hello snags", entry["msg"])
+	assert.Contains(t, entry["caller"], "logger_test.go:")
+	assert.NotEmpty(t, entry["ts"])
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "Second line with \"quotes\"", entry["msg"])
+}
+
+func TestLoggerTextFormatIsDefault(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+
+	logger.Info("Hello snags")
+
+	assert.Contains(t, buf.String(), "[INFO] logger_test.go:")
+	assert.Contains(t, buf.String(), " - Hello snags")
+	assert.False(t, json.Valid(buf.Bytes()))
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, TEXT, format)
+
+	format, err = ParseFormat("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, JSON, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected LogLevel
+	}{
+		{"", INFO},
+		{"debug", DEBUG},
+		{"INFO", INFO},
+		{" warn ", WARN},
+		{"warning", WARN},
+		{"error", ERROR},
+	}
+
+	for _, test := range tests {
+		level, err := ParseLevel(test.name)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, level, test.name)
+	}
+
+	// FATAL isn't a level to log at, since it would hide every error
+	for _, name := range []string{"fatal", "verbose"} {
+		_, err := ParseLevel(name)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(INFO, "", 0)
+	logger.SetOutput(&buf)
+
+	logger.Debug("Hidden")
+	logger.SetLevel(DEBUG)
+	assert.Equal(t, DEBUG, logger.Level())
+	logger.Debug("Shown")
+
+	assert.NotContains(t, buf.String(), "Hidden")
+	assert.Contains(t, buf.String(), "Shown")
+}
+
+func TestSetGlobalOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetGlobalOutput(&buf)
+	defer SetGlobalOutput(os.Stdout)
+
+	Warn("Captured %d snags", 3)
+
+	assert.Contains(t, buf.String(), "[WARN]")
+	assert.Contains(t, buf.String(), "Captured 3 snags")
+}
+
+func TestFatalWritesBeforeExit(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+
+	// Record the exit instead of exiting the test binary
+	originalExit := exitFunc
+	defer func() { exitFunc = originalExit }()
+	exitCode := -1
+	exitFunc = func(code int) {
+		// The message must already be written when exit is called
+		assert.Contains(t, buf.String(), "Out of snags")
+		exitCode = code
+	}
+
+	logger.Fatal("Out of snags")
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestDebugSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+	logger.SetDebugSampling(10)
+
+	for i := 0; i < 100; i++ {
+		logger.Debug("Debug line %d", i)
+		logger.Info("Info line %d", i)
+	}
+
+	output := buf.String()
+	assert.Equal(t, 10, strings.Count(output, "[DEBUG]"))
+	assert.Equal(t, 100, strings.Count(output, "[INFO]"))
+	assert.Contains(t, output, "Debug line 0\n")
+	assert.Contains(t, output, "Debug line 90\n")
+	assert.NotContains(t, output, "Debug line 1\n")
+}
+
+func TestDebugSamplingUsesCounter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+	logger.SetDebugSampling(10)
+
+	// The next message is the 10th since the last one written, so it is skipped
+	logger.debugCount.Store(9)
+	logger.Debug("Skipped")
+	logger.Debug("Written")
+	assert.NotContains(t, buf.String(), "Skipped")
+	assert.Contains(t, buf.String(), "Written")
+}
+
+func TestDebugSamplingIgnoresFilteredMessages(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(INFO, "", 0)
+	logger.SetOutput(&buf)
+	logger.SetDebugSampling(10)
+
+	for i := 0; i < 5; i++ {
+		logger.Debug("Below the level")
+	}
+	assert.Zero(t, logger.debugCount.Load())
+	assert.Empty(t, buf.String())
+}
+
+func TestDebugSamplingOff(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(DEBUG, "", 0)
+	logger.SetOutput(&buf)
+
+	for _, n := range []int{0, 1} {
+		buf.Reset()
+		logger.SetDebugSampling(n)
+		for i := 0; i < 5; i++ {
+			logger.Debug("Debug line")
+		}
+		assert.Equal(t, 5, strings.Count(buf.String(), "[DEBUG]"))
+	}
+}
+
+func TestParseDebugSampling(t *testing.T) {
+	n, err := ParseDebugSampling("")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = ParseDebugSampling(" 10 ")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, n)
+
+	for _, value := range []string{"0", "-5", "ten"} {
+		_, err = ParseDebugSampling(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors for SnagBot
+type Metrics struct {
+	registry *prometheus.Registry
+
+	// MessagesProcessed counts message events processed (excluding bot messages and edits)
+	MessagesProcessed prometheus.Counter
+	// ResponsesSent counts replies and reactions sent to Slack
+	ResponsesSent prometheus.Counter
+	// DollarValuesExtracted counts dollar values found in processed messages
+	DollarValuesExtracted prometheus.Counter
+	// ProcessingDuration tracks how long each message takes to process
+	ProcessingDuration prometheus.Histogram
+	// CommandsHandled counts slash commands by subcommand
+	CommandsHandled *prometheus.CounterVec
+	// EventsDropped counts Slack events dropped because the event queue was full
+	EventsDropped prometheus.Counter
+}
+
+// New creates a Metrics instance with its own registry, including Go runtime and process metrics
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return NewWithRegistry(registry)
+}
+
+// NewWithRegistry creates a Metrics instance registered with the given registry
+func NewWithRegistry(registry *prometheus.Registry) *Metrics {
+	m := &Metrics{
+		registry: registry,
+		MessagesProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snagbot_messages_processed_total",
+			Help: "Number of Slack messages processed.",
+		}),
+		ResponsesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snagbot_responses_sent_total",
+			Help: "Number of responses sent to Slack.",
+		}),
+		DollarValuesExtracted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snagbot_dollar_values_extracted_total",
+			Help: "Number of dollar values extracted from messages.",
+		}),
+		ProcessingDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "snagbot_message_processing_seconds",
+			Help:    "Time taken to process a Slack message.",
+			Buckets: prometheus.DefBuckets,
+		}),
+		CommandsHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "snagbot_commands_handled_total",
+			Help: "Number of slash commands handled, by subcommand.",
+		}, []string{"subcommand"}),
+		EventsDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "snagbot_events_dropped_total",
+			Help: "Number of Slack events dropped because the event queue was full.",
+		}),
+	}
+
+	registry.MustRegister(
+		m.MessagesProcessed,
+		m.ResponsesSent,
+		m.DollarValuesExtracted,
+		m.ProcessingDuration,
+		m.CommandsHandled,
+		m.EventsDropped,
+	)
+
+	return m
+}
+
+// Registry returns the registry the metrics are registered with
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns an HTTP handler exposing the metrics for scraping
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+var (
+	defaultMetrics = New()
+	defaultMutex   sync.RWMutex
+)
+
+// Default returns the metrics instance used by the application
+func Default() *Metrics {
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultMetrics
+}
+
+// SetDefault replaces the metrics instance used by the application
+// Tests use this to inject metrics with a fresh registry
+func SetDefault(m *Metrics) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultMetrics = m
+}
@@ -22,18 +22,18 @@ func NewCommandService(store slack.ChannelConfigStore) *CommandService {
 	}
 }
 
-// HandleConfigCommand processes a configuration command
-func (s *CommandService) HandleConfigCommand(text, channelID string) string {
+// HandleConfigCommand processes a configuration command made by userID
+func (s *CommandService) HandleConfigCommand(text, channelID, userID string) string {
 	// Use the implementation from the command package
 	cmdService := command.NewCommandService(s.ConfigStore)
-	return cmdService.HandleConfigCommand(text, channelID)
+	return cmdService.HandleConfigCommand(text, channelID, userID)
 }
 
-// HandleResetCommand resets a channel's configuration
-func (s *CommandService) HandleResetCommand(channelID string) string {
+// HandleResetCommand resets a channel's configuration on behalf of userID
+func (s *CommandService) HandleResetCommand(channelID, userID string) string {
 	// Use the implementation from the command package
 	cmdService := command.NewCommandService(s.ConfigStore)
-	return cmdService.HandleResetCommand(channelID)
+	return cmdService.HandleResetCommand(channelID, userID)
 }
 
 // HandleStatusCommand returns the current configuration for a channel
@@ -1,10 +1,11 @@
 package service
 
 import (
+	"context"
+
 	"github.com/mcncl/snagbot/internal/calculator"
 	"github.com/mcncl/snagbot/internal/logging"
 	"github.com/mcncl/snagbot/internal/slack"
-	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/slack-go/slack/slackevents"
 )
 
@@ -12,6 +13,7 @@ import (
 type SlackService struct {
 	ChannelConfigStore slack.ChannelConfigStore
 	SlackAPI           slack.SlackAPI
+	Options            *slack.ProcessorOptions // Which messages are processed, nil uses the defaults
 }
 
 // NewSlackService creates a new SlackService
@@ -22,17 +24,10 @@ func NewSlackService(store slack.ChannelConfigStore, api slack.SlackAPI) *SlackS
 	}
 }
 
-// ProcessMessageWithConfig processes a message with the given channel configuration
-// and returns the formatted response string
-func ProcessMessageWithConfig(text string, config *models.ChannelConfig) string {
-	// Use the shared implementation from calculator package
-	return calculator.ProcessMessageWithConfig(text, config)
-}
-
 // HandleMessageEvent processes a Slack message event using the service
 func (s *SlackService) HandleMessageEvent(ev *slackevents.MessageEvent) error {
 	// Skip bot messages to prevent loops
-	if ev.BotID != "" || ev.SubType == "bot_message" {
+	if s.Options.SkipsBotMessage(ev.BotID, ev.User, ev.SubType) {
 		return nil
 	}
 
@@ -49,7 +44,7 @@ func (s *SlackService) HandleMessageEvent(ev *slackevents.MessageEvent) error {
 	}
 
 	// Process the message
-	message := ProcessMessageWithConfig(ev.Text, config)
+	message := calculator.ProcessMessageWithOptions(ev.Text, config, s.Options.AmountOptions())
 
 	// If no message was generated, no dollar values were found
 	if message == "" {
@@ -63,5 +58,5 @@ func (s *SlackService) HandleMessageEvent(ev *slackevents.MessageEvent) error {
 		ThreadTS:  ev.TimeStamp, // Reply in thread
 	}
 
-	return s.SlackAPI.PostMessage(response)
+	return s.SlackAPI.PostMessage(context.Background(), response)
 }
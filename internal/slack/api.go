@@ -1,9 +1,12 @@
 package slack
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"time"
 
 	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/logging"
@@ -16,38 +19,74 @@ type SlackResponse struct {
 	TeamID      string // Optional for multi-team support
 	ChannelID   string
 	Text        string
-	ThreadTS    string
+	ThreadTS    string        // Thread to reply in, empty posts a top-level message
+	Blocks      []slack.Block // Optional Block Kit layout, Text is still sent as the notification fallback
+	UserID      string        // User an ephemeral response is shown to
 }
 
 // SlackAPI interface for interacting with Slack
 type SlackAPI interface {
-	PostMessage(response SlackResponse) error
+	PostMessage(ctx context.Context, response SlackResponse) error
+	PostEphemeral(ctx context.Context, response SlackResponse) error
+	AddReaction(ctx context.Context, channelID, timestamp, emoji string) error
+	OpenView(ctx context.Context, workspaceID, triggerID string, view slack.ModalViewRequest) error
+	GetMessage(ctx context.Context, workspaceID, channelID, timestamp string) (*slack.Message, error)
 	GetClientForWorkspace(workspaceID string) (*slack.Client, error)
 }
 
+// TokenVerifier is an interface for Slack clients that can check their token with auth.test
+type TokenVerifier interface {
+	// VerifyToken returns the bot identity the token belongs to, or an error if Slack rejects it
+	VerifyToken(ctx context.Context) (*slack.AuthTestResponse, error)
+}
+
+// UserTimeZoneLookup is an interface for Slack clients that can look up the time zone a user has set
+type UserTimeZoneLookup interface {
+	// GetUserTimeZone returns the user's time zone from their Slack profile
+	GetUserTimeZone(ctx context.Context, workspaceID, userID string) (*time.Location, error)
+}
+
+const (
+	// DefaultMaxAttempts is how many times a rate limited Slack call is tried before giving up
+	DefaultMaxAttempts = 4
+
+	// DefaultRetryBackoff is the wait before the first retry, doubling for each one after
+	DefaultRetryBackoff = time.Second
+)
+
 // RealSlackAPI implements a real Slack API client
 type RealSlackAPI struct {
-	client      *slack.Client       // Legacy client for single workspace
-	tokenStore  TokenStore          // For multi-workspace support
-	clientCache map[string]*slack.Client
-	cacheMutex  sync.RWMutex
-	cfg         *config.Config
+	client       *slack.Client // Legacy client for single workspace
+	tokenStore   TokenStore    // For multi-workspace support
+	clientCache  map[string]*slack.Client
+	cacheMutex   sync.RWMutex
+	cfg          *config.Config
+	maxAttempts  int                                              // Most tries for a rate limited call
+	retryBackoff time.Duration                                    // Wait before the first retry when Slack doesn't say how long
+	sleep        func(ctx context.Context, d time.Duration) error // Injectable for testing
 }
 
-// NewRealSlackAPI creates a new Slack API client for a single workspace
-func NewRealSlackAPI(token string) *RealSlackAPI {
+// NewRealSlackAPI creates a new Slack API client for a single workspace, with any options for
+// the underlying client such as a different API URL
+func NewRealSlackAPI(token string, options ...slack.Option) *RealSlackAPI {
 	return &RealSlackAPI{
-		client:      slack.New(token),
-		clientCache: make(map[string]*slack.Client),
+		client:       slack.New(token, options...),
+		clientCache:  make(map[string]*slack.Client),
+		maxAttempts:  DefaultMaxAttempts,
+		retryBackoff: DefaultRetryBackoff,
+		sleep:        sleepContext,
 	}
 }
 
 // NewMultiWorkspaceSlackAPI creates a Slack API client for multiple workspaces
 func NewMultiWorkspaceSlackAPI(tokenStore TokenStore, cfg *config.Config) *RealSlackAPI {
 	api := &RealSlackAPI{
-		tokenStore:  tokenStore,
-		clientCache: make(map[string]*slack.Client),
-		cfg:         cfg,
+		tokenStore:   tokenStore,
+		clientCache:  make(map[string]*slack.Client),
+		cfg:          cfg,
+		maxAttempts:  DefaultMaxAttempts,
+		retryBackoff: DefaultRetryBackoff,
+		sleep:        sleepContext,
 	}
 
 	// If single-workspace mode is also enabled, set up the legacy client
@@ -94,11 +133,40 @@ func (s *RealSlackAPI) GetClientForWorkspace(workspaceID string) (*slack.Client,
 	return client, nil
 }
 
-// PostMessage sends a message to Slack
-func (s *RealSlackAPI) PostMessage(response SlackResponse) error {
-	var client *slack.Client
-	var err error
+// PostMessage sends a message to Slack, giving up if the context is cancelled
+func (s *RealSlackAPI) PostMessage(ctx context.Context, response SlackResponse) error {
+	client, err := s.clientForResponse(response)
+	if err != nil {
+		return err
+	}
+
+	options := messageOptions(response)
+	return s.retryRateLimited(ctx, func() error {
+		_, _, err := client.PostMessageContext(ctx, response.ChannelID, options...)
+		return err
+	})
+}
+
+// PostEphemeral sends a message only response.UserID can see, giving up if the context is cancelled
+func (s *RealSlackAPI) PostEphemeral(ctx context.Context, response SlackResponse) error {
+	if response.UserID == "" {
+		return fmt.Errorf("a user ID is required to post an ephemeral message")
+	}
+
+	client, err := s.clientForResponse(response)
+	if err != nil {
+		return err
+	}
+
+	options := messageOptions(response)
+	return s.retryRateLimited(ctx, func() error {
+		_, err := client.PostEphemeralContext(ctx, response.ChannelID, response.UserID, options...)
+		return err
+	})
+}
 
+// clientForResponse returns the client for the response's workspace, or the legacy client
+func (s *RealSlackAPI) clientForResponse(response SlackResponse) (*slack.Client, error) {
 	// For multi-workspace support
 	if s.tokenStore != nil && (response.WorkspaceID != "" || response.TeamID != "") {
 		// Prefer WorkspaceID, but fall back to TeamID if WorkspaceID is not available
@@ -106,46 +174,341 @@ func (s *RealSlackAPI) PostMessage(response SlackResponse) error {
 		if workspaceID == "" {
 			workspaceID = response.TeamID
 		}
-		client, err = s.GetClientForWorkspace(workspaceID)
+		client, err := s.GetClientForWorkspace(workspaceID)
 		if err != nil {
 			logging.Error("Failed to get client for workspace %s: %v", workspaceID, err)
+			return nil, err
+		}
+		return client, nil
+	}
+
+	// For legacy single-workspace mode
+	if s.client == nil {
+		return nil, fmt.Errorf("no Slack client available")
+	}
+	return s.client, nil
+}
+
+// messageOptions converts a response into the options for posting it
+func messageOptions(response SlackResponse) []slack.MsgOption {
+	options := []slack.MsgOption{slack.MsgOptionText(response.Text, false)}
+	if response.ThreadTS != "" {
+		// Reply in thread, otherwise the message is posted to the channel
+		options = append(options, slack.MsgOptionTS(response.ThreadTS))
+	}
+	if len(response.Blocks) > 0 {
+		options = append(options, slack.MsgOptionBlocks(response.Blocks...))
+	}
+	return options
+}
+
+// retryRateLimited runs a Slack call, retrying with exponential backoff while Slack rate limits it
+// Slack's Retry-After is honoured when it asks for a longer wait than the backoff
+// Cancelling the context stops any wait for a retry
+func (s *RealSlackAPI) retryRateLimited(ctx context.Context, call func() error) error {
+	backoff := s.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := call()
+
+		var rateLimited *slack.RateLimitedError
+		if !errors.As(err, &rateLimited) || attempt >= s.maxAttempts {
 			return err
 		}
-	} else {
-		// For legacy single-workspace mode
-		if s.client == nil {
-			return fmt.Errorf("no Slack client available")
+
+		wait := max(backoff, rateLimited.RetryAfter)
+		logging.Warn("Rate limited by Slack, retrying in %s (attempt %d of %d)", wait, attempt+1, s.maxAttempts)
+		if err := s.sleep(ctx, wait); err != nil {
+			return err
 		}
-		client = s.client
+		backoff *= 2
 	}
+}
 
-	_, _, err = client.PostMessage(
-		response.ChannelID,
-		slack.MsgOptionText(response.Text, false),
-		slack.MsgOptionTS(response.ThreadTS), // Reply in thread
-	)
-	return err
+// sleepContext waits for the duration, returning early with the context's error if it's cancelled
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// AddReaction adds an emoji reaction to a message
+func (s *RealSlackAPI) AddReaction(ctx context.Context, channelID, timestamp, emoji string) error {
+	client, err := s.GetClientForWorkspace("")
+	if err != nil {
+		return err
+	}
+
+	return s.retryRateLimited(ctx, func() error {
+		return client.AddReactionContext(ctx, emoji, slack.ItemRef{
+			Channel:   channelID,
+			Timestamp: timestamp,
+		})
+	})
+}
+
+// OpenView opens a modal for the user who triggered an interaction
+func (s *RealSlackAPI) OpenView(ctx context.Context, workspaceID, triggerID string, view slack.ModalViewRequest) error {
+	client, err := s.GetClientForWorkspace(workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return s.retryRateLimited(ctx, func() error {
+		_, err := client.OpenViewContext(ctx, triggerID, view)
+		return err
+	})
+}
+
+// GetMessage fetches a single message, which needs the history scope for the kind of conversation
+// it's in, like channels:history. Replies in threads aren't in the channel's history, so they're
+// looked up among the thread's replies instead
+func (s *RealSlackAPI) GetMessage(ctx context.Context, workspaceID, channelID, timestamp string) (*slack.Message, error) {
+	client, err := s.GetClientForWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var history *slack.GetConversationHistoryResponse
+	err = s.retryRateLimited(ctx, func() error {
+		var historyErr error
+		history, historyErr = client.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+			ChannelID: channelID,
+			Latest:    timestamp,
+			Oldest:    timestamp,
+			Inclusive: true,
+			Limit:     1,
+		})
+		return historyErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	if message := findMessage(history.Messages, timestamp); message != nil {
+		return message, nil
+	}
+
+	var replies []slack.Message
+	err = s.retryRateLimited(ctx, func() error {
+		var repliesErr error
+		replies, _, _, repliesErr = client.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+			ChannelID: channelID,
+			Timestamp: timestamp,
+			Latest:    timestamp,
+			Oldest:    timestamp,
+			Inclusive: true,
+		})
+		return repliesErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	if message := findMessage(replies, timestamp); message != nil {
+		return message, nil
+	}
+	return nil, fmt.Errorf("message %s not found in channel %s", timestamp, channelID)
+}
+
+// findMessage returns the message with the timestamp, or nil if there isn't one
+func findMessage(messages []slack.Message, timestamp string) *slack.Message {
+	for i := range messages {
+		if messages[i].Timestamp == timestamp {
+			return &messages[i]
+		}
+	}
+	return nil
+}
+
+// GetUserTimeZone looks up the user's time zone, which needs the users:read scope
+// Zones the time zone database doesn't know are approximated by the user's current offset
+func (s *RealSlackAPI) GetUserTimeZone(ctx context.Context, workspaceID, userID string) (*time.Location, error) {
+	client, err := s.GetClientForWorkspace(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *slack.User
+	err = s.retryRateLimited(ctx, func() error {
+		var lookupErr error
+		user, lookupErr = client.GetUserInfoContext(ctx, userID)
+		return lookupErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TZ != "" {
+		if location, err := time.LoadLocation(user.TZ); err == nil {
+			return location, nil
+		}
+	}
+	return time.FixedZone(user.TZLabel, user.TZOffset), nil
+}
+
+// VerifyToken checks the single workspace bot token with auth.test, returning who it belongs to
+func (s *RealSlackAPI) VerifyToken(ctx context.Context) (*slack.AuthTestResponse, error) {
+	if s.client == nil {
+		return nil, errors.New("no Slack bot token configured")
+	}
+
+	var identity *slack.AuthTestResponse
+	err := s.retryRateLimited(ctx, func() error {
+		var authErr error
+		identity, authErr = s.client.AuthTestContext(ctx)
+		return authErr
+	})
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// MockReaction records a reaction added through the mock API
+type MockReaction struct {
+	ChannelID string
+	Timestamp string
+	Emoji     string
+}
+
+// MockView records a modal opened through the mock API
+type MockView struct {
+	TriggerID string
+	View      slack.ModalViewRequest
 }
 
 // MockSlackAPI provides a mock implementation for testing
 type MockSlackAPI struct {
-	SentMessages []SlackResponse
+	SentMessages      []SlackResponse
+	EphemeralMessages []SlackResponse
+	Reactions         []MockReaction
+	Views             []MockView
+	UserTimeZones     map[string]*time.Location // Time zones GetUserTimeZone returns, by user ID
+	ChannelMessages   map[string]slack.Message  // Messages GetMessage returns, added with AddMessage
+	mutex             sync.Mutex
 }
 
 // NewMockSlackAPI creates a new mock Slack API
 func NewMockSlackAPI() *MockSlackAPI {
 	return &MockSlackAPI{
-		SentMessages: make([]SlackResponse, 0),
+		SentMessages:      make([]SlackResponse, 0),
+		EphemeralMessages: make([]SlackResponse, 0),
+		Reactions:         make([]MockReaction, 0),
 	}
 }
 
-// PostMessage simulates posting a message to Slack
-func (m *MockSlackAPI) PostMessage(response SlackResponse) error {
+// PostMessage simulates posting a message to Slack, failing like the real API once the context is cancelled
+func (m *MockSlackAPI) PostMessage(ctx context.Context, response SlackResponse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.SentMessages = append(m.SentMessages, response)
 	log.Printf("Mock: Message sent to channel %s: %s", response.ChannelID, response.Text)
 	return nil
 }
 
+// Messages returns a copy of the messages sent so far, safe to call while events are processed
+func (m *MockSlackAPI) Messages() []SlackResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]SlackResponse(nil), m.SentMessages...)
+}
+
+// PostEphemeral simulates posting a message only response.UserID can see
+func (m *MockSlackAPI) PostEphemeral(ctx context.Context, response SlackResponse) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.EphemeralMessages = append(m.EphemeralMessages, response)
+	log.Printf("Mock: Ephemeral message sent to user %s in channel %s: %s", response.UserID, response.ChannelID, response.Text)
+	return nil
+}
+
+// Ephemerals returns a copy of the ephemeral messages sent so far
+func (m *MockSlackAPI) Ephemerals() []SlackResponse {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]SlackResponse(nil), m.EphemeralMessages...)
+}
+
+// AddReaction simulates adding a reaction to a message
+func (m *MockSlackAPI) AddReaction(ctx context.Context, channelID, timestamp, emoji string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Reactions = append(m.Reactions, MockReaction{
+		ChannelID: channelID,
+		Timestamp: timestamp,
+		Emoji:     emoji,
+	})
+	log.Printf("Mock: Reaction :%s: added to message %s in channel %s", emoji, timestamp, channelID)
+	return nil
+}
+
+// OpenView simulates opening a modal
+func (m *MockSlackAPI) OpenView(ctx context.Context, workspaceID, triggerID string, view slack.ModalViewRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.Views = append(m.Views, MockView{TriggerID: triggerID, View: view})
+	log.Printf("Mock: View %s opened for trigger %s", view.CallbackID, triggerID)
+	return nil
+}
+
+// AddMessage stores a message for GetMessage to return
+func (m *MockSlackAPI) AddMessage(channelID string, message slack.Message) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.ChannelMessages == nil {
+		m.ChannelMessages = make(map[string]slack.Message)
+	}
+	m.ChannelMessages[channelID+"/"+message.Timestamp] = message
+}
+
+// GetMessage returns a message stored with AddMessage, or an error if there isn't one
+func (m *MockSlackAPI) GetMessage(ctx context.Context, workspaceID, channelID, timestamp string) (*slack.Message, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if message, ok := m.ChannelMessages[channelID+"/"+timestamp]; ok {
+		return &message, nil
+	}
+	return nil, fmt.Errorf("message %s not found in channel %s", timestamp, channelID)
+}
+
+// GetUserTimeZone returns the user's time zone from UserTimeZones, or an error if it isn't there
+func (m *MockSlackAPI) GetUserTimeZone(ctx context.Context, workspaceID, userID string) (*time.Location, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if location, ok := m.UserTimeZones[userID]; ok {
+		return location, nil
+	}
+	return nil, fmt.Errorf("no time zone for user %s", userID)
+}
+
 // GetClientForWorkspace is a mock implementation
 func (m *MockSlackAPI) GetClientForWorkspace(workspaceID string) (*slack.Client, error) {
 	return nil, nil
@@ -0,0 +1,209 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRateLimitedSlackAPI creates a RealSlackAPI talking to a fake Slack that rate limits
+// the first rateLimited calls, recording the waits instead of sleeping
+func newRateLimitedSlackAPI(t *testing.T, rateLimited int32) (*RealSlackAPI, *int32, *[]time.Duration) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= rateLimited {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "channel": "C12345", "ts": "1234567890.123456"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	var waits []time.Duration
+	api := NewRealSlackAPI("xoxb-test")
+	api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+	api.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	return api, &calls, &waits
+}
+
+func TestRealSlackAPI_PostMessageRetriesRateLimits(t *testing.T) {
+	api, calls, waits := newRateLimitedSlackAPI(t, 2)
+
+	err := api.PostMessage(context.Background(), SlackResponse{ChannelID: "C12345", Text: "That's 10 Bunnings snags!"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	// Slack's Retry-After is used until the backoff grows past it
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second}, *waits)
+}
+
+func TestRealSlackAPI_PostMessageGivesUpAfterMaxAttempts(t *testing.T) {
+	api, calls, waits := newRateLimitedSlackAPI(t, 100)
+
+	err := api.PostMessage(context.Background(), SlackResponse{ChannelID: "C12345", Text: "That's 10 Bunnings snags!"})
+	var rateLimited *slack.RateLimitedError
+	assert.ErrorAs(t, err, &rateLimited)
+	assert.Equal(t, int32(DefaultMaxAttempts), atomic.LoadInt32(calls))
+	assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 4 * time.Second}, *waits)
+}
+
+func TestRealSlackAPI_PostMessageDoesNotRetryOtherErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": false, "error": "channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	api := NewRealSlackAPI("xoxb-test")
+	api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+	api.sleep = func(context.Context, time.Duration) error {
+		t.Fatal("should not wait for non rate limit errors")
+		return nil
+	}
+
+	err := api.PostMessage(context.Background(), SlackResponse{ChannelID: "C12345", Text: "That's 10 Bunnings snags!"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRealSlackAPI_PostMessageStopsWaitingWhenCancelled(t *testing.T) {
+	api, calls, _ := newRateLimitedSlackAPI(t, 100)
+	api.sleep = sleepContext
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Slack asks for a two second wait, but the cancellation cuts it short
+	start := time.Now()
+	err := api.PostMessage(ctx, SlackResponse{ChannelID: "C12345", Text: "That's 10 Bunnings snags!"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRealSlackAPI_PostEphemeral(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postEphemeral", r.URL.Path)
+		assert.NoError(t, r.ParseForm())
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true, "message_ts": "1234567890.123456"}`))
+	}))
+	defer server.Close()
+
+	api := NewRealSlackAPI("xoxb-test")
+	api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	err := api.PostEphemeral(context.Background(), SlackResponse{ChannelID: "C12345", UserID: "U12345", Text: "That's 10 Bunnings snags!"})
+	assert.NoError(t, err)
+	assert.Equal(t, "C12345", form.Get("channel"))
+	assert.Equal(t, "U12345", form.Get("user"))
+	assert.Equal(t, "That's 10 Bunnings snags!", form.Get("text"))
+
+	// Ephemeral messages need someone to show them to
+	err = api.PostEphemeral(context.Background(), SlackResponse{ChannelID: "C12345", Text: "That's 10 Bunnings snags!"})
+	assert.Error(t, err)
+}
+
+func TestRealSlackAPI_GetUserTimeZone(t *testing.T) {
+	tests := []struct {
+		name           string
+		user           string
+		expectedName   string
+		expectedOffset int
+	}{
+		{
+			name:           "Known time zone",
+			user:           `{"id": "U12345", "tz": "Australia/Sydney", "tz_label": "Australian Eastern Daylight Time", "tz_offset": 39600}`,
+			expectedName:   "Australia/Sydney",
+			expectedOffset: 39600,
+		},
+		{
+			name:           "Unknown time zone uses the offset",
+			user:           `{"id": "U12345", "tz": "Mars/Olympus_Mons", "tz_label": "Mars Time", "tz_offset": -3600}`,
+			expectedName:   "Mars Time",
+			expectedOffset: -3600,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/users.info", r.URL.Path)
+				assert.NoError(t, r.ParseForm())
+				assert.Equal(t, "U12345", r.Form.Get("user"))
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"ok": true, "user": ` + tt.user + `}`))
+			}))
+			t.Cleanup(server.Close)
+
+			api := NewRealSlackAPI("xoxb-test")
+			api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+			location, err := api.GetUserTimeZone(context.Background(), "", "U12345")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedName, location.String())
+
+			// Mid-January, so Sydney is on daylight time
+			_, offset := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC).In(location).Zone()
+			assert.Equal(t, tt.expectedOffset, offset)
+		})
+	}
+}
+
+func TestRealSlackAPI_GetMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "C12345", r.Form.Get("channel"))
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.URL.Path == "/conversations.history" && r.Form.Get("latest") == "1111111111.000001":
+			w.Write([]byte(`{"ok": true, "messages": [{"type": "message", "user": "U12345", "text": "Lunch was $35", "ts": "1111111111.000001"}]}`))
+		case r.URL.Path == "/conversations.history":
+			w.Write([]byte(`{"ok": true, "messages": []}`))
+		case r.URL.Path == "/conversations.replies" && r.Form.Get("ts") == "2222222222.000002":
+			w.Write([]byte(`{"ok": true, "messages": [{"type": "message", "user": "U12345", "text": "Dinner was $50", "ts": "2222222222.000002", "thread_ts": "1111111111.000001"}]}`))
+		case r.URL.Path == "/conversations.replies":
+			w.Write([]byte(`{"ok": false, "error": "thread_not_found"}`))
+		default:
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	api := NewRealSlackAPI("xoxb-test")
+	api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	// Messages in the channel come from its history
+	message, err := api.GetMessage(context.Background(), "", "C12345", "1111111111.000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "Lunch was $35", message.Text)
+
+	// Replies in threads are found among the thread's replies
+	message, err = api.GetMessage(context.Background(), "", "C12345", "2222222222.000002")
+	assert.NoError(t, err)
+	assert.Equal(t, "Dinner was $50", message.Text)
+	assert.Equal(t, "1111111111.000001", message.ThreadTimestamp)
+
+	_, err = api.GetMessage(context.Background(), "", "C12345", "3333333333.000003")
+	assert.ErrorContains(t, err, "thread_not_found")
+}
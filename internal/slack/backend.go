@@ -0,0 +1,100 @@
+package slack
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// StoreFactory creates a channel config store for a STORE_BACKEND from the application config
+type StoreFactory func(cfg *config.Config) (ChannelConfigStore, error)
+
+// storeBackends holds the registered config store backends by name
+var (
+	storeBackends      = map[string]StoreFactory{}
+	storeBackendsMutex sync.RWMutex
+)
+
+func init() {
+	RegisterStoreBackend(config.StoreBackendRedis, newRedisStoreBackend)
+	RegisterStoreBackend(config.StoreBackendMemory, newMemoryStoreBackend)
+	RegisterStoreBackend(config.StoreBackendSQLite, newSQLiteStoreBackend)
+}
+
+// RegisterStoreBackend makes a config store backend available under name, replacing any
+// backend already registered with that name
+// Register backends before the stores are created, usually from an init function
+func RegisterStoreBackend(name string, factory StoreFactory) {
+	storeBackendsMutex.Lock()
+	defer storeBackendsMutex.Unlock()
+	storeBackends[name] = factory
+}
+
+// StoreBackends returns the names of the registered config store backends, sorted
+func StoreBackends() []string {
+	storeBackendsMutex.RLock()
+	defer storeBackendsMutex.RUnlock()
+
+	names := make([]string, 0, len(storeBackends))
+	for name := range storeBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newStoreFromBackend creates a config store using the named backend
+func newStoreFromBackend(name string, cfg *config.Config) (ChannelConfigStore, error) {
+	storeBackendsMutex.RLock()
+	factory, ok := storeBackends[name]
+	storeBackendsMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown store backend %q, registered backends are %v", name, StoreBackends())
+	}
+	return factory(cfg)
+}
+
+// newRedisStoreBackend creates a Redis config store from REDIS_URL, caching channel configs
+// in memory for CONFIG_CACHE_TTL_MS if it's set
+func newRedisStoreBackend(cfg *config.Config) (ChannelConfigStore, error) {
+	if cfg == nil || cfg.RedisURL == "" {
+		return nil, fmt.Errorf("the redis store backend needs REDIS_URL to be set")
+	}
+	store, err := NewRedisConfigStore(cfg.RedisURL, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedConfigStore(store, cfg.ConfigCacheTTL), nil
+}
+
+// newSQLiteStoreBackend creates a SQLite config store in the SQLITE_PATH database file
+func newSQLiteStoreBackend(cfg *config.Config) (ChannelConfigStore, error) {
+	if cfg == nil || cfg.SQLitePath == "" {
+		return nil, fmt.Errorf("the sqlite store backend needs SQLITE_PATH to be set")
+	}
+	return NewSQLiteConfigStore(cfg.SQLitePath, cfg)
+}
+
+// newMemoryStoreBackend creates an in-memory config store, restoring configs saved by a previous
+// run if persistence is enabled and evicting unused configs if MEMORY_CONFIG_TTL_SECONDS is set
+// A config file that can't be loaded is never saved over
+func newMemoryStoreBackend(cfg *config.Config) (ChannelConfigStore, error) {
+	store := NewInMemoryConfigStoreWithConfig(cfg)
+
+	if cfg != nil && cfg.ConfigStorePath != "" {
+		if err := store.LoadFromFile(cfg.ConfigStorePath); err != nil {
+			logging.Error("Failed to load channel configs from %s, starting empty and leaving the file alone: %v", cfg.ConfigStorePath, err)
+			store.blockSaving()
+		}
+	}
+
+	if cfg != nil && cfg.MemoryConfigTTL > 0 {
+		store.StartJanitor(min(cfg.MemoryConfigTTL, MaxJanitorInterval), cfg.MemoryConfigTTL)
+	}
+
+	return store, nil
+}
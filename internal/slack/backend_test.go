@@ -0,0 +1,87 @@
+package slack
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConfigStore is a config store from a backend registered by a test
+type fakeConfigStore struct {
+	*InMemoryConfigStore
+}
+
+func TestRegisterStoreBackend(t *testing.T) {
+	var created *config.Config
+	RegisterStoreBackend("fake", func(cfg *config.Config) (ChannelConfigStore, error) {
+		created = cfg
+		return &fakeConfigStore{NewInMemoryConfigStoreWithConfig(cfg)}, nil
+	})
+	t.Cleanup(func() {
+		storeBackendsMutex.Lock()
+		delete(storeBackends, "fake")
+		storeBackendsMutex.Unlock()
+	})
+
+	assert.Contains(t, StoreBackends(), "fake")
+
+	cfg := &config.Config{StoreBackend: "fake", DefaultItemName: "coffee", DefaultItemPrice: 5.00}
+	service, err := NewSlackService(cfg)
+	require.NoError(t, err)
+
+	require.IsType(t, &fakeConfigStore{}, service.ConfigStore)
+	assert.Same(t, cfg, created)
+}
+
+func TestNewConfigStore_Backends(t *testing.T) {
+	assert.Equal(t, []string{config.StoreBackendMemory, config.StoreBackendRedis, config.StoreBackendSQLite}, StoreBackends())
+
+	t.Run("Memory", func(t *testing.T) {
+		store, err := NewConfigStore(&config.Config{StoreBackend: config.StoreBackendMemory})
+		require.NoError(t, err)
+		assert.IsType(t, &InMemoryConfigStore{}, store)
+	})
+
+	t.Run("Memory with a TTL starts the janitor", func(t *testing.T) {
+		store, err := NewConfigStore(&config.Config{StoreBackend: config.StoreBackendMemory, MemoryConfigTTL: time.Hour})
+		require.NoError(t, err)
+		memoryStore, ok := store.(*InMemoryConfigStore)
+		if assert.True(t, ok) {
+			assert.NotNil(t, memoryStore.janitorStop)
+			assert.NoError(t, memoryStore.Close())
+		}
+	})
+
+	t.Run("Unknown backend falls back to memory", func(t *testing.T) {
+		store, err := NewConfigStore(&config.Config{StoreBackend: "dynamodb"})
+		require.NoError(t, err)
+		assert.IsType(t, &InMemoryConfigStore{}, store)
+	})
+
+	t.Run("Redis without a URL falls back to memory", func(t *testing.T) {
+		store, err := NewConfigStore(&config.Config{StoreBackend: config.StoreBackendRedis})
+		require.NoError(t, err)
+		assert.IsType(t, &InMemoryConfigStore{}, store)
+	})
+
+	t.Run("SQLite", func(t *testing.T) {
+		store, err := NewConfigStore(&config.Config{SQLitePath: filepath.Join(t.TempDir(), "snagbot.db")})
+		require.NoError(t, err)
+		assert.IsType(t, &SQLiteConfigStore{}, store)
+		assert.NoError(t, store.Close())
+	})
+
+	t.Run("SQLite without a path is an error", func(t *testing.T) {
+		_, err := NewConfigStore(&config.Config{StoreBackend: config.StoreBackendSQLite})
+		assert.Error(t, err)
+	})
+
+	t.Run("SQLite that can't be opened is an error", func(t *testing.T) {
+		_, err := NewConfigStore(&config.Config{SQLitePath: filepath.Join(t.TempDir(), "missing", "snagbot.db")})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,23 @@
+package slack
+
+// IsOwnMessage reports whether a message was posted by SnagBot, by any instance
+// SnagBot's identity comes from SLACK_BOT_USER_ID and SLACK_BOT_ID, or auth.test at startup
+func (o *ProcessorOptions) IsOwnMessage(botID, userID string) bool {
+	if o == nil {
+		return false
+	}
+	return (botID != "" && botID == o.ownBotID) || (userID != "" && userID == o.ownUserID)
+}
+
+// SkipsBotMessage reports whether a message should be skipped because a bot posted it. SnagBot's
+// own messages always are, and other bots' are unless BOT_MESSAGES is respond
+func (o *ProcessorOptions) SkipsBotMessage(botID, userID, subType string) bool {
+	if o.IsOwnMessage(botID, userID) {
+		return true
+	}
+	if botID == "" && subType != "bot_message" {
+		return false
+	}
+	// Without knowing which bot is SnagBot, responding to bots could mean responding to itself
+	return o == nil || !o.respondToBots || (o.ownUserID == "" && o.ownBotID == "")
+}
@@ -0,0 +1,245 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// cachedConfigStore keeps channel configs in memory for a short time in front of another store,
+// so busy channels don't read the store on every message
+// Changes made through the cached store drop the channel's cached config straight away
+type cachedConfigStore struct {
+	ChannelConfigStore
+	cache *configCache
+}
+
+// configCache holds recently read configs, shared by every context-bound copy of a cached store
+type configCache struct {
+	ttl        time.Duration
+	now        func() time.Time // Injectable clock for testing
+	mutex      sync.Mutex
+	configs    map[string]cacheEntry[*models.ChannelConfig]
+	exists     map[string]cacheEntry[bool]
+	generation uint64 // Bumped by every invalidation, so reads that raced a change aren't cached
+}
+
+// cacheEntry is a cached value and when it stops being used
+type cacheEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// NewCachedConfigStore returns store with channel configs cached for ttl
+// The store is returned unchanged if ttl isn't positive
+func NewCachedConfigStore(store ChannelConfigStore, ttl time.Duration) ChannelConfigStore {
+	if ttl <= 0 {
+		return store
+	}
+
+	return &cachedConfigStore{
+		ChannelConfigStore: store,
+		cache: &configCache{
+			ttl:     ttl,
+			now:     time.Now,
+			configs: make(map[string]cacheEntry[*models.ChannelConfig]),
+			exists:  make(map[string]cacheEntry[bool]),
+		},
+	}
+}
+
+// GetConfig returns a copy of the channel's cached config, reading it from the store if it
+// isn't cached or has expired
+func (s *cachedConfigStore) GetConfig(channelID string) (*models.ChannelConfig, error) {
+	if config, ok := s.cache.config(channelID); ok {
+		return config, nil
+	}
+
+	generation := s.cache.currentGeneration()
+	config, err := s.ChannelConfigStore.GetConfig(channelID)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.storeConfig(channelID, config, generation)
+	return config, nil
+}
+
+// ConfigExists checks if a custom configuration exists for a channel, caching the answer
+func (s *cachedConfigStore) ConfigExists(channelID string) bool {
+	exists, err := s.ConfigExistsChecked(channelID)
+	if err != nil {
+		logging.Warn("Failed to check if config exists for channel %s: %v", channelID, err)
+		return false
+	}
+	return exists
+}
+
+// ConfigExistsChecked checks if a custom configuration exists for a channel like ConfigExists,
+// returning an error if the store couldn't be checked
+// Failed checks aren't cached
+func (s *cachedConfigStore) ConfigExistsChecked(channelID string) (bool, error) {
+	if exists, ok := s.cache.configExists(channelID); ok {
+		return exists, nil
+	}
+
+	generation := s.cache.currentGeneration()
+	exists, err := CheckConfigExists(s.ChannelConfigStore, channelID)
+	if err != nil {
+		return false, err
+	}
+	s.cache.storeExists(channelID, exists, generation)
+	return exists, nil
+}
+
+// UpdateConfig updates the channel's item and price, dropping its cached config
+func (s *cachedConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error {
+	defer s.cache.invalidate(channelID)
+	return s.ChannelConfigStore.UpdateConfig(channelID, itemName, itemPrice, userID)
+}
+
+// UpdateConfigChanged updates the channel's item and price like UpdateConfig, reporting whether
+// they changed
+func (s *cachedConfigStore) UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error) {
+	defer s.cache.invalidate(channelID)
+	return UpdateConfigChanged(s.ChannelConfigStore, channelID, itemName, itemPrice, userID)
+}
+
+// SaveConfig stores a complete channel configuration, dropping its cached config
+func (s *cachedConfigStore) SaveConfig(config *models.ChannelConfig) error {
+	if config != nil {
+		defer s.cache.invalidate(config.ChannelID)
+	}
+	return s.ChannelConfigStore.SaveConfig(config)
+}
+
+// ResetConfig removes the channel's configuration, dropping its cached config
+func (s *cachedConfigStore) ResetConfig(channelID, userID string) error {
+	defer s.cache.invalidate(channelID)
+	return s.ChannelConfigStore.ResetConfig(channelID, userID)
+}
+
+// WithContext ties the underlying store's calls to ctx, keeping the same cache
+func (s *cachedConfigStore) WithContext(ctx context.Context) ChannelConfigStore {
+	return &cachedConfigStore{
+		ChannelConfigStore: WithContext(ctx, s.ChannelConfigStore),
+		cache:              s.cache,
+	}
+}
+
+// GetWorkspaceDefault returns the workspace's default item from the underlying store, or nil
+// if it doesn't keep workspace defaults
+func (s *cachedConfigStore) GetWorkspaceDefault(workspaceID string) (*models.WorkspaceDefault, error) {
+	if defaults, ok := s.ChannelConfigStore.(WorkspaceDefaultsStore); ok {
+		return defaults.GetWorkspaceDefault(workspaceID)
+	}
+	return nil, nil
+}
+
+// SaveWorkspaceDefault stores the workspace's default item in the underlying store
+func (s *cachedConfigStore) SaveWorkspaceDefault(def *models.WorkspaceDefault) error {
+	if defaults, ok := s.ChannelConfigStore.(WorkspaceDefaultsStore); ok {
+		return defaults.SaveWorkspaceDefault(def)
+	}
+	return fmt.Errorf("workspace defaults aren't supported by this store")
+}
+
+// IsIgnored returns true if the channel or user is on the underlying store's ignore list
+func (s *cachedConfigStore) IsIgnored(channelID, userID string) bool {
+	return IsIgnored(s.ChannelConfigStore, channelID, userID)
+}
+
+// SetChannelIgnored adds the channel to the underlying store's ignore list, or removes it
+func (s *cachedConfigStore) SetChannelIgnored(channelID string, ignored bool) error {
+	if ignorer, ok := s.ChannelConfigStore.(IgnoreListStore); ok {
+		return ignorer.SetChannelIgnored(channelID, ignored)
+	}
+	return fmt.Errorf("ignore lists aren't supported by this store")
+}
+
+// SetUserIgnored adds the user to the underlying store's ignore list, or removes them
+func (s *cachedConfigStore) SetUserIgnored(userID string, ignored bool) error {
+	if ignorer, ok := s.ChannelConfigStore.(IgnoreListStore); ok {
+		return ignorer.SetUserIgnored(userID, ignored)
+	}
+	return fmt.Errorf("ignore lists aren't supported by this store")
+}
+
+// config returns a copy of the channel's cached config, if it's cached and hasn't expired
+func (c *configCache) config(channelID string) (*models.ChannelConfig, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.configs[channelID]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return cloneConfig(entry.value), true
+}
+
+// configExists returns whether the channel has a custom configuration, if that's cached and hasn't expired
+func (c *configCache) configExists(channelID string) (bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.exists[channelID]
+	if !ok || !c.now().Before(entry.expires) {
+		return false, false
+	}
+	return entry.value, true
+}
+
+// currentGeneration returns the generation to pass to storeConfig and storeExists once the store has been read
+func (c *configCache) currentGeneration() uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.generation
+}
+
+// storeConfig caches a copy of the channel's config, unless the cache was invalidated since generation
+func (c *configCache) storeConfig(channelID string, config *models.ChannelConfig, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.configs[channelID] = cacheEntry[*models.ChannelConfig]{value: cloneConfig(config), expires: c.now().Add(c.ttl)}
+}
+
+// storeExists caches whether the channel has a custom configuration, unless the cache was
+// invalidated since generation
+func (c *configCache) storeExists(channelID string, exists bool, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.exists[channelID] = cacheEntry[bool]{value: exists, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate drops the channel's cached config, along with anything expired
+func (c *configCache) invalidate(channelID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	delete(c.configs, channelID)
+	delete(c.exists, channelID)
+
+	now := c.now()
+	for id, entry := range c.configs {
+		if !now.Before(entry.expires) {
+			delete(c.configs, id)
+		}
+	}
+	for id, entry := range c.exists {
+		if !now.Before(entry.expires) {
+			delete(c.exists, id)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingConfigStore counts the reads that reach the store behind a cache
+type countingConfigStore struct {
+	*InMemoryConfigStore
+	gets   int
+	checks int
+	err    error // Returned by reads when set
+}
+
+func (s *countingConfigStore) GetConfig(channelID string) (*models.ChannelConfig, error) {
+	s.gets++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.InMemoryConfigStore.GetConfig(channelID)
+}
+
+func (s *countingConfigStore) ConfigExists(channelID string) bool {
+	s.checks++
+	return s.InMemoryConfigStore.ConfigExists(channelID)
+}
+
+// newTestCachedConfigStore creates a cached store in front of a counting store, with a clock the test controls
+func newTestCachedConfigStore(ttl time.Duration) (*cachedConfigStore, *countingConfigStore, *time.Time) {
+	counting := &countingConfigStore{InMemoryConfigStore: NewInMemoryConfigStore()}
+	store := NewCachedConfigStore(counting, ttl).(*cachedConfigStore)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.cache.now = func() time.Time { return now }
+	return store, counting, &now
+}
+
+func TestNewCachedConfigStore_Disabled(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	assert.Same(t, store, NewCachedConfigStore(store, 0))
+}
+
+func TestCachedConfigStore_GetConfig(t *testing.T) {
+	store, counting, now := newTestCachedConfigStore(5 * time.Second)
+	assert.NoError(t, counting.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	// A second read within the TTL is served from the cache
+	config, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 1, counting.gets)
+
+	// Changing the returned config doesn't change the cached one
+	config.ItemName = "tea"
+	config, _ = store.GetConfig("C12345")
+	assert.Equal(t, "coffee", config.ItemName)
+
+	// Once the TTL has passed the store is read again
+	*now = now.Add(5 * time.Second)
+	_, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, counting.gets)
+}
+
+func TestCachedConfigStore_GetConfigError(t *testing.T) {
+	store, counting, _ := newTestCachedConfigStore(5 * time.Second)
+	counting.err = errors.New("connection refused")
+
+	_, err := store.GetConfig("C12345")
+	assert.Error(t, err)
+
+	// Failed reads aren't cached
+	counting.err = nil
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.NotNil(t, config)
+	assert.Equal(t, 2, counting.gets)
+}
+
+func TestCachedConfigStore_ConfigExists(t *testing.T) {
+	store, counting, _ := newTestCachedConfigStore(5 * time.Second)
+
+	assert.False(t, store.ConfigExists("C12345"))
+	assert.False(t, store.ConfigExists("C12345"))
+	assert.Equal(t, 1, counting.checks)
+
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.True(t, store.ConfigExists("C12345"))
+	assert.Equal(t, 2, counting.checks)
+}
+
+func TestCachedConfigStore_Invalidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(store ChannelConfigStore) error
+		wantItem string
+	}{
+		{
+			name: "update",
+			change: func(store ChannelConfigStore) error {
+				return store.UpdateConfig("C12345", "tea", 4.00, "U12345")
+			},
+			wantItem: "tea",
+		},
+		{
+			name: "update reporting changes",
+			change: func(store ChannelConfigStore) error {
+				_, err := UpdateConfigChanged(store, "C12345", "tea", 4.00, "U12345")
+				return err
+			},
+			wantItem: "tea",
+		},
+		{
+			name: "save",
+			change: func(store ChannelConfigStore) error {
+				return store.SaveConfig(&models.ChannelConfig{ChannelID: "C12345", ItemName: "tea", ItemPrice: 4.00})
+			},
+			wantItem: "tea",
+		},
+		{
+			name: "reset",
+			change: func(store ChannelConfigStore) error {
+				return store.ResetConfig("C12345", "U12345")
+			},
+			wantItem: "Bunnings snags",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store, counting, _ := newTestCachedConfigStore(time.Minute)
+			assert.NoError(t, counting.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+			config, err := store.GetConfig("C12345")
+			assert.NoError(t, err)
+			assert.Equal(t, "coffee", config.ItemName)
+
+			assert.NoError(t, tc.change(store))
+
+			config, err = store.GetConfig("C12345")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantItem, config.ItemName)
+			assert.Equal(t, 2, counting.gets)
+		})
+	}
+}
+
+func TestCachedConfigStore_InvalidationDuringRead(t *testing.T) {
+	store, counting, _ := newTestCachedConfigStore(time.Minute)
+	assert.NoError(t, counting.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	// A read that started before a change mustn't cache what it read
+	generation := store.cache.currentGeneration()
+	stale, _ := counting.GetConfig("C12345")
+	assert.NoError(t, store.UpdateConfig("C12345", "tea", 4.00, "U12345"))
+	store.cache.storeConfig("C12345", stale, generation)
+
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "tea", config.ItemName)
+}
+
+func TestCachedConfigStore_WithContext(t *testing.T) {
+	store, counting, _ := newTestCachedConfigStore(time.Minute)
+
+	// Context-bound copies share the cache
+	_, err := WithContext(context.Background(), store).GetConfig("C12345")
+	assert.NoError(t, err)
+	_, err = WithContext(context.Background(), store).GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counting.gets)
+
+	assert.NoError(t, WithContext(context.Background(), store).UpdateConfig("C12345", "tea", 4.00, "U12345"))
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "tea", config.ItemName)
+}
+
+func TestCachedConfigStore_Redis(t *testing.T) {
+	redisStore, server := newTestRedisConfigStore(t)
+	store := NewCachedConfigStore(redisStore, time.Minute)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	config, err := ForWorkspace(store, "T12345").GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	// With Redis gone, the cached config is still returned
+	server.Close()
+	config, err = ForWorkspace(store, "T12345").GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+}
+
+func TestTokenStoreFor_CachedRedis(t *testing.T) {
+	redisStore, _ := newTestRedisConfigStore(t)
+	cfg := &config.Config{EnableMultiWorkspace: true}
+
+	_, ok := TokenStoreFor(cfg, NewCachedConfigStore(redisStore, time.Minute)).(*RedisTokenStore)
+	assert.True(t, ok, "Expected the cached Redis store to keep tokens in Redis")
+}
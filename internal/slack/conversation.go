@@ -0,0 +1,27 @@
+package slack
+
+import "strings"
+
+// Conversation types, told apart by the first letter of a Slack conversation ID
+const (
+	ConversationChannel = "channel" // C, public and newer private channels
+	ConversationDirect  = "direct"  // D, a direct message with SnagBot
+	ConversationGroup   = "group"   // G, older private channels and group direct messages
+)
+
+// ConversationType returns the type of the conversation with the ID, treating unknown prefixes as channels
+func ConversationType(channelID string) string {
+	switch {
+	case strings.HasPrefix(channelID, "D"):
+		return ConversationDirect
+	case strings.HasPrefix(channelID, "G"):
+		return ConversationGroup
+	default:
+		return ConversationChannel
+	}
+}
+
+// IsDirectMessage reports whether the conversation is a direct message with SnagBot
+func IsDirectMessage(channelID string) bool {
+	return ConversationType(channelID) == ConversationDirect
+}
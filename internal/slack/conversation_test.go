@@ -0,0 +1,17 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversationType(t *testing.T) {
+	assert.Equal(t, ConversationChannel, ConversationType("C12345"))
+	assert.Equal(t, ConversationDirect, ConversationType("D12345"))
+	assert.Equal(t, ConversationGroup, ConversationType("G12345"))
+	assert.Equal(t, ConversationChannel, ConversationType(""))
+
+	assert.True(t, IsDirectMessage("D12345"))
+	assert.False(t, IsDirectMessage("G12345"))
+}
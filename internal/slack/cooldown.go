@@ -0,0 +1,52 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+// CooldownTracker limits how often SnagBot responds in each channel
+type CooldownTracker struct {
+	window       time.Duration
+	lastResponse map[string]time.Time
+	mutex        sync.Mutex
+	now          func() time.Time // Injectable clock for testing
+}
+
+// NewCooldownTracker creates a tracker allowing at most one response per window in each channel
+// A zero window disables the cooldown
+func NewCooldownTracker(window time.Duration) *CooldownTracker {
+	return &CooldownTracker{
+		window:       window,
+		lastResponse: make(map[string]time.Time),
+		now:          time.Now,
+	}
+}
+
+// Allow reports whether a response may be sent to the channel now, and if so
+// records the response time so later calls within the window are refused
+// A nil tracker always allows responses
+func (t *CooldownTracker) Allow(channelID string) bool {
+	if t == nil || t.window <= 0 {
+		return true
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+
+	// Forget channels whose window has passed so the map doesn't grow forever
+	for seenChannel, last := range t.lastResponse {
+		if now.Sub(last) >= t.window {
+			delete(t.lastResponse, seenChannel)
+		}
+	}
+
+	if last, ok := t.lastResponse[channelID]; ok && now.Sub(last) < t.window {
+		return false
+	}
+
+	t.lastResponse[channelID] = now
+	return true
+}
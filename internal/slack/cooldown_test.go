@@ -0,0 +1,97 @@
+package slack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for cooldown tests
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.current
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.current = c.current.Add(d)
+}
+
+func TestCooldownTracker_Allow(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	tracker := NewCooldownTracker(30 * time.Second)
+	tracker.now = clock.Now
+
+	// First response is allowed, the second within the window is not
+	assert.True(t, tracker.Allow("C12345"))
+	clock.Advance(10 * time.Second)
+	assert.False(t, tracker.Allow("C12345"))
+
+	// Other channels are tracked separately
+	assert.True(t, tracker.Allow("C67890"))
+
+	// Once the window has passed, responses are allowed again
+	clock.Advance(20 * time.Second)
+	assert.True(t, tracker.Allow("C12345"))
+}
+
+func TestCooldownTracker_ForgetsExpiredChannels(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	tracker := NewCooldownTracker(30 * time.Second)
+	tracker.now = clock.Now
+
+	assert.True(t, tracker.Allow("C12345"))
+	assert.True(t, tracker.Allow("C67890"))
+	assert.Len(t, tracker.lastResponse, 2)
+
+	// Channels past their window are dropped on the next call
+	clock.Advance(30 * time.Second)
+	assert.True(t, tracker.Allow("C11111"))
+	assert.Len(t, tracker.lastResponse, 1)
+	assert.Contains(t, tracker.lastResponse, "C11111")
+}
+
+func TestCooldownTracker_Disabled(t *testing.T) {
+	var nilTracker *CooldownTracker
+	assert.True(t, nilTracker.Allow("C12345"))
+	assert.True(t, nilTracker.Allow("C12345"))
+
+	tracker := NewCooldownTracker(0)
+	assert.True(t, tracker.Allow("C12345"))
+	assert.True(t, tracker.Allow("C12345"))
+}
+
+func TestProcessMessageEventWithCooldown(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	tracker := NewCooldownTracker(time.Minute)
+	tracker.now = clock.Now
+
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "This costs $35",
+		TS:        "1234567890.123456",
+	}
+
+	// The first message gets a response
+	assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event.ToSlackEvent(), store, mockAPI, tracker, nil))
+	assert.Len(t, mockAPI.SentMessages, 1)
+
+	// A second message within the window is skipped
+	clock.Advance(5 * time.Second)
+	event.TS = "1234567895.123456"
+	assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event.ToSlackEvent(), store, mockAPI, tracker, nil))
+	assert.Len(t, mockAPI.SentMessages, 1)
+
+	// After the window, responses resume
+	clock.Advance(time.Minute)
+	event.TS = "1234567965.123456"
+	assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event.ToSlackEvent(), store, mockAPI, tracker, nil))
+	assert.Len(t, mockAPI.SentMessages, 2)
+}
@@ -0,0 +1,108 @@
+package slack
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDedupeWindow is how long a handled message or event is remembered
+	DefaultDedupeWindow = 5 * time.Minute
+
+	// DefaultDedupeSize is the most messages and events remembered at once
+	DefaultDedupeSize = 10000
+)
+
+// Deduplicator remembers what has already been handled so Slack retries and
+// repeated deliveries only get one response
+type Deduplicator interface {
+	// FirstSeen reports whether the key is new, recording it if so
+	FirstSeen(key string) bool
+}
+
+// SeenCache is an in-memory Deduplicator that forgets keys after a window and
+// holds at most maxSize keys, dropping the oldest when full
+type SeenCache struct {
+	window  time.Duration
+	maxSize int
+	seen    map[string]time.Time
+	mutex   sync.Mutex
+	now     func() time.Time // Injectable clock for testing
+}
+
+// NewSeenCache creates a cache remembering keys for the given window, holding at most maxSize keys
+// Zero or negative values use DefaultDedupeWindow and DefaultDedupeSize
+func NewSeenCache(window time.Duration, maxSize int) *SeenCache {
+	if window <= 0 {
+		window = DefaultDedupeWindow
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultDedupeSize
+	}
+
+	return &SeenCache{
+		window:  window,
+		maxSize: maxSize,
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// FirstSeen reports whether the key hasn't been seen within the window, and
+// records it so later calls for the same key are refused
+// A nil cache treats every key as new
+func (c *SeenCache) FirstSeen(key string) bool {
+	if c == nil {
+		return true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+
+	// Forget expired keys so the map doesn't grow forever
+	for seenKey, seenAt := range c.seen {
+		if now.Sub(seenAt) >= c.window {
+			delete(c.seen, seenKey)
+		}
+	}
+
+	if _, ok := c.seen[key]; ok {
+		return false
+	}
+
+	if len(c.seen) >= c.maxSize {
+		c.evictOldest()
+	}
+
+	c.seen[key] = now
+	return true
+}
+
+// evictOldest drops the key seen longest ago, the caller must hold the mutex
+func (c *SeenCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, seenAt := range c.seen {
+		if oldestKey == "" || seenAt.Before(oldest) {
+			oldestKey, oldest = key, seenAt
+		}
+	}
+	delete(c.seen, oldestKey)
+}
+
+// messageKey identifies a Slack message, which may arrive as several events
+func messageKey(channelID, timestamp string) string {
+	return "message:" + channelID + ":" + timestamp
+}
+
+// reactionKey identifies a message someone asked SnagBot to convert with a reaction
+func reactionKey(channelID, timestamp string) string {
+	return "reaction:" + channelID + ":" + timestamp
+}
+
+// eventKey identifies a Slack event delivery, which Slack may retry
+func eventKey(eventID string) string {
+	return "event:" + eventID
+}
@@ -0,0 +1,104 @@
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeenCache_FirstSeen(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cache := NewSeenCache(time.Minute, 10)
+	cache.now = clock.Now
+
+	assert.True(t, cache.FirstSeen(messageKey("C12345", "1.0")))
+	assert.False(t, cache.FirstSeen(messageKey("C12345", "1.0")))
+	assert.True(t, cache.FirstSeen(messageKey("C67890", "1.0")))
+
+	// Keys are forgotten after the window
+	clock.Advance(time.Minute)
+	assert.True(t, cache.FirstSeen(messageKey("C12345", "1.0")))
+
+	// A nil cache treats every key as new
+	var nilCache *SeenCache
+	assert.True(t, nilCache.FirstSeen("anything"))
+}
+
+func TestSeenCache_MaxSize(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cache := NewSeenCache(time.Hour, 2)
+	cache.now = clock.Now
+
+	assert.True(t, cache.FirstSeen(eventKey("Ev1")))
+	clock.Advance(time.Second)
+	assert.True(t, cache.FirstSeen(eventKey("Ev2")))
+	clock.Advance(time.Second)
+
+	// Adding a third key drops the oldest
+	assert.True(t, cache.FirstSeen(eventKey("Ev3")))
+	assert.Len(t, cache.seen, 2)
+	assert.False(t, cache.FirstSeen(eventKey("Ev3")))
+	assert.True(t, cache.FirstSeen(eventKey("Ev1")))
+}
+
+func TestEventHandler_SkipsRetriedEvents(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	mockAPI := NewMockSlackAPI()
+	handler := EventHandlerWithDeduplicator(context.Background(), nil, cfg, NewInMemoryConfigStore(), nil, mockAPI, NewSeenCache(time.Minute, 100), nil)
+
+	body := `{
+		"type": "event_callback",
+		"event_id": "Ev12345",
+		"event": {
+			"type": "message",
+			"channel": "C12345",
+			"user": "U12345",
+			"text": "This costs $35",
+			"ts": "1234567890.123456"
+		}
+	}`
+
+	// Slack delivers the event, then retries it with the same event ID
+	for retry := 0; retry < 2; retry++ {
+		req := signedEventRequest(t, cfg.SlackSigningSecret, body)
+		if retry > 0 {
+			req.Header.Set("X-Slack-Retry-Num", strconv.Itoa(retry))
+			req.Header.Set("X-Slack-Retry-Reason", "http_timeout")
+		}
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// The first delivery is processed in the background
+	assert.Eventually(t, func() bool { return len(mockAPI.Messages()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// The retry was dropped before processing, so there's still only one reply
+	assert.Len(t, mockAPI.Messages(), 1)
+}
+
+// signedEventRequest builds an events request signed the way Slack signs them
+func signedEventRequest(t *testing.T, secret, body string) *http.Request {
+	t.Helper()
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
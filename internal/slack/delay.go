@@ -0,0 +1,61 @@
+package slack
+
+import (
+	"context"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// delayedSlackAPI pauses before each response so SnagBot doesn't answer the instant a message
+// is posted. Slack's Web API has no typing indicator for bots, so the pause stands in for one
+type delayedSlackAPI struct {
+	SlackAPI
+	delay time.Duration
+	sleep func(ctx context.Context, d time.Duration) error // Injectable for testing
+}
+
+// WithResponseDelay returns the API with a pause before each message, private reply and reaction
+// The pause happens while the event is processed, after Slack has had its 200, and is cut short
+// if ctx is cancelled. A zero or negative delay returns api unchanged
+func WithResponseDelay(api SlackAPI, delay time.Duration) SlackAPI {
+	if delay <= 0 {
+		return api
+	}
+
+	return &delayedSlackAPI{
+		SlackAPI: api,
+		delay:    delay,
+		sleep:    sleepContext,
+	}
+}
+
+// PostMessage waits for the delay, then posts the message
+func (d *delayedSlackAPI) PostMessage(ctx context.Context, response SlackResponse) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	return d.SlackAPI.PostMessage(ctx, response)
+}
+
+// PostEphemeral waits for the delay, then posts the private reply
+func (d *delayedSlackAPI) PostEphemeral(ctx context.Context, response SlackResponse) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	return d.SlackAPI.PostEphemeral(ctx, response)
+}
+
+// AddReaction waits for the delay, then adds the reaction
+func (d *delayedSlackAPI) AddReaction(ctx context.Context, channelID, timestamp, emoji string) error {
+	if err := d.wait(ctx); err != nil {
+		return err
+	}
+	return d.SlackAPI.AddReaction(ctx, channelID, timestamp, emoji)
+}
+
+// wait pauses for the delay, returning the context's error if it's cancelled first
+func (d *delayedSlackAPI) wait(ctx context.Context) error {
+	logging.Debug("Waiting %s before responding", d.delay)
+	return d.sleep(ctx, d.delay)
+}
@@ -0,0 +1,84 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithResponseDelay(t *testing.T) {
+	mockAPI := NewMockSlackAPI()
+	api := WithResponseDelay(mockAPI, 2*time.Second)
+
+	// Record each pause along with how many messages had been posted when it started
+	var waits []time.Duration
+	postedBeforeWait := -1
+	api.(*delayedSlackAPI).sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		postedBeforeWait = len(mockAPI.Messages())
+		return nil
+	}
+
+	event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+	assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), NewInMemoryConfigStore(), api))
+
+	assert.Equal(t, []time.Duration{2 * time.Second}, waits)
+	assert.Equal(t, 0, postedBeforeWait, "the pause should come before the message is posted")
+	assert.Len(t, mockAPI.Messages(), 1)
+
+	// Without a delay the API is used as is
+	assert.Same(t, mockAPI, WithResponseDelay(mockAPI, 0))
+}
+
+func TestWithResponseDelay_Cancelled(t *testing.T) {
+	mockAPI := NewMockSlackAPI()
+	api := WithResponseDelay(mockAPI, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, api.PostMessage(ctx, SlackResponse{ChannelID: "C12345", Text: "Hi"}), context.Canceled)
+	assert.Empty(t, mockAPI.Messages())
+}
+
+func TestEventHandler_ResponseDelayDoesNotBlock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &config.Config{SlackSigningSecret: "test-secret", ResponseDelay: time.Hour}
+	mockAPI := NewMockSlackAPI()
+	handler := EventHandlerWithDeduplicator(ctx, nil, cfg, NewInMemoryConfigStore(), nil, mockAPI, NewSeenCache(time.Minute, 100), nil)
+
+	body := `{
+		"type": "event_callback",
+		"event_id": "Ev12345",
+		"event": {
+			"type": "message",
+			"channel": "C12345",
+			"user": "U12345",
+			"text": "This costs $35",
+			"ts": "1234567890.123456"
+		}
+	}`
+
+	// Slack gets its 200 straight away, while the response waits in the background
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler(rec, signedEventRequest(t, cfg.SlackSigningSecret, body))
+		done <- rec.Code
+	}()
+
+	select {
+	case code := <-done:
+		assert.Equal(t, http.StatusOK, code)
+	case <-time.After(time.Second):
+		t.Fatal("handler blocked on the response delay")
+	}
+	assert.Empty(t, mockAPI.Messages())
+}
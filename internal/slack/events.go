@@ -1,27 +1,18 @@
 package slack
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
 	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
-	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
-// min returns the smaller of x or y.
-func min(x, y int) int {
-	if x < y {
-		return x
-	}
-	return y
-}
-
 // DebugHandler creates a simple handler for debugging environment variables
 // WARNING: This should be removed in production as it exposes sensitive information
 func DebugHandler(cfg *config.Config) http.HandlerFunc {
@@ -50,12 +41,13 @@ func DebugHandler(cfg *config.Config) http.HandlerFunc {
 			botTokenInfo = fmt.Sprintf("Length: %d, Prefix: %s", len(cfg.SlackBotToken), prefix)
 		}
 
+		defaultItemName, defaultItemPrice := cfg.DefaultItem()
 		debug := map[string]string{
 			"port":              cfg.Port,
 			"signingSecret":     signingSecretInfo,
 			"botToken":          botTokenInfo,
-			"defaultItemName":   cfg.DefaultItemName,
-			"defaultItemPrice":  fmt.Sprintf("%.2f", cfg.DefaultItemPrice),
+			"defaultItemName":   defaultItemName,
+			"defaultItemPrice":  fmt.Sprintf("%.2f", defaultItemPrice),
 			"timestamp":         time.Now().Format(time.RFC3339),
 			"environmentSource": "Go environment",
 		}
@@ -68,13 +60,58 @@ func DebugHandler(cfg *config.Config) http.HandlerFunc {
 	}
 }
 
+// DefaultEventTimeout is how long an event can take to process, including retries when Slack rate limits responses
+const DefaultEventTimeout = 30 * time.Second
+
 // EventHandler creates a handler for Slack events
 func EventHandler(cfg *config.Config) http.HandlerFunc {
-	// Create the configuration store
-	configStore := NewInMemoryConfigStoreWithConfig(cfg)
+	return EventHandlerWithStore(cfg, NewInMemoryConfigStoreWithConfig(cfg))
+}
+
+// EventHandlerWithStore creates a handler for Slack events using the given config store
+func EventHandlerWithStore(cfg *config.Config, configStore ChannelConfigStore) http.HandlerFunc {
+	return EventHandlerWithContext(context.Background(), nil, cfg, configStore, NewKillSwitch(cfg.Enabled))
+}
+
+// EventHandlerWithContext creates a handler for Slack events using the given config store
+// Events are processed by events' workers, if set, so shutdown can wait for them, and are
+// abandoned once ctx is cancelled
+// Replies and reactions are only posted while responses is on
+func EventHandlerWithContext(ctx context.Context, events *EventGroup, cfg *config.Config, configStore ChannelConfigStore, responses *KillSwitch) http.HandlerFunc {
+	return EventHandlerWithDeduplicator(ctx, events, cfg, configStore, TokenStoreFor(cfg, configStore), NewRealSlackAPI(cfg.SlackBotToken),
+		NewSeenCache(cfg.EventDedupeWindow, cfg.EventDedupeSize), responses)
+}
+
+// TokenStoreFor returns the workspace token store to use alongside the config store
+// Multi-workspace tokens live in the same Redis as the channel configs
+func TokenStoreFor(cfg *config.Config, configStore ChannelConfigStore) TokenStore {
+	if cached, ok := configStore.(*cachedConfigStore); ok {
+		configStore = cached.ChannelConfigStore
+	}
+	if redisStore, ok := configStore.(*RedisConfigStore); ok && cfg.EnableMultiWorkspace {
+		return NewRedisTokenStore(redisStore.client)
+	}
+	return NewSingleTokenStore(cfg)
+}
+
+// EventHandlerWithDeduplicator creates a handler for Slack events using the given config
+// store, token store, Slack API and deduplicator for retried events and repeated messages
+// Each event is processed by events' workers under ctx, for at most DefaultEventTimeout once it
+// starts. Without events, the handler gets its own pool sized by the config
+// A nil responses leaves replies and reactions always on
+func EventHandlerWithDeduplicator(ctx context.Context, events *EventGroup, cfg *config.Config, configStore ChannelConfigStore, tokenStore TokenStore, api SlackAPI, deduper Deduplicator, responses *KillSwitch) http.HandlerFunc {
+	if events == nil {
+		events = NewEventGroup(cfg.EventWorkers, cfg.EventQueueSize)
+	}
+
+	// Limit how often each channel gets a response
+	cooldown := NewCooldownTracker(cfg.ResponseCooldown)
+	opts := NewProcessorOptions(cfg)
+	opts.responses = responses
+	verifier := NewRequestVerifier(cfg.SlackSigningSecret, cfg.SlackRequestMaxAge)
 
-	// Create the Slack API client
-	api := NewRealSlackAPI(cfg.SlackBotToken)
+	// Pause before responding if configured, while the event is processed in the background
+	api = WithResponseDelay(api, cfg.ResponseDelay)
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Only allow POST requests for events
@@ -91,42 +128,18 @@ func EventHandler(cfg *config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Read request body
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			appErr := errors.WrapAndLog(err, "Error reading request body")
-			http.Error(w, appErr.Message, http.StatusBadRequest)
-			return
-		}
-
-		// Verify Slack signature
+		// Verify the Slack signature and that the request is recent
 		logging.Debug("Verifying Slack signature with secret of length: %d", len(cfg.SlackSigningSecret))
-		sv, err := slack.NewSecretsVerifier(r.Header, cfg.SlackSigningSecret)
-		if err != nil {
-			appErr := errors.WrapAndLog(err, "Error creating secrets verifier")
-			http.Error(w, appErr.Message, http.StatusBadRequest)
+		LimitRequestBody(w, r, cfg.MaxRequestBodySize)
+		body, err := verifier.Verify(r)
+		if IsRequestTooLarge(err) {
+			logging.Warn("Rejected oversized event request: %v", err)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
 			return
 		}
-
-		if _, err := sv.Write(body); err != nil {
-			appErr := errors.WrapAndLog(err, "Error writing to verifier")
-			http.Error(w, appErr.Message, http.StatusBadRequest)
-			return
-		}
-
-		if err := sv.Ensure(); err != nil {
-			// Handle signature validation error
+		if err != nil {
 			logging.Error("Signature verification failed: %v", err)
 			logging.Debug("Request headers: %v", r.Header)
-			logging.Debug("Body length: %d bytes", len(body))
-
-			// Don't log the entire body as it may contain sensitive information
-			if len(body) > 0 {
-				// Log just the first few bytes for debugging
-				maxBytes := min(len(body), 20)
-				logging.Debug("First %d bytes of body: %v", maxBytes, body[:maxBytes])
-			}
-
 			http.Error(w, "Invalid request signature", http.StatusUnauthorized)
 			return
 		}
@@ -165,19 +178,24 @@ func EventHandler(cfg *config.Config) http.HandlerFunc {
 				logging.Info("Received Slack callback event: %T", innerEvent.Data)
 			}
 
-			// Process the event in a goroutine to avoid blocking
-			go func() {
-				defer func() {
-					// Recover from any panics in the goroutine to prevent crashing
-					if r := recover(); r != nil {
-						logging.Error("Panic in event handler: %v", r)
-					}
-				}()
+			// Slack retries events it thinks we missed, so only handle each event once
+			if callback, ok := eventsAPIEvent.Data.(*slackevents.EventsAPICallbackEvent); ok && callback.EventID != "" {
+				if deduper != nil && !deduper.FirstSeen(eventKey(callback.EventID)) {
+					logging.Info("Skipping already handled event %s (retry %s)", callback.EventID, r.Header.Get("X-Slack-Retry-Num"))
+					return
+				}
+			}
+
+			// Queue the event for the worker pool to avoid blocking
+			// The request's context ends with this handler, so the event gets its own
+			events.Go(func() {
+				eventCtx, cancel := context.WithTimeout(ctx, DefaultEventTimeout)
+				defer cancel()
 
-				if err := handleCallbackEvent(eventsAPIEvent, configStore, api); err != nil {
+				if err := handleCallbackEvent(eventCtx, eventsAPIEvent, configStore, tokenStore, api, cooldown, deduper, opts); err != nil {
 					logging.Error("Error handling callback event: %v", err)
 				}
-			}()
+			})
 			return
 		}
 
@@ -188,14 +206,49 @@ func EventHandler(cfg *config.Config) http.HandlerFunc {
 }
 
 // handleCallbackEvent processes Slack callback events
-func handleCallbackEvent(event slackevents.EventsAPIEvent, configStore ChannelConfigStore, api SlackAPI) error {
+// A message mentioning SnagBot arrives as both a message and an app_mention event,
+// so the deduper makes sure only the first one gets a response
+func handleCallbackEvent(ctx context.Context, event slackevents.EventsAPIEvent, configStore ChannelConfigStore, tokenStore TokenStore, api SlackAPI, cooldown *CooldownTracker, deduper Deduplicator, opts *ProcessorOptions) error {
 	innerEvent := event.InnerEvent
 
+	// Channels without their own config use their workspace's default item
+	configStore = ForWorkspace(WithContext(ctx, configStore), event.TeamID)
+
 	// Check if it's a message event
 	switch ev := innerEvent.Data.(type) {
 	case *slackevents.MessageEvent:
+		// Leave messages waiting for their trigger word unrecorded, so the app_mention
+		// for a message mentioning SnagBot still gets a reply
+		if waitsForTrigger(configStore, ev) {
+			logging.Debug("Message %s has no trigger word, skipping", ev.TimeStamp)
+			return nil
+		}
+		if deduper != nil && !deduper.FirstSeen(messageKey(ev.Channel, ev.TimeStamp)) {
+			logging.Debug("Message %s already handled, skipping", ev.TimeStamp)
+			return nil
+		}
 		// Process the message
-		return ProcessMessageEvent(ev, configStore, api)
+		return ProcessMessageEventWithCooldown(ctx, ev, configStore, api, cooldown, opts)
+	case *slackevents.AppMentionEvent:
+		if deduper != nil && !deduper.FirstSeen(messageKey(ev.Channel, ev.TimeStamp)) {
+			logging.Debug("Mention %s already handled, skipping", ev.TimeStamp)
+			return nil
+		}
+		// Process the mention
+		return ProcessAppMentionEvent(ctx, ev, configStore, api, cooldown, opts)
+	case *slackevents.ReactionAddedEvent:
+		if !opts.IsConvertReaction(ev) {
+			logging.Debug("Ignoring :%s: reaction", ev.Reaction)
+			return nil
+		}
+		// Several people reacting to the same message only get it converted once
+		if deduper != nil && !deduper.FirstSeen(reactionKey(ev.Item.Channel, ev.Item.Timestamp)) {
+			logging.Debug("Reaction to %s already handled, skipping", ev.Item.Timestamp)
+			return nil
+		}
+		return ProcessReactionAddedEvent(ctx, ev, event.TeamID, configStore, api, cooldown, opts)
+	case *slackevents.AppUninstalledEvent:
+		return handleAppUninstalled(event.TeamID, configStore, tokenStore)
 	default:
 		eventType := fmt.Sprintf("%T", innerEvent.Data)
 		logging.Debug("Unhandled event type: %s", eventType)
@@ -203,13 +256,67 @@ func handleCallbackEvent(event slackevents.EventsAPIEvent, configStore ChannelCo
 	}
 }
 
+// waitsForTrigger reports whether a message is in a channel that only responds to its trigger
+// word and doesn't contain it, using the new text of edited messages
+func waitsForTrigger(configStore ChannelConfigStore, ev *slackevents.MessageEvent) bool {
+	text := ev.Text
+	if ev.Message != nil {
+		text = ev.Message.Text
+	}
+
+	config, err := configStore.GetConfig(ev.Channel)
+	if err != nil {
+		// Let the message pipeline report the error
+		return false
+	}
+	return !config.Triggered(text)
+}
+
+// handleAppUninstalled forgets a workspace that has removed SnagBot, deleting its token
+// and any channel configs saved for it
+func handleAppUninstalled(teamID string, configStore ChannelConfigStore, tokenStore TokenStore) error {
+	if teamID == "" {
+		return errors.New(errors.ErrInvalidRequest, "app_uninstalled event without a team ID")
+	}
+
+	logging.Info("SnagBot was uninstalled from workspace %s", teamID)
+
+	if tokenStore != nil {
+		if err := tokenStore.DeleteToken(teamID); err != nil {
+			return errors.Wrap(err, "Failed to delete workspace token")
+		}
+	}
+
+	// Only configs saved with a workspace ID can be matched to the workspace
+	configs, err := configStore.GetAllConfigs()
+	if err != nil {
+		return errors.Wrap(err, "Failed to list channel configurations")
+	}
+	for channelID, config := range configs {
+		if config.WorkspaceID != teamID {
+			continue
+		}
+		if err := configStore.ResetConfig(channelID, ""); err != nil {
+			logging.Warn("Failed to remove configuration for channel %s: %v", channelID, err)
+		}
+	}
+
+	return nil
+}
+
 // HandleErrorWithResponse sends an error message to the user via Slack
-func HandleErrorWithResponse(err error, ev *slackevents.MessageEvent, api SlackAPI) {
+func HandleErrorWithResponse(ctx context.Context, err error, ev *slackevents.MessageEvent, api SlackAPI, opts *ProcessorOptions) {
 	// Don't send any message for nil errors
 	if err == nil {
 		return
 	}
 
+	// Stay quiet about errors too while responses are disabled
+	if !opts.ResponsesEnabled() {
+		logging.Error("Error processing message with responses disabled: %v", err)
+		return
+	}
+
 	// Create a user-friendly error message
 	message := "Oops! Something went wrong. I couldn't process that message properly."
 
@@ -220,10 +327,10 @@ func HandleErrorWithResponse(err error, ev *slackevents.MessageEvent, api SlackA
 	response := SlackResponse{
 		ChannelID: ev.Channel,
 		Text:      message,
-		ThreadTS:  ev.TimeStamp,
+		ThreadTS:  threadTS(ev),
 	}
 
-	if err := api.PostMessage(response); err != nil {
+	if err := api.PostMessage(ctx, response); err != nil {
 		logging.Error("Failed to send error response to Slack: %v", err)
 	}
 }
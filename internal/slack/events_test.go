@@ -0,0 +1,85 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockTokenStore records which workspace tokens were deleted
+type mockTokenStore struct {
+	mutex   sync.Mutex
+	deleted []string
+}
+
+func (m *mockTokenStore) SaveToken(token *models.WorkspaceToken) error { return nil }
+
+func (m *mockTokenStore) GetToken(workspaceID string) (*models.WorkspaceToken, error) {
+	return &models.WorkspaceToken{WorkspaceID: workspaceID}, nil
+}
+
+func (m *mockTokenStore) DeleteToken(workspaceID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.deleted = append(m.deleted, workspaceID)
+	return nil
+}
+
+func (m *mockTokenStore) ListWorkspaces() ([]string, error) { return nil, nil }
+
+func (m *mockTokenStore) Deleted() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]string(nil), m.deleted...)
+}
+
+func TestEventHandler_AppUninstalled(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	store := NewInMemoryConfigStore()
+	require.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "C11111", WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00}))
+	require.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "C22222", WorkspaceID: "T67890", ItemName: "pie", ItemPrice: 6.00}))
+
+	tokenStore := &mockTokenStore{}
+	handler := EventHandlerWithDeduplicator(context.Background(), nil, cfg, store, tokenStore, NewMockSlackAPI(), NewSeenCache(time.Minute, 100), nil)
+
+	body := `{
+		"type": "event_callback",
+		"team_id": "T12345",
+		"event_id": "Ev12345",
+		"event": {
+			"type": "app_uninstalled"
+		}
+	}`
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedEventRequest(t, cfg.SlackSigningSecret, body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The event is handled in the background
+	assert.Eventually(t, func() bool { return len(tokenStore.Deleted()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"T12345"}, tokenStore.Deleted())
+
+	// Only the uninstalled workspace's channel configs are removed
+	assert.Eventually(t, func() bool { return !store.ConfigExists("C11111") }, time.Second, 10*time.Millisecond)
+	assert.True(t, store.ConfigExists("C22222"))
+}
+
+func TestEventHandler_OversizedBody(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret", MaxRequestBodySize: 64}
+	handler := EventHandlerWithDeduplicator(context.Background(), nil, cfg, NewInMemoryConfigStore(), &mockTokenStore{}, NewMockSlackAPI(), NewSeenCache(time.Minute, 100), nil)
+
+	body := `{"type": "event_callback", "event": {"type": "message", "text": "` + strings.Repeat("$5 ", 100) + `"}}`
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedEventRequest(t, cfg.SlackSigningSecret, body))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
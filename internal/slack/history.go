@@ -0,0 +1,48 @@
+package slack
+
+import "github.com/mcncl/snagbot/pkg/models"
+
+// DefaultHistorySize is how many config changes are kept for each channel
+const DefaultHistorySize = 20
+
+// changeHistory is a fixed-size ring buffer of config changes, overwriting the oldest when full
+type changeHistory struct {
+	entries []models.ConfigChange
+	next    int  // Index the next change is written to
+	full    bool // Whether every slot has been written at least once
+}
+
+// newChangeHistory creates a ring buffer holding at most size changes
+func newChangeHistory(size int) *changeHistory {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &changeHistory{entries: make([]models.ConfigChange, size)}
+}
+
+// add records a change, dropping the oldest one if the buffer is full
+func (h *changeHistory) add(change models.ConfigChange) {
+	h.entries[h.next] = change
+	h.next = (h.next + 1) % len(h.entries)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// recent returns up to limit changes, newest first
+// A limit of zero or less returns every change held
+func (h *changeHistory) recent(limit int) []models.ConfigChange {
+	count := h.next
+	if h.full {
+		count = len(h.entries)
+	}
+	if limit <= 0 || limit > count {
+		limit = count
+	}
+
+	changes := make([]models.ConfigChange, 0, limit)
+	for i := 1; i <= limit; i++ {
+		changes = append(changes, h.entries[(h.next-i+len(h.entries))%len(h.entries)])
+	}
+	return changes
+}
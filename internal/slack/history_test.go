@@ -0,0 +1,69 @@
+package slack
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeHistory_Recent(t *testing.T) {
+	history := newChangeHistory(3)
+	assert.Empty(t, history.recent(5))
+
+	for i := 1; i <= 5; i++ {
+		history.add(models.ConfigChange{NewItemName: fmt.Sprintf("item %d", i)})
+	}
+
+	// Only the newest three are kept, newest first
+	names := func(changes []models.ConfigChange) []string {
+		result := make([]string, len(changes))
+		for i, change := range changes {
+			result[i] = change.NewItemName
+		}
+		return result
+	}
+	assert.Equal(t, []string{"item 5", "item 4", "item 3"}, names(history.recent(0)))
+	assert.Equal(t, []string{"item 5", "item 4"}, names(history.recent(2)))
+	assert.Equal(t, []string{"item 5", "item 4", "item 3"}, names(history.recent(10)))
+}
+
+func TestInMemoryConfigStore_GetHistory(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewInMemoryConfigStoreWithConfig(nil)
+	store.now = clock.Now
+
+	assert.Empty(t, store.GetHistory("C12345", 5))
+
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U111"))
+	clock.Advance(time.Minute)
+	assert.NoError(t, store.UpdateConfig("C12345", "pie", 6.00, "U222"))
+	clock.Advance(time.Minute)
+	assert.NoError(t, store.ResetConfig("C12345", "U333"))
+
+	// Resetting a channel already on the defaults isn't a change
+	assert.NoError(t, store.ResetConfig("C12345", "U333"))
+
+	history := store.GetHistory("C12345", 5)
+	assert.Equal(t, []models.ConfigChange{
+		{ChannelID: "C12345", UserID: "U333", ChangedAt: clock.current, Reset: true,
+			OldItemName: "pie", OldItemPrice: 6.00, NewItemName: "Bunnings snags", NewItemPrice: 3.50},
+		{ChannelID: "C12345", UserID: "U222", ChangedAt: clock.current.Add(-time.Minute),
+			OldItemName: "coffee", OldItemPrice: 5.00, NewItemName: "pie", NewItemPrice: 6.00},
+		{ChannelID: "C12345", UserID: "U111", ChangedAt: clock.current.Add(-2 * time.Minute),
+			OldItemName: "Bunnings snags", OldItemPrice: 3.50, NewItemName: "coffee", NewItemPrice: 5.00},
+	}, history)
+
+	// Other channels have their own history
+	assert.Empty(t, store.GetHistory("C67890", 5))
+
+	// The history is bounded, dropping the oldest changes
+	for i := 0; i < DefaultHistorySize+5; i++ {
+		assert.NoError(t, store.UpdateConfig("C12345", "coffee", float64(i+1), "U111"))
+	}
+	history = store.GetHistory("C12345", 0)
+	assert.Len(t, history, DefaultHistorySize)
+	assert.Equal(t, float64(DefaultHistorySize+5), history[0].NewItemPrice)
+}
@@ -0,0 +1,24 @@
+package slack
+
+import "github.com/mcncl/snagbot/internal/config"
+
+// IsIgnored reports whether SnagBot shouldn't respond to the user in the channel,
+// checking the store's ignore list if it keeps one
+func IsIgnored(store ChannelConfigStore, channelID, userID string) bool {
+	if ignorer, ok := baseStore(store).(IgnoreListStore); ok {
+		return ignorer.IsIgnored(channelID, userID)
+	}
+	return false
+}
+
+// ignoredByConfig reports whether the channel or user is ignored by IGNORED_CHANNELS or IGNORED_USERS,
+// or the channel is a direct message and IGNORE_DIRECT_MESSAGES is set
+func ignoredByConfig(cfg *config.Config, channelID, userID string) bool {
+	if cfg == nil {
+		return false
+	}
+	if cfg.IgnoreDirectMessages && IsDirectMessage(channelID) {
+		return true
+	}
+	return cfg.IgnoredChannels[channelID] || (userID != "" && cfg.IgnoredUsers[userID])
+}
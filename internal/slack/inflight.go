@@ -0,0 +1,95 @@
+package slack
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/metrics"
+)
+
+// Default event pool limits, used when EVENT_WORKERS or EVENT_QUEUE_SIZE aren't set
+const (
+	DefaultEventWorkers   = 16
+	DefaultEventQueueSize = 256
+)
+
+// EventGroup processes Slack events in the background on a fixed number of workers, so a burst
+// of events can't start unbounded goroutines. Events wait in a bounded queue and are dropped
+// when it's full. The group tracks queued and running events, so shutdown can wait for in-flight
+// responses instead of cutting them off
+type EventGroup struct {
+	wg    sync.WaitGroup
+	queue chan func()
+}
+
+// NewEventGroup creates an event group with the given number of workers and queue size
+// Zero or negative values use DefaultEventWorkers and DefaultEventQueueSize
+func NewEventGroup(workers, queueSize int) *EventGroup {
+	if workers <= 0 {
+		workers = DefaultEventWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultEventQueueSize
+	}
+
+	g := &EventGroup{queue: make(chan func(), queueSize)}
+	for i := 0; i < workers; i++ {
+		go g.work()
+	}
+	return g
+}
+
+// work processes queued events until the process exits
+func (g *EventGroup) work() {
+	for process := range g.queue {
+		g.run(process)
+	}
+}
+
+// run processes a single event, keeping a panic from taking the worker down with it
+func (g *EventGroup) run(process func()) {
+	defer g.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error("Panic in event handler: %v", r)
+		}
+	}()
+
+	process()
+}
+
+// Go queues an event to be processed, returning false if the queue is full and it was dropped
+func (g *EventGroup) Go(process func()) bool {
+	g.wg.Add(1)
+	select {
+	case g.queue <- process:
+		return true
+	default:
+		g.wg.Done()
+		metrics.Default().EventsDropped.Inc()
+		logging.Warn("Event queue is full (%d queued), dropping event", cap(g.queue))
+		return false
+	}
+}
+
+// Wait blocks until every queued and running event has been processed, or ctx is done, in which
+// case it returns ctx's error. Stop accepting events before waiting, or the wait may never end
+func (g *EventGroup) Wait(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
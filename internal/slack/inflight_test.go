@@ -0,0 +1,134 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventGroup_Wait(t *testing.T) {
+	group := NewEventGroup(1, 1)
+	release := make(chan struct{})
+	group.Go(func() { <-release })
+
+	// Gives up when ctx is done before the event is processed
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, group.Wait(ctx), context.DeadlineExceeded)
+
+	close(release)
+	assert.NoError(t, group.Wait(context.Background()))
+
+	// Without a group there's nothing to wait for
+	var none *EventGroup
+	assert.NoError(t, none.Wait(context.Background()))
+}
+
+func TestEventGroup_ProcessesQueuedEventsSerially(t *testing.T) {
+	group := NewEventGroup(1, 3)
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var order []int
+	running := 0
+	maxRunning := 0
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, group.Go(func() {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			<-release
+
+			mu.Lock()
+			running--
+			order = append(order, i)
+			mu.Unlock()
+		}))
+	}
+
+	close(release)
+	assert.NoError(t, group.Wait(context.Background()))
+	assert.Equal(t, []int{0, 1, 2}, order)
+	assert.Equal(t, 1, maxRunning)
+}
+
+func TestEventGroup_DropsEventsWhenQueueIsFull(t *testing.T) {
+	original := metrics.Default()
+	defer metrics.SetDefault(original)
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	metrics.SetDefault(m)
+
+	group := NewEventGroup(1, 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var processed atomic.Int32
+
+	// One event blocks the only worker and one waits in the queue
+	assert.True(t, group.Go(func() {
+		close(started)
+		<-release
+		processed.Add(1)
+	}))
+	<-started
+	assert.True(t, group.Go(func() { processed.Add(1) }))
+
+	// The next is dropped and counted
+	assert.False(t, group.Go(func() { processed.Add(1) }))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.EventsDropped))
+
+	close(release)
+	assert.NoError(t, group.Wait(context.Background()))
+	assert.Equal(t, int32(2), processed.Load())
+}
+
+func TestEventGroup_RecoversFromPanics(t *testing.T) {
+	group := NewEventGroup(1, 2)
+	group.Go(func() { panic("boom") })
+
+	processed := false
+	group.Go(func() { processed = true })
+	assert.NoError(t, group.Wait(context.Background()))
+	assert.True(t, processed, "The worker should keep going after a panic")
+}
+
+func TestEventHandler_WaitsForSlowEvents(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret", ResponseDelay: 200 * time.Millisecond}
+	mockAPI := NewMockSlackAPI()
+	events := NewEventGroup(1, 1)
+	handler := EventHandlerWithDeduplicator(context.Background(), events, cfg, NewInMemoryConfigStore(), nil, mockAPI, NewSeenCache(time.Minute, 100), nil)
+
+	body := `{
+		"type": "event_callback",
+		"event_id": "Ev12345",
+		"event": {
+			"type": "message",
+			"channel": "C12345",
+			"user": "U12345",
+			"text": "This costs $35",
+			"ts": "1234567890.123456"
+		}
+	}`
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedEventRequest(t, cfg.SlackSigningSecret, body))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, mockAPI.Messages(), "The response should still be on its way")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, events.Wait(ctx))
+	assert.Len(t, mockAPI.Messages(), 1)
+}
@@ -0,0 +1,186 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// ChangeItemActionID identifies the "Change item" button on responses
+	ChangeItemActionID = "snagbot_change_item"
+
+	// changeItemCallbackID identifies submissions of the change item modal
+	changeItemCallbackID = "snagbot_change_item"
+
+	// Block and action IDs of the change item modal's inputs
+	itemBlockID   = "item"
+	itemActionID  = "item_name"
+	priceBlockID  = "price"
+	priceActionID = "item_price"
+)
+
+// ChangeItemBlocks lays out a response with a "Change item" button that reconfigures the channel
+func ChangeItemBlocks(text, channelID string) []slack.Block {
+	button := slack.NewButtonBlockElement(ChangeItemActionID, channelID,
+		slack.NewTextBlockObject(slack.PlainTextType, "Change item", false, false))
+
+	return []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("snagbot_actions", button),
+	}
+}
+
+// changeItemModal builds the modal for setting a channel's item and price, filled in with the current ones
+func changeItemModal(channelID string, config *models.ChannelConfig) slack.ModalViewRequest {
+	itemInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "e.g. coffee", false, false), itemActionID)
+	itemInput.InitialValue = config.ItemName
+
+	priceInput := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "e.g. 5.00", false, false), priceActionID)
+	priceInput.InitialValue = strconv.FormatFloat(config.ItemPrice, 'f', 2, 64)
+
+	return slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      changeItemCallbackID,
+		PrivateMetadata: channelID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, "Change item", false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Save", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(itemBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Item", false, false), nil, itemInput),
+			slack.NewInputBlock(priceBlockID, slack.NewTextBlockObject(slack.PlainTextType, "Price", false, false), nil, priceInput),
+		}},
+	}
+}
+
+// InteractionHandler creates a handler for Slack interactivity payloads, opening the change
+// item modal when the button is clicked and saving the channel's new item when it's submitted
+func InteractionHandler(cfg *config.Config, configStore ChannelConfigStore, api SlackAPI) http.HandlerFunc {
+	verifier := NewRequestVerifier(cfg.SlackSigningSecret, cfg.SlackRequestMaxAge)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Only allow POST requests for interactions
+		if r.Method != http.MethodPost {
+			logging.Warn("Method not allowed for interaction: %s", r.Method)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Check if the Slack signing secret is configured
+		if cfg.SlackSigningSecret == "" {
+			logging.Error("Slack signing secret not configured")
+			http.Error(w, "Server configuration error", http.StatusInternalServerError)
+			return
+		}
+
+		// Verify the Slack signature and that the request is recent
+		LimitRequestBody(w, r, cfg.MaxRequestBodySize)
+		if _, err := verifier.Verify(r); IsRequestTooLarge(err) {
+			logging.Warn("Rejected oversized interaction request: %v", err)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			logging.Error("Interaction signature verification failed: %v", err)
+			http.Error(w, "Invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		// Slack sends the interaction as JSON in the payload form field
+		var callback slack.InteractionCallback
+		if err := json.Unmarshal([]byte(r.FormValue("payload")), &callback); err != nil {
+			appErr := errors.WrapAndLog(err, "Error parsing interaction payload")
+			http.Error(w, appErr.Message, http.StatusBadRequest)
+			return
+		}
+
+		logging.Info("Received Slack interaction %s from user %s", callback.Type, callback.User.ID)
+
+		switch callback.Type {
+		case slack.InteractionTypeBlockActions:
+			if err := handleBlockActions(r.Context(), callback, configStore, api); err != nil {
+				logging.Error("Error handling block actions: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+		case slack.InteractionTypeViewSubmission:
+			handleViewSubmission(r.Context(), w, callback, configStore)
+		default:
+			logging.Debug("Unhandled interaction type: %s", callback.Type)
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+}
+
+// handleBlockActions opens the change item modal for each "Change item" button click
+func handleBlockActions(ctx context.Context, callback slack.InteractionCallback, configStore ChannelConfigStore, api SlackAPI) error {
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != ChangeItemActionID {
+			continue
+		}
+
+		// The button's value is the channel it reconfigures
+		channelID := action.Value
+		config, err := ForWorkspace(WithContext(ctx, configStore), callback.Team.ID).GetConfig(channelID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get channel configuration")
+		}
+
+		if err := api.OpenView(ctx, callback.Team.ID, callback.TriggerID, changeItemModal(channelID, config)); err != nil {
+			return errors.Wrap(err, "Failed to open change item modal")
+		}
+	}
+	return nil
+}
+
+// handleViewSubmission saves the item and price from a submitted change item modal
+// Invalid input is sent back to Slack to show against the field, keeping the modal open
+func handleViewSubmission(ctx context.Context, w http.ResponseWriter, callback slack.InteractionCallback, configStore ChannelConfigStore) {
+	if callback.View.CallbackID != changeItemCallbackID || callback.View.State == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	values := callback.View.State.Values
+	itemName := strings.TrimSpace(values[itemBlockID][itemActionID].Value)
+	priceText := strings.TrimSpace(values[priceBlockID][priceActionID].Value)
+
+	fieldErrors := make(map[string]string)
+	if itemName == "" {
+		fieldErrors[itemBlockID] = "Please enter an item name."
+	}
+	price, err := strconv.ParseFloat(strings.TrimPrefix(priceText, "$"), 64)
+	if err != nil || price <= 0 {
+		fieldErrors[priceBlockID] = "The price must be a positive number (e.g., 3.50)."
+	}
+	if len(fieldErrors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slack.NewErrorsViewSubmissionResponse(fieldErrors))
+		return
+	}
+
+	channelID := callback.View.PrivateMetadata
+	if err := ForWorkspace(WithContext(ctx, configStore), callback.Team.ID).UpdateConfig(channelID, itemName, price, callback.User.ID); err != nil {
+		if appErr, ok := err.(*errors.AppError); ok && appErr.Is(errors.ErrItemNameTooLong) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(slack.NewErrorsViewSubmissionResponse(map[string]string{itemBlockID: appErr.Message + "."}))
+			return
+		}
+		appErr := errors.WrapAndLog(err, "Failed to update configuration from modal")
+		http.Error(w, appErr.Message, http.StatusInternalServerError)
+		return
+	}
+
+	logging.Info("User %s changed the item for channel %s to %s at $%.2f", callback.User.ID, channelID, itemName, price)
+
+	// An empty 200 closes the modal
+	w.WriteHeader(http.StatusOK)
+}
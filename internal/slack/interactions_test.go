@@ -0,0 +1,139 @@
+package slack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteractionHandler_ChangeItemButton(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	store := NewInMemoryConfigStore()
+	require.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	mockAPI := NewMockSlackAPI()
+	handler := InteractionHandler(cfg, store, mockAPI)
+
+	payload := `{
+		"type": "block_actions",
+		"trigger_id": "12345.98765.abcd",
+		"team": {"id": "T12345"},
+		"user": {"id": "U67890"},
+		"actions": [{"type": "button", "action_id": "snagbot_change_item", "block_id": "snagbot_actions", "value": "C12345"}]
+	}`
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedInteractionRequest(t, cfg.SlackSigningSecret, payload))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The modal is opened for the clicked channel, filled in with its current item
+	require.Len(t, mockAPI.Views, 1)
+	view := mockAPI.Views[0]
+	assert.Equal(t, "12345.98765.abcd", view.TriggerID)
+	assert.Equal(t, "C12345", view.View.PrivateMetadata)
+
+	itemInput := view.View.Blocks.BlockSet[0].(*slack.InputBlock).Element.(*slack.PlainTextInputBlockElement)
+	assert.Equal(t, "coffee", itemInput.InitialValue)
+	priceInput := view.View.Blocks.BlockSet[1].(*slack.InputBlock).Element.(*slack.PlainTextInputBlockElement)
+	assert.Equal(t, "5.00", priceInput.InitialValue)
+}
+
+func TestInteractionHandler_ViewSubmission(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	store := NewInMemoryConfigStore()
+	handler := InteractionHandler(cfg, store, NewMockSlackAPI())
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedInteractionRequest(t, cfg.SlackSigningSecret, viewSubmissionPayload("meat pie", "6.50")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	config, err := store.GetConfig("C12345")
+	require.NoError(t, err)
+	assert.Equal(t, "meat pie", config.ItemName)
+	assert.Equal(t, 6.50, config.ItemPrice)
+	assert.Equal(t, "T12345", config.WorkspaceID)
+
+	// The change is recorded against the user who submitted the modal
+	history := store.GetHistory("C12345", 1)
+	require.Len(t, history, 1)
+	assert.Equal(t, "U67890", history[0].UserID)
+}
+
+func TestInteractionHandler_ViewSubmissionInvalidPrice(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	store := NewInMemoryConfigStore()
+	handler := InteractionHandler(cfg, store, NewMockSlackAPI())
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedInteractionRequest(t, cfg.SlackSigningSecret, viewSubmissionPayload("meat pie", "free")))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// The error is shown against the price field and nothing is saved
+	var response slack.ViewSubmissionResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, slack.RAErrors, response.ResponseAction)
+	assert.Contains(t, response.Errors, "price")
+	assert.False(t, store.ConfigExists("C12345"))
+}
+
+func TestInteractionHandler_InvalidSignature(t *testing.T) {
+	cfg := &config.Config{SlackSigningSecret: "test-secret"}
+	handler := InteractionHandler(cfg, NewInMemoryConfigStore(), NewMockSlackAPI())
+
+	rec := httptest.NewRecorder()
+	handler(rec, signedInteractionRequest(t, "wrong-secret", viewSubmissionPayload("meat pie", "6.50")))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestProcessMessageEvent_ChangeItemButton(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	config := models.NewChannelConfig("C12345")
+	config.ChangeItemButton = true
+	require.NoError(t, store.SaveConfig(config))
+
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+	require.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+
+	require.Len(t, mockAPI.SentMessages, 1)
+	sent := mockAPI.SentMessages[0]
+	assert.Equal(t, "That's 10 Bunnings snags!", sent.Text)
+	require.Len(t, sent.Blocks, 2)
+	button := sent.Blocks[1].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	assert.Equal(t, ChangeItemActionID, button.ActionID)
+	assert.Equal(t, "C12345", button.Value)
+}
+
+// viewSubmissionPayload builds a change item modal submission for channel C12345
+func viewSubmissionPayload(itemName, price string) string {
+	return `{
+		"type": "view_submission",
+		"team": {"id": "T12345"},
+		"user": {"id": "U67890"},
+		"view": {
+			"callback_id": "snagbot_change_item",
+			"private_metadata": "C12345",
+			"state": {"values": {
+				"item": {"item_name": {"type": "plain_text_input", "value": "` + itemName + `"}},
+				"price": {"item_price": {"type": "plain_text_input", "value": "` + price + `"}}
+			}}
+		}
+	}`
+}
+
+// signedInteractionRequest builds an interactivity request with the payload form field, signed the way Slack signs them
+func signedInteractionRequest(t *testing.T, secret, payload string) *http.Request {
+	t.Helper()
+
+	req := signedEventRequest(t, secret, url.Values{"payload": {payload}}.Encode())
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
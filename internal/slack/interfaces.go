@@ -1,7 +1,87 @@
 package slack
 
+import (
+	"context"
+
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
 // ConfigExistsChecker is an interface for checking if a custom configuration exists
 type ConfigExistsChecker interface {
 	// ConfigExists returns true if a custom configuration exists for the given channel ID
 	ConfigExists(channelID string) bool
 }
+
+// CheckedConfigExistsChecker is an interface for stores that can fail to check if a custom
+// configuration exists, so callers can tell "no config" apart from "couldn't check"
+type CheckedConfigExistsChecker interface {
+	// ConfigExistsChecked returns true if a custom configuration exists for the given channel ID,
+	// or an error if the store couldn't be checked
+	ConfigExistsChecked(channelID string) (bool, error)
+}
+
+// ConfigHistoryProvider is an interface for stores that record configuration changes
+type ConfigHistoryProvider interface {
+	// GetHistory returns up to limit of the channel's most recent changes, newest first
+	GetHistory(channelID string, limit int) []models.ConfigChange
+}
+
+// ConfigChangeReporter is an interface for stores that can tell whether an update changed anything
+type ConfigChangeReporter interface {
+	// UpdateConfigChanged updates the channel's item and price like UpdateConfig, reporting whether
+	// they changed and skipping the write, history and undo point when they didn't
+	UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error)
+}
+
+// ConfigWorkspaceSetter is an interface for stores that can note which workspace a channel's
+// config belongs to without it counting as a change to undo
+type ConfigWorkspaceSetter interface {
+	// SetConfigWorkspace records the workspace of the channel's config, doing nothing if the
+	// channel doesn't have one
+	SetConfigWorkspace(channelID, workspaceID string) error
+}
+
+// ConfigUndoer is an interface for stores that can undo a channel's recent config changes
+type ConfigUndoer interface {
+	// Undo restores the channel's config from before its last change, returning an
+	// ErrNothingToUndo error if there isn't one
+	Undo(channelID string) error
+}
+
+// WorkspaceDefaultsStore is an interface for stores that keep a default item per workspace
+type WorkspaceDefaultsStore interface {
+	// GetWorkspaceDefault returns the workspace's default item, or nil if it hasn't set one
+	GetWorkspaceDefault(workspaceID string) (*models.WorkspaceDefault, error)
+	// SaveWorkspaceDefault sets the default item for the workspace's channels
+	SaveWorkspaceDefault(def *models.WorkspaceDefault) error
+}
+
+// ConfigResolver is an interface for stores that can say where a channel's effective config came from
+type ConfigResolver interface {
+	// ResolveConfig returns the channel's effective config and the source of its item and price
+	ResolveConfig(channelID string) (*models.ResolvedConfig, error)
+}
+
+// ChannelStatsRecorder is an interface for stores that count SnagBot's responses per channel
+type ChannelStatsRecorder interface {
+	// RecordResponse counts a response in the channel that converted the given total
+	RecordResponse(channelID string, total float64)
+	// GetStats returns the channel's counts so far
+	GetStats(channelID string) models.ChannelStats
+}
+
+// IgnoreListStore is an interface for stores that keep the channels and users SnagBot ignores
+type IgnoreListStore interface {
+	// IsIgnored returns true if SnagBot shouldn't respond in the channel or to the user
+	IsIgnored(channelID, userID string) bool
+	// SetChannelIgnored adds the channel to the ignore list, or removes it
+	SetChannelIgnored(channelID string, ignored bool) error
+	// SetUserIgnored adds the user to the ignore list, or removes them
+	SetUserIgnored(userID string, ignored bool) error
+}
+
+// ContextBinder is an interface for stores whose calls can be tied to a request's context
+type ContextBinder interface {
+	// WithContext returns a view of the store whose calls give up once ctx is cancelled
+	WithContext(ctx context.Context) ChannelConfigStore
+}
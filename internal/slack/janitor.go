@@ -0,0 +1,97 @@
+package slack
+
+import (
+	"time"
+
+	"github.com/mcncl/snagbot/internal/logging"
+)
+
+// MaxJanitorInterval is the longest the memory store backend waits between janitor checks
+const MaxJanitorInterval = time.Hour
+
+// StartJanitor evicts configs that haven't been read or changed for longer than maxAge, checking
+// every interval in the background until StopJanitor is called
+// Starting the janitor again replaces the running one
+func (s *InMemoryConfigStore) StartJanitor(interval, maxAge time.Duration) {
+	if interval <= 0 || maxAge <= 0 {
+		return
+	}
+
+	s.StopJanitor()
+
+	s.janitorMutex.Lock()
+	defer s.janitorMutex.Unlock()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	s.janitorStop = stop
+	s.janitorDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.EvictStale(maxAge)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	logging.Info("Evicting channel configs unused for %v, checking every %v", maxAge, interval)
+}
+
+// StopJanitor stops the janitor and waits for it to finish, doing nothing if it isn't running
+func (s *InMemoryConfigStore) StopJanitor() {
+	s.janitorMutex.Lock()
+	defer s.janitorMutex.Unlock()
+
+	if s.janitorStop == nil {
+		return
+	}
+
+	close(s.janitorStop)
+	<-s.janitorDone
+	s.janitorStop = nil
+	s.janitorDone = nil
+}
+
+// EvictStale removes configs that haven't been read or changed for longer than maxAge, along with
+// their undo and change history, returning how many were removed
+func (s *InMemoryConfigStore) EvictStale(maxAge time.Duration) int {
+	evicted := s.evictStale(maxAge)
+	if evicted > 0 {
+		s.saveChanges()
+	}
+	return evicted
+}
+
+// evictStale does the work of EvictStale, holding the mutex
+func (s *InMemoryConfigStore) evictStale(maxAge time.Duration) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cutoff := s.now().Add(-maxAge)
+	evicted := 0
+	for channelID := range s.configs {
+		if !s.lastAccess[channelID].Before(cutoff) {
+			continue
+		}
+
+		delete(s.configs, channelID)
+		delete(s.lastAccess, channelID)
+		delete(s.undo, channelID)
+		delete(s.history, channelID)
+		evicted++
+	}
+
+	if evicted > 0 {
+		logging.Info("Evicted %d channel configs unused for %v", evicted, maxAge)
+	}
+	return evicted
+}
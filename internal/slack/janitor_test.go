@@ -0,0 +1,71 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryConfigStore_EvictStale(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewInMemoryConfigStore()
+	store.now = clock.Now
+
+	assert.NoError(t, store.UpdateConfig("CSTALE", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "CFRESH", ItemName: "pie", ItemPrice: 6.00}))
+	assert.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "CREAD", ItemName: "donut", ItemPrice: 4.00}))
+
+	// Reading a config counts as using it
+	clock.Advance(20 * time.Hour)
+	_, err := store.GetConfig("CREAD")
+	assert.NoError(t, err)
+	assert.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "CFRESH", ItemName: "pie", ItemPrice: 6.50}))
+
+	clock.Advance(5 * time.Hour)
+	assert.Equal(t, 1, store.EvictStale(24*time.Hour))
+
+	assert.False(t, store.ConfigExists("CSTALE"))
+	assert.True(t, store.ConfigExists("CFRESH"))
+	assert.True(t, store.ConfigExists("CREAD"))
+
+	// An evicted channel's history and undo steps go with it
+	assert.Empty(t, store.GetHistory("CSTALE", 0))
+	assert.Error(t, store.Undo("CSTALE"))
+}
+
+func TestInMemoryConfigStore_Janitor(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewInMemoryConfigStore()
+	store.now = clock.Now
+
+	assert.NoError(t, store.UpdateConfig("CSTALE", "coffee", 5.00, "U12345"))
+	clock.Advance(2 * time.Hour)
+	assert.NoError(t, store.UpdateConfig("CFRESH", "pie", 6.00, "U12345"))
+
+	store.StartJanitor(time.Millisecond, time.Hour)
+	defer store.StopJanitor()
+
+	assert.Eventually(t, func() bool {
+		return !store.ConfigExists("CSTALE")
+	}, time.Second, time.Millisecond)
+	assert.True(t, store.ConfigExists("CFRESH"))
+}
+
+func TestInMemoryConfigStore_StopJanitor(t *testing.T) {
+	store := NewInMemoryConfigStore()
+
+	// Stopping a janitor that isn't running does nothing
+	store.StopJanitor()
+
+	store.StartJanitor(time.Millisecond, time.Hour)
+	store.StartJanitor(time.Millisecond, time.Hour)
+	store.StopJanitor()
+	store.StopJanitor()
+
+	// Closing the store stops the janitor too
+	store.StartJanitor(time.Millisecond, time.Hour)
+	assert.NoError(t, store.Close())
+	assert.Nil(t, store.janitorStop)
+}
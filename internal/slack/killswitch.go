@@ -0,0 +1,38 @@
+package slack
+
+import "sync/atomic"
+
+// KillSwitch turns SnagBot's replies and reactions on or off at runtime
+// Messages are still processed and logged while responses are off
+type KillSwitch struct {
+	// disabled is stored inverted so responses are on for a zero KillSwitch
+	disabled atomic.Bool
+}
+
+// NewKillSwitch creates a kill switch with responses on or off, as set by SNAGBOT_ENABLED
+func NewKillSwitch(enabled bool) *KillSwitch {
+	k := &KillSwitch{}
+	k.disabled.Store(!enabled)
+	return k
+}
+
+// Enabled reports whether SnagBot posts replies and reactions
+// A nil switch always has responses on
+func (k *KillSwitch) Enabled() bool {
+	return k == nil || !k.disabled.Load()
+}
+
+// Set turns SnagBot's replies and reactions on or off
+func (k *KillSwitch) Set(enabled bool) {
+	k.disabled.Store(!enabled)
+}
+
+// Toggle flips the switch, returning whether responses are now enabled
+func (k *KillSwitch) Toggle() bool {
+	for {
+		disabled := k.disabled.Load()
+		if k.disabled.CompareAndSwap(disabled, !disabled) {
+			return disabled
+		}
+	}
+}
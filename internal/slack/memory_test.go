@@ -1,11 +1,16 @@
 package slack
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestInMemoryConfigStore_GetConfig(t *testing.T) {
@@ -29,7 +34,7 @@ func TestInMemoryConfigStore_GetConfig(t *testing.T) {
 			name:      "Get existing config",
 			channelID: "C67890",
 			setupFunc: func(store *InMemoryConfigStore) {
-				store.UpdateConfig("C67890", "coffee", 5.00)
+				store.UpdateConfig("C67890", "coffee", 5.00, "U12345")
 			},
 			expected: models.ChannelConfig{
 				ChannelID: "C67890",
@@ -109,6 +114,14 @@ func TestInMemoryConfigStore_UpdateConfig(t *testing.T) {
 			expectErr:  true,
 			errorMatch: "item name cannot be empty",
 		},
+		{
+			name:       "Whitespace item name",
+			channelID:  "C12345",
+			itemName:   " \n\t ",
+			itemPrice:  5.00,
+			expectErr:  true,
+			errorMatch: "item name cannot be empty",
+		},
 	}
 
 	for _, test := range tests {
@@ -117,7 +130,7 @@ func TestInMemoryConfigStore_UpdateConfig(t *testing.T) {
 			store := NewInMemoryConfigStoreWithConfig(nil)
 
 			// Update config
-			err := store.UpdateConfig(test.channelID, test.itemName, test.itemPrice)
+			err := store.UpdateConfig(test.channelID, test.itemName, test.itemPrice, "U12345")
 
 			// Check error
 			if test.expectErr {
@@ -141,6 +154,91 @@ func TestInMemoryConfigStore_UpdateConfig(t *testing.T) {
 	}
 }
 
+func TestInMemoryConfigStore_UpdateConfigNormalizesItemName(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(nil)
+
+	err := store.UpdateConfig("C12345", "  flat\nwhite \t coffee\x07 ", 5.00, "U12345")
+	assert.NoError(t, err)
+
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "flat white coffee", config.ItemName)
+}
+
+func TestInMemoryConfigStore_UpdateConfigChanged(t *testing.T) {
+	store := NewInMemoryConfigStore()
+
+	// The first update creates the channel's config
+	changed, err := store.UpdateConfigChanged("C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	// Updating to identical values, once normalised, isn't recorded as a change
+	changed, err = store.UpdateConfigChanged("C12345", " coffee ", 5.00, "U67890")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+	assert.Len(t, store.GetHistory("C12345", 10), 1)
+
+	// New values are a change
+	changed, err = store.UpdateConfigChanged("C12345", "coffee", 5.50, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Len(t, store.GetHistory("C12345", 10), 2)
+
+	// Undo goes back past the no-op to the change before it
+	assert.NoError(t, store.Undo("C12345"))
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, 5.00, config.ItemPrice)
+
+	// Setting the item again clears a unit, so that's still a change
+	config.SetUnit("cup", 2)
+	assert.NoError(t, store.SaveConfig(config))
+	changed, err = store.UpdateConfigChanged("C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	// Channels without their own config get one even when it matches the default
+	changed, err = store.UpdateConfigChanged("C67890", "Bunnings snags", 3.50, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, store.ConfigExists("C67890"))
+}
+
+func TestUpdateConfigChanged(t *testing.T) {
+	store := NewInMemoryConfigStore()
+
+	// The workspace view checks the store behind it
+	scoped := ForWorkspace(store, "T12345")
+	changed, err := UpdateConfigChanged(scoped, "C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = UpdateConfigChanged(scoped, "C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestInMemoryConfigStore_UpdateConfigItemNameLength(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(nil)
+
+	// The default limit counts characters rather than bytes
+	assert.NoError(t, store.UpdateConfig("C12345", strings.Repeat("é", config.DefaultMaxItemNameLength), 5.00, "U12345"))
+
+	err := store.UpdateConfig("C12345", strings.Repeat("a", config.DefaultMaxItemNameLength+1), 5.00, "U12345")
+	if assert.Error(t, err) {
+		appErr, ok := err.(*errors.AppError)
+		assert.True(t, ok)
+		assert.True(t, appErr.Is(errors.ErrItemNameTooLong))
+		assert.Contains(t, appErr.Message, "at most 50 characters")
+	}
+
+	// The limit can be configured
+	store = NewInMemoryConfigStoreWithConfig(&config.Config{MaxItemNameLength: 5})
+	assert.NoError(t, store.UpdateConfig("C12345", "pie", 5.00, "U12345"))
+	assert.Error(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+}
+
 func TestInMemoryConfigStore_ResetConfig(t *testing.T) {
 	// Create a test config
 	testCfg := &config.Config{
@@ -153,7 +251,7 @@ func TestInMemoryConfigStore_ResetConfig(t *testing.T) {
 
 	// Setup initial state
 	channelID := "C12345"
-	err := store.UpdateConfig(channelID, "coffee", 5.00)
+	err := store.UpdateConfig(channelID, "coffee", 5.00, "U12345")
 	assert.NoError(t, err)
 
 	// Verify initial config
@@ -165,7 +263,7 @@ func TestInMemoryConfigStore_ResetConfig(t *testing.T) {
 	assert.Equal(t, 5.00, config.ItemPrice)
 
 	// Reset config
-	err = store.ResetConfig(channelID)
+	err = store.ResetConfig(channelID, "U12345")
 	assert.NoError(t, err)
 
 	// Verify config has been reset
@@ -189,16 +287,217 @@ func TestInMemoryConfigStore_ConfigExists(t *testing.T) {
 	assert.False(t, store.ConfigExists(channelID))
 
 	// Add a config
-	err := store.UpdateConfig(channelID, "coffee", 5.00)
+	err := store.UpdateConfig(channelID, "coffee", 5.00, "U12345")
 	assert.NoError(t, err)
 
 	// Now it exists
 	assert.True(t, store.ConfigExists(channelID))
 
 	// Reset it
-	err = store.ResetConfig(channelID)
+	err = store.ResetConfig(channelID, "U12345")
 	assert.NoError(t, err)
 
 	// Now it doesn't exist again
 	assert.False(t, store.ConfigExists(channelID))
 }
+
+func TestInMemoryConfigStore_SaveConfig(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(nil)
+
+	// Save a config with optional settings
+	config := models.NewChannelConfig("C12345")
+	config.RoundingMode = "down"
+	assert.NoError(t, store.SaveConfig(config))
+	assert.True(t, store.ConfigExists("C12345"))
+
+	// Changes to the caller's copy must not leak into the store
+	config.RoundingMode = "nearest"
+
+	saved, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "down", saved.RoundingMode)
+
+	// Updating the item keeps the other settings
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	saved, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", saved.ItemName)
+	assert.Equal(t, "down", saved.RoundingMode)
+
+	// Invalid configs are rejected
+	assert.Error(t, store.SaveConfig(nil))
+	assert.Error(t, store.SaveConfig(&models.ChannelConfig{}))
+}
+
+func TestInMemoryConfigStore_Undo(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(nil)
+	assert.Error(t, store.Undo("C12345"))
+
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.UpdateConfig("C12345", "donut", 4.00, "U12345"))
+
+	// Each undo steps back one change
+	assert.NoError(t, store.Undo("C12345"))
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 5.00, config.ItemPrice)
+
+	assert.NoError(t, store.Undo("C12345"))
+	assert.False(t, store.ConfigExists("C12345"))
+	config, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bunnings snags", config.ItemName)
+
+	// Nothing is left to undo
+	assert.Error(t, store.Undo("C12345"))
+
+	// Resets and other settings can be undone too
+	config.RoundingMode = "down"
+	assert.NoError(t, store.SaveConfig(config))
+	assert.NoError(t, store.ResetConfig("C12345", "U12345"))
+	assert.NoError(t, store.Undo("C12345"))
+	config, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "down", config.RoundingMode)
+
+	// Only the most recent changes are kept
+	for i := 0; i < MaxUndoSteps+5; i++ {
+		assert.NoError(t, store.UpdateConfig("C67890", "coffee", float64(i+1), "U12345"))
+	}
+	for i := 0; i < MaxUndoSteps; i++ {
+		assert.NoError(t, store.Undo("C67890"))
+	}
+	assert.Error(t, store.Undo("C67890"))
+	config, err = store.GetConfig("C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, 5.00, config.ItemPrice)
+}
+
+func TestInMemoryConfigStore_GetAllConfigs(t *testing.T) {
+	var store ChannelConfigStore = NewInMemoryConfigStoreWithConfig(nil)
+
+	configs, err := store.GetAllConfigs()
+	assert.NoError(t, err)
+	assert.Empty(t, configs)
+
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	config := models.NewChannelConfig("C67890")
+	config.AddItem("pie", 6.00)
+	assert.NoError(t, store.SaveConfig(config))
+
+	configs, err = store.GetAllConfigs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]models.ChannelConfig{
+		"C12345": {ChannelID: "C12345", ItemName: "coffee", ItemPrice: 5.00},
+		"C67890": {ChannelID: "C67890", ItemName: "Bunnings snags", ItemPrice: 3.50,
+			Items: []models.ChannelItem{{Name: "pie", Price: 6.00}}},
+	}, configs)
+
+	// Changes to the export don't leak into the store
+	configs["C67890"].Items[0].Name = "cake"
+	saved, err := store.GetConfig("C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "pie", saved.Items[0].Name)
+}
+
+func TestInMemoryConfigStore_Close(t *testing.T) {
+	var store ChannelConfigStore = NewInMemoryConfigStoreWithConfig(nil)
+	assert.NoError(t, store.Close())
+}
+
+func TestInMemoryConfigStore_SaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configs.json")
+
+	// Save a couple of channel configs
+	store := NewInMemoryConfigStoreWithConfig(nil)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.UpdateConfig("C67890", "donut", 2.50, "U12345"))
+	assert.NoError(t, store.SaveToFile(path))
+
+	// Load them into a fresh store
+	loaded := NewInMemoryConfigStoreWithConfig(nil)
+	assert.NoError(t, loaded.LoadFromFile(path))
+	assert.Equal(t, 2, loaded.Count())
+
+	config, err := loaded.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, 5.00, config.ItemPrice)
+
+	config, err = loaded.GetConfig("C67890")
+	assert.NoError(t, err)
+	assert.Equal(t, "donut", config.ItemName)
+	assert.Equal(t, 2.50, config.ItemPrice)
+}
+
+func TestInMemoryConfigStore_LoadMissingFile(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(nil)
+	err := store.LoadFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, store.Count())
+}
+
+func TestInMemoryConfigStore_CloseSavesToConfiguredPath(t *testing.T) {
+	cfg := &config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+		ConfigStorePath:  filepath.Join(t.TempDir(), "configs.json"),
+	}
+
+	store := NewInMemoryConfigStoreWithConfig(cfg)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.Close())
+
+	// A new store for the same config picks up the saved channel
+	restored, err := NewConfigStore(cfg)
+	require.NoError(t, err)
+	assert.True(t, restored.ConfigExists("C12345"))
+}
+
+func TestInMemoryConfigStore_SavesAfterEachChange(t *testing.T) {
+	cfg := &config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+		ConfigStorePath:  filepath.Join(t.TempDir(), "configs.json"),
+	}
+
+	store := NewInMemoryConfigStoreWithConfig(cfg)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.UpdateConfig("C67890", "tea", 4.00, "U12345"))
+
+	// The changes are on disk without the store being closed
+	restored, err := NewConfigStore(cfg)
+	require.NoError(t, err)
+	assert.True(t, restored.ConfigExists("C12345"))
+	assert.True(t, restored.ConfigExists("C67890"))
+
+	assert.NoError(t, store.ResetConfig("C67890", "U12345"))
+	restored, err = NewConfigStore(cfg)
+	require.NoError(t, err)
+	assert.True(t, restored.ConfigExists("C12345"))
+	assert.False(t, restored.ConfigExists("C67890"))
+}
+
+func TestNewConfigStore_CorruptFileIsKept(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configs.json")
+	corrupt := []byte(`{"C12345": {"channel_id": "C12345", "item_na`)
+	assert.NoError(t, os.WriteFile(path, corrupt, 0o600))
+
+	cfg := &config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+		ConfigStorePath:  path,
+	}
+
+	// The store starts empty, and neither changes nor closing it write over the file
+	store, err := NewConfigStore(cfg)
+	require.NoError(t, err)
+	assert.False(t, store.ConfigExists("C12345"))
+	assert.NoError(t, store.UpdateConfig("C67890", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, corrupt, data)
+}
@@ -0,0 +1,47 @@
+package slack
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// leadingMentionRegex matches the user mention Slack puts at the start of an app_mention,
+// e.g. "<@U0123ABCD> what's $50?" or "<@U0123ABCD|snagbot> what's $50?"
+var leadingMentionRegex = regexp.MustCompile(`^\s*<@[^>]+>\s*`)
+
+// StripMention removes the leading bot mention from an app_mention message
+func StripMention(text string) string {
+	return strings.TrimSpace(leadingMentionRegex.ReplaceAllString(text, ""))
+}
+
+// ProcessAppMentionEvent handles an @SnagBot mention, replying in thread just like a message,
+// even in channels that only respond to their trigger word
+func ProcessAppMentionEvent(ctx context.Context, ev *slackevents.AppMentionEvent, configStore ChannelConfigStore, api SlackAPI, cooldown *CooldownTracker, opts *ProcessorOptions) error {
+	if ev == nil {
+		return errors.New(errors.ErrInvalidRequest, "nil app mention event")
+	}
+
+	// Skip edited mentions, matching how edited messages are handled
+	if ev.Edited != nil {
+		logging.Debug("Skipping edited app mention")
+		return nil
+	}
+
+	// Run the mention through the message pipeline without the mention itself
+	// Mentioning SnagBot asks for a reply, so channels' trigger words don't apply
+	return processMessageEvent(ctx, &slackevents.MessageEvent{
+		Type:            "message",
+		User:            ev.User,
+		Text:            StripMention(ev.Text),
+		TimeStamp:       ev.TimeStamp,
+		ThreadTimeStamp: ev.ThreadTimeStamp,
+		Channel:         ev.Channel,
+		EventTimeStamp:  ev.EventTimeStamp,
+		BotID:           ev.BotID,
+	}, configStore, api, cooldown, opts, true)
+}
@@ -0,0 +1,98 @@
+package slack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripMention(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Leading mention", text: "<@U0123ABCD> what's $50?", expected: "what's $50?"},
+		{name: "Mention with label", text: "<@U0123ABCD|snagbot>   what's $50?", expected: "what's $50?"},
+		{name: "Only the leading mention is removed", text: "<@U0123ABCD> ask <@U999> about $5", expected: "ask <@U999> about $5"},
+		{name: "No mention", text: "what's $50?", expected: "what's $50?"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, StripMention(test.text))
+		})
+	}
+}
+
+func TestProcessAppMentionEvent(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+
+	event := &slackevents.AppMentionEvent{
+		Type:      "app_mention",
+		User:      "U12345",
+		Text:      "<@U0123ABCD> what's $35?",
+		TimeStamp: "1234567890.123456",
+		Channel:   "C12345",
+	}
+
+	assert.NoError(t, ProcessAppMentionEvent(context.Background(), event, store, mockAPI, nil, nil))
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Equal(t, SlackResponse{
+			ChannelID: "C12345",
+			Text:      "That's 10 Bunnings snags!",
+			ThreadTS:  "1234567890.123456",
+		}, mockAPI.SentMessages[0])
+	}
+
+	// Edited mentions are ignored
+	event.Edited = &slackevents.Edited{User: "U12345", TimeStamp: "1234567899.000000"}
+	assert.NoError(t, ProcessAppMentionEvent(context.Background(), event, store, mockAPI, nil, nil))
+	assert.Len(t, mockAPI.SentMessages, 1)
+}
+
+func TestHandleCallbackEvent_MentionRespondsOnce(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	deduper := NewSeenCache(DefaultDedupeWindow, DefaultDedupeSize)
+
+	// Slack sends the same message as both a message and an app_mention event
+	message := slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{
+		Data: &slackevents.MessageEvent{
+			Channel:   "C12345",
+			User:      "U12345",
+			Text:      "<@U0123ABCD> what's $35?",
+			TimeStamp: "1234567890.123456",
+		},
+	}}
+	mention := slackevents.EventsAPIEvent{InnerEvent: slackevents.EventsAPIInnerEvent{
+		Data: &slackevents.AppMentionEvent{
+			Channel:   "C12345",
+			User:      "U12345",
+			Text:      "<@U0123ABCD> what's $35?",
+			TimeStamp: "1234567890.123456",
+		},
+	}}
+
+	assert.NoError(t, handleCallbackEvent(context.Background(), mention, store, nil, mockAPI, nil, deduper, nil))
+	assert.NoError(t, handleCallbackEvent(context.Background(), message, store, nil, mockAPI, nil, deduper, nil))
+	assert.Len(t, mockAPI.SentMessages, 1)
+
+	// In a channel that only responds to its trigger word, the mention still asks for a reply,
+	// even when the message event arrives first
+	keyword := models.NewChannelConfig("C67890")
+	keyword.TriggerMode = models.TriggerModeKeyword
+	assert.NoError(t, store.SaveConfig(keyword))
+	message.InnerEvent.Data.(*slackevents.MessageEvent).Channel = "C67890"
+	mention.InnerEvent.Data.(*slackevents.AppMentionEvent).Channel = "C67890"
+
+	assert.NoError(t, handleCallbackEvent(context.Background(), message, store, nil, mockAPI, nil, deduper, nil))
+	assert.NoError(t, handleCallbackEvent(context.Background(), mention, store, nil, mockAPI, nil, deduper, nil))
+	if assert.Len(t, mockAPI.SentMessages, 2) {
+		assert.Equal(t, "C67890", mockAPI.SentMessages[1].ChannelID)
+	}
+}
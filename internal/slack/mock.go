@@ -62,6 +62,8 @@ func SetGlobalMockAPI(api *MockSlackAPI) {
 // ResetGlobalMockAPI clears the sent messages in the global mock API
 func ResetGlobalMockAPI() {
 	globalMockAPI.SentMessages = nil
+	globalMockAPI.EphemeralMessages = nil
+	globalMockAPI.Reactions = nil
 }
 
 // SetGlobalConfigStore sets the global config store for testing
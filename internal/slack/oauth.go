@@ -1,7 +1,14 @@
 package slack
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,17 +21,69 @@ import (
 	"github.com/mcncl/snagbot/pkg/models"
 )
 
+const (
+	// slackOAuthAccessURL is Slack's endpoint for exchanging an authorization code for a token
+	slackOAuthAccessURL = "https://slack.com/api/oauth.v2.access"
+
+	// DefaultOAuthTimeout is how long a single token exchange request may take
+	DefaultOAuthTimeout = 10 * time.Second
+
+	// DefaultOAuthMaxAttempts is how many times a token exchange is tried before giving up
+	DefaultOAuthMaxAttempts = 3
+
+	// botScopes are the bot token scopes requested on install, matching manifest.json
+	botScopes = "app_mentions:read,channels:history,chat:write,commands,groups:history,im:history,mpim:history,reactions:read,reactions:write,users:read"
+)
+
+// OAuthAPIError is returned when Slack answers the token exchange with ok set to false,
+// like an invalid or already used code, which retrying won't fix
+type OAuthAPIError struct {
+	Code string // Slack's error code, like "invalid_code"
+}
+
+func (e *OAuthAPIError) Error() string {
+	return fmt.Sprintf("slack API error: %s", e.Code)
+}
+
+// OAuthTransportError is returned when the token exchange couldn't reach Slack or Slack
+// answered with a server error, after every attempt failed
+type OAuthTransportError struct {
+	StatusCode int // HTTP status of the last response, or 0 if no response was received
+	Err        error
+}
+
+func (e *OAuthTransportError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("slack OAuth request failed with status %d: %v", e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("slack OAuth request failed: %v", e.Err)
+}
+
+func (e *OAuthTransportError) Unwrap() error {
+	return e.Err
+}
+
 // OAuthHandler handles Slack OAuth flow
 type OAuthHandler struct {
-	TokenStore TokenStore
-	Config     *config.Config
+	TokenStore   TokenStore
+	Config       *config.Config
+	httpClient   *http.Client
+	tokenURL     string                                           // Injectable for testing
+	maxAttempts  int                                              // Most tries for a token exchange
+	retryBackoff time.Duration                                    // Wait before the first retry, doubling for each one after
+	sleep        func(ctx context.Context, d time.Duration) error // Injectable for testing
 }
 
 // NewOAuthHandler creates a new OAuth handler
 func NewOAuthHandler(tokenStore TokenStore, cfg *config.Config) *OAuthHandler {
 	return &OAuthHandler{
-		TokenStore: tokenStore,
-		Config:     cfg,
+		TokenStore:   tokenStore,
+		Config:       cfg,
+		httpClient:   &http.Client{Timeout: DefaultOAuthTimeout},
+		tokenURL:     slackOAuthAccessURL,
+		maxAttempts:  DefaultOAuthMaxAttempts,
+		retryBackoff: DefaultRetryBackoff,
+		sleep:        sleepContext,
 	}
 }
 
@@ -42,7 +101,12 @@ func (h *OAuthHandler) HandleInstall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate state parameter for security
-	state := fmt.Sprintf("%d", time.Now().UnixNano())
+	state, err := h.newState()
+	if err != nil {
+		logging.Error("Failed to generate OAuth state: %v", err)
+		http.Error(w, "Failed to start OAuth flow", http.StatusInternalServerError)
+		return
+	}
 
 	// Store state in cookie for verification
 	cookie := http.Cookie{
@@ -58,8 +122,9 @@ func (h *OAuthHandler) HandleInstall(w http.ResponseWriter, r *http.Request) {
 
 	// Construct the OAuth URL
 	authURL := fmt.Sprintf(
-		"https://slack.com/oauth/v2/authorize?client_id=%s&scope=channels:history,chat:write,commands&redirect_uri=%s&state=%s",
+		"https://slack.com/oauth/v2/authorize?client_id=%s&scope=%s&redirect_uri=%s&state=%s",
 		h.Config.SlackClientID,
+		botScopes,
 		url.QueryEscape(h.Config.OAuthRedirectURL),
 		state,
 	)
@@ -83,7 +148,7 @@ func (h *OAuthHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	state := r.URL.Query().Get("state")
-	if state == "" || state != stateCookie.Value {
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(stateCookie.Value)) != 1 || !h.validState(state) {
 		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
 		return
 	}
@@ -105,7 +170,7 @@ func (h *OAuthHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange code for token
-	token, err := h.exchangeCodeForToken(code)
+	token, err := h.exchangeCodeForToken(r.Context(), code)
 	if err != nil {
 		logging.Error("Failed to exchange code for token: %v", err)
 		http.Error(w, "Failed to complete OAuth flow", http.StatusInternalServerError)
@@ -145,8 +210,44 @@ func (h *OAuthHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
 </html>`, token.TeamName)
 }
 
+// newState generates a random OAuth state, signed with COOKIE_SECRET when it's set so a
+// state can't be forged along with its cookie
+func (h *OAuthHandler) newState() (string, error) {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	state := hex.EncodeToString(nonce)
+	if h.Config.CookieSecret == "" {
+		return state, nil
+	}
+	return state + "." + h.signState(state), nil
+}
+
+// validState checks an OAuth state carries a valid signature, when COOKIE_SECRET is set
+func (h *OAuthHandler) validState(state string) bool {
+	if h.Config.CookieSecret == "" {
+		return true
+	}
+
+	nonce, signature, ok := strings.Cut(state, ".")
+	if !ok || nonce == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(h.signState(nonce)))
+}
+
+// signState returns the hex HMAC-SHA256 of a state nonce keyed with COOKIE_SECRET
+func (h *OAuthHandler) signState(nonce string) string {
+	mac := hmac.New(sha256.New, []byte(h.Config.CookieSecret))
+	mac.Write([]byte(nonce))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // exchangeCodeForToken exchanges an authorization code for a token
-func (h *OAuthHandler) exchangeCodeForToken(code string) (*models.WorkspaceToken, error) {
+// Network failures and server errors are retried with backoff, Slack API errors are not
+func (h *OAuthHandler) exchangeCodeForToken(ctx context.Context, code string) (*models.WorkspaceToken, error) {
 	// Prepare the request body
 	data := url.Values{}
 	data.Set("code", code)
@@ -154,16 +255,24 @@ func (h *OAuthHandler) exchangeCodeForToken(code string) (*models.WorkspaceToken
 	data.Set("client_secret", h.Config.SlackClientSecret)
 	data.Set("redirect_uri", h.Config.OAuthRedirectURL)
 
-	// Make the request to Slack
-	resp, err := http.PostForm("https://slack.com/api/oauth.v2.access", data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request to Slack API: %w", err)
-	}
-	defer resp.Body.Close()
+	var body []byte
+	backoff := h.retryBackoff
+	for attempt := 1; ; attempt++ {
+		var err error
+		body, err = h.postTokenRequest(ctx, data)
+		if err == nil {
+			break
+		}
+		var transportErr *OAuthTransportError
+		if !errors.As(err, &transportErr) || attempt >= h.maxAttempts || ctx.Err() != nil {
+			return nil, err
+		}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		logging.Warn("Slack token exchange failed, retrying in %s (attempt %d of %d): %v", backoff, attempt+1, h.maxAttempts, err)
+		if err := h.sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+		backoff *= 2
 	}
 
 	// Parse the response
@@ -189,7 +298,7 @@ func (h *OAuthHandler) exchangeCodeForToken(code string) (*models.WorkspaceToken
 	}
 
 	if !tokenResp.OK {
-		return nil, fmt.Errorf("slack API error: %s", tokenResp.Error)
+		return nil, &OAuthAPIError{Code: tokenResp.Error}
 	}
 
 	// Create and return the workspace token
@@ -206,6 +315,33 @@ func (h *OAuthHandler) exchangeCodeForToken(code string) (*models.WorkspaceToken
 	return token, nil
 }
 
+// postTokenRequest makes one token exchange request, returning the response body
+// Failures to reach Slack and server errors are returned as an OAuthTransportError
+func (h *OAuthHandler) postTokenRequest(ctx context.Context, data url.Values) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, &OAuthTransportError{Err: err}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &OAuthTransportError{StatusCode: resp.StatusCode, Err: fmt.Errorf("failed to read response body: %w", err)}
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return nil, &OAuthTransportError{StatusCode: resp.StatusCode, Err: fmt.Errorf("server error: %s", strings.TrimSpace(string(body)))}
+	}
+
+	return body, nil
+}
+
 // SetupOAuthHandlers registers the OAuth endpoints
 func SetupOAuthHandlers(mux *http.ServeMux, tokenStore TokenStore, cfg *config.Config) {
 	handler := NewOAuthHandler(tokenStore, cfg)
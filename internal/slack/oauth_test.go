@@ -0,0 +1,239 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tokenResponse is a successful oauth.v2.access response
+const tokenResponse = `{"ok":true,"access_token":"xoxb-test","token_type":"bot","scope":"chat:write","bot_user_id":"B123","team":{"id":"T123","name":"Test Team"},"authed_user":{"id":"U123"}}`
+
+// newTestOAuthHandler creates an OAuthHandler exchanging tokens with a fake Slack that
+// answers each request with handler, recording retry waits instead of sleeping
+func newTestOAuthHandler(t *testing.T, handler http.HandlerFunc) (*OAuthHandler, *[]time.Duration) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	h := NewOAuthHandler(nil, &config.Config{SlackClientID: "client", SlackClientSecret: "secret"})
+	h.tokenURL = server.URL
+
+	var waits []time.Duration
+	h.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return h, &waits
+}
+
+func TestExchangeCodeForToken_RetriesServerErrors(t *testing.T) {
+	var calls int32
+	h, waits := newTestOAuthHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			http.Error(w, "upstream unavailable", http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "the-code", r.FormValue("code"))
+		fmt.Fprint(w, tokenResponse)
+	})
+
+	token, err := h.exchangeCodeForToken(context.Background(), "the-code")
+	require.NoError(t, err)
+	assert.Equal(t, "T123", token.WorkspaceID)
+	assert.Equal(t, "xoxb-test", token.AccessToken)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, []time.Duration{DefaultRetryBackoff}, *waits)
+}
+
+func TestExchangeCodeForToken_GivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int32
+	h, waits := newTestOAuthHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := h.exchangeCodeForToken(context.Background(), "the-code")
+
+	var transportErr *OAuthTransportError
+	require.True(t, errors.As(err, &transportErr))
+	assert.Equal(t, http.StatusBadGateway, transportErr.StatusCode)
+	assert.Equal(t, int32(DefaultOAuthMaxAttempts), atomic.LoadInt32(&calls))
+	assert.Equal(t, []time.Duration{DefaultRetryBackoff, 2 * DefaultRetryBackoff}, *waits)
+}
+
+func TestExchangeCodeForToken_DoesNotRetryAPIErrors(t *testing.T) {
+	var calls int32
+	h, waits := newTestOAuthHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"ok":false,"error":"invalid_code"}`)
+	})
+
+	_, err := h.exchangeCodeForToken(context.Background(), "the-code")
+
+	var apiErr *OAuthAPIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "invalid_code", apiErr.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Empty(t, *waits)
+}
+
+func TestExchangeCodeForToken_RetriesNetworkErrors(t *testing.T) {
+	h, waits := newTestOAuthHandler(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	// Nothing listens on the closed server's address, so every request fails to connect
+	server := httptest.NewServer(http.NotFoundHandler())
+	h.tokenURL = server.URL
+	server.Close()
+
+	_, err := h.exchangeCodeForToken(context.Background(), "the-code")
+
+	var transportErr *OAuthTransportError
+	require.True(t, errors.As(err, &transportErr))
+	assert.Zero(t, transportErr.StatusCode)
+	assert.Len(t, *waits, DefaultOAuthMaxAttempts-1)
+}
+
+// startOAuthFlow runs HandleInstall, returning the state Slack would be sent and the state cookie
+func startOAuthFlow(t *testing.T, h *OAuthHandler) (string, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.HandleInstall(rec, httptest.NewRequest(http.MethodGet, "/api/oauth/install", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	return location.Query().Get("state"), cookies[0]
+}
+
+// callbackRequest builds the request Slack redirects back with, attaching the cookie if there is one
+func callbackRequest(state string, cookie *http.Cookie) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/oauth/callback?code=the-code&state="+url.QueryEscape(state), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+// newStateTestOAuthHandler creates an OAuthHandler with multi-workspace enabled and a fake Slack
+func newStateTestOAuthHandler(t *testing.T) *OAuthHandler {
+	t.Helper()
+
+	h, _ := newTestOAuthHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, tokenResponse)
+	})
+	h.TokenStore = &mockTokenStore{}
+	h.Config.EnableMultiWorkspace = true
+	h.Config.UseRedis = true
+	h.Config.CookieSecret = "test-cookie-secret"
+	return h
+}
+
+func TestOAuthState_ValidFlow(t *testing.T) {
+	h := newStateTestOAuthHandler(t)
+	state, cookie := startOAuthFlow(t, h)
+
+	nonce, signature, ok := strings.Cut(state, ".")
+	require.True(t, ok, "state should be signed")
+	assert.Len(t, nonce, 64)
+	assert.NotEmpty(t, signature)
+	assert.Equal(t, state, cookie.Value)
+
+	rec := httptest.NewRecorder()
+	h.HandleCallback(rec, callbackRequest(state, cookie))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Test Team")
+}
+
+func TestHandleInstall_RequestsManifestScopes(t *testing.T) {
+	data, err := os.ReadFile("../../manifest.json")
+	require.NoError(t, err)
+	var manifest struct {
+		OAuthConfig struct {
+			Scopes struct {
+				Bot []string `json:"bot"`
+			} `json:"scopes"`
+		} `json:"oauth_config"`
+	}
+	require.NoError(t, json.Unmarshal(data, &manifest))
+
+	h := newStateTestOAuthHandler(t)
+	rec := httptest.NewRecorder()
+	h.HandleInstall(rec, httptest.NewRequest(http.MethodGet, "/api/oauth/install", nil))
+	require.Equal(t, http.StatusFound, rec.Code)
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.ElementsMatch(t, manifest.OAuthConfig.Scopes.Bot, strings.Split(location.Query().Get("scope"), ","))
+}
+
+func TestOAuthState_IsRandom(t *testing.T) {
+	h := newStateTestOAuthHandler(t)
+	first, _ := startOAuthFlow(t, h)
+	second, _ := startOAuthFlow(t, h)
+	assert.NotEqual(t, first, second)
+}
+
+func TestOAuthState_ForgedState(t *testing.T) {
+	h := newStateTestOAuthHandler(t)
+	state, _ := startOAuthFlow(t, h)
+	nonce, _, _ := strings.Cut(state, ".")
+
+	tests := []struct {
+		name  string
+		state string
+	}{
+		{"unsigned", nonce},
+		{"wrong signature", nonce + ".deadbeef"},
+		{"signed with another secret", nonce + "." + (&OAuthHandler{Config: &config.Config{CookieSecret: "other"}}).signState(nonce)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The attacker controls both the cookie and the state, so they match
+			cookie := &http.Cookie{Name: "snagbot_state", Value: tt.state}
+
+			rec := httptest.NewRecorder()
+			h.HandleCallback(rec, callbackRequest(tt.state, cookie))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
+
+func TestOAuthState_MismatchedCookie(t *testing.T) {
+	h := newStateTestOAuthHandler(t)
+	state, _ := startOAuthFlow(t, h)
+	_, otherCookie := startOAuthFlow(t, h)
+
+	rec := httptest.NewRecorder()
+	h.HandleCallback(rec, callbackRequest(state, otherCookie))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestOAuthState_MissingCookie(t *testing.T) {
+	h := newStateTestOAuthHandler(t)
+	state, _ := startOAuthFlow(t, h)
+
+	rec := httptest.NewRecorder()
+	h.HandleCallback(rec, callbackRequest(state, nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "missing cookie")
+}
@@ -1,51 +1,185 @@
 package slack
 
 import (
+	"context"
+	"slices"
+	"time"
+
 	"github.com/mcncl/snagbot/internal/calculator"
+	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
 	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/metrics"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 )
 
-// ProcessMessageEvent handles a message event from Slack
+// DefaultReactionEmoji is the emoji used when a channel responds with reactions and hasn't chosen one
+const DefaultReactionEmoji = models.DefaultEmoji
+
+// ProcessorOptions holds the deployment's settings for which messages are processed, built
+// from config by NewProcessorOptions. A nil *ProcessorOptions uses the defaults
+type ProcessorOptions struct {
+	allowedSubTypes map[string]bool // When not empty, the only subtypes processed
+	ignoredSubTypes map[string]bool // Subtypes never processed, nil uses the defaults
+	ownUserID       string          // SnagBot's own bot user ID, empty if not known
+	ownBotID        string          // SnagBot's own bot ID, empty if not known
+	respondToBots   bool            // Whether other bots' messages get responses
+	convertReaction string          // Emoji that asks SnagBot to convert a message, empty turns this off
+	amounts         *calculator.Options
+	responses       *KillSwitch // Turns replies and reactions off at runtime, nil leaves them on
+}
+
+// NewProcessorOptions returns the processor options set by cfg
+func NewProcessorOptions(cfg *config.Config) *ProcessorOptions {
+	if cfg == nil {
+		return nil
+	}
+	return &ProcessorOptions{
+		allowedSubTypes: cfg.MessageSubTypes,
+		ignoredSubTypes: cfg.IgnoredMessageSubTypes,
+		ownUserID:       cfg.SlackBotUserID,
+		ownBotID:        cfg.SlackBotID,
+		respondToBots:   cfg.BotMessages == config.BotMessagesRespond,
+		convertReaction: cfg.ConvertReaction,
+		amounts:         calculator.NewOptions(cfg),
+	}
+}
+
+// ResponsesEnabled reports whether replies and reactions are posted, always true without options
+func (o *ProcessorOptions) ResponsesEnabled() bool {
+	return o == nil || o.responses.Enabled()
+}
+
+// AmountOptions returns the options amounts in messages are read with, and items and wordings
+// are picked with, nil for the defaults
+func (o *ProcessorOptions) AmountOptions() *calculator.Options {
+	if o == nil {
+		return nil
+	}
+	return o.amounts
+}
+
+// ProcessMessageEvent handles a message event from Slack with the default processor options
 func ProcessMessageEvent(ev *slackevents.MessageEvent, configStore ChannelConfigStore, api SlackAPI) error {
+	return ProcessMessageEventWithCooldown(context.Background(), ev, configStore, api, nil, nil)
+}
+
+// ProcessMessageEventWithCooldown handles a message event from Slack, skipping the
+// response if the channel has already had one within the cooldown window
+// Processing stops, without responding, once ctx is cancelled
+func ProcessMessageEventWithCooldown(ctx context.Context, ev *slackevents.MessageEvent, configStore ChannelConfigStore, api SlackAPI, cooldown *CooldownTracker, opts *ProcessorOptions) error {
+	return processMessageEvent(ctx, ev, configStore, api, cooldown, opts, false)
+}
+
+// processMessageEvent does the work of ProcessMessageEventWithCooldown
+// requested is set for messages someone asked SnagBot to convert, by mentioning it or reacting,
+// so they're converted even in channels that only respond to their trigger word
+func processMessageEvent(ctx context.Context, ev *slackevents.MessageEvent, configStore ChannelConfigStore, api SlackAPI, cooldown *CooldownTracker, opts *ProcessorOptions, requested bool) error {
 	// Skip processing if the event is nil
 	if ev == nil {
 		return errors.New(errors.ErrInvalidRequest, "nil message event")
 	}
 
-	// Skip bot messages to prevent loops
-	if ev.BotID != "" || ev.SubType == "bot_message" {
+	// Don't start on a message nobody is waiting for any more
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	configStore = WithContext(ctx, configStore)
+
+	// Stay quiet in ignored channels and for ignored users, such as noisy integrations
+	// Direct messages are ignored this way too when IGNORE_DIRECT_MESSAGES is set
+	if IsIgnored(configStore, ev.Channel, ev.User) {
+		logging.Debug("Ignoring message from user %s in %s %s", ev.User, ConversationType(ev.Channel), ev.Channel)
+		return nil
+	}
+
+	// Skip SnagBot's own messages, and other bots' unless configured, to prevent loops
+	if opts.SkipsBotMessage(ev.BotID, ev.User, ev.SubType) {
 		logging.Debug("Skipping bot message from BotID: %s", ev.BotID)
 		return nil
 	}
 
-	// Skip message changes/edits for now (can be implemented later)
-	if ev.SubType == "message_changed" {
-		logging.Debug("Skipping message_changed event")
+	// Skip subtypes this deployment doesn't respond to, like channel joins
+	if !opts.ProcessesSubType(ev.SubType) {
+		logging.Debug("Skipping message with subtype %s", ev.SubType)
 		return nil
 	}
 
+	// Process the new text of edited messages as if it had just been posted
+	if ev.SubType == "message_changed" {
+		edited := editedMessageEvent(ev, configStore, opts)
+		if edited == nil {
+			logging.Debug("Skipping message_changed event")
+			return nil
+		}
+		return processMessageEvent(ctx, edited, configStore, api, cooldown, opts, requested)
+	}
+
+	// Record the message and how long it takes to process
+	m := metrics.Default()
+	m.MessagesProcessed.Inc()
+	start := time.Now()
+	defer func() {
+		m.ProcessingDuration.Observe(time.Since(start).Seconds())
+	}()
+
 	// Get channel configuration
 	config, err := configStore.GetConfig(ev.Channel)
 	if err != nil {
 		appErr := errors.Wrap(err, "Failed to get channel configuration")
 		logging.Error("Config retrieval error: %v", appErr)
-		HandleErrorWithResponse(appErr, ev, api)
+		HandleErrorWithResponse(ctx, appErr, ev, api, opts)
 		return appErr
 	}
 
+	// Channels switched off with /snagbot off stay quiet, keeping their config for later
+	if !config.Enabled() {
+		logging.Debug("SnagBot is switched off in channel %s, skipping", ev.Channel)
+		return nil
+	}
+
+	// Channels in keyword mode only get a response when they ask for one, by trigger word or reaction
+	if !config.Triggered(ev.Text) && !requested {
+		logging.Debug("Message has no trigger word %q, skipping", config.TriggerKeyword())
+		return nil
+	}
+
+	// Pick the item for this response so the count and name always match
+	config = calculator.ChooseItemWithOptions(config, opts.AmountOptions())
+
 	logging.Debug("Processing message: %s", ev.Text)
 	logging.Debug("Using channel config: item=%s, price=%.2f", config.ItemName, config.ItemPrice)
 
-	// Extract dollar values from the message
-	dollarValues, err := calculator.ExtractDollarValues(ev.Text)
-	if err != nil {
+	// Extract dollar values from the message, up to the channel's cap
+	text := ev.Text
+	dollarValues, err := calculator.ExtractDollarValuesWithOptions(text, config, opts.AmountOptions())
+
+	// Formatted messages can have their amounts only in rich_text blocks, so try those next
+	if err == nil && len(dollarValues) == 0 {
+		if blockText := RichTextFromBlocks(ev.Blocks); blockText != "" && blockText != text {
+			logging.Debug("No dollar values in message text, trying its rich text blocks")
+			text = blockText
+			dollarValues, err = calculator.ExtractDollarValuesWithOptions(text, config, opts.AmountOptions())
+		}
+	}
+	truncated := calculator.IsTooManyDollarValues(err)
+	if truncated && config.SkipsTooManyValues() {
+		logging.Info("Message has more than %d dollar values, skipping", config.DollarValueLimit())
+		return nil
+	}
+	if err != nil && !truncated {
 		appErr := errors.Wrap(err, "Failed to extract dollar values")
 		logging.Error("Dollar value extraction error: %v", appErr)
 		return appErr
 	}
 
+	// Drop amounts that aren't really spending, like "a $0 fee" or "$35 off", if the channel asks
+	dollarValues = calculator.FilterFalseMatchesWithOptions(text, dollarValues, config, opts.AmountOptions())
+
+	m.DollarValuesExtracted.Add(float64(len(dollarValues)))
+
 	if len(dollarValues) == 0 {
 		// No dollar values found, nothing to do
 		logging.Debug("No dollar values found in message, skipping")
@@ -54,59 +188,273 @@ func ProcessMessageEvent(ev *slackevents.MessageEvent, configStore ChannelConfig
 
 	logging.Info("Found %d dollar values in message", len(dollarValues))
 
+	// Fall back to including negative amounts if the stored policy is unrecognised
+	policy, err := calculator.ParseNegativeHandling(config.NegativeHandling)
+	if err != nil {
+		logging.Warn("Invalid negative handling for channel %s, including negatives: %v", ev.Channel, err)
+	}
+
 	// Calculate total dollar amount
-	total, err := calculator.SumDollarValues(dollarValues)
+	total, err := calculator.SumDollarValuesWithPolicy(dollarValues, policy)
 	if err != nil {
 		appErr := errors.Wrap(err, "Failed to sum dollar values")
 		logging.Error("Dollar value summation error: %v", appErr)
-		HandleErrorWithResponse(appErr, ev, api)
+		HandleErrorWithResponse(ctx, appErr, ev, api, opts)
 		return appErr
 	}
 
 	logging.Debug("Total dollar amount: $%.2f", total)
 
+	// Nothing sensible to say about a net loss
+	if total < 0 {
+		logging.Debug("Total $%.2f is negative, skipping", total)
+		return nil
+	}
+
+	// Stay quiet for totals under the channel's minimum
+	if config.BelowThreshold(total) {
+		logging.Debug("Total $%.2f is below channel threshold $%.2f, skipping", total, config.MinThreshold)
+		return nil
+	}
+
+	// The kill switch stops replies while still processing messages, so the logs show what
+	// SnagBot would have said
+	if !opts.ResponsesEnabled() {
+		logging.Info("Responses are disabled, not responding to $%.2f in channel %s", total, ev.Channel)
+		return nil
+	}
+
+	// Let people know when some amounts were left out
+	note := ""
+	if truncated {
+		note = calculator.FormatTruncatedNote(config.DollarValueLimit())
+	}
+
+	// Convert each line on its own if the channel asks, for invoices with one cost per line
+	// Reactions can't list lines, so they still use the total
+	if config.SumsPerLine() && !config.RespondsWithReaction() {
+		if message := calculator.FormatPerLineResponseWithOptions(text, config, opts.AmountOptions()); message != "" {
+			logging.Info("Responding with per-line message: %s", message+note)
+			return postTextResponse(ctx, api, configStore, cooldown, ev, config, message+note, total)
+		}
+	}
+
 	// For very small amounts that don't reach 1 item
-	if total < config.ItemPrice {
-		// Use the standard "zero" response
-		message := calculator.FormatResponse(0, config.ItemName, true)
+	if total < config.UnitPrice() {
+		// A reaction can't say "not even one", so stay quiet in reaction mode
+		if config.RespondsWithReaction() {
+			logging.Debug("Amount too small for one item, skipping reaction")
+			return nil
+		}
+
+		// Use the "zero" response, or the channel's own message
+		message := calculator.FormatResponseWithOptions(0, total, true, config, opts.AmountOptions()) + note
 		logging.Debug("Amount too small for one item, using zero response: %s", message)
 
-		return api.PostMessage(SlackResponse{
+		if !cooldown.Allow(ev.Channel) {
+			logging.Debug("Channel %s is in cooldown, skipping response", ev.Channel)
+			return nil
+		}
+
+		if err := postResponse(ctx, api, SlackResponse{
 			ChannelID: ev.Channel,
 			Text:      message,
-			ThreadTS:  ev.TimeStamp,
-		})
+			ThreadTS:  replyThreadTS(ev, config),
+			Blocks:    responseBlocks(message, ev, config),
+		}, ev, config); err != nil {
+			return err
+		}
+
+		m.ResponsesSent.Inc()
+		recordResponse(configStore, ev.Channel, total)
+		return nil
+	}
+
+	// Fall back to rounding up if the stored mode is unrecognised
+	mode, err := calculator.ParseRoundingMode(config.RoundingMode)
+	if err != nil {
+		logging.Warn("Invalid rounding mode for channel %s, rounding up: %v", ev.Channel, err)
 	}
 
 	// Check if the division is exact (to decide whether to use "nearly")
-	isExactDivision := calculator.IsExactDivision(total, config.ItemPrice)
+	isExactDivision := calculator.UsesExactWording(total, config.UnitPrice(), mode)
 
 	// Calculate number of items
-	count, err := calculator.CalculateItemCount(total, config.ItemPrice)
+	count, err := calculator.CalculateItemCountWithMode(total, config.UnitPrice(), mode)
 	if err != nil {
 		appErr := errors.Wrap(err, "Failed to calculate item count")
 		logging.Error("Item count calculation error: %v", appErr)
-		HandleErrorWithResponse(appErr, ev, api)
+		HandleErrorWithResponse(ctx, appErr, ev, api, opts)
 		return appErr
 	}
 
+	if !cooldown.Allow(ev.Channel) {
+		logging.Debug("Channel %s is in cooldown, skipping response", ev.Channel)
+		return nil
+	}
+
+	// React to the original message instead of replying if the channel prefers it
+	if config.RespondsWithReaction() {
+		if err := api.AddReaction(ctx, ev.Channel, ev.TimeStamp, config.EmojiName()); err != nil {
+			appErr := errors.Wrap(err, "Failed to add reaction in Slack")
+			logging.Error("Slack API error: %v", appErr)
+			return appErr
+		}
+
+		m.ResponsesSent.Inc()
+		recordResponse(configStore, ev.Channel, total)
+		logging.Info("Successfully reacted to message in channel %s", ev.Channel)
+		return nil
+	}
+
 	// Format response message
-	message := calculator.FormatResponse(count, config.ItemName, isExactDivision)
+	message := calculator.FormatResponseWithBreakdown(count, dollarValues, total, isExactDivision, config, opts.AmountOptions()) + note
 	logging.Info("Responding with message: %s", message)
 
-	// Send response as a thread
+	// Send response in the message's thread, or to the channel if it prefers
 	response := SlackResponse{
 		ChannelID: ev.Channel,
 		Text:      message,
-		ThreadTS:  ev.TimeStamp,
+		ThreadTS:  replyThreadTS(ev, config),
+		Blocks:    responseBlocks(message, ev, config),
 	}
 
-	if err := api.PostMessage(response); err != nil {
+	if err := postResponse(ctx, api, response, ev, config); err != nil {
 		appErr := errors.Wrap(err, "Failed to post message to Slack")
 		logging.Error("Slack API error: %v", appErr)
 		return appErr
 	}
 
+	m.ResponsesSent.Inc()
+	recordResponse(configStore, ev.Channel, total)
 	logging.Info("Successfully posted response to channel %s", ev.Channel)
 	return nil
 }
+
+// postTextResponse sends a text response to the message unless the channel is in cooldown,
+// counting it in the channel's stats
+func postTextResponse(ctx context.Context, api SlackAPI, configStore ChannelConfigStore, cooldown *CooldownTracker, ev *slackevents.MessageEvent, config *models.ChannelConfig, message string, total float64) error {
+	if !cooldown.Allow(ev.Channel) {
+		logging.Debug("Channel %s is in cooldown, skipping response", ev.Channel)
+		return nil
+	}
+
+	response := SlackResponse{
+		ChannelID: ev.Channel,
+		Text:      message,
+		ThreadTS:  replyThreadTS(ev, config),
+		Blocks:    responseBlocks(message, ev, config),
+	}
+	if err := postResponse(ctx, api, response, ev, config); err != nil {
+		appErr := errors.Wrap(err, "Failed to post message to Slack")
+		logging.Error("Slack API error: %v", appErr)
+		return appErr
+	}
+
+	metrics.Default().ResponsesSent.Inc()
+	recordResponse(configStore, ev.Channel, total)
+	return nil
+}
+
+// postResponse sends a response to the message, only to its author if the channel wants
+// responses kept private
+func postResponse(ctx context.Context, api SlackAPI, response SlackResponse, ev *slackevents.MessageEvent, config *models.ChannelConfig) error {
+	if config.Ephemeral && ev.User != "" {
+		response.UserID = ev.User
+		return api.PostEphemeral(ctx, response)
+	}
+	return api.PostMessage(ctx, response)
+}
+
+// recordResponse adds a response to the channel's stats if the store keeps them
+func recordResponse(configStore ChannelConfigStore, channelID string, total float64) {
+	if recorder, ok := baseStore(configStore).(ChannelStatsRecorder); ok {
+		recorder.RecordResponse(channelID, total)
+	}
+}
+
+// replyThreadTS returns the thread a response to the message belongs in, or an empty
+// string to post it as a top-level message in the channel
+// A direct message is already a one-to-one conversation, so replies there only go in a
+// thread if the message was in one
+func replyThreadTS(ev *slackevents.MessageEvent, config *models.ChannelConfig) string {
+	if !config.ReplyInThread() {
+		return ""
+	}
+	if IsDirectMessage(ev.Channel) {
+		return parentThreadTS(ev)
+	}
+	return threadTS(ev)
+}
+
+// threadTS returns the thread a reply to the message goes in: the thread the message is
+// already in, or a new one started from the message
+func threadTS(ev *slackevents.MessageEvent) string {
+	if parent := parentThreadTS(ev); parent != "" {
+		return parent
+	}
+	return ev.TimeStamp
+}
+
+// parentThreadTS returns the thread the message was posted in, or an empty string if it
+// wasn't posted in one
+// Replies also sent to the channel (thread_broadcast) are in their root message's thread,
+// which Slack includes with the event as well as the thread_ts
+func parentThreadTS(ev *slackevents.MessageEvent) string {
+	if ev.ThreadTimeStamp != "" {
+		return ev.ThreadTimeStamp
+	}
+	if ev.SubType == slack.MsgSubTypeThreadBroadcast && ev.Root != nil {
+		return ev.Root.TimeStamp
+	}
+	return ""
+}
+
+// responseBlocks returns the Block Kit layout for a response, or nil for a plain text message
+func responseBlocks(message string, ev *slackevents.MessageEvent, config *models.ChannelConfig) []slack.Block {
+	if !config.ChangeItemButton {
+		return nil
+	}
+	return ChangeItemBlocks(message, ev.Channel)
+}
+
+// editedMessageEvent returns the edited message from a message_changed event so it can be
+// processed like a new message, replying in the original message's thread
+// Returns nil if the edit should be ignored: bot edits, nested edits, and edits that don't
+// change the dollar amounts (e.g. typo fixes or link unfurls)
+func editedMessageEvent(ev *slackevents.MessageEvent, configStore ChannelConfigStore, opts *ProcessorOptions) *slackevents.MessageEvent {
+	message := ev.Message
+	if message == nil || message.SubType == "message_changed" {
+		return nil
+	}
+
+	// Don't reprocess edits to our own replies, or other bots' messages unless configured
+	if opts.SkipsBotMessage(message.BotID, message.User, message.SubType) {
+		return nil
+	}
+
+	if ev.PreviousMessage != nil {
+		config, err := configStore.GetConfig(ev.Channel)
+		if err != nil {
+			logging.Warn("Failed to get configuration for edited message: %v", err)
+			return nil
+		}
+
+		previous, _ := calculator.ExtractDollarValuesWithOptions(ev.PreviousMessage.Text, config, opts.AmountOptions())
+		current, _ := calculator.ExtractDollarValuesWithOptions(message.Text, config, opts.AmountOptions())
+		if slices.Equal(previous, current) {
+			return nil
+		}
+	}
+
+	return &slackevents.MessageEvent{
+		Type:            "message",
+		User:            message.User,
+		Text:            message.Text,
+		Blocks:          message.Blocks,
+		TimeStamp:       message.TimeStamp,
+		ThreadTimeStamp: message.ThreadTimeStamp,
+		Channel:         ev.Channel,
+		EventTimeStamp:  ev.EventTimeStamp,
+	}
+}
@@ -0,0 +1,795 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/metrics"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessMessageEvent_ReactionMode(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	config := models.NewChannelConfig("C12345")
+	config.ResponseMode = models.ResponseModeReaction
+	assert.NoError(t, store.SaveConfig(config))
+
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "This costs $35",
+		TS:        "1234567890.123456",
+	}
+
+	err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+	assert.NoError(t, err)
+
+	// The message gets a reaction rather than a reply
+	assert.Len(t, mockAPI.SentMessages, 0)
+	if assert.Len(t, mockAPI.Reactions, 1) {
+		assert.Equal(t, MockReaction{
+			ChannelID: "C12345",
+			Timestamp: "1234567890.123456",
+			Emoji:     DefaultReactionEmoji,
+		}, mockAPI.Reactions[0])
+	}
+
+	// Amounts too small for one item are skipped entirely
+	event.Text = "This costs $2"
+	event.TS = "1234567890.654321"
+	err = ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+	assert.NoError(t, err)
+	assert.Len(t, mockAPI.SentMessages, 0)
+	assert.Len(t, mockAPI.Reactions, 1)
+}
+
+func TestProcessMessageEvent_ReactionEmoji(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	config := models.NewChannelConfig("C12345")
+	config.ResponseMode = models.ResponseModeReaction
+	config.Emoji = "taco"
+	assert.NoError(t, store.SaveConfig(config))
+
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "This costs $35",
+		TS:        "1234567890.123456",
+	}
+
+	err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+	assert.NoError(t, err)
+	if assert.Len(t, mockAPI.Reactions, 1) {
+		assert.Equal(t, "taco", mockAPI.Reactions[0].Emoji)
+	}
+}
+
+func TestProcessMessageEvent_MessageModeByDefault(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "This costs $35",
+		TS:        "1234567890.123456",
+	}
+
+	err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+	assert.NoError(t, err)
+	assert.Len(t, mockAPI.Reactions, 0)
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Equal(t, "That's 10 Bunnings snags!", mockAPI.SentMessages[0].Text)
+		assert.Equal(t, "1234567890.123456", mockAPI.SentMessages[0].ThreadTS)
+	}
+}
+
+func TestProcessMessageEvent_ReplyPlacement(t *testing.T) {
+	tests := []struct {
+		name             string
+		placement        string
+		text             string
+		expectedThreadTS string
+	}{
+		{name: "Thread by default", text: "This costs $35", expectedThreadTS: "1234567890.123456"},
+		{name: "Thread", placement: models.ReplyPlacementThread, text: "This costs $35", expectedThreadTS: "1234567890.123456"},
+		{name: "Channel", placement: models.ReplyPlacementChannel, text: "This costs $35", expectedThreadTS: ""},
+		{name: "Channel with too small amount", placement: models.ReplyPlacementChannel, text: "This costs $2", expectedThreadTS: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStore()
+			config := models.NewChannelConfig("C12345")
+			config.ReplyPlacement = test.placement
+			assert.NoError(t, store.SaveConfig(config))
+
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      test.text,
+				TS:        "1234567890.123456",
+			}
+
+			err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+			assert.NoError(t, err)
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, test.expectedThreadTS, mockAPI.SentMessages[0].ThreadTS)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_TriggerMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		triggerMode string
+		triggerWord string
+		text        string
+		responds    bool
+	}{
+		{name: "Always without trigger", triggerMode: models.TriggerModeAlways, text: "This costs $35", responds: true},
+		{name: "Always with trigger", triggerMode: models.TriggerModeAlways, text: "This costs $35 snagify", responds: true},
+		{name: "Unset mode responds always", text: "This costs $35", responds: true},
+		{name: "Keyword without trigger", triggerMode: models.TriggerModeKeyword, text: "This costs $35", responds: false},
+		{name: "Keyword with trigger", triggerMode: models.TriggerModeKeyword, text: "Snagify! This costs $35", responds: true},
+		{name: "Keyword with trigger inside a word", triggerMode: models.TriggerModeKeyword, text: "This costs $35 #snagifying", responds: false},
+		{name: "Keyword with custom trigger", triggerMode: models.TriggerModeKeyword, triggerWord: "sausage", text: "This costs $35, sausage it", responds: true},
+		{name: "Keyword with default trigger after custom", triggerMode: models.TriggerModeKeyword, triggerWord: "sausage", text: "This costs $35 snagify", responds: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStore()
+			assert.NoError(t, store.SaveConfig(&models.ChannelConfig{
+				ChannelID:   "C12345",
+				ItemName:    "Bunnings snags",
+				ItemPrice:   3.50,
+				TriggerMode: test.triggerMode,
+				TriggerWord: test.triggerWord,
+			}))
+
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: test.text, TS: "1234567890.123456"}
+
+			assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+			if !test.responds {
+				assert.Empty(t, mockAPI.Messages())
+				return
+			}
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, "That's 10 Bunnings snags!", mockAPI.SentMessages[0].Text)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_ConversationTypes(t *testing.T) {
+	tests := []struct {
+		name             string
+		channelID        string
+		threadTS         string
+		ignoreDMs        bool
+		responds         bool
+		expectedThreadTS string
+	}{
+		{name: "Channel", channelID: "C12345", responds: true, expectedThreadTS: "1234567890.123456"},
+		{name: "Threaded reply in channel", channelID: "C12345", threadTS: "1234567800.000001", responds: true, expectedThreadTS: "1234567800.000001"},
+		{name: "Channel ignoring DMs", channelID: "C12345", ignoreDMs: true, responds: true, expectedThreadTS: "1234567890.123456"},
+		{name: "Direct message", channelID: "D12345", responds: true, expectedThreadTS: ""},
+		{name: "Direct message in a thread", channelID: "D12345", threadTS: "1234567800.000001", responds: true, expectedThreadTS: "1234567800.000001"},
+		{name: "Direct message ignoring DMs", channelID: "D12345", ignoreDMs: true, responds: false},
+		{name: "Group", channelID: "G12345", responds: true, expectedThreadTS: "1234567890.123456"},
+		{name: "Group ignoring DMs", channelID: "G12345", ignoreDMs: true, responds: true, expectedThreadTS: "1234567890.123456"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStoreWithConfig(&config.Config{
+				DefaultItemName:      "Bunnings snags",
+				DefaultItemPrice:     3.50,
+				IgnoreDirectMessages: test.ignoreDMs,
+			})
+
+			mockAPI := NewMockSlackAPI()
+			event := (&MockMessageEvent{
+				ChannelID: test.channelID,
+				UserID:    "U12345",
+				Text:      "This costs $35",
+				TS:        "1234567890.123456",
+			}).ToSlackEvent()
+			event.ThreadTimeStamp = test.threadTS
+
+			assert.NoError(t, ProcessMessageEvent(event, store, mockAPI))
+			if !test.responds {
+				assert.Empty(t, mockAPI.Messages())
+				return
+			}
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, test.channelID, mockAPI.SentMessages[0].ChannelID)
+				assert.Equal(t, test.expectedThreadTS, mockAPI.SentMessages[0].ThreadTS)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_ThreadBroadcast(t *testing.T) {
+	tests := []struct {
+		name     string
+		threadTS string
+		root     *slackevents.MessageEvent
+	}{
+		{name: "With thread_ts", threadTS: "1234567800.000001"},
+		{name: "With only the root message", root: &slackevents.MessageEvent{TimeStamp: "1234567800.000001"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockAPI := NewMockSlackAPI()
+			event := (&MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      "This costs $35",
+				TS:        "1234567890.123456",
+				SubType:   slack.MsgSubTypeThreadBroadcast,
+			}).ToSlackEvent()
+			event.ThreadTimeStamp = test.threadTS
+			event.Root = test.root
+
+			assert.NoError(t, ProcessMessageEvent(event, NewInMemoryConfigStore(), mockAPI))
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, "1234567800.000001", mockAPI.SentMessages[0].ThreadTS)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_BotMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		respond   bool
+		ownUserID string
+		ownBotID  string
+		userID    string
+		botID     string
+		subType   string
+		responded bool
+	}{
+		{name: "Person", userID: "U12345", responded: true},
+		{name: "Other bot ignored by default", userID: "U99999", botID: "B99999", subType: "bot_message"},
+		{name: "Own bot ignored by default", ownUserID: "U0SNAG", ownBotID: "B0SNAG", userID: "U0SNAG", botID: "B0SNAG"},
+		{name: "Other bot when responding", respond: true, ownUserID: "U0SNAG", ownBotID: "B0SNAG", userID: "U99999", botID: "B99999", responded: true},
+		{name: "Other bot_message subtype when responding", respond: true, ownUserID: "U0SNAG", ownBotID: "B0SNAG", botID: "B99999", subType: "bot_message", responded: true},
+		{name: "Own bot ID when responding", respond: true, ownUserID: "U0SNAG", ownBotID: "B0SNAG", botID: "B0SNAG", subType: "bot_message"},
+		{name: "Own bot user when responding", respond: true, ownUserID: "U0SNAG", ownBotID: "B0SNAG", userID: "U0SNAG", botID: "B77777"},
+		{name: "Own bot user without bot ID", respond: true, ownUserID: "U0SNAG", userID: "U0SNAG"},
+		{name: "Other bot when own identity unknown", respond: true, userID: "U99999", botID: "B99999"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			botMessages := config.BotMessagesIgnore
+			if test.respond {
+				botMessages = config.BotMessagesRespond
+			}
+			opts := NewProcessorOptions(&config.Config{BotMessages: botMessages, SlackBotUserID: test.ownUserID, SlackBotID: test.ownBotID})
+
+			mockAPI := NewMockSlackAPI()
+			event := (&MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    test.userID,
+				Text:      "This costs $35",
+				TS:        "1234567890.123456",
+				SubType:   test.subType,
+			}).ToSlackEvent()
+			event.BotID = test.botID
+
+			assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event, NewInMemoryConfigStore(), mockAPI, nil, opts))
+			if test.responded {
+				assert.Len(t, mockAPI.SentMessages, 1)
+			} else {
+				assert.Empty(t, mockAPI.SentMessages)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_ExchangeRates(t *testing.T) {
+	opts := NewProcessorOptions(&config.Config{ExchangeCurrency: "AUD", ExchangeRates: map[string]float64{"EUR": 1.6}})
+	event := (&MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "Dinner was €35",
+		TS:        "1234567890.123456",
+	}).ToSlackEvent()
+
+	// €35 is $56 AUD, 16 snags at $3.50
+	mockAPI := NewMockSlackAPI()
+	assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event, NewInMemoryConfigStore(), mockAPI, nil, opts))
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Contains(t, mockAPI.SentMessages[0].Text, "16 Bunnings snags")
+	}
+
+	// Without the rates the euro amount isn't read
+	mockAPI = NewMockSlackAPI()
+	assert.NoError(t, ProcessMessageEvent(event, NewInMemoryConfigStore(), mockAPI))
+	assert.Empty(t, mockAPI.SentMessages)
+}
+
+func TestProcessMessageEvent_SubTypes(t *testing.T) {
+	set := func(subTypes ...string) map[string]bool {
+		s := make(map[string]bool)
+		for _, subType := range subTypes {
+			s[subType] = true
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		allowed   map[string]bool
+		ignored   map[string]bool
+		defaults  bool
+		subType   string
+		botID     string
+		responded bool
+	}{
+		{name: "Plain message by default", defaults: true, responded: true},
+		{name: "File share by default", defaults: true, subType: "file_share", responded: true},
+		{name: "Me message by default", defaults: true, subType: "me_message", responded: true},
+		{name: "Channel join ignored by default", defaults: true, subType: "channel_join"},
+		{name: "Channel topic ignored by default", defaults: true, subType: "channel_topic"},
+		{name: "Bot message by default", defaults: true, subType: "bot_message", botID: "B12345"},
+		{name: "Allowed subtype", allowed: set("file_share"), subType: "file_share", responded: true},
+		{name: "Subtype missing from allow list", allowed: set("file_share"), subType: "me_message"},
+		{name: "Plain message with allow list", allowed: set("file_share"), responded: true},
+		{name: "Ignored subtype", ignored: set("me_message"), subType: "me_message"},
+		{name: "Ignore list replaces the defaults", ignored: set("me_message"), subType: "channel_join", responded: true},
+		{name: "Ignore list beats allow list", allowed: set("file_share"), ignored: set("file_share"), subType: "file_share"},
+		{name: "Bot messages can't be allowed", allowed: set("bot_message"), subType: "bot_message", botID: "B12345"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var opts *ProcessorOptions
+			if !test.defaults {
+				opts = NewProcessorOptions(&config.Config{MessageSubTypes: test.allowed, IgnoredMessageSubTypes: test.ignored})
+			}
+
+			mockAPI := NewMockSlackAPI()
+			event := (&MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      "This costs $35",
+				TS:        "1234567890.123456",
+				SubType:   test.subType,
+			}).ToSlackEvent()
+			event.BotID = test.botID
+
+			assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event, NewInMemoryConfigStore(), mockAPI, nil, opts))
+			if test.responded {
+				assert.Len(t, mockAPI.SentMessages, 1)
+			} else {
+				assert.Empty(t, mockAPI.SentMessages)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_ResponsesDisabled(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	reactions := models.NewChannelConfig("CREACT")
+	reactions.ResponseMode = models.ResponseModeReaction
+	require.NoError(t, store.SaveConfig(reactions))
+
+	responses := NewKillSwitch(false)
+	opts := NewProcessorOptions(&config.Config{})
+	opts.responses = responses
+
+	process := func(channelID string) *MockSlackAPI {
+		mockAPI := NewMockSlackAPI()
+		event := &MockMessageEvent{ChannelID: channelID, UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+		assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), event.ToSlackEvent(), store, mockAPI, nil, opts))
+		return mockAPI
+	}
+
+	// Switched off, nothing is posted or reacted
+	assert.False(t, opts.ResponsesEnabled())
+	assert.Empty(t, process("C12345").Messages())
+	assert.Empty(t, process("CREACT").Reactions)
+
+	// Toggling switches responses back on
+	assert.True(t, responses.Toggle())
+	assert.Len(t, process("C12345").Messages(), 1)
+	assert.Len(t, process("CREACT").Reactions, 1)
+
+	assert.False(t, responses.Toggle())
+	assert.Empty(t, process("C12345").Messages())
+
+	// Other options, and no options at all, are unaffected by this switch
+	assert.True(t, NewProcessorOptions(&config.Config{}).ResponsesEnabled())
+	var noOptions *ProcessorOptions
+	assert.True(t, noOptions.ResponsesEnabled())
+}
+
+func TestProcessMessageEvent_SumMode(t *testing.T) {
+	invoice := "Catering: $35\nVenue hire $14\nCoffee: $7"
+
+	tests := []struct {
+		name     string
+		sumMode  string
+		expected string
+	}{
+		{name: "Total", sumMode: models.SumModeTotal, expected: "That's 16 Bunnings snags!"},
+		{name: "Per line", sumMode: models.SumModePerLine, expected: "Line 1: 10 Bunnings snags, line 2: 4 Bunnings snags, line 3: 2 Bunnings snags"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStore()
+			config := models.NewChannelConfig("C12345")
+			config.SumMode = test.sumMode
+			assert.NoError(t, store.SaveConfig(config))
+
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      invoice,
+				TS:        "1234567890.123456",
+			}
+
+			assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, test.expected, mockAPI.SentMessages[0].Text)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_Ephemeral(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Count", text: "This costs $35", expected: "That's 10 Bunnings snags!"},
+		{name: "Too small amount", text: "This costs $2", expected: "That wouldn't even buy a single Bunnings snag!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStore()
+			config := models.NewChannelConfig("C12345")
+			config.Ephemeral = true
+			assert.NoError(t, store.SaveConfig(config))
+
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      test.text,
+				TS:        "1234567890.123456",
+			}
+
+			err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+			assert.NoError(t, err)
+			assert.Len(t, mockAPI.SentMessages, 0)
+			if assert.Len(t, mockAPI.Ephemerals(), 1) {
+				response := mockAPI.Ephemerals()[0]
+				assert.Equal(t, "U12345", response.UserID)
+				assert.Equal(t, "C12345", response.ChannelID)
+				assert.Equal(t, "1234567890.123456", response.ThreadTS)
+				assert.Equal(t, test.expected, response.Text)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_TooManyValues(t *testing.T) {
+	amounts := make([]string, models.DefaultMaxDollarValues+10)
+	for i := range amounts {
+		amounts[i] = fmt.Sprintf("$%d", i+1)
+	}
+	text := strings.Join(amounts, " ")
+
+	tests := []struct {
+		name     string
+		policy   string
+		expected []string
+	}{
+		{name: "Truncate by default", expected: []string{"That's nearly 365 Bunnings snags! (Only the first 50 amounts were counted.)"}},
+		{name: "Skip", policy: models.TooManyValuesSkip, expected: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewInMemoryConfigStore()
+			config := models.NewChannelConfig("C12345")
+			config.TooManyValues = test.policy
+			assert.NoError(t, store.SaveConfig(config))
+
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: text, TS: "1234567890.123456"}
+			assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+
+			var sent []string
+			for _, message := range mockAPI.SentMessages {
+				sent = append(sent, message.Text)
+			}
+			assert.Equal(t, test.expected, sent)
+		})
+	}
+}
+
+func TestProcessMessageEvent_MinThreshold(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	config := models.NewChannelConfig("C12345")
+	config.MinThreshold = 20
+	assert.NoError(t, store.SaveConfig(config))
+
+	tests := []struct {
+		name          string
+		text          string
+		shouldRespond bool
+	}{
+		{name: "Under threshold", text: "This costs $1", shouldRespond: false},
+		{name: "Under threshold across values", text: "This costs $10 and $9.99", shouldRespond: false},
+		{name: "At threshold", text: "This costs $20", shouldRespond: true},
+		{name: "Over threshold", text: "This costs $35", shouldRespond: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{
+				ChannelID: "C12345",
+				UserID:    "U12345",
+				Text:      test.text,
+				TS:        "1234567890.123456",
+			}
+
+			err := ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI)
+			assert.NoError(t, err)
+
+			if test.shouldRespond {
+				assert.Len(t, mockAPI.SentMessages, 1)
+			} else {
+				assert.Len(t, mockAPI.SentMessages, 0)
+			}
+		})
+	}
+}
+
+func TestProcessMessageEvent_MessageChanged(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+
+	edit := func(previousText, newText string) *slackevents.MessageEvent {
+		return &slackevents.MessageEvent{
+			Type:           "message",
+			SubType:        "message_changed",
+			Channel:        "C12345",
+			TimeStamp:      "1234567899.000001",
+			EventTimeStamp: "1234567899.000001",
+			Message: &slackevents.MessageEvent{
+				Type:      "message",
+				User:      "U12345",
+				Text:      newText,
+				TimeStamp: "1234567890.123456",
+				Edited:    &slackevents.Edited{User: "U12345", TimeStamp: "1234567899.000000"},
+			},
+			PreviousMessage: &slackevents.MessageEvent{
+				Type:      "message",
+				User:      "U12345",
+				Text:      previousText,
+				TimeStamp: "1234567890.123456",
+			},
+		}
+	}
+
+	// Adding an amount gets a reply in the original message's thread
+	assert.NoError(t, ProcessMessageEvent(edit("Lunch was pricey", "Lunch was $35"), store, mockAPI))
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Equal(t, SlackResponse{
+			ChannelID: "C12345",
+			Text:      "That's 10 Bunnings snags!",
+			ThreadTS:  "1234567890.123456",
+		}, mockAPI.SentMessages[0])
+	}
+
+	// Changing the amount gets a fresh reply
+	assert.NoError(t, ProcessMessageEvent(edit("Lunch was $35", "Lunch was $70"), store, mockAPI))
+	if assert.Len(t, mockAPI.SentMessages, 2) {
+		assert.Equal(t, "That's 20 Bunnings snags!", mockAPI.SentMessages[1].Text)
+	}
+
+	// Edits that leave the amounts alone are ignored
+	assert.NoError(t, ProcessMessageEvent(edit("Lunch was $70", "Lunch was $70 (ouch)"), store, mockAPI))
+	assert.Len(t, mockAPI.SentMessages, 2)
+
+	// Edits to bot messages, including SnagBot's own replies, are ignored
+	botEdit := edit("That's 10 Bunnings snags!", "That's $35 worth")
+	botEdit.Message.BotID = "B12345"
+	assert.NoError(t, ProcessMessageEvent(botEdit, store, mockAPI))
+	assert.Len(t, mockAPI.SentMessages, 2)
+
+	// Events without the edited message are ignored
+	missing := edit("", "")
+	missing.Message = nil
+	assert.NoError(t, ProcessMessageEvent(missing, store, mockAPI))
+	assert.Len(t, mockAPI.SentMessages, 2)
+
+	// Amounts are compared the way replies read them, including exchange rates
+	opts := NewProcessorOptions(&config.Config{ExchangeCurrency: "AUD", ExchangeRates: map[string]float64{"EUR": 1.6}})
+	assert.NoError(t, ProcessMessageEventWithCooldown(context.Background(), edit("Lunch was €10", "Lunch was €100"), store, mockAPI, nil, opts))
+	if assert.Len(t, mockAPI.SentMessages, 3) {
+		assert.Equal(t, "That's nearly 46 Bunnings snags!", mockAPI.SentMessages[2].Text)
+	}
+
+	// and word amounts
+	words := models.NewChannelConfig("C12345")
+	words.WordAmounts = true
+	assert.NoError(t, store.SaveConfig(words))
+	assert.NoError(t, ProcessMessageEvent(edit("Lunch was thirty dollars", "Lunch was ninety dollars"), store, mockAPI))
+	if assert.Len(t, mockAPI.SentMessages, 4) {
+		assert.Equal(t, "That's nearly 26 Bunnings snags!", mockAPI.SentMessages[3].Text)
+	}
+}
+
+func TestProcessMessageEvent_Metrics(t *testing.T) {
+	// Inject metrics with a fresh registry so counts start at zero
+	original := metrics.Default()
+	defer metrics.SetDefault(original)
+	m := metrics.NewWithRegistry(prometheus.NewRegistry())
+	metrics.SetDefault(m)
+
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+
+	// A message with two dollar values gets one response
+	event := &MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "This costs $20 and that costs $15",
+		TS:        "1234567890.123456",
+	}
+	assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+
+	// A message with no dollar values is processed but gets no response
+	event.Text = "No money talk here"
+	assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+
+	// Bot messages aren't counted at all
+	event.BotID = "B12345"
+	assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.MessagesProcessed))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.DollarValuesExtracted))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.ResponsesSent))
+
+	// Each processed message records a latency observation
+	families, err := m.Registry().Gather()
+	assert.NoError(t, err)
+	var observations uint64
+	for _, family := range families {
+		if family.GetName() == "snagbot_message_processing_seconds" {
+			observations = family.GetMetric()[0].GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, uint64(2), observations)
+}
+
+func TestProcessMessageEvent_CancelledContext(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A message whose context is already cancelled isn't processed at all
+	err := ProcessMessageEventWithCooldown(ctx, event.ToSlackEvent(), store, mockAPI, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, mockAPI.Messages())
+}
+
+func TestProcessMessageEvent_CancelledWhilePosting(t *testing.T) {
+	// A Slack that doesn't answer until the test is over
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	api := NewRealSlackAPI("xoxb-test")
+	api.client = slack.New("xoxb-test", slack.OptionAPIURL(server.URL+"/"))
+
+	event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Processing stops promptly once the context is cancelled, rather than waiting on Slack
+	start := time.Now()
+	err := ProcessMessageEventWithCooldown(ctx, event.ToSlackEvent(), NewInMemoryConfigStore(), api, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestProcessMessageEvent_Ignored(t *testing.T) {
+	store := NewInMemoryConfigStoreWithConfig(&config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+		IgnoredChannels:  map[string]bool{"CBOTS": true},
+	})
+	require.NoError(t, store.SetUserIgnored("UNOISY", true))
+
+	tests := []struct {
+		name      string
+		channelID string
+		userID    string
+		responds  bool
+	}{
+		{name: "Ignored channel", channelID: "CBOTS", userID: "U12345", responds: false},
+		{name: "Ignored user", channelID: "C12345", userID: "UNOISY", responds: false},
+		{name: "Other channel and user", channelID: "C12345", userID: "U12345", responds: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockAPI := NewMockSlackAPI()
+			event := &MockMessageEvent{ChannelID: test.channelID, UserID: test.userID, Text: "This costs $35", TS: "1234567890.123456"}
+
+			assert.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), store, mockAPI))
+			if test.responds {
+				assert.Len(t, mockAPI.Messages(), 1)
+			} else {
+				assert.Empty(t, mockAPI.Messages())
+			}
+		})
+	}
+
+	// Users can be taken off the ignore list
+	require.NoError(t, store.SetUserIgnored("UNOISY", false))
+	assert.False(t, store.IsIgnored("C12345", "UNOISY"))
+}
+
+func TestProcessMessageEvent_DisabledChannel(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	config.Disabled = true
+	assert.NoError(t, store.SaveConfig(config))
+
+	mockAPI := NewMockSlackAPI()
+	event := (&MockMessageEvent{
+		ChannelID: "C12345",
+		UserID:    "U12345",
+		Text:      "Lunch was $35",
+		TS:        "1234567890.123456",
+	}).ToSlackEvent()
+	assert.NoError(t, ProcessMessageEvent(event, store, mockAPI))
+	assert.Empty(t, mockAPI.SentMessages)
+
+	// Other channels still get responses
+	event.Channel = "C67890"
+	assert.NoError(t, ProcessMessageEvent(event, store, mockAPI))
+	assert.Len(t, mockAPI.SentMessages, 1)
+}
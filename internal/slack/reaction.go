@@ -0,0 +1,57 @@
+package slack
+
+import (
+	"context"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// IsConvertReaction reports whether the reaction asks SnagBot to convert the message it was added
+// to, using the CONVERT_REACTION emoji, or config.DefaultConvertReaction without options
+func (o *ProcessorOptions) IsConvertReaction(ev *slackevents.ReactionAddedEvent) bool {
+	emoji := config.DefaultConvertReaction
+	if o != nil {
+		emoji = o.convertReaction
+	}
+	return emoji != "" && ev.Reaction == emoji && ev.Item.Type == "message"
+}
+
+// ProcessReactionAddedEvent converts the dollar amounts in a message someone reacted to with the
+// convert emoji, fetching the message since the event only says which one it was
+// The message goes through the same pipeline as a new one, apart from the channel's trigger word
+func ProcessReactionAddedEvent(ctx context.Context, ev *slackevents.ReactionAddedEvent, workspaceID string, configStore ChannelConfigStore, api SlackAPI, cooldown *CooldownTracker, opts *ProcessorOptions) error {
+	if ev == nil {
+		return errors.New(errors.ErrInvalidRequest, "nil reaction added event")
+	}
+
+	if !opts.IsConvertReaction(ev) {
+		logging.Debug("Ignoring :%s: reaction", ev.Reaction)
+		return nil
+	}
+
+	// SnagBot's own reactions, like in reaction mode, never ask for a conversion
+	if opts.IsOwnMessage("", ev.User) {
+		logging.Debug("Skipping SnagBot's own reaction")
+		return nil
+	}
+
+	message, err := api.GetMessage(ctx, workspaceID, ev.Item.Channel, ev.Item.Timestamp)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch the message reacted to")
+	}
+
+	logging.Info("User %s asked for message %s in channel %s to be converted", ev.User, ev.Item.Timestamp, ev.Item.Channel)
+	return processMessageEvent(ctx, &slackevents.MessageEvent{
+		Type:            "message",
+		User:            message.User,
+		Text:            message.Text,
+		TimeStamp:       ev.Item.Timestamp,
+		ThreadTimeStamp: message.ThreadTimestamp,
+		Channel:         ev.Item.Channel,
+		BotID:           message.BotID,
+		Blocks:          message.Blocks,
+	}, configStore, api, cooldown, opts, true)
+}
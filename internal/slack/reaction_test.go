@@ -0,0 +1,189 @@
+package slack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+)
+
+// reactionAdded creates a reaction_added event for a message in C12345
+func reactionAdded(reaction, timestamp string) *slackevents.ReactionAddedEvent {
+	return &slackevents.ReactionAddedEvent{
+		Type:     "reaction_added",
+		User:     "U67890",
+		Reaction: reaction,
+		Item:     slackevents.Item{Type: "message", Channel: "C12345", Timestamp: timestamp},
+	}
+}
+
+func TestProcessReactionAddedEvent(t *testing.T) {
+	tests := []struct {
+		name        string
+		reaction    string
+		emoji       *string
+		user        string
+		itemType    string
+		message     *slack.Message
+		keyword     bool
+		expectError bool
+		response    string
+	}{
+		{
+			name:     "Convert emoji",
+			reaction: "moneybag",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+			response: "That's 10 Bunnings snags!",
+		},
+		{
+			name:     "Channel in keyword mode",
+			reaction: "moneybag",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+			keyword:  true,
+			response: "That's 10 Bunnings snags!",
+		},
+		{
+			name:     "Configured emoji",
+			reaction: "money_with_wings",
+			emoji:    stringPointer("money_with_wings"),
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+			response: "That's 10 Bunnings snags!",
+		},
+		{
+			name:     "Other emoji",
+			reaction: "thumbsup",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:     "Turned off",
+			reaction: "moneybag",
+			emoji:    stringPointer(""),
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:     "Reaction to a file",
+			reaction: "moneybag",
+			itemType: "file",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:     "SnagBot's own reaction",
+			reaction: "moneybag",
+			user:     "U0SNAG",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:     "SnagBot's own reply",
+			reaction: "moneybag",
+			message:  &slack.Message{Msg: slack.Msg{User: "U0SNAG", BotID: "B0SNAG", Text: "That's 10 Bunnings snags for $35!", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:     "Message without amounts",
+			reaction: "moneybag",
+			message:  &slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was great", Timestamp: "1234567890.123456"}},
+		},
+		{
+			name:        "Message can't be fetched",
+			reaction:    "moneybag",
+			expectError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &config.Config{SlackBotUserID: "U0SNAG", SlackBotID: "B0SNAG", ConvertReaction: config.DefaultConvertReaction}
+			if test.emoji != nil {
+				cfg.ConvertReaction = *test.emoji
+			}
+
+			store := NewInMemoryConfigStore()
+			if test.keyword {
+				config, err := store.GetConfig("C12345")
+				assert.NoError(t, err)
+				config.TriggerMode = models.TriggerModeKeyword
+				assert.NoError(t, store.SaveConfig(config))
+			}
+
+			mockAPI := NewMockSlackAPI()
+			if test.message != nil {
+				mockAPI.AddMessage("C12345", *test.message)
+			}
+
+			event := reactionAdded(test.reaction, "1234567890.123456")
+			if test.user != "" {
+				event.User = test.user
+			}
+			if test.itemType != "" {
+				event.Item.Type = test.itemType
+			}
+
+			err := ProcessReactionAddedEvent(context.Background(), event, "T12345", store, mockAPI, nil, NewProcessorOptions(cfg))
+			if test.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			if test.response == "" {
+				assert.Empty(t, mockAPI.SentMessages)
+				return
+			}
+			if assert.Len(t, mockAPI.SentMessages, 1) {
+				assert.Equal(t, "C12345", mockAPI.SentMessages[0].ChannelID)
+				assert.Equal(t, "1234567890.123456", mockAPI.SentMessages[0].ThreadTS)
+				assert.Contains(t, mockAPI.SentMessages[0].Text, test.response)
+			}
+		})
+	}
+}
+
+func TestHandleCallbackEvent_ReactionConvertsOnce(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	mockAPI.AddMessage("C12345", slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}})
+	deduper := NewSeenCache(DefaultDedupeWindow, DefaultDedupeSize)
+
+	reaction := func(emoji string) slackevents.EventsAPIEvent {
+		return slackevents.EventsAPIEvent{TeamID: "T12345", InnerEvent: slackevents.EventsAPIInnerEvent{
+			Data: reactionAdded(emoji, "1234567890.123456"),
+		}}
+	}
+
+	// Other reactions don't stop the convert emoji working
+	assert.NoError(t, handleCallbackEvent(context.Background(), reaction("thumbsup"), store, nil, mockAPI, nil, deduper, nil))
+	assert.Empty(t, mockAPI.SentMessages)
+
+	// A second person adding the emoji doesn't get another reply
+	assert.NoError(t, handleCallbackEvent(context.Background(), reaction("moneybag"), store, nil, mockAPI, nil, deduper, nil))
+	assert.NoError(t, handleCallbackEvent(context.Background(), reaction("moneybag"), store, nil, mockAPI, nil, deduper, nil))
+	assert.Len(t, mockAPI.SentMessages, 1)
+}
+
+func TestHandleCallbackEvent_ConfiguredReaction(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+	mockAPI.AddMessage("C12345", slack.Message{Msg: slack.Msg{User: "U12345", Text: "Lunch was $35", Timestamp: "1234567890.123456"}})
+	opts := NewProcessorOptions(&config.Config{ConvertReaction: "money_with_wings"})
+
+	reaction := func(emoji string) slackevents.EventsAPIEvent {
+		return slackevents.EventsAPIEvent{TeamID: "T12345", InnerEvent: slackevents.EventsAPIInnerEvent{
+			Data: reactionAdded(emoji, "1234567890.123456"),
+		}}
+	}
+
+	// The default emoji does nothing once another is configured
+	assert.NoError(t, handleCallbackEvent(context.Background(), reaction("moneybag"), store, nil, mockAPI, nil, nil, opts))
+	assert.Empty(t, mockAPI.SentMessages)
+
+	assert.NoError(t, handleCallbackEvent(context.Background(), reaction("money_with_wings"), store, nil, mockAPI, nil, nil, opts))
+	assert.Len(t, mockAPI.SentMessages, 1)
+}
+
+// stringPointer returns a pointer to a copy of s
+func stringPointer(s string) *string {
+	return &s
+}
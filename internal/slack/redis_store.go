@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 
 	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/logging"
 	"github.com/mcncl/snagbot/pkg/models"
 )
 
@@ -44,12 +46,29 @@ func NewRedisConfigStore(redisURL string, appCfg *config.Config) (*RedisConfigSt
 	}, nil
 }
 
+// WithContext returns a copy of the store whose Redis calls use ctx, so they're abandoned
+// once it's cancelled. The copy shares the Redis connection
+func (s *RedisConfigStore) WithContext(ctx context.Context) ChannelConfigStore {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
 // getConfigKey returns the Redis key for a channel's configuration
 func (s *RedisConfigStore) getConfigKey(channelID string) string {
 	return s.keyBase + channelID
 }
 
+// configTTL returns how long channel configs are kept without being read or changed, 0 for forever
+func (s *RedisConfigStore) configTTL() time.Duration {
+	if s.appCfg == nil {
+		return 0
+	}
+	return s.appCfg.RedisConfigTTL
+}
+
 // GetConfig retrieves a channel's configuration or returns the default
+// Reading a stored config restarts its expiry, so channels in use keep their settings
 func (s *RedisConfigStore) GetConfig(channelID string) (*models.ChannelConfig, error) {
 	key := s.getConfigKey(channelID)
 	
@@ -61,10 +80,11 @@ func (s *RedisConfigStore) GetConfig(channelID string) (*models.ChannelConfig, e
 	
 	// If config doesn't exist, return a new one with defaults
 	if exists == 0 {
+		itemName, itemPrice := s.appCfg.DefaultItem()
 		return &models.ChannelConfig{
 			ChannelID: channelID,
-			ItemName:  s.appCfg.DefaultItemName,
-			ItemPrice: s.appCfg.DefaultItemPrice,
+			ItemName:  itemName,
+			ItemPrice: itemPrice,
 		}, nil
 	}
 	
@@ -79,27 +99,66 @@ func (s *RedisConfigStore) GetConfig(channelID string) (*models.ChannelConfig, e
 	if err := json.Unmarshal([]byte(jsonData), &config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	defaultItemName, _ := s.appCfg.DefaultItem()
+	config.Inherit(defaultItemName)
+
+	if ttl := s.configTTL(); ttl > 0 {
+		if err := s.client.Expire(s.ctx, key, ttl).Err(); err != nil {
+			// The config was still read, so don't fail the request
+			logging.Warn("Failed to refresh config expiry for channel %s: %v", channelID, err)
+		}
+	}
 	
 	return &config, nil
 }
 
 // UpdateConfig updates or creates a channel's configuration
-func (s *RedisConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64) error {
-	config := &models.ChannelConfig{
-		ChannelID: channelID,
-		ItemName:  itemName,
-		ItemPrice: itemPrice,
+// Change history isn't kept in Redis yet, so userID is unused
+func (s *RedisConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error {
+	_, err := s.UpdateConfigChanged(channelID, itemName, itemPrice, userID)
+	return err
+}
+
+// UpdateConfigChanged updates or creates a channel's configuration like UpdateConfig, reporting
+// whether the item or price changed and skipping the write when they didn't
+func (s *RedisConfigStore) UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error) {
+	itemName, err := normalizeItemName(itemName, s.appCfg)
+	if err != nil {
+		return false, err
 	}
-	
+
+	exists, err := s.ConfigExistsChecked(channelID)
+	if err != nil {
+		return false, err
+	}
+
+	// Start from the existing config so other channel settings are preserved
+	config, err := s.GetConfig(channelID)
+	if err != nil {
+		return false, err
+	}
+	if exists && sameItem(config, itemName, itemPrice) {
+		return false, nil
+	}
+	config.SetItem(itemName, itemPrice)
+
+	if err := s.SaveConfig(config); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveConfig stores a complete channel configuration, including optional settings
+func (s *RedisConfigStore) SaveConfig(config *models.ChannelConfig) error {
 	// Marshal the config to JSON
 	jsonData, err := json.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("error marshaling config: %w", err)
 	}
 	
-	// Store in Redis with 30-day expiry
-	key := s.getConfigKey(channelID)
-	err = s.client.Set(s.ctx, key, jsonData, 30*24*time.Hour).Err()
+	// Store in Redis with the configured expiry, where 0 means it never expires
+	key := s.getConfigKey(config.ChannelID)
+	err = s.client.Set(s.ctx, key, jsonData, s.configTTL()).Err()
 	if err != nil {
 		return fmt.Errorf("error storing config in Redis: %w", err)
 	}
@@ -108,7 +167,8 @@ func (s *RedisConfigStore) UpdateConfig(channelID, itemName string, itemPrice fl
 }
 
 // ResetConfig removes a channel's configuration so it uses defaults
-func (s *RedisConfigStore) ResetConfig(channelID string) error {
+// Change history isn't kept in Redis yet, so userID is unused
+func (s *RedisConfigStore) ResetConfig(channelID, userID string) error {
 	key := s.getConfigKey(channelID)
 	err := s.client.Del(s.ctx, key).Err()
 	if err != nil {
@@ -119,16 +179,162 @@ func (s *RedisConfigStore) ResetConfig(channelID string) error {
 }
 
 // ConfigExists checks if a custom configuration exists for a channel
+// Returns false if Redis can't be reached, use ConfigExistsChecked to tell the two apart
 func (s *RedisConfigStore) ConfigExists(channelID string) bool {
-	key := s.getConfigKey(channelID)
-	exists, err := s.client.Exists(s.ctx, key).Result()
+	exists, err := s.ConfigExistsChecked(channelID)
 	if err != nil {
-		// Log error and default to false
-		fmt.Printf("Error checking if config exists: %v\n", err)
+		logging.Warn("Failed to check if config exists for channel %s: %v", channelID, err)
 		return false
 	}
-	
-	return exists > 0
+
+	return exists
+}
+
+// ConfigExistsChecked checks if a custom configuration exists for a channel, returning an
+// error if Redis can't be reached
+func (s *RedisConfigStore) ConfigExistsChecked(channelID string) (bool, error) {
+	exists, err := s.client.Exists(s.ctx, s.getConfigKey(channelID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("error checking if config exists: %w", err)
+	}
+
+	return exists > 0, nil
+}
+
+// GetAllConfigs returns every custom channel configuration, keyed by channel ID
+// Keys are found with SCAN so large keyspaces don't block Redis
+func (s *RedisConfigStore) GetAllConfigs() (map[string]models.ChannelConfig, error) {
+	configs := make(map[string]models.ChannelConfig)
+
+	iter := s.client.Scan(s.ctx, 0, s.keyBase+"*", 100).Iterator()
+	for iter.Next(s.ctx) {
+		key := iter.Val()
+
+		jsonData, err := s.client.Get(s.ctx, key).Result()
+		if err == redis.Nil {
+			// The key expired or was reset since the scan found it
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving config from Redis: %w", err)
+		}
+
+		var config models.ChannelConfig
+		if err := json.Unmarshal([]byte(jsonData), &config); err != nil {
+			return nil, fmt.Errorf("error unmarshaling config for %s: %w", key, err)
+		}
+
+		configs[strings.TrimPrefix(key, s.keyBase)] = config
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning configs in Redis: %w", err)
+	}
+
+	return configs, nil
+}
+
+// workspaceDefaultKeyBase prefixes the Redis keys holding each workspace's default item
+const workspaceDefaultKeyBase = "snagbot:workspace_default:"
+
+// GetWorkspaceDefault returns the workspace's default item, or nil if it hasn't set one
+func (s *RedisConfigStore) GetWorkspaceDefault(workspaceID string) (*models.WorkspaceDefault, error) {
+	jsonData, err := s.client.Get(s.ctx, workspaceDefaultKeyBase+workspaceID).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving workspace default from Redis: %w", err)
+	}
+
+	var def models.WorkspaceDefault
+	if err := json.Unmarshal([]byte(jsonData), &def); err != nil {
+		return nil, fmt.Errorf("error unmarshaling workspace default: %w", err)
+	}
+	return &def, nil
+}
+
+// SaveWorkspaceDefault sets the default item for the workspace's channels
+// Workspace defaults don't expire, unlike channel configs
+func (s *RedisConfigStore) SaveWorkspaceDefault(def *models.WorkspaceDefault) error {
+	if def == nil || def.WorkspaceID == "" {
+		return fmt.Errorf("workspace ID is required")
+	}
+
+	jsonData, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("error marshaling workspace default: %w", err)
+	}
+
+	if err := s.client.Set(s.ctx, workspaceDefaultKeyBase+def.WorkspaceID, jsonData, 0).Err(); err != nil {
+		return fmt.Errorf("error storing workspace default in Redis: %w", err)
+	}
+	return nil
+}
+
+// Redis sets holding the IDs SnagBot ignores
+const (
+	ignoredChannelsKey = "snagbot:ignored_channels"
+	ignoredUsersKey    = "snagbot:ignored_users"
+)
+
+// IsIgnored returns true if the channel or user is on the ignore list, or ignored by the app config
+// If Redis can't be reached the message isn't ignored, so SnagBot errs on the side of responding
+func (s *RedisConfigStore) IsIgnored(channelID, userID string) bool {
+	if ignoredByConfig(s.appCfg, channelID, userID) {
+		return true
+	}
+
+	ignored, err := s.client.SIsMember(s.ctx, ignoredChannelsKey, channelID).Result()
+	if err != nil {
+		logging.Warn("Failed to check if channel %s is ignored: %v", channelID, err)
+		return false
+	}
+	if ignored || userID == "" {
+		return ignored
+	}
+
+	ignored, err = s.client.SIsMember(s.ctx, ignoredUsersKey, userID).Result()
+	if err != nil {
+		logging.Warn("Failed to check if user %s is ignored: %v", userID, err)
+		return false
+	}
+	return ignored
+}
+
+// SetChannelIgnored adds the channel to the ignore list, or removes it
+func (s *RedisConfigStore) SetChannelIgnored(channelID string, ignored bool) error {
+	return s.setIgnored(ignoredChannelsKey, channelID, ignored)
+}
+
+// SetUserIgnored adds the user to the ignore list, or removes them
+func (s *RedisConfigStore) SetUserIgnored(userID string, ignored bool) error {
+	return s.setIgnored(ignoredUsersKey, userID, ignored)
+}
+
+// setIgnored adds the ID to the ignore set, or removes it
+func (s *RedisConfigStore) setIgnored(key, id string, ignored bool) error {
+	if id == "" {
+		return fmt.Errorf("ID is required")
+	}
+
+	var err error
+	if ignored {
+		err = s.client.SAdd(s.ctx, key, id).Err()
+	} else {
+		err = s.client.SRem(s.ctx, key, id).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("error updating ignore list in Redis: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that Redis is reachable
+func (s *RedisConfigStore) Ping(ctx context.Context) error {
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("unable to reach Redis: %w", err)
+	}
+	return nil
 }
 
 // Close closes the Redis connection
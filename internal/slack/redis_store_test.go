@@ -0,0 +1,196 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRedisConfigStore creates a store backed by an in-process Redis server
+func newTestRedisConfigStore(t *testing.T) (*RedisConfigStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	store, err := NewRedisConfigStore("redis://"+server.Addr(), &config.Config{
+		DefaultItemName:  "Bunnings snags",
+		DefaultItemPrice: 3.50,
+	})
+	if err != nil {
+		t.Fatalf("failed to create Redis config store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return store, server
+}
+
+func TestRedisConfigStore_Close(t *testing.T) {
+	// The client connects lazily, so no Redis server is needed
+	store := &RedisConfigStore{
+		client:  redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"}),
+		ctx:     context.Background(),
+		keyBase: "snagbot:channel_config:",
+	}
+
+	var configStore ChannelConfigStore = store
+	assert.NoError(t, configStore.Close())
+
+	// Once closed, the client refuses further commands
+	err := store.client.Ping(context.Background()).Err()
+	assert.True(t, errors.Is(err, redis.ErrClosed), "Expected closed client error, got %v", err)
+}
+
+func TestRedisConfigStore_ConfigExistsChecked(t *testing.T) {
+	store, server := newTestRedisConfigStore(t)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	exists, err := store.ConfigExistsChecked("C12345")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = CheckConfigExists(ForWorkspace(store, "T12345"), "C67890")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// A Redis error is reported rather than looking like a missing config
+	server.SetError("LOADING Redis is loading the dataset in memory")
+	exists, err = store.ConfigExistsChecked("C12345")
+	assert.Error(t, err)
+	assert.False(t, exists)
+	assert.False(t, store.ConfigExists("C12345"))
+
+	// Including through a workspace view
+	_, err = CheckConfigExists(ForWorkspace(store, "T12345"), "C12345")
+	assert.Error(t, err)
+}
+
+func TestRedisConfigStore_UpdateConfigChanged(t *testing.T) {
+	store, _ := newTestRedisConfigStore(t)
+
+	changed, err := store.UpdateConfigChanged("C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	changed, err = store.UpdateConfigChanged("C12345", "coffee", 5.00, "U12345")
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = store.UpdateConfigChanged("C12345", "pie", 6.00, "U12345")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "pie", config.ItemName)
+	assert.Equal(t, 6.00, config.ItemPrice)
+}
+
+func TestRedisConfigStore_GetAllConfigs(t *testing.T) {
+	store, server := newTestRedisConfigStore(t)
+
+	configs, err := store.GetAllConfigs()
+	assert.NoError(t, err)
+	assert.Empty(t, configs)
+
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.UpdateConfig("C67890", "donut", 2.50, "U12345"))
+
+	// Keys outside the store's prefix are ignored
+	assert.NoError(t, server.Set("snagbot:workspace_token:T12345", "{}"))
+
+	configs, err = store.GetAllConfigs()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]models.ChannelConfig{
+		"C12345": {ChannelID: "C12345", ItemName: "coffee", ItemPrice: 5.00},
+		"C67890": {ChannelID: "C67890", ItemName: "donut", ItemPrice: 2.50},
+	}, configs)
+
+	// Reset channels are no longer exported
+	assert.NoError(t, store.ResetConfig("C67890", "U12345"))
+	configs, err = store.GetAllConfigs()
+	assert.NoError(t, err)
+	assert.Len(t, configs, 1)
+	assert.Contains(t, configs, "C12345")
+}
+
+func TestRedisConfigStore_ConfigTTL(t *testing.T) {
+	store, server := newTestRedisConfigStore(t)
+	store.appCfg.RedisConfigTTL = time.Hour
+	key := store.getConfigKey("C12345")
+
+	// Saving sets the expiry
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.Equal(t, time.Hour, server.TTL(key))
+
+	// Reading the config restarts the expiry
+	server.FastForward(30 * time.Minute)
+	assert.Equal(t, 30*time.Minute, server.TTL(key))
+	config, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+	assert.Equal(t, time.Hour, server.TTL(key))
+
+	// Unused configs expire and the channel falls back to the defaults
+	server.FastForward(time.Hour)
+	config, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "Bunnings snags", config.ItemName)
+}
+
+func TestRedisConfigStore_NoConfigTTL(t *testing.T) {
+	store, server := newTestRedisConfigStore(t)
+	key := store.getConfigKey("C12345")
+
+	// A zero TTL keeps configs forever, even after reads
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.Equal(t, time.Duration(0), server.TTL(key))
+
+	_, err := store.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), server.TTL(key))
+	assert.True(t, server.Exists(key))
+}
+
+func TestRedisConfigStore_WithContext(t *testing.T) {
+	store, _ := newTestRedisConfigStore(t)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bound := WithContext(ctx, store)
+
+	config, err := bound.GetConfig("C12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+
+	// Once the context is cancelled the bound store stops talking to Redis
+	cancel()
+	_, err = bound.GetConfig("C12345")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The original store is unaffected
+	_, err = store.GetConfig("C12345")
+	assert.NoError(t, err)
+}
+
+func TestRedisConfigStore_IgnoreList(t *testing.T) {
+	store, _ := newTestRedisConfigStore(t)
+	store.appCfg.IgnoredUsers = map[string]bool{"UCONFIG": true}
+
+	assert.False(t, store.IsIgnored("C12345", "U12345"))
+
+	assert.NoError(t, store.SetChannelIgnored("CBOTS", true))
+	assert.NoError(t, store.SetUserIgnored("UNOISY", true))
+	assert.True(t, store.IsIgnored("CBOTS", "U12345"))
+	assert.True(t, store.IsIgnored("C12345", "UNOISY"))
+	assert.True(t, store.IsIgnored("C12345", "UCONFIG"))
+	assert.False(t, store.IsIgnored("C12345", "U12345"))
+
+	assert.NoError(t, store.SetChannelIgnored("CBOTS", false))
+	assert.False(t, store.IsIgnored("CBOTS", "U12345"))
+}
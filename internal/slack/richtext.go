@@ -0,0 +1,62 @@
+package slack
+
+import (
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// RichTextFromBlocks returns the plain text of a message's rich_text blocks, putting each section,
+// list item, quote and code block on its own line
+// Newer Slack clients can send formatted messages with their content only in blocks
+func RichTextFromBlocks(blocks slack.Blocks) string {
+	var lines []string
+	for _, block := range blocks.BlockSet {
+		richText, ok := block.(*slack.RichTextBlock)
+		if !ok {
+			continue
+		}
+		lines = appendRichTextLines(lines, richText.Elements)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendRichTextLines adds a line of text for each section in the elements, including sections
+// nested in lists
+func appendRichTextLines(lines []string, elements []slack.RichTextElement) []string {
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *slack.RichTextSection:
+			lines = appendSectionLine(lines, element.Elements)
+		case *slack.RichTextQuote:
+			lines = appendSectionLine(lines, element.Elements)
+		case *slack.RichTextPreformatted:
+			lines = appendSectionLine(lines, element.Elements)
+		case *slack.RichTextList:
+			lines = appendRichTextLines(lines, element.Elements)
+		}
+	}
+	return lines
+}
+
+// appendSectionLine adds the text of a section's elements as one line, skipping empty sections
+func appendSectionLine(lines []string, elements []slack.RichTextSectionElement) []string {
+	var b strings.Builder
+	for _, element := range elements {
+		switch element := element.(type) {
+		case *slack.RichTextSectionTextElement:
+			b.WriteString(element.Text)
+		case *slack.RichTextSectionLinkElement:
+			if element.Text != "" {
+				b.WriteString(element.Text)
+			} else {
+				b.WriteString(element.URL)
+			}
+		}
+	}
+
+	if line := strings.TrimRight(b.String(), "\n"); strings.TrimSpace(line) != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
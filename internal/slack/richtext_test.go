@@ -0,0 +1,81 @@
+package slack
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// richTextMessage is a message event as sent for a formatted message pasted into a newer Slack
+// client, with its amounts only in the blocks
+const richTextMessage = `{
+	"type": "message",
+	"channel": "C12345",
+	"user": "U12345",
+	"text": "",
+	"ts": "1234567890.123456",
+	"blocks": [{
+		"type": "rich_text",
+		"block_id": "abc12",
+		"elements": [
+			{
+				"type": "rich_text_section",
+				"elements": [
+					{"type": "text", "text": "Tickets were "},
+					{"type": "text", "text": "$50", "style": {"bold": true}},
+					{"type": "text", "text": " each"}
+				]
+			},
+			{
+				"type": "rich_text_list",
+				"style": "bullet",
+				"indent": 0,
+				"elements": [
+					{"type": "rich_text_section", "elements": [{"type": "text", "text": "Parking $15"}]},
+					{"type": "rich_text_section", "elements": [{"type": "link", "url": "https://example.com", "text": "Menu"}]}
+				]
+			},
+			{
+				"type": "rich_text_quote",
+				"elements": [{"type": "text", "text": "Worth it"}]
+			}
+		]
+	}]
+}`
+
+func TestRichTextFromBlocks(t *testing.T) {
+	var ev slackevents.MessageEvent
+	require.NoError(t, json.Unmarshal([]byte(richTextMessage), &ev))
+
+	assert.Equal(t, "Tickets were $50 each\nParking $15\nMenu\nWorth it", RichTextFromBlocks(ev.Blocks))
+
+	// Messages without rich text have no block text
+	assert.Empty(t, RichTextFromBlocks(slack.Blocks{}))
+	assert.Empty(t, RichTextFromBlocks(slack.Blocks{BlockSet: []slack.Block{slack.NewDividerBlock()}}))
+}
+
+func TestProcessMessageEvent_RichTextBlocks(t *testing.T) {
+	var ev slackevents.MessageEvent
+	require.NoError(t, json.Unmarshal([]byte(richTextMessage), &ev))
+
+	store := NewInMemoryConfigStore()
+	mockAPI := NewMockSlackAPI()
+
+	// The amounts in the blocks are used when the text has none
+	require.NoError(t, ProcessMessageEvent(&ev, store, mockAPI))
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Equal(t, "That's nearly 19 Bunnings snags!", mockAPI.SentMessages[0].Text)
+	}
+
+	// The text still wins when it has amounts of its own
+	ev.Text = "Tickets were $7"
+	mockAPI = NewMockSlackAPI()
+	require.NoError(t, ProcessMessageEvent(&ev, store, mockAPI))
+	if assert.Len(t, mockAPI.SentMessages, 1) {
+		assert.Equal(t, "That's 2 Bunnings snags!", mockAPI.SentMessages[0].Text)
+	}
+}
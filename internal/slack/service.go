@@ -19,12 +19,12 @@ type SlackService struct {
 }
 
 // NewSlackService creates a new SlackService
-func NewSlackService(cfg *config.Config) *SlackService {
-	var configStore ChannelConfigStore
+// An error is returned if the config store can't be created
+func NewSlackService(cfg *config.Config) (*SlackService, error) {
 	var tokenStore TokenStore
 	var slackAPI SlackAPI
 
-	// Setup Redis client if configured
+	// Setup Redis client for workspace tokens if configured
 	var redisClient *redis.Client
 	if cfg.UseRedis {
 		opts, err := redis.ParseURL(cfg.RedisURL)
@@ -43,20 +43,10 @@ func NewSlackService(cfg *config.Config) *SlackService {
 		}
 	}
 
-	// Configure config store
-	if redisClient != nil {
-		// Use Redis store when Redis is available
-		configStore = &RedisConfigStore{
-			client:  redisClient,
-			ctx:     context.Background(),
-			appCfg:  cfg,
-			keyBase: "snagbot:channel_config:",
-		}
-		logging.Info("Using Redis config store")
-	} else {
-		// Use in-memory store when Redis is not available
-		configStore = NewInMemoryConfigStoreWithConfig(cfg)
-		logging.Info("Using in-memory config store")
+	// Configure config store from the registered STORE_BACKEND
+	configStore, err := NewConfigStore(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	// Configure token store and API client based on multi-workspace setting
@@ -75,13 +65,14 @@ func NewSlackService(cfg *config.Config) *SlackService {
 		TokenStore:  tokenStore,
 		SlackAPI:    slackAPI,
 		Config:      cfg,
-	}
+	}, nil
 }
 
 // ProcessMessageEvent processes a Slack message event
 func (s *SlackService) ProcessMessageEvent(ev *slackevents.MessageEvent) error {
 	// Skip bot messages to prevent loops
-	if ev.BotID != "" || ev.SubType == "bot_message" {
+	opts := NewProcessorOptions(s.Config)
+	if opts.SkipsBotMessage(ev.BotID, ev.User, ev.SubType) {
 		return nil
 	}
 
@@ -98,7 +89,7 @@ func (s *SlackService) ProcessMessageEvent(ev *slackevents.MessageEvent) error {
 	}
 
 	// Process the message using the shared utility function
-	message := calculator.ProcessMessageWithConfig(ev.Text, config)
+	message := calculator.ProcessMessageWithOptions(ev.Text, config, opts.AmountOptions())
 
 	// If no message was generated, no dollar values were found
 	if message == "" {
@@ -111,8 +102,8 @@ func (s *SlackService) ProcessMessageEvent(ev *slackevents.MessageEvent) error {
 		TeamID:    ev.SourceTeam, // Using SourceTeam as TeamID
 		ChannelID: ev.Channel,
 		Text:      message,
-		ThreadTS:  ev.TimeStamp,
+		ThreadTS:  threadTS(ev),
 	}
 
-	return s.SlackAPI.PostMessage(response)
+	return s.SlackAPI.PostMessage(context.Background(), response)
 }
@@ -0,0 +1,4 @@
+package slack
+
+// Registers the "sqlite" database/sql driver used by SQLiteConfigStore
+import _ "modernc.org/sqlite"
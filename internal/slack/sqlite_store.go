@@ -0,0 +1,199 @@
+package slack
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// sqliteDriverName is the database/sql driver the SQLite store opens, registered by
+// modernc.org/sqlite
+const sqliteDriverName = "sqlite"
+
+// sqliteSchema creates the table holding each channel's configuration as JSON
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS channel_configs (
+	channel_id TEXT PRIMARY KEY,
+	config     TEXT NOT NULL,
+	updated_at INTEGER NOT NULL
+)`
+
+// SQLiteConfigStore implements ChannelConfigStore using a SQLite database file, for single
+// instances that want configs to survive restarts without running Redis
+type SQLiteConfigStore struct {
+	db     *sql.DB
+	appCfg *config.Config
+}
+
+// NewSQLiteConfigStore opens the SQLite database at path, creating it and the channel_configs
+// table if they don't exist yet
+func NewSQLiteConfigStore(path string, appCfg *config.Config) (*SQLiteConfigStore, error) {
+	db, err := sql.Open(sqliteDriverName, path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite database: %w", err)
+	}
+
+	// SQLite allows one writer at a time, so share a single connection
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating channel_configs table: %w", err)
+	}
+
+	return &SQLiteConfigStore{
+		db:     db,
+		appCfg: appCfg,
+	}, nil
+}
+
+// GetConfig retrieves a channel's configuration or returns the default
+func (s *SQLiteConfigStore) GetConfig(channelID string) (*models.ChannelConfig, error) {
+	var jsonData string
+	err := s.db.QueryRow(`SELECT config FROM channel_configs WHERE channel_id = ?`, channelID).Scan(&jsonData)
+	if err == sql.ErrNoRows {
+		itemName, itemPrice := s.appCfg.DefaultItem()
+		return &models.ChannelConfig{
+			ChannelID: channelID,
+			ItemName:  itemName,
+			ItemPrice: itemPrice,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving config from SQLite: %w", err)
+	}
+
+	var config models.ChannelConfig
+	if err := json.Unmarshal([]byte(jsonData), &config); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	defaultItemName, _ := s.appCfg.DefaultItem()
+	config.Inherit(defaultItemName)
+	return &config, nil
+}
+
+// UpdateConfig updates or creates a channel's configuration
+// Change history isn't kept in SQLite yet, so userID is unused
+func (s *SQLiteConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error {
+	_, err := s.UpdateConfigChanged(channelID, itemName, itemPrice, userID)
+	return err
+}
+
+// UpdateConfigChanged updates or creates a channel's configuration like UpdateConfig, reporting
+// whether the item or price changed and skipping the write when they didn't
+func (s *SQLiteConfigStore) UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error) {
+	itemName, err := normalizeItemName(itemName, s.appCfg)
+	if err != nil {
+		return false, err
+	}
+
+	exists, err := s.ConfigExistsChecked(channelID)
+	if err != nil {
+		return false, err
+	}
+
+	// Start from the existing config so other channel settings are preserved
+	config, err := s.GetConfig(channelID)
+	if err != nil {
+		return false, err
+	}
+	if exists && sameItem(config, itemName, itemPrice) {
+		return false, nil
+	}
+	config.SetItem(itemName, itemPrice)
+
+	if err := s.SaveConfig(config); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveConfig stores a complete channel configuration, including optional settings
+func (s *SQLiteConfigStore) SaveConfig(config *models.ChannelConfig) error {
+	jsonData, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error marshaling config: %w", err)
+	}
+
+	_, err = s.db.Exec(`INSERT INTO channel_configs (channel_id, config, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (channel_id) DO UPDATE SET config = excluded.config, updated_at = excluded.updated_at`,
+		config.ChannelID, string(jsonData), time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("error storing config in SQLite: %w", err)
+	}
+	return nil
+}
+
+// ResetConfig removes a channel's configuration so it uses defaults
+// Change history isn't kept in SQLite yet, so userID is unused
+func (s *SQLiteConfigStore) ResetConfig(channelID, userID string) error {
+	if _, err := s.db.Exec(`DELETE FROM channel_configs WHERE channel_id = ?`, channelID); err != nil {
+		return fmt.Errorf("error deleting config from SQLite: %w", err)
+	}
+	return nil
+}
+
+// ConfigExists checks if a custom configuration exists for a channel
+// Returns false if the database can't be read, use ConfigExistsChecked to tell the two apart
+func (s *SQLiteConfigStore) ConfigExists(channelID string) bool {
+	exists, err := s.ConfigExistsChecked(channelID)
+	if err != nil {
+		logging.Warn("Failed to check if config exists for channel %s: %v", channelID, err)
+		return false
+	}
+	return exists
+}
+
+// ConfigExistsChecked checks if a custom configuration exists for a channel, returning an
+// error if the database can't be read
+func (s *SQLiteConfigStore) ConfigExistsChecked(channelID string) (bool, error) {
+	var exists int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM channel_configs WHERE channel_id = ?`, channelID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking if config exists: %w", err)
+	}
+	return exists > 0, nil
+}
+
+// GetAllConfigs returns every custom channel configuration, keyed by channel ID
+func (s *SQLiteConfigStore) GetAllConfigs() (map[string]models.ChannelConfig, error) {
+	rows, err := s.db.Query(`SELECT channel_id, config FROM channel_configs`)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving configs from SQLite: %w", err)
+	}
+	defer rows.Close()
+
+	configs := make(map[string]models.ChannelConfig)
+	for rows.Next() {
+		var channelID, jsonData string
+		if err := rows.Scan(&channelID, &jsonData); err != nil {
+			return nil, fmt.Errorf("error reading config row: %w", err)
+		}
+
+		var config models.ChannelConfig
+		if err := json.Unmarshal([]byte(jsonData), &config); err != nil {
+			return nil, fmt.Errorf("error unmarshaling config for %s: %w", channelID, err)
+		}
+		configs[channelID] = config
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading configs from SQLite: %w", err)
+	}
+
+	return configs, nil
+}
+
+// Ping checks the SQLite database can still be reached
+func (s *SQLiteConfigStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Close closes the SQLite database
+func (s *SQLiteConfigStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,90 @@
+package slack
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSQLiteStore creates a SQLite config store in a temporary database file
+func newTestSQLiteStore(t *testing.T, path string) *SQLiteConfigStore {
+	t.Helper()
+
+	cfg := &config.Config{DefaultItemName: "Bunnings snags", DefaultItemPrice: 3.50}
+	store, err := NewSQLiteConfigStore(path, cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteConfigStore(t *testing.T) {
+	store := newTestSQLiteStore(t, filepath.Join(t.TempDir(), "snagbot.db"))
+	assert.NoError(t, store.Ping(context.Background()))
+
+	t.Run("Defaults", func(t *testing.T) {
+		config, err := store.GetConfig("C12345")
+		require.NoError(t, err)
+		assert.Equal(t, "Bunnings snags", config.ItemName)
+		assert.Equal(t, 3.50, config.ItemPrice)
+		assert.False(t, store.ConfigExists("C12345"))
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		require.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+		assert.True(t, store.ConfigExists("C12345"))
+
+		config, err := store.GetConfig("C12345")
+		require.NoError(t, err)
+		assert.Equal(t, "coffee", config.ItemName)
+		assert.Equal(t, 5.00, config.ItemPrice)
+	})
+
+	t.Run("Update keeps other settings", func(t *testing.T) {
+		config, err := store.GetConfig("C12345")
+		require.NoError(t, err)
+		config.ShowBreakdown = true
+		require.NoError(t, store.SaveConfig(config))
+
+		require.NoError(t, store.UpdateConfig("C12345", "pie", 6.00, "U12345"))
+		config, err = store.GetConfig("C12345")
+		require.NoError(t, err)
+		assert.Equal(t, "pie", config.ItemName)
+		assert.True(t, config.ShowBreakdown)
+	})
+
+	t.Run("All configs", func(t *testing.T) {
+		require.NoError(t, store.UpdateConfig("C67890", "donut", 4.00, "U12345"))
+
+		configs, err := store.GetAllConfigs()
+		require.NoError(t, err)
+		assert.Len(t, configs, 2)
+		assert.Equal(t, "donut", configs["C67890"].ItemName)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		require.NoError(t, store.ResetConfig("C12345", "U12345"))
+		assert.False(t, store.ConfigExists("C12345"))
+
+		config, err := store.GetConfig("C12345")
+		require.NoError(t, err)
+		assert.Equal(t, "Bunnings snags", config.ItemName)
+	})
+}
+
+func TestSQLiteConfigStore_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snagbot.db")
+
+	store := newTestSQLiteStore(t, path)
+	require.NoError(t, store.SaveConfig(&models.ChannelConfig{ChannelID: "C12345", ItemName: "coffee", ItemPrice: 5.00}))
+	require.NoError(t, store.Close())
+
+	reopened := newTestSQLiteStore(t, path)
+	config, err := reopened.GetConfig("C12345")
+	require.NoError(t, err)
+	assert.Equal(t, "coffee", config.ItemName)
+}
@@ -1,7 +1,14 @@
 package slack
 
 import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/mcncl/snagbot/internal/config"
 	"github.com/mcncl/snagbot/internal/errors"
@@ -12,31 +19,120 @@ import (
 // ChannelConfigStore interface for storing channel configurations
 type ChannelConfigStore interface {
 	GetConfig(channelID string) (*models.ChannelConfig, error)
-	UpdateConfig(channelID, itemName string, itemPrice float64) error
-	ResetConfig(channelID string) error
+	UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error
+	SaveConfig(config *models.ChannelConfig) error
+	ResetConfig(channelID, userID string) error
 	ConfigExists(channelID string) bool
+	GetAllConfigs() (map[string]models.ChannelConfig, error)
+	Ping(ctx context.Context) error
+	Close() error
 }
 
+// InMemoryConfigStore, RedisConfigStore and SQLiteConfigStore are the built-in ChannelConfigStore implementations
+var (
+	_ ChannelConfigStore         = (*InMemoryConfigStore)(nil)
+	_ ChannelConfigStore         = (*RedisConfigStore)(nil)
+	_ ChannelConfigStore         = (*SQLiteConfigStore)(nil)
+	_ ConfigHistoryProvider      = (*InMemoryConfigStore)(nil)
+	_ ChannelStatsRecorder       = (*InMemoryConfigStore)(nil)
+	_ WorkspaceDefaultsStore     = (*InMemoryConfigStore)(nil)
+	_ WorkspaceDefaultsStore     = (*RedisConfigStore)(nil)
+	_ ContextBinder              = (*RedisConfigStore)(nil)
+	_ ConfigResolver             = (*workspaceConfigStore)(nil)
+	_ ConfigChangeReporter       = (*workspaceConfigStore)(nil)
+	_ ConfigWorkspaceSetter      = (*InMemoryConfigStore)(nil)
+	_ IgnoreListStore            = (*InMemoryConfigStore)(nil)
+	_ IgnoreListStore            = (*RedisConfigStore)(nil)
+	_ CheckedConfigExistsChecker = (*RedisConfigStore)(nil)
+	_ CheckedConfigExistsChecker = (*SQLiteConfigStore)(nil)
+	_ CheckedConfigExistsChecker = (*cachedConfigStore)(nil)
+	_ ConfigChangeReporter       = (*cachedConfigStore)(nil)
+	_ WorkspaceDefaultsStore     = (*cachedConfigStore)(nil)
+	_ IgnoreListStore            = (*cachedConfigStore)(nil)
+	_ ContextBinder              = (*cachedConfigStore)(nil)
+)
+
+// WithContext ties the store's calls to ctx if it supports it, otherwise it's returned unchanged
+func WithContext(ctx context.Context, store ChannelConfigStore) ChannelConfigStore {
+	if binder, ok := store.(ContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+	return store
+}
+
+// MaxUndoSteps is how many config changes per channel can be undone
+const MaxUndoSteps = 10
+
 // InMemoryConfigStore provides a simple in-memory implementation of ChannelConfigStore
 type InMemoryConfigStore struct {
-	configs map[string]*models.ChannelConfig
-	mutex   sync.RWMutex
-	cfg     *config.Config
+	configs    map[string]*models.ChannelConfig
+	lastAccess map[string]time.Time               // When each config was last read or changed, for the janitor
+	history    map[string]*changeHistory          // Recent item/price changes per channel
+	undo       map[string][]*models.ChannelConfig // Configs from before recent changes, oldest first, nil for the defaults
+	mutex      sync.RWMutex
+	cfg        *config.Config
+	now        func() time.Time // Injectable clock for testing
+
+	janitorMutex sync.Mutex
+	janitorStop  chan struct{} // Closed to stop the janitor, nil when it isn't running
+	janitorDone  chan struct{} // Closed once the janitor has stopped
+
+	fileMutex   sync.Mutex // Serialises writes to the config file
+	saveBlocked bool       // Set when the config file couldn't be loaded, so it isn't written over
+
+	workspaceDefaults map[string]models.WorkspaceDefault
+	stats             map[string]models.ChannelStats
+	ignoredChannels   map[string]bool
+	ignoredUsers      map[string]bool
+}
+
+// NewConfigStore creates the channel config store for the application using the STORE_BACKEND
+// backend, falling back to in-memory storage if the backend is unknown or unavailable
+// SQLite is never swapped for memory, as its configs are expected to survive a restart, so
+// an error is returned if that backend can't be opened
+func NewConfigStore(cfg *config.Config) (ChannelConfigStore, error) {
+	backend := config.StoreBackendMemory
+	if cfg != nil && cfg.StoreBackend != "" {
+		backend = cfg.StoreBackend
+	} else if cfg != nil && cfg.UseRedis {
+		backend = config.StoreBackendRedis
+	} else if cfg != nil && cfg.SQLitePath != "" {
+		backend = config.StoreBackendSQLite
+	}
+
+	store, err := newStoreFromBackend(backend, cfg)
+	if err == nil {
+		logging.Info("Using %s config store", backend)
+		return store, nil
+	}
+	if backend == config.StoreBackendSQLite {
+		return nil, errors.Wrap(err, "Failed to create sqlite config store")
+	}
+	logging.Error("Failed to create %s config store, falling back to in-memory: %v", backend, err)
+
+	return newMemoryStoreBackend(cfg)
 }
 
-// NewInMemoryConfigStore creates a new in-memory config store
-// For backwards compatibility, this now wraps the new implementation
+// NewInMemoryConfigStore creates a new in-memory config store using the built-in default item
 func NewInMemoryConfigStore() *InMemoryConfigStore {
 	return NewInMemoryConfigStoreWithConfig(nil)
 }
 
-// NewInMemoryConfigStoreWithConfig creates a new in-memory config store with provided configuration
-// This is the new function that takes a config parameter
+// NewInMemoryConfigStoreWithConfig creates a new in-memory config store using the application's default item
 func NewInMemoryConfigStoreWithConfig(cfg *config.Config) *InMemoryConfigStore {
 	logging.Debug("Creating new in-memory config store")
 	return &InMemoryConfigStore{
-		configs: make(map[string]*models.ChannelConfig),
-		cfg:     cfg,
+		configs:    make(map[string]*models.ChannelConfig),
+		lastAccess: make(map[string]time.Time),
+		history:    make(map[string]*changeHistory),
+		undo:       make(map[string][]*models.ChannelConfig),
+		cfg:        cfg,
+		now:        time.Now,
+
+		workspaceDefaults: make(map[string]models.WorkspaceDefault),
+		stats:             make(map[string]models.ChannelStats),
+		ignoredChannels:   make(map[string]bool),
+		ignoredUsers:      make(map[string]bool),
 	}
 }
 
@@ -46,31 +142,22 @@ func (s *InMemoryConfigStore) GetConfig(channelID string) (*models.ChannelConfig
 		return nil, errors.New(errors.ErrInvalidRequest, "empty channel ID")
 	}
 
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	// Reading a config counts as using it, which the janitor needs to know
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
 	if config, ok := s.configs[channelID]; ok {
 		logging.Debug("Found existing configuration for channel %s", channelID)
+		s.touch(channelID)
 		// Return a copy to prevent concurrent modification issues
-		return &models.ChannelConfig{
-			ChannelID: config.ChannelID,
-			ItemName:  config.ItemName,
-			ItemPrice: config.ItemPrice,
-		}, nil
+		config = cloneConfig(config)
+		defaultItemName, _ := s.defaultItem()
+		config.Inherit(defaultItemName)
+		return config, nil
 	}
 
 	// Create new default config using application defaults
-	var defaultItemName string
-	var defaultItemPrice float64
-
-	if s.cfg != nil {
-		defaultItemName = s.cfg.DefaultItemName
-		defaultItemPrice = s.cfg.DefaultItemPrice
-	} else {
-		// Fallback to hardcoded defaults if no config is provided
-		defaultItemName = "Bunnings snags"
-		defaultItemPrice = 3.50
-	}
+	defaultItemName, defaultItemPrice := s.defaultItem()
 
 	logging.Debug("No configuration found for channel %s, using defaults: %s at $%.2f",
 		channelID, defaultItemName, defaultItemPrice)
@@ -86,20 +173,72 @@ func (s *InMemoryConfigStore) GetConfig(channelID string) (*models.ChannelConfig
 	return newConfig, nil
 }
 
-// UpdateConfig updates the configuration for a channel
-func (s *InMemoryConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64) error {
+// defaultItem returns the item and price used by channels without a custom configuration
+func (s *InMemoryConfigStore) defaultItem() (string, float64) {
+	if s.cfg != nil {
+		return s.cfg.DefaultItem()
+	}
+
+	// Fallback to hardcoded defaults if no config is provided
+	return "Bunnings snags", 3.50
+}
+
+// normalizeItemName tidies an item name with models.NormalizeItemName and checks it fits within
+// the configured length limit
+func normalizeItemName(name string, cfg *config.Config) (string, error) {
+	name = models.NormalizeItemName(name)
+	if name == "" {
+		return "", errors.New(errors.ErrInvalidRequest, "item name cannot be empty")
+	}
+
+	maxLength := config.DefaultMaxItemNameLength
+	if cfg != nil && cfg.MaxItemNameLength > 0 {
+		maxLength = cfg.MaxItemNameLength
+	}
+	if length := utf8.RuneCountInString(name); length > maxLength {
+		return "", errors.Newf(errors.ErrItemNameTooLong,
+			"Item names can be at most %d characters, that one is %d", maxLength, length)
+	}
+	return name, nil
+}
+
+// recordChange adds a change to the channel's history, the caller must hold the mutex
+func (s *InMemoryConfigStore) recordChange(change models.ConfigChange) {
+	history, ok := s.history[change.ChannelID]
+	if !ok {
+		history = newChangeHistory(DefaultHistorySize)
+		s.history[change.ChannelID] = history
+	}
+
+	change.ChangedAt = s.now()
+	history.add(change)
+}
+
+// UpdateConfig updates the configuration for a channel, recording the change against userID
+func (s *InMemoryConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error {
+	_, err := s.UpdateConfigChanged(channelID, itemName, itemPrice, userID)
+	return err
+}
+
+// UpdateConfigChanged updates the configuration for a channel like UpdateConfig, reporting whether
+// the item or price changed
+// Updates to the channel's current item and price aren't recorded in its history or undo steps
+func (s *InMemoryConfigStore) UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error) {
 	if channelID == "" {
-		return errors.New(errors.ErrInvalidRequest, "empty channel ID")
+		return false, errors.New(errors.ErrInvalidRequest, "empty channel ID")
 	}
 
 	if itemPrice <= 0 {
-		return errors.Newf(errors.ErrInvalidRequest, "item price must be greater than zero: %.2f", itemPrice)
+		return false, errors.Newf(errors.ErrInvalidRequest, "item price must be greater than zero: %.2f", itemPrice)
 	}
 
-	if itemName == "" {
-		return errors.New(errors.ErrInvalidRequest, "item name cannot be empty")
+	itemName, err := normalizeItemName(itemName, s.cfg)
+	if err != nil {
+		return false, err
 	}
 
+	// Saved once the mutex is released
+	defer s.saveChanges()
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
@@ -107,47 +246,207 @@ func (s *InMemoryConfigStore) UpdateConfig(channelID, itemName string, itemPrice
 	var config *models.ChannelConfig
 	var ok bool
 
+	if config, ok = s.configs[channelID]; ok && sameItem(config, itemName, itemPrice) {
+		s.touch(channelID)
+		logging.Debug("Configuration for channel %s already uses item=%s, price=%.2f, nothing to update",
+			channelID, itemName, itemPrice)
+		return false, nil
+	}
+
+	s.saveUndoPoint(channelID)
+
 	if config, ok = s.configs[channelID]; !ok {
-		// If config doesn't exist, create a new one
+		// If config doesn't exist, create a new one starting from the defaults
 		config = &models.ChannelConfig{
 			ChannelID: channelID,
 		}
+		config.ItemName, config.ItemPrice = s.defaultItem()
 		s.configs[channelID] = config
 	}
 
+	s.recordChange(models.ConfigChange{
+		ChannelID:    channelID,
+		UserID:       userID,
+		OldItemName:  config.ItemName,
+		OldItemPrice: config.ItemPrice,
+		NewItemName:  itemName,
+		NewItemPrice: itemPrice,
+	})
+
 	// Update the configuration
-	config.ItemName = itemName
-	config.ItemPrice = itemPrice
+	config.SetItem(itemName, itemPrice)
+	s.touch(channelID)
 
 	logging.Info("Updated configuration for channel %s: item=%s, price=%.2f",
 		channelID, itemName, itemPrice)
 
+	return true, nil
+}
+
+// sameItem reports whether setting the item and price would leave the config as it is
+// Setting an item clears its unit and stops it being inherited, so those configs would still change
+func sameItem(config *models.ChannelConfig, itemName string, itemPrice float64) bool {
+	return config.ItemName == itemName && config.ItemPrice == itemPrice && !config.HasUnit() && !config.InheritItem
+}
+
+// UpdateConfigChanged updates the channel's item and price, reporting whether they changed
+// Stores that can't tell always write the update and report a change
+func UpdateConfigChanged(store ChannelConfigStore, channelID, itemName string, itemPrice float64, userID string) (bool, error) {
+	if reporter, ok := store.(ConfigChangeReporter); ok {
+		return reporter.UpdateConfigChanged(channelID, itemName, itemPrice, userID)
+	}
+	if err := store.UpdateConfig(channelID, itemName, itemPrice, userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveConfig stores a complete channel configuration, including optional settings
+func (s *InMemoryConfigStore) SaveConfig(config *models.ChannelConfig) error {
+	if config == nil {
+		return errors.New(errors.ErrInvalidRequest, "nil channel config")
+	}
+
+	if config.ChannelID == "" {
+		return errors.New(errors.ErrInvalidRequest, "empty channel ID")
+	}
+
+	// Saved once the mutex is released
+	defer s.saveChanges()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.saveUndoPoint(config.ChannelID)
+
+	// Store a copy so later changes by the caller don't leak into the store
+	s.configs[config.ChannelID] = cloneConfig(config)
+	s.touch(config.ChannelID)
+
+	logging.Info("Saved configuration for channel %s", config.ChannelID)
 	return nil
 }
 
-// ResetConfig resets a channel's configuration to the default
-func (s *InMemoryConfigStore) ResetConfig(channelID string) error {
+// SetConfigWorkspace records the workspace of the channel's config, without an undo point
+func (s *InMemoryConfigStore) SetConfigWorkspace(channelID, workspaceID string) error {
+	// Saved once the mutex is released
+	defer s.saveChanges()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if config, ok := s.configs[channelID]; ok {
+		config.WorkspaceID = workspaceID
+	}
+	return nil
+}
+
+// ResetConfig resets a channel's configuration to the default, recording the change against userID
+func (s *InMemoryConfigStore) ResetConfig(channelID, userID string) error {
 	if channelID == "" {
 		return errors.New(errors.ErrInvalidRequest, "empty channel ID")
 	}
 
+	// Saved once the mutex is released
+	defer s.saveChanges()
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
 	// Check if config exists before deleting
-	if _, ok := s.configs[channelID]; !ok {
+	config, ok := s.configs[channelID]
+	if !ok {
 		// Already using defaults, nothing to do
 		logging.Debug("Channel %s already using defaults, no reset needed", channelID)
 		return nil
 	}
 
+	s.saveUndoPoint(channelID)
+
+	defaultItemName, defaultItemPrice := s.defaultItem()
+	s.recordChange(models.ConfigChange{
+		ChannelID:    channelID,
+		UserID:       userID,
+		Reset:        true,
+		OldItemName:  config.ItemName,
+		OldItemPrice: config.ItemPrice,
+		NewItemName:  defaultItemName,
+		NewItemPrice: defaultItemPrice,
+	})
+
 	// Delete the config from the map
 	delete(s.configs, channelID)
+	delete(s.lastAccess, channelID)
 	logging.Info("Reset configuration for channel %s to default", channelID)
 
 	return nil
 }
 
+// touch records that the channel's config was just used, the caller must hold the mutex
+func (s *InMemoryConfigStore) touch(channelID string) {
+	s.lastAccess[channelID] = s.now()
+}
+
+// saveUndoPoint remembers the channel's config before a change so it can be undone, the caller
+// must hold the mutex
+func (s *InMemoryConfigStore) saveUndoPoint(channelID string) {
+	var previous *models.ChannelConfig
+	if config, ok := s.configs[channelID]; ok {
+		previous = cloneConfig(config)
+	}
+
+	points := append(s.undo[channelID], previous)
+	if len(points) > MaxUndoSteps {
+		points = points[len(points)-MaxUndoSteps:]
+	}
+	s.undo[channelID] = points
+}
+
+// Undo restores the channel's config from before its last change, a step at a time
+func (s *InMemoryConfigStore) Undo(channelID string) error {
+	if channelID == "" {
+		return errors.New(errors.ErrInvalidRequest, "empty channel ID")
+	}
+
+	// Saved once the mutex is released
+	defer s.saveChanges()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	points := s.undo[channelID]
+	if len(points) == 0 {
+		return errors.Newf(errors.ErrNothingToUndo, "no changes to undo in channel %s", channelID)
+	}
+
+	previous := points[len(points)-1]
+	if len(points) == 1 {
+		delete(s.undo, channelID)
+	} else {
+		s.undo[channelID] = points[:len(points)-1]
+	}
+
+	// A nil config means the channel was using the defaults
+	if previous == nil {
+		delete(s.configs, channelID)
+		delete(s.lastAccess, channelID)
+	} else {
+		s.configs[channelID] = previous
+		s.touch(channelID)
+	}
+
+	logging.Info("Undid the last configuration change for channel %s", channelID)
+	return nil
+}
+
+// cloneConfig copies a config, including its slices, so the copy can be changed independently
+func cloneConfig(config *models.ChannelConfig) *models.ChannelConfig {
+	configCopy := *config
+	configCopy.CurrencySymbols = append([]string(nil), config.CurrencySymbols...)
+	configCopy.Items = append([]models.ChannelItem(nil), config.Items...)
+	if config.Phrases != nil {
+		phrases := *config.Phrases
+		configCopy.Phrases = &phrases
+	}
+	return &configCopy
+}
+
 // ConfigExists checks if a custom configuration exists for a channel
 func (s *InMemoryConfigStore) ConfigExists(channelID string) bool {
 	if channelID == "" {
@@ -162,6 +461,128 @@ func (s *InMemoryConfigStore) ConfigExists(channelID string) bool {
 	return exists
 }
 
+// GetHistory returns up to limit of the channel's most recent item and price changes, newest first
+// A limit of zero or less returns every change still held
+func (s *InMemoryConfigStore) GetHistory(channelID string, limit int) []models.ConfigChange {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	history, ok := s.history[channelID]
+	if !ok {
+		return nil
+	}
+	return history.recent(limit)
+}
+
+// RecordResponse counts a response in the channel that converted the given total
+func (s *InMemoryConfigStore) RecordResponse(channelID string, total float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.stats[channelID]
+	stats.Responses++
+	stats.TotalDollars = math.Round((stats.TotalDollars+total)*100) / 100
+	s.stats[channelID] = stats
+}
+
+// GetStats returns the channel's response counts, which are kept in memory only
+func (s *InMemoryConfigStore) GetStats(channelID string) models.ChannelStats {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.stats[channelID]
+}
+
+// GetWorkspaceDefault returns the workspace's default item, or nil if it hasn't set one
+func (s *InMemoryConfigStore) GetWorkspaceDefault(workspaceID string) (*models.WorkspaceDefault, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	def, ok := s.workspaceDefaults[workspaceID]
+	if !ok {
+		return nil, nil
+	}
+	return &def, nil
+}
+
+// SaveWorkspaceDefault sets the default item for the workspace's channels
+func (s *InMemoryConfigStore) SaveWorkspaceDefault(def *models.WorkspaceDefault) error {
+	if def == nil || def.WorkspaceID == "" {
+		return errors.New(errors.ErrInvalidRequest, "workspace default without a workspace ID")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.workspaceDefaults[def.WorkspaceID] = *def
+	logging.Info("Set default item for workspace %s to %s at $%.2f", def.WorkspaceID, def.ItemName, def.ItemPrice)
+	return nil
+}
+
+// IsIgnored returns true if the channel or user is on the ignore list, or ignored by the app config
+func (s *InMemoryConfigStore) IsIgnored(channelID, userID string) bool {
+	if ignoredByConfig(s.cfg, channelID, userID) {
+		return true
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.ignoredChannels[channelID] || (userID != "" && s.ignoredUsers[userID])
+}
+
+// SetChannelIgnored adds the channel to the ignore list, or removes it
+func (s *InMemoryConfigStore) SetChannelIgnored(channelID string, ignored bool) error {
+	if channelID == "" {
+		return errors.New(errors.ErrInvalidRequest, "empty channel ID")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	setMember(s.ignoredChannels, channelID, ignored)
+	return nil
+}
+
+// SetUserIgnored adds the user to the ignore list, or removes them
+func (s *InMemoryConfigStore) SetUserIgnored(userID string, ignored bool) error {
+	if userID == "" {
+		return errors.New(errors.ErrInvalidRequest, "empty user ID")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	setMember(s.ignoredUsers, userID, ignored)
+	return nil
+}
+
+// setMember adds the ID to the set or removes it
+func setMember(set map[string]bool, id string, member bool) {
+	if member {
+		set[id] = true
+	} else {
+		delete(set, id)
+	}
+}
+
+// Ping always succeeds, since the configs are held in memory
+func (s *InMemoryConfigStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close stops the janitor and saves the configs to disk if a store path is configured
+func (s *InMemoryConfigStore) Close() error {
+	s.StopJanitor()
+
+	path := s.savePath()
+	if path == "" {
+		return nil
+	}
+
+	return s.SaveToFile(path)
+}
+
 // GetAllChannelIDs returns a list of all channel IDs that have custom configs
 func (s *InMemoryConfigStore) GetAllChannelIDs() []string {
 	s.mutex.RLock()
@@ -196,6 +617,18 @@ func (s *InMemoryConfigStore) BackupConfigs() map[string]models.ChannelConfig {
 	return backup
 }
 
+// GetAllConfigs returns a copy of every custom channel configuration, keyed by channel ID
+func (s *InMemoryConfigStore) GetAllConfigs() (map[string]models.ChannelConfig, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	configs := make(map[string]models.ChannelConfig, len(s.configs))
+	for id, config := range s.configs {
+		configs[id] = *cloneConfig(config)
+	}
+	return configs, nil
+}
+
 // RestoreConfigs restores configurations from a backup
 func (s *InMemoryConfigStore) RestoreConfigs(backup map[string]models.ChannelConfig) error {
 	if backup == nil {
@@ -207,17 +640,116 @@ func (s *InMemoryConfigStore) RestoreConfigs(backup map[string]models.ChannelCon
 
 	// Clear existing configs
 	s.configs = make(map[string]*models.ChannelConfig, len(backup))
+	s.lastAccess = make(map[string]time.Time, len(backup))
 
-	// Restore from backup
+	// Restore from backup, counting restored configs as just used
 	for id, config := range backup {
 		// Create a copy to avoid issues with map values
 		copyConfig := config
 		s.configs[id] = &copyConfig
+		s.touch(id)
 	}
 
 	logging.Info("Restored %d channel configurations from backup", len(backup))
 	return nil
 }
 
+// savePath returns the file configs are saved to, or "" if they aren't saved
+func (s *InMemoryConfigStore) savePath() string {
+	if s.cfg == nil || s.cfg.ConfigStorePath == "" {
+		return ""
+	}
+
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+	if s.saveBlocked {
+		return ""
+	}
+	return s.cfg.ConfigStorePath
+}
+
+// blockSaving stops configs being saved to CONFIG_STORE_PATH, so a file that couldn't be loaded
+// is kept for someone to look at rather than replaced
+func (s *InMemoryConfigStore) blockSaving() {
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+	s.saveBlocked = true
+}
+
+// saveChanges saves the configs to CONFIG_STORE_PATH after a change, so they survive a crash
+// The caller mustn't hold the mutex
+func (s *InMemoryConfigStore) saveChanges() {
+	path := s.savePath()
+	if path == "" {
+		return
+	}
+	if err := s.SaveToFile(path); err != nil {
+		logging.Error("Failed to save channel configs to %s: %v", path, err)
+	}
+}
+
+// SaveToFile writes all configurations to a JSON file
+// The file is written atomically so a crash mid-write never leaves a partial file
+func (s *InMemoryConfigStore) SaveToFile(path string) error {
+	// Snapshots are written in the order they're taken, so an older one never replaces a newer one
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+
+	data, err := json.MarshalIndent(s.BackupConfigs(), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to marshal channel configs")
+	}
+
+	// Write to a temp file in the same directory, then rename over the target
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "Failed to create temp file").WithContext(path)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return errors.Wrap(err, "Failed to write channel configs").WithContext(path)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return errors.Wrap(err, "Failed to write channel configs").WithContext(path)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return errors.Wrap(err, "Failed to replace channel config file").WithContext(path)
+	}
+
+	logging.Info("Saved channel configurations to %s", path)
+	return nil
+}
+
+// LoadFromFile restores configurations from a JSON file written by SaveToFile
+// A missing file is not an error, since there is nothing to restore on first run
+func (s *InMemoryConfigStore) LoadFromFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logging.Info("No channel config file found at %s, starting fresh", path)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to read channel config file").WithContext(path)
+	}
+
+	var backup map[string]models.ChannelConfig
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return errors.Wrap(err, "Failed to parse channel config file").WithContext(path)
+	}
+
+	if backup == nil {
+		backup = make(map[string]models.ChannelConfig)
+	}
+
+	return s.RestoreConfigs(backup)
+}
+
 // Global store instance for backward compatibility and testing
 var globalConfigStore ChannelConfigStore = NewInMemoryConfigStore()
@@ -0,0 +1,40 @@
+package slack
+
+import (
+	"strings"
+
+	"github.com/mcncl/snagbot/internal/config"
+)
+
+// defaultIgnoredSubTypes are the subtypes skipped when IGNORED_MESSAGE_SUBTYPES isn't configured
+var defaultIgnoredSubTypes = subTypeSet(config.DefaultIgnoredMessageSubTypes)
+
+// ProcessesSubType reports whether messages with the subtype are processed. When
+// MESSAGE_SUBTYPES is set only those subtypes are processed, and IGNORED_MESSAGE_SUBTYPES never are
+// Plain messages, with no subtype, are always processed
+func (o *ProcessorOptions) ProcessesSubType(subType string) bool {
+	if subType == "" {
+		return true
+	}
+
+	allowed, ignored := map[string]bool(nil), defaultIgnoredSubTypes
+	if o != nil {
+		allowed = o.allowedSubTypes
+		if o.ignoredSubTypes != nil {
+			ignored = o.ignoredSubTypes
+		}
+	}
+	if ignored[subType] {
+		return false
+	}
+	return len(allowed) == 0 || allowed[subType]
+}
+
+// subTypeSet splits a comma separated list of subtypes into a set
+func subTypeSet(value string) map[string]bool {
+	set := make(map[string]bool)
+	for _, subType := range strings.Split(value, ",") {
+		set[strings.TrimSpace(subType)] = true
+	}
+	return set
+}
@@ -43,11 +43,23 @@ func (s *RedisTokenStore) getTokenKey(workspaceID string) string {
 }
 
 // SaveToken saves a workspace token to Redis
+// Reinstalling into a workspace updates its existing token, keeping when and by whom SnagBot was
+// first installed
 func (s *RedisTokenStore) SaveToken(token *models.WorkspaceToken) error {
 	if token.WorkspaceID == "" {
 		return errors.New("workspace ID is required")
 	}
 
+	// A token that can't be read is replaced, since reinstalling is how it gets fixed
+	existing, err := s.findToken(token.WorkspaceID)
+	if err != nil {
+		logging.Warn("Failed to read existing token for workspace %s, saving as a new install: %v", token.WorkspaceID, err)
+	}
+	if existing != nil {
+		logging.Info("Updating existing token for workspace %s", token.WorkspaceID)
+		token = reinstalledToken(existing, token)
+	}
+
 	jsonData, err := json.Marshal(token)
 	if err != nil {
 		return fmt.Errorf("error marshaling token: %w", err)
@@ -71,12 +83,24 @@ func (s *RedisTokenStore) SaveToken(token *models.WorkspaceToken) error {
 
 // GetToken retrieves a workspace token from Redis
 func (s *RedisTokenStore) GetToken(workspaceID string) (*models.WorkspaceToken, error) {
+	token, err := s.findToken(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, fmt.Errorf("token not found for workspace %s", workspaceID)
+	}
+	return token, nil
+}
+
+// findToken returns the workspace's stored token, or nil if it doesn't have one
+func (s *RedisTokenStore) findToken(workspaceID string) (*models.WorkspaceToken, error) {
 	key := s.getTokenKey(workspaceID)
 	
 	jsonData, err := s.client.Get(s.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return nil, fmt.Errorf("token not found for workspace %s", workspaceID)
+			return nil, nil
 		}
 		return nil, fmt.Errorf("error retrieving token from Redis: %w", err)
 	}
@@ -89,6 +113,32 @@ func (s *RedisTokenStore) GetToken(workspaceID string) (*models.WorkspaceToken,
 	return &token, nil
 }
 
+// reinstalledToken returns the existing token updated with the new install's access token and
+// scopes, and any details the new install knows
+func reinstalledToken(existing, token *models.WorkspaceToken) *models.WorkspaceToken {
+	updated := *existing
+	updated.UpdateToken(token.AccessToken, token.Scope)
+	if token.TeamName != "" {
+		updated.TeamName = token.TeamName
+	}
+	if token.BotUserID != "" {
+		updated.BotUserID = token.BotUserID
+	}
+	if token.TokenType != "" {
+		updated.TokenType = token.TokenType
+	}
+	if updated.InstalledBy == "" {
+		updated.InstalledBy = token.InstalledBy
+	}
+	if updated.InstalledAt.IsZero() {
+		updated.InstalledAt = token.InstalledAt
+	}
+	if updated.InstallationID == "" {
+		updated.InstallationID = token.InstallationID
+	}
+	return &updated
+}
+
 // DeleteToken removes a workspace token from Redis
 func (s *RedisTokenStore) DeleteToken(workspaceID string) error {
 	key := s.getTokenKey(workspaceID)
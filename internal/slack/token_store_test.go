@@ -0,0 +1,89 @@
+package slack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRedisTokenStore creates a token store backed by an in-process Redis server
+func newTestRedisTokenStore(t *testing.T) (*RedisTokenStore, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisTokenStore(client), server
+}
+
+func TestRedisTokenStore_FirstInstall(t *testing.T) {
+	store, _ := newTestRedisTokenStore(t)
+	token := models.NewWorkspaceToken("T12345", "Snag Lovers", "xoxb-first", "B12345", "chat:write", "bot", "U12345")
+
+	assert.NoError(t, store.SaveToken(token))
+
+	saved, err := store.GetToken("T12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "xoxb-first", saved.AccessToken)
+	assert.True(t, token.InstalledAt.Equal(saved.InstalledAt))
+
+	workspaces, err := store.ListWorkspaces()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T12345"}, workspaces)
+}
+
+func TestRedisTokenStore_Reinstall(t *testing.T) {
+	store, _ := newTestRedisTokenStore(t)
+	installedAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	first := models.NewWorkspaceToken("T12345", "Snag Lovers", "xoxb-first", "B12345", "chat:write", "bot", "U12345")
+	first.InstalledAt, first.LastUpdated = installedAt, installedAt
+	first.InstallationID = "install-1"
+	assert.NoError(t, store.SaveToken(first))
+
+	second := models.NewWorkspaceToken("T12345", "Sausage Sizzlers", "xoxb-second", "B12345", "chat:write,reactions:read", "bot", "U67890")
+	assert.NoError(t, store.SaveToken(second))
+
+	saved, err := store.GetToken("T12345")
+	assert.NoError(t, err)
+
+	// The new install's token, scopes and team name replace the old ones
+	assert.Equal(t, "xoxb-second", saved.AccessToken)
+	assert.Equal(t, "chat:write,reactions:read", saved.Scope)
+	assert.Equal(t, "Sausage Sizzlers", saved.TeamName)
+	assert.True(t, saved.LastUpdated.After(installedAt))
+
+	// The original install is remembered
+	assert.True(t, installedAt.Equal(saved.InstalledAt), "Expected install time %v, got %v", installedAt, saved.InstalledAt)
+	assert.Equal(t, "U12345", saved.InstalledBy)
+	assert.Equal(t, "install-1", saved.InstallationID)
+
+	workspaces, err := store.ListWorkspaces()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"T12345"}, workspaces)
+}
+
+func TestRedisTokenStore_ReinstallOverUnreadableToken(t *testing.T) {
+	store, server := newTestRedisTokenStore(t)
+	assert.NoError(t, server.Set(store.getTokenKey("T12345"), "not json"))
+
+	token := models.NewWorkspaceToken("T12345", "Snag Lovers", "xoxb-new", "B12345", "chat:write", "bot", "U12345")
+	assert.NoError(t, store.SaveToken(token))
+
+	saved, err := store.GetToken("T12345")
+	assert.NoError(t, err)
+	assert.Equal(t, "xoxb-new", saved.AccessToken)
+	assert.True(t, token.InstalledAt.Equal(saved.InstalledAt))
+}
+
+func TestRedisTokenStore_GetTokenNotFound(t *testing.T) {
+	store, _ := newTestRedisTokenStore(t)
+
+	token, err := store.GetToken("T12345")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+}
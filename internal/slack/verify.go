@@ -1,32 +1,108 @@
 package slack
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"time"
 
-	slackgo "github.com/slack-go/slack"
+	"github.com/mcncl/snagbot/internal/errors"
 )
 
-// VerifySlackRequest verifies that a request is coming from Slack
-// Returns the request body if verification succeeds, or an error if it fails
-func VerifySlackRequest(r *http.Request, signingSecret string) ([]byte, error) {
-	// Verify that the request is coming from Slack
-	sv, err := slackgo.NewSecretsVerifier(r.Header, signingSecret)
+// DefaultRequestMaxAge is how far a request's timestamp may be from now before it is
+// rejected as a possible replay
+const DefaultRequestMaxAge = 5 * time.Minute
+
+// DefaultMaxRequestBodySize is the largest request body read from Slack when no limit is configured
+const DefaultMaxRequestBodySize int64 = 1 << 20
+
+// LimitRequestBody caps how much of the request body can be read, so an oversized request fails
+// with ErrRequestTooLarge instead of being read into memory
+// A zero or negative limit uses DefaultMaxRequestBodySize
+func LimitRequestBody(w http.ResponseWriter, r *http.Request, limit int64) {
+	if limit <= 0 {
+		limit = DefaultMaxRequestBodySize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+}
+
+// IsRequestTooLarge reports whether err came from reading a body over the limit set by LimitRequestBody
+func IsRequestTooLarge(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Is(errors.ErrRequestTooLarge)
+}
+
+// RequestVerifier checks that requests were signed by Slack and are recent
+type RequestVerifier struct {
+	signingSecret string
+	maxAge        time.Duration
+	now           func() time.Time // Injectable clock for testing
+}
+
+// NewRequestVerifier creates a verifier for the signing secret, rejecting requests whose
+// timestamp is more than maxAge from now in either direction
+// A zero or negative maxAge uses DefaultRequestMaxAge
+func NewRequestVerifier(signingSecret string, maxAge time.Duration) *RequestVerifier {
+	if maxAge <= 0 {
+		maxAge = DefaultRequestMaxAge
+	}
+
+	return &RequestVerifier{
+		signingSecret: signingSecret,
+		maxAge:        maxAge,
+		now:           time.Now,
+	}
+}
+
+// Verify checks the request's timestamp and signature, returning the request body if both are valid
+// The body is put back on the request so later handlers can still read it
+func (v *RequestVerifier) Verify(r *http.Request) ([]byte, error) {
+	timestampHeader := r.Header.Get("X-Slack-Request-Timestamp")
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, errors.New(errors.ErrInvalidSignature, "Missing or invalid request timestamp").WithContext(timestampHeader)
+	}
+
+	// Reject old requests so a captured request can't be replayed later,
+	// allowing the same leeway for clocks running ahead of ours
+	age := v.now().Sub(time.Unix(timestamp, 0))
+	if age > v.maxAge || age < -v.maxAge {
+		return nil, errors.Newf(errors.ErrInvalidSignature, "Request timestamp is outside the allowed window of %s", v.maxAge).
+			WithContext(timestampHeader)
 	}
 
-	// Read the body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return nil, err
+		var maxBytesErr *http.MaxBytesError
+		if stderrors.As(err, &maxBytesErr) {
+			return nil, errors.Newf(errors.ErrRequestTooLarge, "Request body is larger than %d bytes", maxBytesErr.Limit).
+				WithStatus(http.StatusRequestEntityTooLarge)
+		}
+		return nil, errors.Wrap(err, "Failed to read request body")
 	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
 
-	// Add the body to the signature verification
-	sv.Write(body)
-	if err := sv.Ensure(); err != nil {
-		return nil, err
+	// Slack signs "v0:<timestamp>:<body>" with the signing secret
+	mac := hmac.New(sha256.New, []byte(v.signingSecret))
+	fmt.Fprintf(mac, "v0:%s:", timestampHeader)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return nil, errors.New(errors.ErrInvalidSignature, "Request signature doesn't match")
 	}
 
 	return body, nil
 }
+
+// VerifySlackRequest verifies that a request is coming from Slack and isn't older than DefaultRequestMaxAge
+// Returns the request body if verification succeeds, or an error if it fails
+func VerifySlackRequest(r *http.Request, signingSecret string) ([]byte, error) {
+	return NewRequestVerifier(signingSecret, DefaultRequestMaxAge).Verify(r)
+}
@@ -0,0 +1,115 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mcncl/snagbot/internal/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// signedRequestAt builds a request signed the way Slack signs them, stamped with the given time
+func signedRequestAt(secret, body string, at time.Time) *http.Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/commands", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestRequestVerifier_Verify(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	verifier := NewRequestVerifier("test-secret", DefaultRequestMaxAge)
+	verifier.now = clock.Now
+	body := "command=/snagbot&text=status"
+
+	t.Run("Fresh request", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current.Add(-time.Minute))
+		verified, err := verifier.Verify(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(verified))
+
+		// The body can still be read by the handler
+		remaining, err := io.ReadAll(req.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(remaining))
+	})
+
+	t.Run("Ten minute old request", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current.Add(-10*time.Minute))
+		_, err := verifier.Verify(req)
+		assert.ErrorIs(t, err, errors.ErrInvalidSignature)
+	})
+
+	t.Run("Request from the future", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current.Add(10*time.Minute))
+		_, err := verifier.Verify(req)
+		assert.ErrorIs(t, err, errors.ErrInvalidSignature)
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		req := signedRequestAt("other-secret", body, clock.current)
+		_, err := verifier.Verify(req)
+		assert.ErrorIs(t, err, errors.ErrInvalidSignature)
+	})
+
+	t.Run("Missing timestamp", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current)
+		req.Header.Del("X-Slack-Request-Timestamp")
+		_, err := verifier.Verify(req)
+		assert.ErrorIs(t, err, errors.ErrInvalidSignature)
+	})
+}
+
+func TestRequestVerifier_ConfigurableWindow(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	verifier := NewRequestVerifier("test-secret", 15*time.Minute)
+	verifier.now = clock.Now
+
+	req := signedRequestAt("test-secret", "text=status", clock.current.Add(-10*time.Minute))
+	_, err := verifier.Verify(req)
+	assert.NoError(t, err)
+}
+
+func TestRequestVerifier_BodySizeLimit(t *testing.T) {
+	clock := &fakeClock{current: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	verifier := NewRequestVerifier("test-secret", DefaultRequestMaxAge)
+	verifier.now = clock.Now
+	body := "command=/snagbot&text=status"
+
+	t.Run("Body within the limit", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current)
+		LimitRequestBody(httptest.NewRecorder(), req, int64(len(body)))
+		verified, err := verifier.Verify(req)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(verified))
+	})
+
+	t.Run("Body over the limit", func(t *testing.T) {
+		req := signedRequestAt("test-secret", body, clock.current)
+		LimitRequestBody(httptest.NewRecorder(), req, int64(len(body)-1))
+		_, err := verifier.Verify(req)
+		assert.ErrorIs(t, err, errors.ErrRequestTooLarge)
+		assert.True(t, IsRequestTooLarge(err))
+	})
+
+	t.Run("Other failures aren't too large", func(t *testing.T) {
+		req := signedRequestAt("other-secret", body, clock.current)
+		LimitRequestBody(httptest.NewRecorder(), req, 0)
+		_, err := verifier.Verify(req)
+		assert.Error(t, err)
+		assert.False(t, IsRequestTooLarge(err))
+	})
+}
@@ -0,0 +1,182 @@
+package slack
+
+import (
+	"context"
+
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/pkg/models"
+)
+
+// workspaceConfigStore looks up channel configs for one workspace, so channels without
+// their own config use the workspace's default item before the global default
+// Configs written through it are marked as belonging to the workspace
+type workspaceConfigStore struct {
+	ChannelConfigStore
+	defaults    WorkspaceDefaultsStore // nil if the store doesn't keep workspace defaults
+	workspaceID string
+}
+
+// ForWorkspace returns the config store as seen from a workspace
+// The store is returned unchanged if the workspace is unknown
+func ForWorkspace(store ChannelConfigStore, workspaceID string) ChannelConfigStore {
+	if workspaceID == "" {
+		return store
+	}
+	defaults, _ := store.(WorkspaceDefaultsStore)
+
+	return &workspaceConfigStore{
+		ChannelConfigStore: store,
+		defaults:           defaults,
+		workspaceID:        workspaceID,
+	}
+}
+
+// GetConfig returns the channel's own config, falling back to the workspace default
+// and then the global default, for the whole config or just the item if the channel only
+// set its own price
+func (s *workspaceConfigStore) GetConfig(channelID string) (*models.ChannelConfig, error) {
+	resolved, err := s.ResolveConfig(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.Config, nil
+}
+
+// ResolveConfig returns the channel's effective config like GetConfig, noting whether its item
+// and price came from the channel, the workspace default or the global default
+func (s *workspaceConfigStore) ResolveConfig(channelID string) (*models.ResolvedConfig, error) {
+	config, err := s.ChannelConfigStore.GetConfig(channelID)
+	if err != nil {
+		return nil, err
+	}
+	exists := s.ChannelConfigStore.ConfigExists(channelID)
+	if exists && !config.InheritItem {
+		return newResolvedConfig(config, models.ConfigSourceChannel), nil
+	}
+
+	resolved := newResolvedConfig(config, models.ConfigSourceGlobal)
+	if exists {
+		resolved.PriceSource = models.ConfigSourceChannel
+	}
+	if s.defaults == nil {
+		return resolved, nil
+	}
+
+	def, err := s.defaults.GetWorkspaceDefault(s.workspaceID)
+	if err != nil {
+		// The global default still gives a sensible answer
+		logging.Warn("Failed to get default item for workspace %s: %v", s.workspaceID, err)
+		return resolved, nil
+	}
+	if def != nil {
+		config.ItemName = def.ItemName
+		resolved.ItemSource = models.ConfigSourceWorkspace
+		if !exists {
+			config.ItemPrice = def.ItemPrice
+			resolved.PriceSource = models.ConfigSourceWorkspace
+		}
+	}
+	config.WorkspaceID = s.workspaceID
+
+	return resolved, nil
+}
+
+// UpdateConfig updates the channel's item and price, marking its config as the workspace's
+func (s *workspaceConfigStore) UpdateConfig(channelID, itemName string, itemPrice float64, userID string) error {
+	_, err := s.UpdateConfigChanged(channelID, itemName, itemPrice, userID)
+	return err
+}
+
+// UpdateConfigChanged updates the channel's item and price like UpdateConfig, reporting whether
+// they changed
+func (s *workspaceConfigStore) UpdateConfigChanged(channelID, itemName string, itemPrice float64, userID string) (bool, error) {
+	changed, err := UpdateConfigChanged(s.ChannelConfigStore, channelID, itemName, itemPrice, userID)
+	if err != nil {
+		return false, err
+	}
+	if err := s.markWorkspace(channelID); err != nil {
+		logging.Warn("Failed to record workspace %s for channel %s: %v", s.workspaceID, channelID, err)
+	}
+	return changed, nil
+}
+
+// SaveConfig stores a complete channel configuration, marking it as the workspace's
+func (s *workspaceConfigStore) SaveConfig(config *models.ChannelConfig) error {
+	if config != nil {
+		config.WorkspaceID = s.workspaceID
+	}
+	return s.ChannelConfigStore.SaveConfig(config)
+}
+
+// markWorkspace records that the channel's stored config belongs to the workspace, using the
+// store's own way of doing so if it has one so the change can't be undone on its own
+func (s *workspaceConfigStore) markWorkspace(channelID string) error {
+	if setter, ok := baseStore(s.ChannelConfigStore).(ConfigWorkspaceSetter); ok {
+		return setter.SetConfigWorkspace(channelID, s.workspaceID)
+	}
+
+	exists, err := CheckConfigExists(s.ChannelConfigStore, channelID)
+	if err != nil || !exists {
+		return err
+	}
+	config, err := s.ChannelConfigStore.GetConfig(channelID)
+	if err != nil || config.WorkspaceID == s.workspaceID {
+		return err
+	}
+	config.WorkspaceID = s.workspaceID
+	return s.ChannelConfigStore.SaveConfig(config)
+}
+
+// ResolveConfig returns the channel's effective config and where its item and price came from
+// Stores that can't tell workspace defaults apart report either the channel or the global default
+func ResolveConfig(store ChannelConfigStore, channelID string) (*models.ResolvedConfig, error) {
+	if resolver, ok := store.(ConfigResolver); ok {
+		return resolver.ResolveConfig(channelID)
+	}
+
+	config, err := store.GetConfig(channelID)
+	if err != nil {
+		return nil, err
+	}
+	if !store.ConfigExists(channelID) {
+		return newResolvedConfig(config, models.ConfigSourceGlobal), nil
+	}
+
+	resolved := newResolvedConfig(config, models.ConfigSourceChannel)
+	if config.InheritItem {
+		resolved.ItemSource = models.ConfigSourceGlobal
+	}
+	return resolved, nil
+}
+
+// CheckConfigExists reports whether the channel has a custom configuration, returning an error
+// when the store couldn't be checked
+// Stores that can't fail to check, like the in-memory store, never return an error
+func CheckConfigExists(store ChannelConfigStore, channelID string) (bool, error) {
+	if checker, ok := baseStore(store).(CheckedConfigExistsChecker); ok {
+		return checker.ConfigExistsChecked(channelID)
+	}
+	return store.ConfigExists(channelID), nil
+}
+
+// newResolvedConfig returns a resolved config whose item and price both came from source
+func newResolvedConfig(config *models.ChannelConfig, source string) *models.ResolvedConfig {
+	return &models.ResolvedConfig{
+		Config:      config,
+		ItemSource:  source,
+		PriceSource: source,
+	}
+}
+
+// WithContext ties the underlying store's calls to ctx, keeping the workspace view
+func (s *workspaceConfigStore) WithContext(ctx context.Context) ChannelConfigStore {
+	return ForWorkspace(WithContext(ctx, s.ChannelConfigStore), s.workspaceID)
+}
+
+// baseStore returns the store behind a workspace view, so optional interfaces can be checked on it
+func baseStore(store ChannelConfigStore) ChannelConfigStore {
+	if scoped, ok := store.(*workspaceConfigStore); ok {
+		return scoped.ChannelConfigStore
+	}
+	return store
+}
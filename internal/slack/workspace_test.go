@@ -0,0 +1,161 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/mcncl/snagbot/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForWorkspace_FallbackChain(t *testing.T) {
+	redisStore, _ := newTestRedisConfigStore(t)
+	stores := map[string]ChannelConfigStore{
+		"in-memory": NewInMemoryConfigStore(),
+		"redis":     redisStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			defaults := store.(WorkspaceDefaultsStore)
+			require.NoError(t, defaults.SaveWorkspaceDefault(&models.WorkspaceDefault{
+				WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00, UpdatedBy: "U12345",
+			}))
+			require.NoError(t, store.UpdateConfig("C11111", "pie", 6.00, "U12345"))
+
+			scoped := ForWorkspace(store, "T12345")
+
+			// A channel's own config comes first
+			config, err := scoped.GetConfig("C11111")
+			require.NoError(t, err)
+			assert.Equal(t, "pie", config.ItemName)
+			assert.Equal(t, 6.00, config.ItemPrice)
+
+			// Then the workspace default
+			config, err = scoped.GetConfig("C22222")
+			require.NoError(t, err)
+			assert.Equal(t, "coffee", config.ItemName)
+			assert.Equal(t, 5.00, config.ItemPrice)
+			assert.Equal(t, "T12345", config.WorkspaceID)
+
+			// Then the global default, for workspaces without one
+			config, err = ForWorkspace(store, "T67890").GetConfig("C22222")
+			require.NoError(t, err)
+			assert.Equal(t, "Bunnings snags", config.ItemName)
+			assert.Equal(t, 3.50, config.ItemPrice)
+
+			// Without a workspace the store is used as is
+			assert.Same(t, store, ForWorkspace(store, ""))
+		})
+	}
+}
+
+func TestForWorkspace_PartialOverride(t *testing.T) {
+	redisStore, _ := newTestRedisConfigStore(t)
+	stores := map[string]ChannelConfigStore{
+		"in-memory": NewInMemoryConfigStore(),
+		"redis":     redisStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			defaults := store.(WorkspaceDefaultsStore)
+			require.NoError(t, defaults.SaveWorkspaceDefault(&models.WorkspaceDefault{
+				WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00, UpdatedBy: "U12345",
+			}))
+
+			// The channel overrides the price only
+			override := models.NewChannelConfig("C11111")
+			override.ItemPrice = 4.00
+			override.InheritItem = true
+			require.NoError(t, store.SaveConfig(override))
+
+			scoped := ForWorkspace(store, "T12345")
+			resolved, err := ResolveConfig(scoped, "C11111")
+			require.NoError(t, err)
+			assert.Equal(t, "coffee", resolved.Config.ItemName)
+			assert.Equal(t, 4.00, resolved.Config.ItemPrice)
+			assert.Equal(t, models.ConfigSourceWorkspace, resolved.ItemSource)
+			assert.Equal(t, models.ConfigSourceChannel, resolved.PriceSource)
+
+			// Changing the template changes the inherited item, but not the channel's price
+			require.NoError(t, defaults.SaveWorkspaceDefault(&models.WorkspaceDefault{
+				WorkspaceID: "T12345", ItemName: "pie", ItemPrice: 6.00, UpdatedBy: "U12345",
+			}))
+			config, err := scoped.GetConfig("C11111")
+			require.NoError(t, err)
+			assert.Equal(t, "pie", config.ItemName)
+			assert.Equal(t, 4.00, config.ItemPrice)
+
+			// Without a template the item comes from the global default
+			resolved, err = ResolveConfig(ForWorkspace(store, "T67890"), "C11111")
+			require.NoError(t, err)
+			assert.Equal(t, "Bunnings snags", resolved.Config.ItemName)
+			assert.Equal(t, 4.00, resolved.Config.ItemPrice)
+			assert.Equal(t, models.ConfigSourceGlobal, resolved.ItemSource)
+			assert.Equal(t, models.ConfigSourceChannel, resolved.PriceSource)
+
+			// Setting the channel's own item stops the inheritance, even if it's the same item
+			require.NoError(t, store.UpdateConfig("C11111", "pie", 4.00, "U12345"))
+			require.NoError(t, defaults.SaveWorkspaceDefault(&models.WorkspaceDefault{
+				WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00, UpdatedBy: "U12345",
+			}))
+			config, err = scoped.GetConfig("C11111")
+			require.NoError(t, err)
+			assert.Equal(t, "pie", config.ItemName)
+			assert.False(t, config.InheritItem)
+		})
+	}
+}
+
+func TestWorkspaceDefaults_NotSet(t *testing.T) {
+	def, err := NewInMemoryConfigStore().GetWorkspaceDefault("T12345")
+	assert.NoError(t, err)
+	assert.Nil(t, def)
+
+	redisStore, _ := newTestRedisConfigStore(t)
+	def, err = redisStore.GetWorkspaceDefault("T12345")
+	assert.NoError(t, err)
+	assert.Nil(t, def)
+}
+
+func TestForWorkspace_RecordsStats(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	event := &MockMessageEvent{ChannelID: "C12345", UserID: "U12345", Text: "This costs $35", TS: "1234567890.123456"}
+
+	// Responses are counted on the underlying store when processing through a workspace view
+	require.NoError(t, ProcessMessageEvent(event.ToSlackEvent(), ForWorkspace(store, "T12345"), NewMockSlackAPI()))
+	assert.Equal(t, models.ChannelStats{Responses: 1, TotalDollars: 35}, store.GetStats("C12345"))
+}
+
+func TestResolveConfig_Sources(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	require.NoError(t, store.SaveWorkspaceDefault(&models.WorkspaceDefault{
+		WorkspaceID: "T12345", ItemName: "coffee", ItemPrice: 5.00, UpdatedBy: "U12345",
+	}))
+	require.NoError(t, store.UpdateConfig("C11111", "pie", 6.00, "U12345"))
+
+	tests := []struct {
+		name      string
+		store     ChannelConfigStore
+		channelID string
+		itemName  string
+		source    string
+	}{
+		{name: "Channel override", store: ForWorkspace(store, "T12345"), channelID: "C11111", itemName: "pie", source: models.ConfigSourceChannel},
+		{name: "Workspace default", store: ForWorkspace(store, "T12345"), channelID: "C22222", itemName: "coffee", source: models.ConfigSourceWorkspace},
+		{name: "Global default", store: ForWorkspace(store, "T67890"), channelID: "C22222", itemName: "Bunnings snags", source: models.ConfigSourceGlobal},
+		{name: "Channel override without a workspace", store: store, channelID: "C11111", itemName: "pie", source: models.ConfigSourceChannel},
+		{name: "Global default without a workspace", store: store, channelID: "C22222", itemName: "Bunnings snags", source: models.ConfigSourceGlobal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := ResolveConfig(test.store, test.channelID)
+			require.NoError(t, err)
+			assert.Equal(t, test.itemName, resolved.Config.ItemName)
+			assert.Equal(t, test.source, resolved.ItemSource)
+			assert.Equal(t, test.source, resolved.PriceSource)
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+	"unicode"
+)
 
 // ChannelConfig holds the custom configuration for a channel
 type ChannelConfig struct {
@@ -8,8 +12,247 @@ type ChannelConfig struct {
 	WorkspaceID string  `json:"workspace_id,omitempty"` // Optional - for multi-workspace support
 	ItemName    string  `json:"item_name"`
 	ItemPrice   float64 `json:"item_price"`
+
+	// Disabled silences SnagBot in the channel, keeping the rest of the config for when it's
+	// switched back on. Stored inverted so channels are enabled by default
+	Disabled bool `json:"disabled,omitempty"`
+
+	// CurrencySymbols overrides the symbols matched in messages (defaults to "$")
+	CurrencySymbols []string `json:"currency_symbols,omitempty"`
+
+	// Currency is the symbol prices are displayed with (defaults to "$")
+	Currency string `json:"currency,omitempty"`
+
+	// NumberFormat is how amounts in messages are written: "point" (default) like $1,234.50 or
+	// "comma" like $1.234,50
+	NumberFormat string `json:"number_format,omitempty"`
+
+	// RoundingMode controls how item counts are rounded: "up" (default), "down" or "nearest"
+	RoundingMode string `json:"rounding_mode,omitempty"`
+
+	// ResponseTemplate replaces the default response, e.g. "That's {count} {item}!"
+	ResponseTemplate string `json:"response_template,omitempty"`
+
+	// ZeroMessage replaces the response when the total wouldn't buy a single item, e.g. "Not even one {item}!"
+	ZeroMessage string `json:"zero_message,omitempty"`
+
+	// Locale picks the built-in phrasing of responses: "en-AU" (default), "en-US" or "en-GB"
+	Locale string `json:"locale,omitempty"`
+
+	// Phrases overrides parts of the locale's phrasing, with blank phrases keeping the locale's wording
+	Phrases *Phrases `json:"phrases,omitempty"`
+
+	// ResponseMode is how SnagBot responds: "message" (default) or "reaction"
+	ResponseMode string `json:"response_mode,omitempty"`
+
+	// Emoji is the name of the emoji SnagBot reacts with, without colons (defaults to "hotdog")
+	Emoji string `json:"emoji,omitempty"`
+
+	// ReplyPlacement is where message responses go: "thread" (default) or "channel"
+	ReplyPlacement string `json:"reply_placement,omitempty"`
+
+	// TriggerMode is when SnagBot responds: "always" (default), or "keyword" for only messages
+	// containing the trigger word
+	TriggerMode string `json:"trigger_mode,omitempty"`
+
+	// TriggerWord is the word that asks for a response in keyword mode (defaults to "snagify")
+	TriggerWord string `json:"trigger_word,omitempty"`
+
+	// Ephemeral makes message responses visible only to the person who posted the amounts
+	Ephemeral bool `json:"ephemeral,omitempty"`
+
+	// ChangeItemButton adds a "Change item" button to message responses
+	ChangeItemButton bool `json:"change_item_button,omitempty"`
+
+	// MinThreshold is the smallest total SnagBot will respond to (0 means no minimum)
+	MinThreshold float64 `json:"min_threshold,omitempty"`
+
+	// MaxDollarValues caps how many amounts are counted from one message (0 uses DefaultMaxDollarValues)
+	MaxDollarValues int `json:"max_dollar_values,omitempty"`
+
+	// MaxItemCount caps the count shown in a response, anything larger gets a "more than anyone could
+	// handle" reply instead (0 uses DefaultMaxItemCount)
+	MaxItemCount int `json:"max_item_count,omitempty"`
+
+	// TooManyValues is what happens to messages over the cap: "truncate" (default) or "skip"
+	TooManyValues string `json:"too_many_values,omitempty"`
+
+	// SumMode is how a message's amounts are converted: "total" (default) sums them all, and
+	// "lines" converts each line separately, for invoices pasted one cost per line
+	SumMode string `json:"sum_mode,omitempty"`
+
+	// NegativeHandling is how amounts like -$50 count: "include" (default), "ignore" or "absolute"
+	NegativeHandling string `json:"negative_handling,omitempty"`
+
+	// CountRepeats counts every occurrence of an amount, so "I paid $35 and you paid $35" adds up to
+	// $70, rather than only the first
+	CountRepeats bool `json:"count_repeats,omitempty"`
+
+	// WordAmounts also counts amounts spelled out in words, like "thirty-five dollars"
+	WordAmounts bool `json:"word_amounts,omitempty"`
+
+	// FilterFalseMatches skips zero amounts like "a $0 fee" and counts discounts like "$35 off" as negative
+	FilterFalseMatches bool `json:"filter_false_matches,omitempty"`
+
+	// ShowBreakdown shows how a message's amounts add up, like "That's $35 + $15 = $50, nearly 15 snags!"
+	ShowBreakdown bool `json:"show_breakdown,omitempty"`
+
+	// UnitName counts the item in larger units, e.g. "tank" for an item of "L" (litres of petrol)
+	UnitName string `json:"unit_name,omitempty"`
+
+	// UnitSize is how many of the item make up one unit, e.g. 60 for a 60 L tank
+	UnitSize float64 `json:"unit_size,omitempty"`
+
+	// InheritItem keeps following the workspace's default item, or the global default, after the
+	// channel set only its own price. ItemName holds the inherited item when the config is read
+	InheritItem bool `json:"inherit_item,omitempty"`
+
+	// Items are extra items picked at random alongside ItemName/ItemPrice
+	Items []ChannelItem `json:"items,omitempty"`
+
+	// RandomItems converts to a random item from ItemCatalog in each response, instead of
+	// the channel's own items
+	RandomItems bool `json:"random_items,omitempty"`
+
+	// Variety words each response with one of Variations, or DefaultVariations, picked at random
+	Variety bool `json:"variety,omitempty"`
+
+	// Variations replaces the built-in wordings Variety picks from, using only their prefix and suffix
+	Variations []Phrases `json:"variations,omitempty"`
 }
 
+// ChannelItem is an item and price a channel can convert dollar amounts to
+type ChannelItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+// MaxChannelItems caps how many extra items a channel can add
+const MaxChannelItems = 10
+
+// ItemCatalog is the built-in list of fun items channels in random mode convert to
+var ItemCatalog = []ChannelItem{
+	{Name: "flat whites", Price: 5.00},
+	{Name: "smashed avocados", Price: 22.00},
+	{Name: "parking hours", Price: 8.00},
+	{Name: "meat pies", Price: 6.50},
+	{Name: "sausage rolls", Price: 5.50},
+	{Name: "schooners", Price: 11.00},
+	{Name: "movie tickets", Price: 25.00},
+	{Name: "Freddo Frogs", Price: 1.50},
+	{Name: "bus fares", Price: 4.50},
+	{Name: "Bunnings snags", Price: 3.50},
+}
+
+// MaxVariations caps how many wordings a channel's variety pool can have
+const MaxVariations = 20
+
+// DefaultVariations are the built-in wordings channels with variety turned on pick from, around
+// a count like "nearly 10 Bunnings snags"
+var DefaultVariations = []Phrases{
+	{Prefix: "That's ", Suffix: "!"},
+	{Prefix: "Whoa, ", Suffix: "!"},
+	{Prefix: "That's ", Suffix: " worth!"},
+	{Prefix: "That'd get you ", Suffix: "!"},
+	{Prefix: "Hold up, that's ", Suffix: "!"},
+}
+
+// VariationPool returns the wordings the channel picks from when variety is on
+func (c *ChannelConfig) VariationPool() []Phrases {
+	if len(c.Variations) > 0 {
+		return c.Variations
+	}
+	return DefaultVariations
+}
+
+// Response modes for ChannelConfig.ResponseMode
+const (
+	ResponseModeMessage  = "message"
+	ResponseModeReaction = "reaction"
+)
+
+// Reply placements for ChannelConfig.ReplyPlacement
+const (
+	ReplyPlacementThread  = "thread"
+	ReplyPlacementChannel = "channel"
+)
+
+// Trigger modes for ChannelConfig.TriggerMode
+const (
+	TriggerModeAlways  = "always"
+	TriggerModeKeyword = "keyword"
+)
+
+// DefaultTriggerWord asks for a response in keyword mode when a channel hasn't chosen its own word
+const DefaultTriggerWord = "snagify"
+
+// Sum modes for ChannelConfig.SumMode
+const (
+	SumModeTotal   = "total"
+	SumModePerLine = "lines"
+)
+
+// Number formats for ChannelConfig.NumberFormat
+const (
+	NumberFormatPoint = "point"
+	NumberFormatComma = "comma"
+)
+
+// DefaultMaxDollarValues is how many amounts are counted from one message by default
+const DefaultMaxDollarValues = 50
+
+// DefaultMaxItemCount is the largest count shown in a response by default
+const DefaultMaxItemCount = 100000
+
+// Policies for ChannelConfig.TooManyValues
+const (
+	TooManyValuesTruncate = "truncate"
+	TooManyValuesSkip     = "skip"
+)
+
+// Phrases is the wording around the count in a response, like "That's " + "nearly " + "15 snags" + "!"
+type Phrases struct {
+	Prefix string `json:"prefix,omitempty"` // Before the count
+	Nearly string `json:"nearly,omitempty"` // Before counts that were rounded, like "nearly " or "about "
+	Suffix string `json:"suffix,omitempty"` // After the count
+	Zero   string `json:"zero,omitempty"`   // When the total wouldn't buy one, {item} is the singular item name
+}
+
+// Locales with built-in phrasing for ChannelConfig.Locale
+const (
+	LocaleAU = "en-AU"
+	LocaleUS = "en-US"
+	LocaleGB = "en-GB"
+)
+
+// DefaultLocale is the phrasing used when a channel hasn't chosen a locale
+const DefaultLocale = LocaleAU
+
+// LocalePhrases holds the built-in phrasing of each locale
+var LocalePhrases = map[string]Phrases{
+	LocaleAU: {Prefix: "That's ", Nearly: "nearly ", Suffix: "!", Zero: "That wouldn't even buy a single {item}!"},
+	LocaleUS: {Prefix: "That's ", Nearly: "about ", Suffix: "!", Zero: "That wouldn't even buy one {item}!"},
+	LocaleGB: {Prefix: "That's ", Nearly: "roughly ", Suffix: "!", Zero: "That wouldn't even stretch to a single {item}!"},
+}
+
+// NormalizeLocale returns the built-in locale matching name case-insensitively, like "en-us" or
+// "en_US" for "en-US", and false if there isn't one
+func NormalizeLocale(name string) (string, bool) {
+	name = strings.ReplaceAll(strings.TrimSpace(name), "_", "-")
+	for locale := range LocalePhrases {
+		if strings.EqualFold(locale, name) {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// DefaultCurrency is the currency symbol used when a channel hasn't set one
+const DefaultCurrency = "$"
+
+// DefaultEmoji is the emoji SnagBot reacts with when a channel hasn't set one
+const DefaultEmoji = "hotdog"
+
 // NewChannelConfig creates a new ChannelConfig with default values
 func NewChannelConfig(channelID string) *ChannelConfig {
 	return &ChannelConfig{
@@ -19,10 +262,243 @@ func NewChannelConfig(channelID string) *ChannelConfig {
 	}
 }
 
-// SetItem updates the item name and price
+// NormalizeItemName tidies an item name for responses, turning line breaks and other whitespace
+// into single spaces, removing control characters and trimming the ends
+func NormalizeItemName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// SetItem updates the item name and price, which the channel no longer inherits
+// Any unit belonged to the old item, so it is cleared
 func (c *ChannelConfig) SetItem(name string, price float64) {
 	c.ItemName = name
 	c.ItemPrice = price
+	c.InheritItem = false
+	c.UnitName = ""
+	c.UnitSize = 0
+}
+
+// Inherit fills in the item the channel inherits, if it only set its own price
+func (c *ChannelConfig) Inherit(itemName string) {
+	if c.InheritItem {
+		c.ItemName = itemName
+	}
+}
+
+// SetUnit counts the item in units of size, like tanks of 60 L
+func (c *ChannelConfig) SetUnit(name string, size float64) {
+	c.UnitName = name
+	c.UnitSize = size
+}
+
+// HasUnit reports whether the item is counted in larger units
+func (c *ChannelConfig) HasUnit() bool {
+	return c.UnitName != "" && c.UnitSize > 0
+}
+
+// UnitPrice returns the price of one of whatever responses count, the unit if there is one
+func (c *ChannelConfig) UnitPrice() float64 {
+	if c.HasUnit() {
+		return c.ItemPrice * c.UnitSize
+	}
+	return c.ItemPrice
+}
+
+// CountedName returns the name of whatever responses count, the unit if there is one
+func (c *ChannelConfig) CountedName() string {
+	if c.HasUnit() {
+		return c.UnitName
+	}
+	return c.ItemName
+}
+
+// SetCurrency sets the symbol prices are displayed with and recognised in messages
+func (c *ChannelConfig) SetCurrency(symbol string) {
+	c.Currency = symbol
+	c.CurrencySymbols = []string{symbol}
+}
+
+// CurrencySymbol returns the symbol prices are displayed with
+func (c *ChannelConfig) CurrencySymbol() string {
+	if c.Currency == "" {
+		return DefaultCurrency
+	}
+	return c.Currency
+}
+
+// ResponsePhrases returns the wording of the channel's responses, its locale's phrasing with any
+// phrases the channel has overridden
+// Unknown locales use the default locale's phrasing
+func (c *ChannelConfig) ResponsePhrases() Phrases {
+	phrases, ok := LocalePhrases[c.Locale]
+	if !ok {
+		phrases = LocalePhrases[DefaultLocale]
+	}
+
+	if c.Phrases != nil {
+		if c.Phrases.Prefix != "" {
+			phrases.Prefix = c.Phrases.Prefix
+		}
+		if c.Phrases.Nearly != "" {
+			phrases.Nearly = c.Phrases.Nearly
+		}
+		if c.Phrases.Suffix != "" {
+			phrases.Suffix = c.Phrases.Suffix
+		}
+		if c.Phrases.Zero != "" {
+			phrases.Zero = c.Phrases.Zero
+		}
+	}
+	return phrases
+}
+
+// EmojiName returns the name of the emoji SnagBot reacts with
+func (c *ChannelConfig) EmojiName() string {
+	if c.Emoji == "" {
+		return DefaultEmoji
+	}
+	return c.Emoji
+}
+
+// AddItem adds an extra item to pick from at random
+func (c *ChannelConfig) AddItem(name string, price float64) {
+	c.Items = append(c.Items, ChannelItem{Name: name, Price: price})
+}
+
+// AllItems returns the main item followed by any extra items
+func (c *ChannelConfig) AllItems() []ChannelItem {
+	items := make([]ChannelItem, 0, len(c.Items)+1)
+	items = append(items, ChannelItem{Name: c.ItemName, Price: c.ItemPrice})
+	return append(items, c.Items...)
+}
+
+// Enabled reports whether SnagBot responds in the channel
+func (c *ChannelConfig) Enabled() bool {
+	return !c.Disabled
+}
+
+// RespondsWithReaction reports whether the channel prefers emoji reactions over messages
+func (c *ChannelConfig) RespondsWithReaction() bool {
+	return c.ResponseMode == ResponseModeReaction
+}
+
+// ReplyInThread reports whether responses are posted in the message's thread rather than
+// as a new message in the channel
+func (c *ChannelConfig) ReplyInThread() bool {
+	return c.ReplyPlacement != ReplyPlacementChannel
+}
+
+// SumsPerLine reports whether each line of a message is converted separately rather than summed
+func (c *ChannelConfig) SumsPerLine() bool {
+	return c.SumMode == SumModePerLine
+}
+
+// TriggerKeyword returns the word that asks for a response in keyword mode
+func (c *ChannelConfig) TriggerKeyword() string {
+	if c.TriggerWord == "" {
+		return DefaultTriggerWord
+	}
+	return c.TriggerWord
+}
+
+// Triggered reports whether a message should get a response, which in keyword mode means it
+// contains the trigger word, in any case and not as part of a longer word
+func (c *ChannelConfig) Triggered(text string) bool {
+	if c.TriggerMode != TriggerModeKeyword {
+		return true
+	}
+
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if strings.EqualFold(word, c.TriggerKeyword()) {
+			return true
+		}
+	}
+	return false
+}
+
+// DecimalComma reports whether amounts in the channel's messages use a comma as the decimal separator
+func (c *ChannelConfig) DecimalComma() bool {
+	return c.NumberFormat == NumberFormatComma
+}
+
+// DollarValueLimit returns how many amounts are counted from one message
+func (c *ChannelConfig) DollarValueLimit() int {
+	if c.MaxDollarValues <= 0 {
+		return DefaultMaxDollarValues
+	}
+	return c.MaxDollarValues
+}
+
+// ItemCountLimit returns the largest count shown in a response
+func (c *ChannelConfig) ItemCountLimit() int {
+	if c.MaxItemCount <= 0 {
+		return DefaultMaxItemCount
+	}
+	return c.MaxItemCount
+}
+
+// SkipsTooManyValues reports whether messages with more amounts than the cap get no response,
+// rather than a response counting only the first amounts
+func (c *ChannelConfig) SkipsTooManyValues() bool {
+	return c.TooManyValues == TooManyValuesSkip
+}
+
+// BelowThreshold reports whether a total is too small for the channel to get a response
+func (c *ChannelConfig) BelowThreshold(total float64) bool {
+	return c.MinThreshold > 0 && total < c.MinThreshold
+}
+
+// ConfigChange records who changed a channel's item and price, and when
+type ConfigChange struct {
+	ChannelID    string    `json:"channel_id"`
+	UserID       string    `json:"user_id"`
+	ChangedAt    time.Time `json:"changed_at"`
+	Reset        bool      `json:"reset,omitempty"` // True when the channel was reset to the defaults
+	OldItemName  string    `json:"old_item_name"`
+	OldItemPrice float64   `json:"old_item_price"`
+	NewItemName  string    `json:"new_item_name"`
+	NewItemPrice float64   `json:"new_item_price"`
+}
+
+// ChannelStats counts SnagBot's responses in a channel
+type ChannelStats struct {
+	Responses    int     `json:"responses"`
+	TotalDollars float64 `json:"total_dollars"` // Sum of the totals SnagBot has converted
+}
+
+// WorkspaceDefault is the item a workspace's channels use until they choose their own
+type WorkspaceDefault struct {
+	WorkspaceID string    `json:"workspace_id"`
+	ItemName    string    `json:"item_name"`
+	ItemPrice   float64   `json:"item_price"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Where a channel's effective item and price come from, for ResolvedConfig
+const (
+	ConfigSourceChannel   = "channel"   // Set in the channel itself
+	ConfigSourceWorkspace = "workspace" // The workspace's default item
+	ConfigSourceGlobal    = "global"    // SnagBot's default item, from DEFAULT_ITEM_NAME and DEFAULT_ITEM_PRICE
+)
+
+// ResolvedConfig is a channel's effective config along with where its item and price came from
+type ResolvedConfig struct {
+	Config      *ChannelConfig
+	ItemSource  string
+	PriceSource string
 }
 
 // WorkspaceToken holds OAuth token data for a Slack workspace
@@ -1,13 +1,26 @@
 package integration
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/mcncl/snagbot/internal/api"
 	"github.com/mcncl/snagbot/internal/config"
+	"github.com/mcncl/snagbot/internal/logging"
+	"github.com/mcncl/snagbot/internal/slack"
+	"github.com/mcncl/snagbot/pkg/models"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,6 +53,84 @@ func TestHealthCheckEndpoint(t *testing.T) {
 	assert.Equal(t, "OK", response.Status)
 }
 
+// TestHealthCheckEndpoint_RedisUnreachable tests the health check reporting a failing Redis store
+func TestHealthCheckEndpoint_RedisUnreachable(t *testing.T) {
+	cfg := config.New()
+	redisServer := miniredis.RunT(t)
+	store, err := slack.NewRedisConfigStore("redis://"+redisServer.Addr(), cfg)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	// Healthy while Redis is up
+	resp, err := http.Get(server.URL + "/health")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Degraded once Redis goes away
+	redisServer.Close()
+
+	resp, err = http.Get(server.URL + "/health")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var response api.Response
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "degraded", response.Status)
+}
+
+// TestLivenessAndReadinessEndpoints tests liveness staying up while readiness follows the store
+func TestLivenessAndReadinessEndpoints(t *testing.T) {
+	cfg := config.New()
+	redisServer := miniredis.RunT(t)
+	store, err := slack.NewRedisConfigStore("redis://"+redisServer.Addr(), cfg)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	status := func(path string) (int, api.Response) {
+		resp, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var response api.Response
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+		return resp.StatusCode, response
+	}
+
+	// Both OK while Redis is up
+	code, response := status("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "OK", response.Status)
+
+	code, response = status("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "OK", response.Status)
+
+	// Only readiness fails once Redis goes away
+	redisServer.Close()
+
+	code, response = status("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "OK", response.Status)
+
+	code, response = status("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "degraded", response.Status)
+
+	// Probes only use GET
+	resp, err := http.Post(server.URL+"/healthz", "application/json", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
 // TestHelloWorldEndpoint tests the hello world endpoint
 func TestHelloWorldEndpoint(t *testing.T) {
 	// Create a test config
@@ -108,3 +199,419 @@ func TestMethodNotAllowed(t *testing.T) {
 	// Check response status code - should be 405 Method Not Allowed
 	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
 }
+
+// TestMetricsEndpoint tests that Prometheus metrics are exposed
+func TestMetricsEndpoint(t *testing.T) {
+	// Create a test config
+	cfg := config.New()
+
+	// Create handler with the simple router
+	handler := api.SetupSimpleRouter(cfg)
+
+	// Create a test server
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// Make a request to the metrics endpoint
+	resp, err := http.Get(server.URL + "/metrics")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Check response status code
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Check the SnagBot metrics are registered
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "snagbot_messages_processed_total")
+	assert.Contains(t, string(body), "snagbot_responses_sent_total")
+	assert.Contains(t, string(body), "snagbot_message_processing_seconds")
+}
+
+// TestPreviewEndpoint tests previewing a response using the channel's configuration
+func TestPreviewEndpoint(t *testing.T) {
+	// Create a test config and a store with a custom channel item and a workspace default
+	cfg := config.New()
+	cfg.AdminToken = "admin-secret"
+	store := slack.NewInMemoryConfigStore()
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+	assert.NoError(t, store.SaveWorkspaceDefault(&models.WorkspaceDefault{WorkspaceID: "T12345", ItemName: "pie", ItemPrice: 7.00}))
+
+	// Create handler sharing the store
+	handler := api.SetupRouterWithStore(cfg, store)
+
+	// Create a test server
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	post := func(token, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/preview", strings.NewReader(body))
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		status   int
+		expected string
+	}{
+		{name: "Custom channel", body: `{"channel_id": "C12345", "text": "This costs $35"}`, status: http.StatusOK, expected: "That's 7 coffees!"},
+		{name: "Default channel", body: `{"channel_id": "C67890", "text": "This costs $35"}`, status: http.StatusOK, expected: "That's 10 Bunnings snags!"},
+		{name: "Workspace default", body: `{"channel_id": "C67890", "team_id": "T12345", "text": "This costs $35"}`, status: http.StatusOK, expected: "That's 5 pies!"},
+		{name: "No dollar amount", body: `{"channel_id": "C12345", "text": "Hello"}`, status: http.StatusOK, expected: ""},
+		{name: "Missing channel", body: `{"text": "This costs $35"}`, status: http.StatusBadRequest},
+		{name: "Invalid JSON", body: `{`, status: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := post("admin-secret", test.body)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.status, resp.StatusCode)
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var response api.PreviewResponse
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, test.expected, response.Response)
+		})
+	}
+
+	// Missing or wrong tokens are rejected
+	for _, token := range []string{"", "wrong-secret"} {
+		resp := post(token, `{"channel_id": "C12345", "text": "This costs $35"}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Only POST is allowed
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/preview", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// Without an admin token configured the endpoint doesn't exist
+	cfg.AdminToken = ""
+	resp = post("admin-secret", `{"channel_id": "C12345", "text": "This costs $35"}`)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestAdminConfigsEndpoint tests exporting every channel configuration
+func TestAdminConfigsEndpoint(t *testing.T) {
+	cfg := config.New()
+	cfg.AdminToken = "admin-secret"
+	store := slack.NewInMemoryConfigStore()
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	get := func(token string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/admin/configs", nil)
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	resp := get("admin-secret")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var response api.ConfigExportResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	if assert.Contains(t, response.Configs, "C12345") {
+		assert.Equal(t, "coffee", response.Configs["C12345"].ItemName)
+	}
+
+	// Missing or wrong tokens are rejected
+	for _, token := range []string{"", "wrong-secret"} {
+		resp := get(token)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Without an admin token configured the endpoint doesn't exist
+	cfg.AdminToken = ""
+	resp = get("admin-secret")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// TestAdminResetEndpoint tests resetting a channel's configuration
+func TestAdminResetEndpoint(t *testing.T) {
+	cfg := config.New()
+	cfg.AdminToken = "admin-secret"
+	store := slack.NewInMemoryConfigStoreWithConfig(cfg)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	post := func(token, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/admin/reset", strings.NewReader(body))
+		assert.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		// Missing or wrong tokens are rejected and the channel is left alone
+		for _, token := range []string{"", "wrong-secret"} {
+			resp := post(token, `{"channel_id": "C12345"}`)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		}
+		assert.True(t, store.ConfigExists("C12345"))
+	})
+
+	t.Run("Authorized", func(t *testing.T) {
+		resp := post("admin-secret", `{"channel_id": "C12345"}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var config models.ChannelConfig
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&config))
+		assert.Equal(t, "C12345", config.ChannelID)
+		assert.Equal(t, cfg.DefaultItemName, config.ItemName)
+		assert.Equal(t, cfg.DefaultItemPrice, config.ItemPrice)
+		assert.False(t, store.ConfigExists("C12345"))
+	})
+
+	t.Run("Missing channel", func(t *testing.T) {
+		resp := post("admin-secret", `{}`)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+// TestAdminToggleEndpoint tests switching SnagBot's responses off and on at runtime
+func TestAdminToggleEndpoint(t *testing.T) {
+	cfg := config.New()
+	cfg.AdminToken = "admin-secret"
+	store := slack.NewInMemoryConfigStoreWithConfig(cfg)
+	slack.SetGlobalConfigStore(store)
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	toggle := func(token, body string) (int, bool) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/admin/toggle", strings.NewReader(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var toggled api.ToggleResponse
+		if resp.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&toggled))
+		}
+		return resp.StatusCode, toggled.Enabled
+	}
+
+	// Only admins can flip the switch
+	status, _ := toggle("wrong-secret", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// An empty body flips it, starting from SNAGBOT_ENABLED
+	status, enabled := toggle("admin-secret", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, enabled)
+
+	status, enabled = toggle("admin-secret", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, enabled)
+
+	// It can also be set explicitly
+	status, enabled = toggle("admin-secret", `{"enabled": false}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, enabled)
+
+	status, _ = toggle("admin-secret", `{"enabled": "maybe"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// Each router has its own switch, so other routers keep responding
+	other := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer other.Close()
+	req, err := http.NewRequest(http.MethodPost, other.URL+"/api/admin/toggle", strings.NewReader(""))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	var toggled api.ToggleResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&toggled))
+	assert.False(t, toggled.Enabled)
+}
+
+// TestAdminReloadEndpoint tests reloading the default item and log level from the environment
+func TestAdminReloadEndpoint(t *testing.T) {
+	t.Cleanup(func() { logging.SetGlobalLevel(logging.INFO) })
+
+	t.Setenv("DEFAULT_ITEM_NAME", "")
+	t.Setenv("DEFAULT_ITEM_PRICE", "")
+	t.Setenv("LOG_LEVEL", "")
+	cfg := config.New()
+	cfg.AdminToken = "admin-secret"
+	store := slack.NewInMemoryConfigStoreWithConfig(cfg)
+	slack.SetGlobalConfigStore(store)
+	assert.NoError(t, store.UpdateConfig("C99999", "coffee", 5.00, "U12345"))
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	reload := func(token string) (int, api.ReloadResponse) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/api/admin/reload", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		var reloaded api.ReloadResponse
+		if resp.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&reloaded))
+		}
+		return resp.StatusCode, reloaded
+	}
+
+	response, err := HandleMockMessage("C12345", "This costs $35")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "10 Bunnings Snags")
+
+	t.Setenv("DEFAULT_ITEM_NAME", "flat whites")
+	t.Setenv("DEFAULT_ITEM_PRICE", "5.00")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	// Only admins can reload
+	status, _ := reload("wrong-secret")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	name, _ := cfg.DefaultItem()
+	assert.Equal(t, config.DefaultItemName, name)
+
+	status, reloaded := reload("admin-secret")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, api.ReloadResponse{DefaultItemName: "flat whites", DefaultItemPrice: 5.00, LogLevel: "debug"}, reloaded)
+	assert.Equal(t, logging.DEBUG, logging.GlobalLevel())
+
+	// Channels on the default see the new item straight away, custom ones keep theirs
+	response, err = HandleMockMessage("C12345", "This costs $35")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "7 flat whites")
+
+	channelConfig, err := store.GetConfig("C99999")
+	assert.NoError(t, err)
+	assert.Equal(t, "coffee", channelConfig.ItemName)
+}
+
+// TestAdminJWT tests the admin endpoints with JWTs signed with the JWT secret
+func TestAdminJWT(t *testing.T) {
+	cfg := config.New()
+	cfg.AdminToken = ""
+	cfg.JWTSecret = "jwt-test-secret"
+	store := slack.NewInMemoryConfigStoreWithConfig(cfg)
+	assert.NoError(t, store.UpdateConfig("C12345", "coffee", 5.00, "U12345"))
+
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, store))
+	defer server.Close()
+
+	get := func(token string) int {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/admin/configs", nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	valid, err := api.GenerateAdminToken(cfg.JWTSecret, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, api.ValidateAdminToken(cfg.JWTSecret, valid))
+
+	expired, err := api.GenerateAdminToken(cfg.JWTSecret, -time.Minute)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, api.ValidateAdminToken(cfg.JWTSecret, expired), api.ErrTokenExpired)
+
+	// Swapping in claims with a later expiry breaks the signature
+	parts := strings.Split(expired, ".")
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"snagbot-admin","exp":%d}`, time.Now().Add(time.Hour).Unix())))
+	tampered := parts[0] + "." + claims + "." + parts[2]
+	assert.ErrorIs(t, api.ValidateAdminToken(cfg.JWTSecret, tampered), api.ErrInvalidTokenSignature)
+
+	otherSecret, err := api.GenerateAdminToken("some-other-secret", time.Hour)
+	assert.NoError(t, err)
+
+	// An unsigned token can't skip the signature check
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + claims + "."
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "Valid", token: valid, status: http.StatusOK},
+		{name: "Expired", token: expired, status: http.StatusUnauthorized},
+		{name: "Tampered", token: tampered, status: http.StatusUnauthorized},
+		{name: "Signed with another secret", token: otherSecret, status: http.StatusUnauthorized},
+		{name: "Unsigned", token: unsigned, status: http.StatusUnauthorized},
+		{name: "Not a JWT", token: "admin-secret", status: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.status, get(test.token))
+		})
+	}
+
+	// Tokens signed with the placeholder secret are never trusted
+	cfg.JWTSecret = config.DefaultJWTSecret
+	placeholder, err := api.GenerateAdminToken(config.DefaultJWTSecret, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, get(placeholder))
+}
+
+// TestCommandEndpoint_OversizedBody tests that slash command requests over the body size limit are refused
+func TestCommandEndpoint_OversizedBody(t *testing.T) {
+	cfg := config.New()
+	cfg.SlackSigningSecret = "test-secret"
+	cfg.MaxRequestBodySize = 1024
+	server := httptest.NewServer(api.SetupRouterWithStore(cfg, slack.NewInMemoryConfigStoreWithConfig(cfg)))
+	defer server.Close()
+
+	body := "command=/snagbot&channel_id=C12345&text=" + strings.Repeat("a", 2048)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(cfg.SlackSigningSecret))
+	fmt.Fprintf(mac, "v0:%s:%s", timestamp, body)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/api/commands", strings.NewReader(body))
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
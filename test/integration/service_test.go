@@ -41,7 +41,7 @@ func TestChannelConfigWithMessageHandling(t *testing.T) {
 			name:      "Custom config with dollar value",
 			channelID: "C67890",
 			setupFunc: func() {
-				configStore.UpdateConfig("C67890", "coffee", 5.00)
+				configStore.UpdateConfig("C67890", "coffee", 5.00, "U12345")
 			},
 			messageText:     "This costs $35",
 			expectedMessage: "That's 7 coffees!",
@@ -52,9 +52,9 @@ func TestChannelConfigWithMessageHandling(t *testing.T) {
 			channelID: "C13579",
 			setupFunc: func() {
 				// First set a custom config
-				configStore.UpdateConfig("C13579", "donut", 2.00)
+				configStore.UpdateConfig("C13579", "donut", 2.00, "U12345")
 				// Then reset it to default
-				configStore.ResetConfig("C13579")
+				configStore.ResetConfig("C13579", "U12345")
 			},
 			messageText:     "This costs $35",
 			expectedMessage: "That's 10 Bunnings snags!",
@@ -64,7 +64,7 @@ func TestChannelConfigWithMessageHandling(t *testing.T) {
 			name:      "Message with multiple dollar values and custom config",
 			channelID: "C24680",
 			setupFunc: func() {
-				configStore.UpdateConfig("C24680", "cookie", 1.50)
+				configStore.UpdateConfig("C24680", "cookie", 1.50, "U12345")
 			},
 			messageText:     "This costs $20 and that costs $15",
 			expectedMessage: "That's nearly 24 cookies!",